@@ -17,9 +17,13 @@ import (
 	dbdriver "github.com/WebDeveloperBen/ai-gateway/internal/drivers/db"
 	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/async"
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/batch"
 	gwmiddleware "github.com/WebDeveloperBen/ai-gateway/internal/gateway/middleware"
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/pricing"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/scheduling"
 	"github.com/WebDeveloperBen/ai-gateway/internal/model"
 
 	adminappconfigs "github.com/WebDeveloperBen/ai-gateway/internal/api/admin/application_configs"
@@ -27,13 +31,16 @@ import (
 	"github.com/WebDeveloperBen/ai-gateway/internal/api/admin/catalog"
 	"github.com/WebDeveloperBen/ai-gateway/internal/api/admin/keys"
 	adminpolicies "github.com/WebDeveloperBen/ai-gateway/internal/api/admin/policies"
+	adminpricing "github.com/WebDeveloperBen/ai-gateway/internal/api/admin/pricing"
 	adminusage "github.com/WebDeveloperBen/ai-gateway/internal/api/admin/usage"
 	appconfigrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/application_configs"
 	apprepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/applications"
+	batchrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/batches"
 	catalogrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/catalog"
 	keyrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/keys"
 	orgrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/organisations"
 	policiesrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/policies"
+	pricingrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/pricing"
 	usagerepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/usage"
 	userrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/users"
 	"github.com/WebDeveloperBen/ai-gateway/internal/server"
@@ -98,9 +105,11 @@ func main() {
 	}
 	appRepo := apprepo.NewPostgresRepo(pg.Queries)
 	appConfigRepo := appconfigrepo.NewPostgresRepo(pg.Queries)
+	batchRepo := batchrepo.NewPostgresRepo(pg.Pool)
 	catalogRepo := catalogrepo.NewPostgresRepo(pg.Queries)
 	orgRepo := orgrepo.NewPostgresRepo(pg.Queries)
 	policiesRepo := policiesrepo.NewPostgresRepo(pg.Queries)
+	pricingRepo := pricingrepo.NewPostgresRepo(pg.Queries)
 	usageRepo := usagerepo.NewPostgresRepo(pg.Queries)
 	userRepo := userrepo.NewPostgresRepo(pg.Queries)
 
@@ -128,8 +137,17 @@ func main() {
 
 	// ---------- Policy Engine -------- //
 	policyEngine := policies.NewEngine(pg.Queries, kvStore)
+	if cfg.AlertWebhookURL != "" {
+		policyEngine.SetAlertSink(policies.NewWebhookAlertSink(cfg.AlertWebhookURL, cfg.AlertWebhookSecret))
+	}
+	batchTracker := gwmiddleware.NewBatchTracker(batchRepo)
 	requestBuffer := gwmiddleware.NewRequestBuffer()
 	policyEnforcer := gwmiddleware.NewPolicyEnforcer(policyEngine)
+	priorityScheduler := gwmiddleware.NewPriorityScheduler(
+		scheduling.NewScheduler(len(policies.PriorityTiers), cfg.SchedulerMaxInFlight, cfg.SchedulerCooldown),
+		policyEngine,
+	)
+	responseGuard := gwmiddleware.NewResponseGuard()
 	usageRecorder := gwmiddleware.NewUsageRecorder(pg.Queries, policyEngine)
 
 	// ------------- Services ------------ //
@@ -138,7 +156,8 @@ func main() {
 	appsSvc := applications.NewService(appRepo)
 	appConfigsSvc := adminappconfigs.NewService(appConfigRepo)
 	catalogSvc := catalog.NewService(catalogRepo)
-	policiesSvc := adminpolicies.NewService(policiesRepo)
+	policiesSvc := adminpolicies.NewService(policiesRepo, gwmiddleware.NewPolicySimulator(policyEngine), policyEngine)
+	pricingSvc := adminpricing.NewService(pricingRepo)
 	usageSvc := adminusage.NewService(usageRepo)
 
 	// ----------- API Router Setup ---------- //
@@ -172,19 +191,41 @@ func main() {
 	adminappconfigs.NewRouter(appConfigsSvc).RegisterRoutes(admingrp)
 	catalog.NewRouter(catalogSvc).RegisterRoutes(admingrp)
 	adminpolicies.NewRouter(policiesSvc).RegisterRoutes(admingrp)
+	adminpricing.NewRouter(pricingSvc).RegisterRoutes(admingrp)
 	adminusage.NewRouter(usageSvc).RegisterRoutes(admingrp)
 
 	// ------------ Gateway Proxy Setup ----------- //
 	transport := gateway.Chain(
 		http.DefaultTransport,
 		gateway.WithAuth(authn),
-		requestBuffer.Middleware,  // Buffer request body once
-		policyEnforcer.Middleware, // Policy enforcement (pre-check)
-		usageRecorder.Middleware,  // Usage recording (post-check, async)
+		batchTracker.Middleware,      // Files/Batch API ownership
+		requestBuffer.Middleware,     // Buffer request body once
+		policyEnforcer.Middleware,    // Policy enforcement (pre-check)
+		priorityScheduler.Middleware, // Priority admission to saturated deployments
+		responseGuard.Middleware,     // Response guardrails (redact, refuse or abort)
+		usageRecorder.Middleware,     // Usage recording (post-check, async)
 		// TODO: add the load balancer
 	)
 	core := gateway.NewCoreWithRegistry(transport, authn, reg)
 
+	// Poll upstream batches and record usage once they complete
+	batchPoller := batch.NewPoller(batchRepo, keyRepo, pricing.NewCalculator(pricingRepo, pricing.DefaultCacheTTL), core.Adapters, http.DefaultTransport, cfg.BatchPollInterval)
+	go batchPoller.Run(ctx)
+
+	// Execute "Prefer: respond-async" requests in the background. Results are
+	// POSTed to caller-supplied URLs, so deliveries must be signed.
+	if cfg.AsyncWebhookSecret != "" {
+		core.Async = async.NewQueue(kvStore, cfg.AsyncJobRetention, async.DefaultLeaseTTL)
+		asyncPool := async.NewPool(core.Async, core, async.Options{
+			Workers:       cfg.AsyncWorkers,
+			MaxAttempts:   cfg.AsyncMaxAttempts,
+			WebhookSecret: cfg.AsyncWebhookSecret,
+		})
+		go asyncPool.Run(ctx)
+	} else {
+		log.Println("ASYNC_WEBHOOK_SECRET is not set; async requests are disabled")
+	}
+
 	// ------------ AI Providers ----------- //
 	// Register all supported providers under /api/providers
 	apigw.RegisterAllProviders(providersgrp, core)
@@ -0,0 +1,62 @@
+-- +goose Up
+-- create "batch_files" table
+CREATE TABLE "public"."batch_files" (
+  "id" uuid NOT NULL DEFAULT public.uuid_generate_v4(),
+  "org_id" uuid NOT NULL DEFAULT public.app_current_org(),
+  "app_id" uuid NOT NULL,
+  "key_prefix" text NOT NULL,
+  "provider" text NOT NULL,
+  "file_id" text NOT NULL,
+  "purpose" text NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "batch_files_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."applications" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "batch_files_org_id_fkey" FOREIGN KEY ("org_id") REFERENCES "public"."organisations" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- create index "idx_batch_files_app" to table: "batch_files"
+CREATE INDEX "idx_batch_files_app" ON "public"."batch_files" ("app_id");
+-- create index "idx_batch_files_provider_file" to table: "batch_files"
+CREATE UNIQUE INDEX "idx_batch_files_provider_file" ON "public"."batch_files" ("provider", "file_id");
+-- create "batch_jobs" table
+CREATE TABLE "public"."batch_jobs" (
+  "id" uuid NOT NULL DEFAULT public.uuid_generate_v4(),
+  "org_id" uuid NOT NULL DEFAULT public.app_current_org(),
+  "app_id" uuid NOT NULL,
+  "key_prefix" text NOT NULL,
+  "provider" text NOT NULL,
+  "batch_id" text NOT NULL,
+  "endpoint" text NOT NULL DEFAULT '',
+  "status" text NOT NULL,
+  "input_file_id" text NOT NULL,
+  "output_file_id" text NULL,
+  "error_file_id" text NULL,
+  "usage_recorded" boolean NOT NULL DEFAULT false,
+  "completed_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "batch_jobs_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."applications" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "batch_jobs_org_id_fkey" FOREIGN KEY ("org_id") REFERENCES "public"."organisations" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- create index "idx_batch_jobs_app" to table: "batch_jobs"
+CREATE INDEX "idx_batch_jobs_app" ON "public"."batch_jobs" ("app_id");
+-- create index "idx_batch_jobs_pending" to table: "batch_jobs"
+CREATE INDEX "idx_batch_jobs_pending" ON "public"."batch_jobs" ("usage_recorded", "created_at");
+-- create index "idx_batch_jobs_provider_batch" to table: "batch_jobs"
+CREATE UNIQUE INDEX "idx_batch_jobs_provider_batch" ON "public"."batch_jobs" ("provider", "batch_id");
+
+-- +goose Down
+-- reverse: create index "idx_batch_jobs_provider_batch" to table: "batch_jobs"
+DROP INDEX "public"."idx_batch_jobs_provider_batch";
+-- reverse: create index "idx_batch_jobs_pending" to table: "batch_jobs"
+DROP INDEX "public"."idx_batch_jobs_pending";
+-- reverse: create index "idx_batch_jobs_app" to table: "batch_jobs"
+DROP INDEX "public"."idx_batch_jobs_app";
+-- reverse: create "batch_jobs" table
+DROP TABLE "public"."batch_jobs";
+-- reverse: create index "idx_batch_files_provider_file" to table: "batch_files"
+DROP INDEX "public"."idx_batch_files_provider_file";
+-- reverse: create index "idx_batch_files_app" to table: "batch_files"
+DROP INDEX "public"."idx_batch_files_app";
+-- reverse: create "batch_files" table
+DROP TABLE "public"."batch_files";
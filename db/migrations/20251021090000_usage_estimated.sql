@@ -0,0 +1,7 @@
+-- +goose Up
+-- modify "usage_metrics" table
+ALTER TABLE "public"."usage_metrics" ADD COLUMN "estimated" boolean NOT NULL DEFAULT false;
+
+-- +goose Down
+-- reverse: modify "usage_metrics" table
+ALTER TABLE "public"."usage_metrics" DROP COLUMN "estimated";
@@ -0,0 +1,31 @@
+-- +goose Up
+-- create "model_prices" table
+CREATE TABLE "public"."model_prices" (
+  "id" uuid NOT NULL DEFAULT public.uuid_generate_v4(),
+  "org_id" uuid NOT NULL DEFAULT public.app_current_org(),
+  "provider" text NOT NULL,
+  "model_name" text NOT NULL,
+  "deployment_type" text NOT NULL DEFAULT 'standard',
+  "currency" text NOT NULL DEFAULT 'USD',
+  "input_per_million" double precision NOT NULL DEFAULT 0,
+  "cached_input_per_million" double precision NOT NULL DEFAULT 0,
+  "output_per_million" double precision NOT NULL DEFAULT 0,
+  "per_image" double precision NOT NULL DEFAULT 0,
+  "per_audio_second" double precision NOT NULL DEFAULT 0,
+  "effective_from" timestamptz NOT NULL DEFAULT now(),
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "model_prices_org_id_fkey" FOREIGN KEY ("org_id") REFERENCES "public"."organisations" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- create index "idx_model_prices_version" to table: "model_prices"
+CREATE UNIQUE INDEX "idx_model_prices_version" ON "public"."model_prices" ("org_id", "provider", "model_name", "deployment_type", "effective_from");
+-- modify "usage_metrics" table
+ALTER TABLE "public"."usage_metrics" ADD COLUMN "cost_micros" bigint NULL, ADD COLUMN "currency" text NULL;
+
+-- +goose Down
+-- reverse: modify "usage_metrics" table
+ALTER TABLE "public"."usage_metrics" DROP COLUMN "currency", DROP COLUMN "cost_micros";
+-- reverse: create index "idx_model_prices_version" to table: "model_prices"
+DROP INDEX "public"."idx_model_prices_version";
+-- reverse: create "model_prices" table
+DROP TABLE "public"."model_prices";
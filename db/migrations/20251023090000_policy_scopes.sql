@@ -0,0 +1,48 @@
+-- +goose Up
+-- create "policy_organisations" table
+CREATE TABLE "public"."policy_organisations" (
+  "policy_id" uuid NOT NULL,
+  "org_id" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("policy_id", "org_id"),
+  CONSTRAINT "policy_organisations_org_id_fkey" FOREIGN KEY ("org_id") REFERENCES "public"."organisations" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "policy_organisations_policy_id_fkey" FOREIGN KEY ("policy_id") REFERENCES "public"."policies" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- create index "idx_policy_organisations_org" to table: "policy_organisations"
+CREATE INDEX "idx_policy_organisations_org" ON "public"."policy_organisations" ("org_id");
+-- create "policy_users" table
+CREATE TABLE "public"."policy_users" (
+  "policy_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("policy_id", "user_id"),
+  CONSTRAINT "policy_users_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "policy_users_policy_id_fkey" FOREIGN KEY ("policy_id") REFERENCES "public"."policies" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- create index "idx_policy_users_user" to table: "policy_users"
+CREATE INDEX "idx_policy_users_user" ON "public"."policy_users" ("user_id");
+-- create "policy_api_keys" table
+CREATE TABLE "public"."policy_api_keys" (
+  "policy_id" uuid NOT NULL,
+  "key_id" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("policy_id", "key_id"),
+  CONSTRAINT "policy_api_keys_key_id_fkey" FOREIGN KEY ("key_id") REFERENCES "public"."api_keys" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "policy_api_keys_policy_id_fkey" FOREIGN KEY ("policy_id") REFERENCES "public"."policies" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- create index "idx_policy_api_keys_key" to table: "policy_api_keys"
+CREATE INDEX "idx_policy_api_keys_key" ON "public"."policy_api_keys" ("key_id");
+
+-- +goose Down
+-- reverse: create index "idx_policy_api_keys_key" to table: "policy_api_keys"
+DROP INDEX "public"."idx_policy_api_keys_key";
+-- reverse: create "policy_api_keys" table
+DROP TABLE "public"."policy_api_keys";
+-- reverse: create index "idx_policy_users_user" to table: "policy_users"
+DROP INDEX "public"."idx_policy_users_user";
+-- reverse: create "policy_users" table
+DROP TABLE "public"."policy_users";
+-- reverse: create index "idx_policy_organisations_org" to table: "policy_organisations"
+DROP INDEX "public"."idx_policy_organisations_org";
+-- reverse: create "policy_organisations" table
+DROP TABLE "public"."policy_organisations";
@@ -0,0 +1,7 @@
+-- +goose Up
+-- modify "usage_metrics" table
+ALTER TABLE "public"."usage_metrics" ADD COLUMN "metadata" jsonb NOT NULL DEFAULT '{}';
+
+-- +goose Down
+-- reverse: modify "usage_metrics" table
+ALTER TABLE "public"."usage_metrics" DROP COLUMN "metadata";
@@ -0,0 +1,7 @@
+-- +goose Up
+-- modify "policies" table
+ALTER TABLE "public"."policies" ADD COLUMN "mode" text NOT NULL DEFAULT 'enforce';
+
+-- +goose Down
+-- reverse: modify "policies" table
+ALTER TABLE "public"."policies" DROP COLUMN "mode";
@@ -0,0 +1,15 @@
+-- +goose Up
+-- modify "batch_jobs" table
+ALTER TABLE "public"."batch_jobs" ADD COLUMN "poll_failures" integer NOT NULL DEFAULT 0, ADD COLUMN "next_poll_at" timestamptz NOT NULL DEFAULT now(), ADD COLUMN "poll_error" text NULL;
+-- drop index "idx_batch_jobs_pending" from table: "batch_jobs"
+DROP INDEX "public"."idx_batch_jobs_pending";
+-- create index "idx_batch_jobs_pending" to table: "batch_jobs"
+CREATE INDEX "idx_batch_jobs_pending" ON "public"."batch_jobs" ("usage_recorded", "next_poll_at");
+
+-- +goose Down
+-- reverse: create index "idx_batch_jobs_pending" to table: "batch_jobs"
+DROP INDEX "public"."idx_batch_jobs_pending";
+-- reverse: drop index "idx_batch_jobs_pending" from table: "batch_jobs"
+CREATE INDEX "idx_batch_jobs_pending" ON "public"."batch_jobs" ("usage_recorded", "created_at");
+-- reverse: modify "batch_jobs" table
+ALTER TABLE "public"."batch_jobs" DROP COLUMN "poll_error", DROP COLUMN "next_poll_at", DROP COLUMN "poll_failures";
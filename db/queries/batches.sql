@@ -0,0 +1,55 @@
+-- name: CreateBatchFile :exec
+INSERT INTO batch_files (
+  org_id, app_id, key_prefix, provider, file_id, purpose
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (provider, file_id) DO NOTHING;
+
+-- name: GetBatchFile :one
+SELECT * FROM batch_files
+WHERE provider = $1 AND file_id = $2 LIMIT 1;
+
+-- name: CreateBatchJob :one
+INSERT INTO batch_jobs (
+  org_id, app_id, key_prefix, provider, batch_id, endpoint, status, input_file_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+)
+RETURNING *;
+
+-- name: GetBatchJob :one
+SELECT * FROM batch_jobs
+WHERE provider = $1 AND batch_id = $2 LIMIT 1;
+
+-- name: UpdateBatchJobStatus :exec
+UPDATE batch_jobs
+SET status = $2,
+    output_file_id = $3,
+    error_file_id = $4,
+    completed_at = $5,
+    updated_at = now()
+WHERE id = $1;
+
+-- name: MarkBatchJobUsageRecorded :execrows
+UPDATE batch_jobs
+SET usage_recorded = true,
+    updated_at = now()
+WHERE id = $1 AND usage_recorded = false;
+
+-- name: SetBatchJobPollState :exec
+UPDATE batch_jobs
+SET poll_failures = $2,
+    next_poll_at = $3,
+    poll_error = $4,
+    updated_at = now()
+WHERE id = $1;
+
+-- name: ListPendingBatchJobs :many
+-- Jobs that have failed max_failures polls in a row are left for an operator
+SELECT * FROM batch_jobs
+WHERE usage_recorded = false
+  AND poll_failures < @max_failures
+  AND next_poll_at <= now()
+ORDER BY next_poll_at
+LIMIT @row_limit;
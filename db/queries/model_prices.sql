@@ -0,0 +1,35 @@
+-- name: CreateModelPrice :one
+INSERT INTO model_prices (
+  org_id, provider, model_name, deployment_type, currency,
+  input_per_million, cached_input_per_million, output_per_million,
+  per_image, per_audio_second, effective_from
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+RETURNING *;
+
+-- name: GetModelPrice :one
+SELECT * FROM model_prices
+WHERE id = $1 LIMIT 1;
+
+-- name: ListModelPrices :many
+SELECT * FROM model_prices
+WHERE org_id = $1
+ORDER BY provider, model_name, deployment_type, effective_from DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetEffectiveModelPrice :one
+-- Picks the latest version in force at the given time, preferring an exact
+-- deployment type match over the 'standard' fallback.
+SELECT * FROM model_prices
+WHERE org_id = $1
+  AND provider = $2
+  AND model_name = $3
+  AND deployment_type IN ($4, 'standard')
+  AND effective_from <= $5
+ORDER BY (deployment_type = $4) DESC, effective_from DESC
+LIMIT 1;
+
+-- name: DeleteModelPrice :exec
+DELETE FROM model_prices
+WHERE id = $1;
@@ -24,9 +24,9 @@ ORDER BY p.created_at;
 
 -- name: CreatePolicy :one
 INSERT INTO policies (
-  org_id, policy_type, config, enabled
+  org_id, policy_type, config, enabled, mode
 ) VALUES (
-  $1, $2, $3, $4
+  $1, $2, $3, $4, $5
 )
 RETURNING *;
 
@@ -35,6 +35,7 @@ UPDATE policies
 SET policy_type = $2,
     config = $3,
     enabled = $4,
+    mode = $5,
     updated_at = now()
 WHERE id = $1
 RETURNING *;
@@ -75,3 +76,67 @@ SELECT p.* FROM policies p
 JOIN policy_applications pa ON p.id = pa.policy_id
 WHERE pa.app_id = $1
 ORDER BY p.policy_type;
+
+-- name: AttachPolicyToOrg :exec
+INSERT INTO policy_organisations (policy_id, org_id)
+VALUES ($1, $2)
+ON CONFLICT (policy_id, org_id) DO NOTHING;
+
+-- name: DetachPolicyFromOrg :exec
+DELETE FROM policy_organisations
+WHERE policy_id = $1 AND org_id = $2;
+
+-- name: AttachPolicyToUser :exec
+INSERT INTO policy_users (policy_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (policy_id, user_id) DO NOTHING;
+
+-- name: DetachPolicyFromUser :exec
+DELETE FROM policy_users
+WHERE policy_id = $1 AND user_id = $2;
+
+-- name: AttachPolicyToAPIKey :exec
+INSERT INTO policy_api_keys (policy_id, key_id)
+VALUES ($1, $2)
+ON CONFLICT (policy_id, key_id) DO NOTHING;
+
+-- name: DetachPolicyFromAPIKey :exec
+DELETE FROM policy_api_keys
+WHERE policy_id = $1 AND key_id = $2;
+
+-- name: ListPolicyAttachments :many
+-- Every org, app, user and API key a policy is attached to
+SELECT 'org'::text AS scope, po.org_id AS target_id FROM policy_organisations po WHERE po.policy_id = $1
+UNION ALL
+SELECT 'app'::text AS scope, pa.app_id AS target_id FROM policy_applications pa WHERE pa.policy_id = $1
+UNION ALL
+SELECT 'user'::text AS scope, pu.user_id AS target_id FROM policy_users pu WHERE pu.policy_id = $1
+UNION ALL
+SELECT 'key'::text AS scope, pk.key_id AS target_id FROM policy_api_keys pk WHERE pk.policy_id = $1;
+
+-- name: PolicyScopeInOrg :one
+-- Whether a policy and the org, app, user or API key it is attached to both
+-- belong to the organisation
+SELECT EXISTS (
+  SELECT 1 FROM policies p WHERE p.id = @policy_id AND p.org_id = @org_id
+) AND CASE @scope::text
+  WHEN 'org' THEN @target_id::uuid = @org_id
+  WHEN 'app' THEN EXISTS (SELECT 1 FROM applications a WHERE a.id = @target_id AND a.org_id = @org_id)
+  WHEN 'user' THEN EXISTS (SELECT 1 FROM users u WHERE u.id = @target_id AND u.org_id = @org_id)
+  WHEN 'key' THEN EXISTS (SELECT 1 FROM api_keys k WHERE k.id = @target_id AND k.org_id = @org_id)
+  ELSE false
+END AS in_org;
+
+-- name: ListEnabledPoliciesForScope :many
+SELECT p.*, s.scope FROM (
+  SELECT po.policy_id, 'org'::text AS scope FROM policy_organisations po WHERE po.org_id = @org_id
+  UNION ALL
+  SELECT pa.policy_id, 'app'::text AS scope FROM policy_applications pa WHERE pa.app_id = @app_id
+  UNION ALL
+  SELECT pu.policy_id, 'user'::text AS scope FROM policy_users pu WHERE pu.user_id = @user_id
+  UNION ALL
+  SELECT pk.policy_id, 'key'::text AS scope FROM policy_api_keys pk WHERE pk.key_id = @key_id
+) s
+JOIN policies p ON p.id = s.policy_id
+WHERE p.enabled = true
+ORDER BY p.policy_type, p.created_at;
@@ -2,9 +2,11 @@
 INSERT INTO usage_metrics (
   org_id, app_id, api_key_id, model_id, provider, model_name,
   prompt_tokens, completion_tokens, total_tokens,
-  request_size_bytes, response_size_bytes, timestamp
+  request_size_bytes, response_size_bytes, timestamp, estimated,
+  cost_micros, currency, metadata
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
+  COALESCE($16::jsonb, '{}'::jsonb)
 )
 RETURNING *;
 
@@ -35,7 +37,8 @@ SELECT
   COALESCE(SUM(prompt_tokens), 0) as total_prompt_tokens,
   COALESCE(SUM(completion_tokens), 0) as total_completion_tokens,
   COALESCE(SUM(total_tokens), 0) as total_tokens,
-  COUNT(*) as request_count
+  COUNT(*) as request_count,
+  COUNT(*) FILTER (WHERE cost_micros IS NULL) as unpriced_count
 FROM usage_metrics
 WHERE app_id = $1
   AND timestamp >= $2
@@ -46,7 +49,8 @@ SELECT
   COALESCE(SUM(prompt_tokens), 0) as total_prompt_tokens,
   COALESCE(SUM(completion_tokens), 0) as total_completion_tokens,
   COALESCE(SUM(total_tokens), 0) as total_tokens,
-  COUNT(*) as request_count
+  COUNT(*) as request_count,
+  COUNT(*) FILTER (WHERE cost_micros IS NULL) as unpriced_count
 FROM usage_metrics
 WHERE org_id = $1
   AND timestamp >= $2
@@ -67,3 +71,27 @@ WHERE app_id = $1
 GROUP BY model_name, provider
 ORDER BY total_tokens DESC
 LIMIT $4 OFFSET $5;
+
+-- name: SumCostByApp :many
+SELECT
+  currency,
+  COALESCE(SUM(cost_micros), 0)::bigint as total_cost_micros
+FROM usage_metrics
+WHERE app_id = $1
+  AND timestamp >= $2
+  AND timestamp <= $3
+  AND cost_micros IS NOT NULL
+GROUP BY currency
+ORDER BY currency;
+
+-- name: SumCostByOrg :many
+SELECT
+  currency,
+  COALESCE(SUM(cost_micros), 0)::bigint as total_cost_micros
+FROM usage_metrics
+WHERE org_id = $1
+  AND timestamp >= $2
+  AND timestamp <= $3
+  AND cost_micros IS NOT NULL
+GROUP BY currency
+ORDER BY currency;
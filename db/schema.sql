@@ -183,6 +183,7 @@ CREATE TABLE "public"."policies" (
   "enabled" boolean NOT NULL DEFAULT true,
   "created_at" timestamptz NOT NULL DEFAULT now(),
   "updated_at" timestamptz NOT NULL DEFAULT now(),
+  "mode" text NOT NULL DEFAULT 'enforce',
   PRIMARY KEY ("id"),
   CONSTRAINT "policies_org_id_fkey" FOREIGN KEY ("org_id") REFERENCES "public"."organisations" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
 );
@@ -203,6 +204,39 @@ CREATE TABLE "public"."policy_applications" (
 CREATE INDEX "idx_policy_applications_app" ON "public"."policy_applications" ("app_id");
 -- Create index "idx_policy_applications_policy" to table: "policy_applications"
 CREATE INDEX "idx_policy_applications_policy" ON "public"."policy_applications" ("policy_id");
+-- Create "policy_organisations" table
+CREATE TABLE "public"."policy_organisations" (
+  "policy_id" uuid NOT NULL,
+  "org_id" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("policy_id", "org_id"),
+  CONSTRAINT "policy_organisations_org_id_fkey" FOREIGN KEY ("org_id") REFERENCES "public"."organisations" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "policy_organisations_policy_id_fkey" FOREIGN KEY ("policy_id") REFERENCES "public"."policies" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_policy_organisations_org" to table: "policy_organisations"
+CREATE INDEX "idx_policy_organisations_org" ON "public"."policy_organisations" ("org_id");
+-- Create "policy_users" table
+CREATE TABLE "public"."policy_users" (
+  "policy_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("policy_id", "user_id"),
+  CONSTRAINT "policy_users_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "policy_users_policy_id_fkey" FOREIGN KEY ("policy_id") REFERENCES "public"."policies" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_policy_users_user" to table: "policy_users"
+CREATE INDEX "idx_policy_users_user" ON "public"."policy_users" ("user_id");
+-- Create "policy_api_keys" table
+CREATE TABLE "public"."policy_api_keys" (
+  "policy_id" uuid NOT NULL,
+  "key_id" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("policy_id", "key_id"),
+  CONSTRAINT "policy_api_keys_key_id_fkey" FOREIGN KEY ("key_id") REFERENCES "public"."api_keys" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "policy_api_keys_policy_id_fkey" FOREIGN KEY ("policy_id") REFERENCES "public"."policies" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_policy_api_keys_key" to table: "policy_api_keys"
+CREATE INDEX "idx_policy_api_keys_key" ON "public"."policy_api_keys" ("key_id");
 -- Create "usage_metrics" table
 CREATE TABLE "public"."usage_metrics" (
   "id" uuid NOT NULL DEFAULT public.uuid_generate_v4(),
@@ -218,6 +252,10 @@ CREATE TABLE "public"."usage_metrics" (
   "request_size_bytes" integer NOT NULL DEFAULT 0,
   "response_size_bytes" integer NOT NULL DEFAULT 0,
   "timestamp" timestamptz NOT NULL DEFAULT now(),
+  "estimated" boolean NOT NULL DEFAULT false,
+  "cost_micros" bigint NULL,
+  "currency" text NULL,
+  "metadata" jsonb NOT NULL DEFAULT '{}',
   PRIMARY KEY ("id"),
   CONSTRAINT "usage_metrics_api_key_id_fkey" FOREIGN KEY ("api_key_id") REFERENCES "public"."api_keys" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
   CONSTRAINT "usage_metrics_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."applications" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
@@ -245,3 +283,71 @@ CREATE TABLE "public"."user_roles" (
 CREATE INDEX "idx_user_roles_org_role" ON "public"."user_roles" ("org_id", "role_id");
 -- Create index "idx_user_roles_org_user" to table: "user_roles"
 CREATE INDEX "idx_user_roles_org_user" ON "public"."user_roles" ("org_id", "user_id");
+-- Create "batch_files" table
+CREATE TABLE "public"."batch_files" (
+  "id" uuid NOT NULL DEFAULT public.uuid_generate_v4(),
+  "org_id" uuid NOT NULL DEFAULT public.app_current_org(),
+  "app_id" uuid NOT NULL,
+  "key_prefix" text NOT NULL,
+  "provider" text NOT NULL,
+  "file_id" text NOT NULL,
+  "purpose" text NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "batch_files_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."applications" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "batch_files_org_id_fkey" FOREIGN KEY ("org_id") REFERENCES "public"."organisations" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_batch_files_app" to table: "batch_files"
+CREATE INDEX "idx_batch_files_app" ON "public"."batch_files" ("app_id");
+-- Create index "idx_batch_files_provider_file" to table: "batch_files"
+CREATE UNIQUE INDEX "idx_batch_files_provider_file" ON "public"."batch_files" ("provider", "file_id");
+-- Create "batch_jobs" table
+CREATE TABLE "public"."batch_jobs" (
+  "id" uuid NOT NULL DEFAULT public.uuid_generate_v4(),
+  "org_id" uuid NOT NULL DEFAULT public.app_current_org(),
+  "app_id" uuid NOT NULL,
+  "key_prefix" text NOT NULL,
+  "provider" text NOT NULL,
+  "batch_id" text NOT NULL,
+  "endpoint" text NOT NULL DEFAULT '',
+  "status" text NOT NULL,
+  "input_file_id" text NOT NULL,
+  "output_file_id" text NULL,
+  "error_file_id" text NULL,
+  "usage_recorded" boolean NOT NULL DEFAULT false,
+  "completed_at" timestamptz NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "updated_at" timestamptz NOT NULL DEFAULT now(),
+  "poll_failures" integer NOT NULL DEFAULT 0,
+  "next_poll_at" timestamptz NOT NULL DEFAULT now(),
+  "poll_error" text NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "batch_jobs_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."applications" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "batch_jobs_org_id_fkey" FOREIGN KEY ("org_id") REFERENCES "public"."organisations" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_batch_jobs_app" to table: "batch_jobs"
+CREATE INDEX "idx_batch_jobs_app" ON "public"."batch_jobs" ("app_id");
+-- Create index "idx_batch_jobs_pending" to table: "batch_jobs"
+CREATE INDEX "idx_batch_jobs_pending" ON "public"."batch_jobs" ("usage_recorded", "next_poll_at");
+-- Create index "idx_batch_jobs_provider_batch" to table: "batch_jobs"
+CREATE UNIQUE INDEX "idx_batch_jobs_provider_batch" ON "public"."batch_jobs" ("provider", "batch_id");
+-- Create "model_prices" table
+CREATE TABLE "public"."model_prices" (
+  "id" uuid NOT NULL DEFAULT public.uuid_generate_v4(),
+  "org_id" uuid NOT NULL DEFAULT public.app_current_org(),
+  "provider" text NOT NULL,
+  "model_name" text NOT NULL,
+  "deployment_type" text NOT NULL DEFAULT 'standard',
+  "currency" text NOT NULL DEFAULT 'USD',
+  "input_per_million" double precision NOT NULL DEFAULT 0,
+  "cached_input_per_million" double precision NOT NULL DEFAULT 0,
+  "output_per_million" double precision NOT NULL DEFAULT 0,
+  "per_image" double precision NOT NULL DEFAULT 0,
+  "per_audio_second" double precision NOT NULL DEFAULT 0,
+  "effective_from" timestamptz NOT NULL DEFAULT now(),
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "model_prices_org_id_fkey" FOREIGN KEY ("org_id") REFERENCES "public"."organisations" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_model_prices_version" to table: "model_prices"
+CREATE UNIQUE INDEX "idx_model_prices_version" ON "public"."model_prices" ("org_id", "provider", "model_name", "deployment_type", "effective_from");
@@ -0,0 +1,170 @@
+table "batch_files" {
+  schema = schema.public
+  column "id" {
+    null    = false
+    type    = uuid
+    default = sql("uuid_generate_v4()")
+  }
+  column "org_id" {
+    null    = false
+    type    = uuid
+    default = sql("app_current_org()")
+  }
+  column "app_id" {
+    null = false
+    type = uuid
+  }
+  column "key_prefix" {
+    null = false
+    type = text
+  }
+  column "provider" {
+    null = false
+    type = text
+  }
+  column "file_id" {
+    null = false
+    type = text
+  }
+  column "purpose" {
+    null    = false
+    type    = text
+    default = ""
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  primary_key {
+    columns = [column.id]
+  }
+  foreign_key "batch_files_org_id_fkey" {
+    columns     = [column.org_id]
+    ref_columns = [table.organisations.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+  foreign_key "batch_files_app_id_fkey" {
+    columns     = [column.app_id]
+    ref_columns = [table.applications.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+  index "idx_batch_files_provider_file" {
+    columns = [column.provider, column.file_id]
+    unique  = true
+  }
+  index "idx_batch_files_app" {
+    columns = [column.app_id]
+  }
+}
+
+table "batch_jobs" {
+  schema = schema.public
+  column "id" {
+    null    = false
+    type    = uuid
+    default = sql("uuid_generate_v4()")
+  }
+  column "org_id" {
+    null    = false
+    type    = uuid
+    default = sql("app_current_org()")
+  }
+  column "app_id" {
+    null = false
+    type = uuid
+  }
+  column "key_prefix" {
+    null = false
+    type = text
+  }
+  column "provider" {
+    null = false
+    type = text
+  }
+  column "batch_id" {
+    null = false
+    type = text
+  }
+  column "endpoint" {
+    null    = false
+    type    = text
+    default = ""
+  }
+  column "status" {
+    null = false
+    type = text
+  }
+  column "input_file_id" {
+    null = false
+    type = text
+  }
+  column "output_file_id" {
+    null = true
+    type = text
+  }
+  column "error_file_id" {
+    null = true
+    type = text
+  }
+  column "usage_recorded" {
+    null    = false
+    type    = boolean
+    default = false
+  }
+  column "completed_at" {
+    null = true
+    type = timestamptz
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  column "updated_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  column "poll_failures" {
+    null    = false
+    type    = integer
+    default = 0
+  }
+  column "next_poll_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  column "poll_error" {
+    null = true
+    type = text
+  }
+  primary_key {
+    columns = [column.id]
+  }
+  foreign_key "batch_jobs_org_id_fkey" {
+    columns     = [column.org_id]
+    ref_columns = [table.organisations.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+  foreign_key "batch_jobs_app_id_fkey" {
+    columns     = [column.app_id]
+    ref_columns = [table.applications.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+  index "idx_batch_jobs_provider_batch" {
+    columns = [column.provider, column.batch_id]
+    unique  = true
+  }
+  index "idx_batch_jobs_app" {
+    columns = [column.app_id]
+  }
+  index "idx_batch_jobs_pending" {
+    columns = [column.usage_recorded, column.next_poll_at]
+  }
+}
@@ -0,0 +1,79 @@
+table "model_prices" {
+  schema = schema.public
+  column "id" {
+    null    = false
+    type    = uuid
+    default = sql("uuid_generate_v4()")
+  }
+  column "org_id" {
+    null    = false
+    type    = uuid
+    default = sql("app_current_org()")
+  }
+  column "provider" {
+    null = false
+    type = text
+  }
+  column "model_name" {
+    null = false
+    type = text
+  }
+  column "deployment_type" {
+    null    = false
+    type    = text
+    default = "standard"
+  }
+  column "currency" {
+    null    = false
+    type    = text
+    default = "USD"
+  }
+  column "input_per_million" {
+    null    = false
+    type    = double_precision
+    default = 0
+  }
+  column "cached_input_per_million" {
+    null    = false
+    type    = double_precision
+    default = 0
+  }
+  column "output_per_million" {
+    null    = false
+    type    = double_precision
+    default = 0
+  }
+  column "per_image" {
+    null    = false
+    type    = double_precision
+    default = 0
+  }
+  column "per_audio_second" {
+    null    = false
+    type    = double_precision
+    default = 0
+  }
+  column "effective_from" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  primary_key {
+    columns = [column.id]
+  }
+  foreign_key "model_prices_org_id_fkey" {
+    columns     = [column.org_id]
+    ref_columns = [table.organisations.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+  index "idx_model_prices_version" {
+    unique  = true
+    columns = [column.org_id, column.provider, column.model_name, column.deployment_type, column.effective_from]
+  }
+}
@@ -34,6 +34,11 @@ table "policies" {
     type    = timestamptz
     default = sql("now()")
   }
+  column "mode" {
+    null    = false
+    type    = text
+    default = "enforce"
+  }
   primary_key {
     columns = [column.id]
   }
@@ -88,3 +93,108 @@ table "policy_applications" {
     columns = [column.policy_id]
   }
 }
+
+table "policy_organisations" {
+  schema = schema.public
+  column "policy_id" {
+    null = false
+    type = uuid
+  }
+  column "org_id" {
+    null = false
+    type = uuid
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  primary_key {
+    columns = [column.policy_id, column.org_id]
+  }
+  foreign_key "policy_organisations_policy_id_fkey" {
+    columns     = [column.policy_id]
+    ref_columns = [table.policies.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+  foreign_key "policy_organisations_org_id_fkey" {
+    columns     = [column.org_id]
+    ref_columns = [table.organisations.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+  index "idx_policy_organisations_org" {
+    columns = [column.org_id]
+  }
+}
+
+table "policy_users" {
+  schema = schema.public
+  column "policy_id" {
+    null = false
+    type = uuid
+  }
+  column "user_id" {
+    null = false
+    type = uuid
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  primary_key {
+    columns = [column.policy_id, column.user_id]
+  }
+  foreign_key "policy_users_policy_id_fkey" {
+    columns     = [column.policy_id]
+    ref_columns = [table.policies.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+  foreign_key "policy_users_user_id_fkey" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+  index "idx_policy_users_user" {
+    columns = [column.user_id]
+  }
+}
+
+table "policy_api_keys" {
+  schema = schema.public
+  column "policy_id" {
+    null = false
+    type = uuid
+  }
+  column "key_id" {
+    null = false
+    type = uuid
+  }
+  column "created_at" {
+    null    = false
+    type    = timestamptz
+    default = sql("now()")
+  }
+  primary_key {
+    columns = [column.policy_id, column.key_id]
+  }
+  foreign_key "policy_api_keys_policy_id_fkey" {
+    columns     = [column.policy_id]
+    ref_columns = [table.policies.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+  foreign_key "policy_api_keys_key_id_fkey" {
+    columns     = [column.key_id]
+    ref_columns = [table.api_keys.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+  index "idx_policy_api_keys_key" {
+    columns = [column.key_id]
+  }
+}
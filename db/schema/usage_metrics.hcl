@@ -60,6 +60,24 @@ table "usage_metrics" {
     type    = timestamptz
     default = sql("now()")
   }
+  column "estimated" {
+    null    = false
+    type    = boolean
+    default = false
+  }
+  column "cost_micros" {
+    null = true
+    type = bigint
+  }
+  column "currency" {
+    null = true
+    type = text
+  }
+  column "metadata" {
+    null    = false
+    type    = jsonb
+    default = sql("'{}'::jsonb")
+  }
   primary_key {
     columns = [column.id]
   }
@@ -31,6 +31,11 @@ CREATE TRIGGER policies_set_updated_at
 BEFORE UPDATE ON policies
 FOR EACH ROW EXECUTE FUNCTION set_updated_at();
 
+DROP TRIGGER IF EXISTS batch_jobs_set_updated_at ON batch_jobs;
+CREATE TRIGGER batch_jobs_set_updated_at
+BEFORE UPDATE ON batch_jobs
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
 -- Row Level Security Policies (safe creation - only create if not exists)
 DO $$
 BEGIN
@@ -117,12 +122,65 @@ BEGIN
           USING (org_id = app_current_org()) WITH CHECK (org_id = app_current_org());
     END IF;
 
+    -- policy_organisations, policy_users and policy_api_keys have no org_id;
+    -- both the policy and the target must be in the current org
+    IF NOT EXISTS (SELECT 1 FROM pg_policies WHERE tablename = 'policy_organisations' AND policyname = 'org_isolation_policy_organisations') THEN
+        ALTER TABLE policy_organisations ENABLE ROW LEVEL SECURITY;
+        CREATE POLICY org_isolation_policy_organisations ON policy_organisations
+          USING (org_id = app_current_org())
+          WITH CHECK (
+            org_id = app_current_org()
+            AND EXISTS (SELECT 1 FROM policies p WHERE p.id = policy_id AND p.org_id = app_current_org())
+          );
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_policies WHERE tablename = 'policy_users' AND policyname = 'org_isolation_policy_users') THEN
+        ALTER TABLE policy_users ENABLE ROW LEVEL SECURITY;
+        CREATE POLICY org_isolation_policy_users ON policy_users
+          USING (EXISTS (SELECT 1 FROM policies p WHERE p.id = policy_id AND p.org_id = app_current_org()))
+          WITH CHECK (
+            EXISTS (SELECT 1 FROM policies p WHERE p.id = policy_id AND p.org_id = app_current_org())
+            AND EXISTS (SELECT 1 FROM users u WHERE u.id = user_id AND u.org_id = app_current_org())
+          );
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_policies WHERE tablename = 'policy_api_keys' AND policyname = 'org_isolation_policy_api_keys') THEN
+        ALTER TABLE policy_api_keys ENABLE ROW LEVEL SECURITY;
+        CREATE POLICY org_isolation_policy_api_keys ON policy_api_keys
+          USING (EXISTS (SELECT 1 FROM policies p WHERE p.id = policy_id AND p.org_id = app_current_org()))
+          WITH CHECK (
+            EXISTS (SELECT 1 FROM policies p WHERE p.id = policy_id AND p.org_id = app_current_org())
+            AND EXISTS (SELECT 1 FROM api_keys k WHERE k.id = key_id AND k.org_id = app_current_org())
+          );
+    END IF;
+
     -- usage_metrics
     IF NOT EXISTS (SELECT 1 FROM pg_policies WHERE tablename = 'usage_metrics' AND policyname = 'org_isolation_usage_metrics') THEN
         ALTER TABLE usage_metrics ENABLE ROW LEVEL SECURITY;
         CREATE POLICY org_isolation_usage_metrics ON usage_metrics
           USING (org_id = app_current_org()) WITH CHECK (org_id = app_current_org());
     END IF;
+
+    -- batch_files
+    IF NOT EXISTS (SELECT 1 FROM pg_policies WHERE tablename = 'batch_files' AND policyname = 'org_isolation_batch_files') THEN
+        ALTER TABLE batch_files ENABLE ROW LEVEL SECURITY;
+        CREATE POLICY org_isolation_batch_files ON batch_files
+          USING (org_id = app_current_org()) WITH CHECK (org_id = app_current_org());
+    END IF;
+
+    -- batch_jobs
+    IF NOT EXISTS (SELECT 1 FROM pg_policies WHERE tablename = 'batch_jobs' AND policyname = 'org_isolation_batch_jobs') THEN
+        ALTER TABLE batch_jobs ENABLE ROW LEVEL SECURITY;
+        CREATE POLICY org_isolation_batch_jobs ON batch_jobs
+          USING (org_id = app_current_org()) WITH CHECK (org_id = app_current_org());
+    END IF;
+
+    -- model_prices
+    IF NOT EXISTS (SELECT 1 FROM pg_policies WHERE tablename = 'model_prices' AND policyname = 'org_isolation_model_prices') THEN
+        ALTER TABLE model_prices ENABLE ROW LEVEL SECURITY;
+        CREATE POLICY org_isolation_model_prices ON model_prices
+          USING (org_id = app_current_org()) WITH CHECK (org_id = app_current_org());
+    END IF;
 END $$;
 
 -- Insert seed data for roles
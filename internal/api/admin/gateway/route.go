@@ -12,6 +12,7 @@ import (
 )
 
 type endpointSpec struct {
+	Method      string // defaults to POST
 	Path        string
 	Summary     string
 	Description string
@@ -44,6 +45,45 @@ var (
 			Description: "Creates an embedding vector representing the input text for semantic search and similarity tasks.",
 		},
 	}
+
+	// Files and Batch API endpoints. Resources are scoped to the app that created them.
+	batchEndpoints = []endpointSpec{
+		{
+			Path:        "/v1/files",
+			Summary:     "Upload file",
+			Description: "Uploads a JSONL batch input file. Models referenced by the file are checked against the app's policies.",
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/files/{file_id}",
+			Summary:     "Retrieve file",
+			Description: "Returns metadata for a file uploaded by, or produced for, the calling application.",
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/files/{file_id}/content",
+			Summary:     "Retrieve file content",
+			Description: "Returns the contents of a batch input, output or error file owned by the calling application.",
+		},
+		{
+			Path:        "/v1/batches",
+			Summary:     "Create batch",
+			Description: "Creates a batch from an uploaded input file. Token usage is recorded against the application when the batch completes.",
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/batches/{batch_id}",
+			Summary:     "Retrieve batch",
+			Description: "Returns the status of a batch created by the calling application.",
+		},
+		{
+			Path:        "/v1/batches/{batch_id}/cancel",
+			Summary:     "Cancel batch",
+			Description: "Cancels an in-progress batch created by the calling application.",
+		},
+	}
+
+	providerEndpoints = append(append([]endpointSpec{}, openAICompatibleEndpoints...), batchEndpoints...)
 )
 
 var supportedProviders = []providerConfig{
@@ -52,14 +92,14 @@ var supportedProviders = []providerConfig{
 		DisplayName: "Azure OpenAI",
 		Description: "Microsoft Azure OpenAI Service with deployment-based routing and API key authentication",
 		Enabled:     true,
-		Endpoints:   openAICompatibleEndpoints,
+		Endpoints:   providerEndpoints,
 	},
 	{
 		Prefix:      provider.OpenAIPrefix,
 		DisplayName: "OpenAI",
 		Description: "OpenAI API with Bearer token authentication and organization header support",
 		Enabled:     true,
-		Endpoints:   openAICompatibleEndpoints,
+		Endpoints:   providerEndpoints,
 	},
 }
 
@@ -70,6 +110,21 @@ func RegisterAllProviders(grp *huma.Group, core *gateway.Core) {
 		}
 		registerProvider(grp, &providerCfg, core)
 	}
+	if core.Async != nil {
+		RegisterJobRoutes(grp, core)
+	}
+}
+
+// RegisterJobRoutes exposes the status endpoint for "Prefer: respond-async" requests
+func RegisterJobRoutes(grp *huma.Group, core *gateway.Core) {
+	huma.Register(grp, huma.Operation{
+		OperationID: "get-async-job",
+		Method:      http.MethodGet,
+		Path:        "/jobs/{job_id}",
+		Summary:     "Get async job",
+		Description: "Returns the status of a request submitted with `Prefer: respond-async` and, once it has finished, the upstream response.",
+		Tags:        []string{"Async Jobs"},
+	}, core.JobStatusHandler())
 }
 
 // RegisterProvider registers a single provider (useful for testing)
@@ -80,7 +135,7 @@ func RegisterProvider(grp *huma.Group, providerCfg *provider.ProviderConfig, cor
 		DisplayName: providerCfg.DisplayName,
 		Description: providerCfg.Description,
 		Enabled:     providerCfg.Enabled,
-		Endpoints:   openAICompatibleEndpoints,
+		Endpoints:   providerEndpoints,
 	}
 	registerProvider(grp, &localCfg, core)
 }
@@ -89,9 +144,13 @@ func registerProvider(grp *huma.Group, providerCfg *providerConfig, core *gatewa
 	h := core.StreamingHandler()
 
 	for _, spec := range providerCfg.Endpoints {
+		method := spec.Method
+		if method == "" {
+			method = http.MethodPost
+		}
 		huma.Register(grp, huma.Operation{
 			OperationID:   sanitizeOperationID(providerCfg.Prefix + spec.Path),
-			Method:        http.MethodPost,
+			Method:        method,
 			Path:          providerCfg.Prefix + spec.Path,
 			Summary:       spec.Summary,
 			Description:   buildDescription(spec.Description, providerCfg),
@@ -113,6 +172,9 @@ func buildDescription(baseDesc string, cfg *providerConfig) string {
 func sanitizeOperationID(path string) string {
 	operationID := "proxy"
 	for _, char := range path {
+		if char == '{' || char == '}' {
+			continue
+		}
 		if char == '/' {
 			operationID += "-"
 		} else {
@@ -12,6 +12,7 @@ type Policy struct {
 	PolicyType model.PolicyType `json:"policy_type"`
 	Config     map[string]any   `json:"config"`
 	Enabled    bool             `json:"enabled"`
+	Mode       model.PolicyMode `json:"mode"`
 	CreatedAt  time.Time        `json:"created_at"`
 	UpdatedAt  time.Time        `json:"updated_at"`
 }
@@ -22,6 +23,7 @@ type CreatePolicyBody struct {
 	PolicyType model.PolicyType `json:"policy_type" required:"true"`
 	Config     map[string]any   `json:"config" required:"true"`
 	Enabled    bool             `json:"enabled"`
+	Mode       model.PolicyMode `json:"mode,omitempty" enum:"enforce,dry_run" doc:"enforce denies violating requests; dry_run only records them (default enforce)"`
 }
 
 type UpdatePolicyBody struct {
@@ -29,6 +31,7 @@ type UpdatePolicyBody struct {
 	PolicyType model.PolicyType `json:"policy_type" required:"true"`
 	Config     map[string]any   `json:"config" required:"true"`
 	Enabled    bool             `json:"enabled"`
+	Mode       model.PolicyMode `json:"mode,omitempty" enum:"enforce,dry_run" doc:"enforce denies violating requests; dry_run only records them (default enforce)"`
 }
 
 type CreatePolicyRequest struct {
@@ -70,3 +73,60 @@ type ListEnabledPoliciesRequest struct {
 	AppID string `query:"app_id" required:"true"`
 	model.ListRequest
 }
+
+// ScopedAttachmentRequest identifies a policy and the org, app, end-user or
+// API key it is attached to
+type ScopedAttachmentRequest struct {
+	PolicyID string `path:"id" required:"true"`
+	Scope    string `path:"scope" required:"true" enum:"org,app,user,key" doc:"What the policy is attached to; more specific scopes override broader ones"`
+	TargetID string `path:"target_id" required:"true"`
+}
+
+type SimulatePolicyBody struct {
+	AppID    string            `json:"app_id,omitempty" doc:"Application whose policies are checked; required unless key_id is given"`
+	KeyID    string            `json:"key_id,omitempty" doc:"API key (its prefix or ID) to check as; adds the key's and its user's policies"`
+	Endpoint string            `json:"endpoint,omitempty" doc:"Gateway endpoint the request is sent to (default /v1/chat/completions)"`
+	Headers  map[string]string `json:"headers,omitempty" doc:"Request headers, for policies that read them"`
+	Request  map[string]any    `json:"request" required:"true" doc:"The sample request body"`
+}
+
+type SimulatePolicyRequest struct {
+	Body SimulatePolicyBody `json:"body"`
+}
+
+// SimulatedPolicy is how one attached policy handled a sample request
+type SimulatedPolicy struct {
+	PolicyType    model.PolicyType `json:"policy_type"`
+	Mode          model.PolicyMode `json:"mode"`
+	Passed        bool             `json:"passed"`
+	Phase         string           `json:"phase,omitempty" doc:"Where the policy failed: transform or pre_check"`
+	Status        int              `json:"status,omitempty" doc:"The status the request would be refused with"`
+	Reason        string           `json:"reason,omitempty"`
+	ModelOverride string           `json:"model_override,omitempty"`
+	Priority      string           `json:"priority,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+	Metadata      map[string]any   `json:"metadata,omitempty" doc:"Values the policy records with usage, e.g. the rules it matched"`
+	BodyRewritten bool             `json:"body_rewritten,omitempty"`
+}
+
+// PolicySimulation is how the gateway would handle a sample request
+type PolicySimulation struct {
+	OrgID           string            `json:"org_id"`
+	AppID           string            `json:"app_id"`
+	UserID          string            `json:"user_id,omitempty"`
+	KeyID           string            `json:"key_id,omitempty"`
+	Allowed         bool              `json:"allowed"`
+	Status          int               `json:"status,omitempty" doc:"The status of the first enforced refusal"`
+	Reason          string            `json:"reason,omitempty"`
+	Model           string            `json:"model"`
+	RoutedModel     string            `json:"routed_model" doc:"The model the request would be sent to"`
+	Priority        string            `json:"priority"`
+	EstimatedTokens int               `json:"estimated_tokens"`
+	BodyRewritten   bool              `json:"body_rewritten"`
+	Tags            []string          `json:"tags,omitempty"`
+	Policies        []SimulatedPolicy `json:"policies"`
+}
+
+type SimulatePolicyResponse struct {
+	Body *PolicySimulation `json:"body"`
+}
@@ -2,10 +2,14 @@ package policies
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
+	"github.com/WebDeveloperBen/ai-gateway/internal/api/middleware"
 	"github.com/WebDeveloperBen/ai-gateway/internal/exceptions"
+	gwpolicies "github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
 	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/repository/policies"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/google/uuid"
 )
@@ -42,6 +46,32 @@ func (s *PolicyService) RegisterRoutes(grp *huma.Group) {
 		return &CreatePolicyResponse{Body: policy}, nil
 	}))
 
+	// POST /policies/simulate
+	huma.Register(grp, huma.Operation{
+		OperationID: "admin-simulate-policies",
+		Method:      http.MethodPost,
+		Path:        "/policies/simulate",
+		Summary:     "Simulate a request against policies",
+		Description: "Runs a sample request through the policies attached to an application or API key and reports what each would do, the estimated tokens and where the request would be routed. Nothing is sent upstream and no limits are consumed.",
+		Tags:        []string{"Policies"},
+	}, exceptions.Handle(func(ctx context.Context, in *SimulatePolicyRequest) (*SimulatePolicyResponse, error) {
+		// Only apps and keys in the caller's organisation can be simulated
+		claims, ok := middleware.GetScopedToken(ctx)
+		if !ok || claims.OrgID == "" {
+			return nil, exceptions.Unauthorized("organization not found in context")
+		}
+
+		sim, err := s.Policies.SimulatePolicies(ctx, claims.OrgID, in.Body)
+		if errors.Is(err, gwpolicies.ErrScopeNotFound) {
+			return nil, huma.Error404NotFound("app or API key not found")
+		}
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+
+		return &SimulatePolicyResponse{Body: sim}, nil
+	}))
+
 	// GET /policies?app_id={app_id}
 	huma.Register(grp, huma.Operation{
 		OperationID: "admin-list-policies",
@@ -266,4 +296,91 @@ func (s *PolicyService) RegisterRoutes(grp *huma.Group) {
 
 		return &struct{}{}, nil
 	}))
+
+	// POST /policies/{id}/attach/{scope}/{target_id}
+	huma.Register(grp, huma.Operation{
+		OperationID:   "admin-attach-policy-to-scope",
+		Method:        http.MethodPost,
+		Path:          "/policies/{id}/attach/{scope}/{target_id}",
+		Summary:       "Attach policy to an org, app, user or API key",
+		Description:   "Attaches an existing policy to an organisation (inherited by all of its apps), an application, an end-user or a single API key. Where several scopes attach the same built-in policy type, the most specific (key, then user, app and org) wins.",
+		DefaultStatus: http.StatusNoContent,
+		Tags:          []string{"Policies"},
+	}, exceptions.Handle(func(ctx context.Context, in *ScopedAttachmentRequest) (*struct{}, error) {
+		// Policies and targets must both be in the caller's organisation
+		orgID, err := sessionOrgID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		policyID, err := uuid.Parse(in.PolicyID)
+		if err != nil {
+			return nil, huma.Error400BadRequest("invalid policy ID")
+		}
+
+		targetID, err := uuid.Parse(in.TargetID)
+		if err != nil {
+			return nil, huma.Error400BadRequest("invalid target ID")
+		}
+
+		err = s.Policies.AttachPolicy(ctx, orgID, policyID, model.PolicyScope(in.Scope), targetID)
+		if errors.Is(err, policies.ErrNotFound) {
+			return nil, huma.Error404NotFound("policy or " + in.Scope + " not found")
+		}
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to attach policy to " + in.Scope)
+		}
+
+		return &struct{}{}, nil
+	}))
+
+	// POST /policies/{id}/detach/{scope}/{target_id}
+	huma.Register(grp, huma.Operation{
+		OperationID:   "admin-detach-policy-from-scope",
+		Method:        http.MethodPost,
+		Path:          "/policies/{id}/detach/{scope}/{target_id}",
+		Summary:       "Detach policy from an org, app, user or API key",
+		Description:   "Detaches a policy from an organisation, application, end-user or API key.",
+		DefaultStatus: http.StatusNoContent,
+		Tags:          []string{"Policies"},
+	}, exceptions.Handle(func(ctx context.Context, in *ScopedAttachmentRequest) (*struct{}, error) {
+		// Policies and targets must both be in the caller's organisation
+		orgID, err := sessionOrgID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		policyID, err := uuid.Parse(in.PolicyID)
+		if err != nil {
+			return nil, huma.Error400BadRequest("invalid policy ID")
+		}
+
+		targetID, err := uuid.Parse(in.TargetID)
+		if err != nil {
+			return nil, huma.Error400BadRequest("invalid target ID")
+		}
+
+		err = s.Policies.DetachPolicy(ctx, orgID, policyID, model.PolicyScope(in.Scope), targetID)
+		if errors.Is(err, policies.ErrNotFound) {
+			return nil, huma.Error404NotFound("policy or " + in.Scope + " not found")
+		}
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to detach policy from " + in.Scope)
+		}
+
+		return &struct{}{}, nil
+	}))
+}
+
+// sessionOrgID returns the organisation of the admin session
+func sessionOrgID(ctx context.Context) (uuid.UUID, error) {
+	claims, ok := middleware.GetScopedToken(ctx)
+	if !ok || claims.OrgID == "" {
+		return uuid.Nil, exceptions.Unauthorized("organization not found in context")
+	}
+	orgID, err := uuid.Parse(claims.OrgID)
+	if err != nil {
+		return uuid.Nil, exceptions.Unauthorized("organization not found in context")
+	}
+	return orgID, nil
 }
@@ -8,11 +8,16 @@ import (
 	"testing"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/api/admin/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/api/middleware"
 	dbdriver "github.com/WebDeveloperBen/ai-gateway/internal/drivers/db"
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	gwmiddleware "github.com/WebDeveloperBen/ai-gateway/internal/gateway/middleware"
+	gwpolicies "github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
 	"github.com/WebDeveloperBen/ai-gateway/internal/model"
 	policiesrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/policies"
 	"github.com/WebDeveloperBen/ai-gateway/internal/testkit"
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -28,7 +33,7 @@ func TestCreatePolicy_Integration(t *testing.T) {
 
 	policiesRepo := policiesrepo.NewPostgresRepo(pg.Queries)
 
-	svc := policies.NewService(policiesRepo)
+	svc := policies.NewService(policiesRepo, nil, nil)
 	router := policies.NewRouter(svc)
 
 	api := testkit.SetupPublicTestAPI(t, func(grp *huma.Group) {
@@ -71,7 +76,7 @@ func TestListPolicies_Integration(t *testing.T) {
 	policyID := fixtures.CreateTestPolicy(t, orgID, appID, model.PolicyTypeRateLimit, `{"requests_per_minute": 60}`)
 
 	policiesRepo := policiesrepo.NewPostgresRepo(pg.Queries)
-	svc := policies.NewService(policiesRepo)
+	svc := policies.NewService(policiesRepo, nil, nil)
 	router := policies.NewRouter(svc)
 
 	api := testkit.SetupPublicTestAPI(t, func(grp *huma.Group) {
@@ -107,7 +112,7 @@ func TestGetPolicy_Integration(t *testing.T) {
 	// Create a test policy directly in the database
 	policyID := fixtures.CreateTestPolicy(t, orgID, appID, model.PolicyTypeRateLimit, `{"requests_per_minute": 60}`)
 
-	svc := policies.NewService(policiesRepo)
+	svc := policies.NewService(policiesRepo, nil, nil)
 	router := policies.NewRouter(svc)
 
 	api := testkit.SetupPublicTestAPI(t, func(grp *huma.Group) {
@@ -146,7 +151,7 @@ func TestEnableDisablePolicy_Integration(t *testing.T) {
 	err = policiesRepo.Disable(context.Background(), policyID)
 	require.NoError(t, err)
 
-	svc := policies.NewService(policiesRepo)
+	svc := policies.NewService(policiesRepo, nil, nil)
 	router := policies.NewRouter(svc)
 
 	api := testkit.SetupPublicTestAPI(t, func(grp *huma.Group) {
@@ -195,7 +200,7 @@ func TestUpdatePolicy_Integration(t *testing.T) {
 	// Create a test policy directly in the database
 	policyID := fixtures.CreateTestPolicy(t, orgID, appID, model.PolicyTypeRateLimit, `{"requests_per_minute": 60}`)
 
-	svc := policies.NewService(policiesRepo)
+	svc := policies.NewService(policiesRepo, nil, nil)
 	router := policies.NewRouter(svc)
 
 	api := testkit.SetupPublicTestAPI(t, func(grp *huma.Group) {
@@ -243,7 +248,7 @@ func TestListEnabledPolicies_Integration(t *testing.T) {
 	err = policiesRepo.Disable(context.Background(), disabledPolicyID)
 	require.NoError(t, err)
 
-	svc := policies.NewService(policiesRepo)
+	svc := policies.NewService(policiesRepo, nil, nil)
 	router := policies.NewRouter(svc)
 
 	api := testkit.SetupPublicTestAPI(t, func(grp *huma.Group) {
@@ -279,7 +284,7 @@ func TestAttachDetachPolicy_Integration(t *testing.T) {
 	// Create a policy not attached to any app
 	policyID := fixtures.CreateTestPolicy(t, orgID, appID, model.PolicyTypeRateLimit, `{"requests_per_minute": 60}`)
 
-	svc := policies.NewService(policiesRepo)
+	svc := policies.NewService(policiesRepo, nil, nil)
 	router := policies.NewRouter(svc)
 
 	api := testkit.SetupPublicTestAPI(t, func(grp *huma.Group) {
@@ -321,6 +326,209 @@ func TestAttachDetachPolicy_Integration(t *testing.T) {
 	require.Equal(t, policyID.String(), policiesList[0].ID)
 }
 
+// recordingInvalidator records the scopes whose cached policies are dropped
+type recordingInvalidator struct {
+	invalidated []string
+}
+
+func (r *recordingInvalidator) InvalidateCache(ctx context.Context, level model.PolicyScope, id string) error {
+	r.invalidated = append(r.invalidated, string(level)+":"+id)
+	return nil
+}
+
+func TestAttachPolicyToScopes_Integration(t *testing.T) {
+	pgConnStr, _ := testkit.SetupTestContainers(t, testkit.DefaultContainerConfig())
+
+	pg, err := dbdriver.NewPostgresDriver(context.Background(), pgConnStr)
+	require.NoError(t, err)
+	defer pg.Pool.Close()
+
+	fixtures := testkit.NewDBFixtures(pg.Queries, pg.Pool)
+	orgID, appID := fixtures.CreateTestOrgAndApp(t)
+	keyID := fixtures.CreateTestAPIKey(t, orgID, appID)
+
+	policyID := fixtures.CreateTestPolicy(t, orgID, appID, model.PolicyTypeRateLimit, `{"requests_per_minute": 60}`)
+
+	cache := &recordingInvalidator{}
+	svc := policies.NewService(policiesrepo.NewPostgresRepo(pg.Queries), nil, cache)
+	router := policies.NewRouter(svc)
+
+	api := testkit.SetupPublicTestAPI(t, func(grp *huma.Group) {
+		router.RegisterRoutes(grp)
+	})
+
+	session := context.WithValue(context.Background(), middleware.ScopedTokenKey, model.ScopedToken{OrgID: orgID.String()})
+	base := "/api/policies/" + policyID.String()
+	for _, target := range []struct{ scope, id string }{{"org", orgID.String()}, {"key", keyID.String()}} {
+		resp := api.PostCtx(session, base+"/attach/"+target.scope+"/"+target.id)
+		require.Equal(t, http.StatusNoContent, resp.Code, target.scope)
+
+		// Attaching twice is a no-op
+		resp = api.PostCtx(session, base+"/attach/"+target.scope+"/"+target.id)
+		require.Equal(t, http.StatusNoContent, resp.Code, target.scope)
+
+		resp = api.PostCtx(session, base+"/detach/"+target.scope+"/"+target.id)
+		require.Equal(t, http.StatusNoContent, resp.Code, target.scope)
+	}
+	require.Equal(t, []string{
+		"org:" + orgID.String(), "org:" + orgID.String(), "org:" + orgID.String(),
+		// The gateway caches a key's policies under its prefix
+		"key:test_", "key:test_", "key:test_",
+	}, cache.invalidated)
+
+	// Unknown scopes and targets that don't exist are rejected
+	resp := api.PostCtx(session, base+"/attach/team/"+orgID.String())
+	require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+
+	resp = api.PostCtx(session, base+"/attach/user/"+orgID.String())
+	require.Equal(t, http.StatusNotFound, resp.Code)
+
+	// Targets and policies in another organisation look like they don't exist
+	otherOrgID, otherAppID := fixtures.CreateTestOrgAndAppWithSuffix(t, "other")
+	resp = api.PostCtx(session, base+"/attach/app/"+otherAppID.String())
+	require.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp = api.PostCtx(session, base+"/attach/org/"+otherOrgID.String())
+	require.Equal(t, http.StatusNotFound, resp.Code)
+
+	other := context.WithValue(context.Background(), middleware.ScopedTokenKey, model.ScopedToken{OrgID: otherOrgID.String()})
+	resp = api.PostCtx(other, base+"/attach/app/"+otherAppID.String())
+	require.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp = api.Post(base + "/attach/org/" + orgID.String())
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestPolicyChangesInvalidateCache_Integration(t *testing.T) {
+	pgConnStr, _ := testkit.SetupTestContainers(t, testkit.DefaultContainerConfig())
+
+	pg, err := dbdriver.NewPostgresDriver(context.Background(), pgConnStr)
+	require.NoError(t, err)
+	defer pg.Pool.Close()
+
+	fixtures := testkit.NewDBFixtures(pg.Queries, pg.Pool)
+	orgID, appID := fixtures.CreateTestOrgAndApp(t)
+	keyID := fixtures.CreateTestAPIKey(t, orgID, appID)
+	policyID := fixtures.CreateTestPolicy(t, orgID, appID, model.PolicyTypeRateLimit, `{"requests_per_minute": 60}`)
+
+	cache := &recordingInvalidator{}
+	svc := policies.NewService(policiesrepo.NewPostgresRepo(pg.Queries), nil, cache)
+	require.NoError(t, svc.AttachPolicy(context.Background(), orgID, policyID, model.PolicyScopeKey, keyID))
+
+	api := testkit.SetupPublicTestAPI(t, func(grp *huma.Group) {
+		policies.NewRouter(svc).RegisterRoutes(grp)
+	})
+	base := "/api/policies/" + policyID.String()
+	attached := []string{"app:" + appID.String(), "key:test_"}
+
+	// Switching from dry run to enforce applies to every scope straight away
+	body, _ := json.Marshal(policies.UpdatePolicyBody{
+		PolicyType: model.PolicyTypeRateLimit,
+		Config:     map[string]any{"requests_per_minute": 60},
+		Enabled:    true,
+		Mode:       model.PolicyModeEnforce,
+	})
+	cache.invalidated = nil
+	resp := api.Put(base, "Content-Type: application/json", bytes.NewReader(body))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.ElementsMatch(t, attached, cache.invalidated)
+
+	cache.invalidated = nil
+	resp = api.Post(base + "/disable")
+	require.Equal(t, http.StatusNoContent, resp.Code)
+	require.ElementsMatch(t, attached, cache.invalidated)
+
+	cache.invalidated = nil
+	resp = api.Post(base + "/enable")
+	require.Equal(t, http.StatusNoContent, resp.Code)
+	require.ElementsMatch(t, attached, cache.invalidated)
+
+	// Deleting drops the scopes the policy was attached to
+	cache.invalidated = nil
+	resp = api.Delete(base)
+	require.Equal(t, http.StatusNoContent, resp.Code)
+	require.ElementsMatch(t, attached, cache.invalidated)
+}
+
+func TestSimulatePolicies_Integration(t *testing.T) {
+	pgConnStr, _ := testkit.SetupTestContainers(t, testkit.DefaultContainerConfig())
+
+	pg, err := dbdriver.NewPostgresDriver(context.Background(), pgConnStr)
+	require.NoError(t, err)
+	defer pg.Pool.Close()
+
+	fixtures := testkit.NewDBFixtures(pg.Queries, pg.Pool)
+	orgID, appID := fixtures.CreateTestOrgAndApp(t)
+	fixtures.CreateTestAPIKey(t, orgID, appID)
+	fixtures.CreateTestPolicy(t, orgID, appID, model.PolicyTypeModelAllowlist, `{"allowed_model_ids": ["gpt-4o"]}`)
+
+	engine := gwpolicies.NewEngine(pg.Queries, kv.NewMemoryStore())
+	svc := policies.NewService(policiesrepo.NewPostgresRepo(pg.Queries), gwmiddleware.NewPolicySimulator(engine), nil)
+	router := policies.NewRouter(svc)
+
+	api := testkit.SetupPublicTestAPI(t, func(grp *huma.Group) {
+		router.RegisterRoutes(grp)
+	})
+
+	session := context.WithValue(context.Background(), middleware.ScopedTokenKey, model.ScopedToken{OrgID: orgID.String()})
+	simulate := func(req policies.SimulatePolicyBody) (int, policies.PolicySimulation) {
+		body, _ := json.Marshal(req)
+		resp := api.PostCtx(session, "/api/policies/simulate", "Content-Type: application/json", bytes.NewReader(body))
+		var sim policies.PolicySimulation
+		if resp.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &sim))
+		}
+		return resp.Code, sim
+	}
+	request := map[string]any{"model": "gpt-4", "messages": []map[string]any{{"role": "user", "content": "Hello"}}}
+
+	code, sim := simulate(policies.SimulatePolicyBody{AppID: appID.String(), Request: request})
+	require.Equal(t, http.StatusOK, code)
+	require.False(t, sim.Allowed)
+	require.Equal(t, http.StatusForbidden, sim.Status)
+	require.Equal(t, orgID.String(), sim.OrgID)
+	require.Positive(t, sim.EstimatedTokens)
+	require.Len(t, sim.Policies, 1)
+	require.Equal(t, model.PolicyTypeModelAllowlist, sim.Policies[0].PolicyType)
+	require.False(t, sim.Policies[0].Passed)
+
+	// A key is looked up by its prefix, as the gateway identifies it
+	request["model"] = "gpt-4o"
+	code, sim = simulate(policies.SimulatePolicyBody{KeyID: "test_", Request: request})
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, sim.Allowed)
+	require.Equal(t, appID.String(), sim.AppID)
+	require.Equal(t, "test_", sim.KeyID)
+	require.Equal(t, "gpt-4o", sim.RoutedModel)
+
+	code, _ = simulate(policies.SimulatePolicyBody{AppID: orgID.String(), Request: request})
+	require.Equal(t, http.StatusNotFound, code)
+
+	code, _ = simulate(policies.SimulatePolicyBody{AppID: orgID.String(), KeyID: "test_", Request: request})
+	require.Equal(t, http.StatusNotFound, code, "the key belongs to another app")
+
+	code, _ = simulate(policies.SimulatePolicyBody{Request: request})
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = simulate(policies.SimulatePolicyBody{AppID: appID.String(), Endpoint: "/v1/batches", Request: request})
+	require.Equal(t, http.StatusBadRequest, code)
+
+	// Apps and keys in another organisation look like they don't exist
+	_, otherAppID := fixtures.CreateTestOrgAndAppWithSuffix(t, "other")
+	code, _ = simulate(policies.SimulatePolicyBody{AppID: otherAppID.String(), Request: request})
+	require.Equal(t, http.StatusNotFound, code)
+
+	other := context.WithValue(context.Background(), middleware.ScopedTokenKey, model.ScopedToken{OrgID: uuid.NewString()})
+	body, _ := json.Marshal(policies.SimulatePolicyBody{KeyID: "test_", Request: request})
+	resp := api.PostCtx(other, "/api/policies/simulate", "Content-Type: application/json", bytes.NewReader(body))
+	require.Equal(t, http.StatusNotFound, resp.Code)
+
+	// A session without an organisation can't simulate at all
+	body, _ = json.Marshal(policies.SimulatePolicyBody{AppID: appID.String(), Request: request})
+	resp = api.Post("/api/policies/simulate", "Content-Type: application/json", bytes.NewReader(body))
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
 func TestDeletePolicy_Integration(t *testing.T) {
 	pgConnStr, _ := testkit.SetupTestContainers(t, testkit.DefaultContainerConfig())
 
@@ -336,7 +544,7 @@ func TestDeletePolicy_Integration(t *testing.T) {
 	// Create a test policy directly in the database
 	policyID := fixtures.CreateTestPolicy(t, orgID, appID, model.PolicyTypeRateLimit, `{"requests_per_minute": 60}`)
 
-	svc := policies.NewService(policiesRepo)
+	svc := policies.NewService(policiesRepo, nil, nil)
 	router := policies.NewRouter(svc)
 
 	api := testkit.SetupPublicTestAPI(t, func(grp *huma.Group) {
@@ -363,7 +571,7 @@ func TestPolicyErrorCases_Integration(t *testing.T) {
 	orgID, appID := fixtures.CreateTestOrgAndApp(t)
 
 	policiesRepo := policiesrepo.NewPostgresRepo(pg.Queries)
-	svc := policies.NewService(policiesRepo)
+	svc := policies.NewService(policiesRepo, nil, nil)
 	router := policies.NewRouter(svc)
 
 	api := testkit.SetupPublicTestAPI(t, func(grp *huma.Group) {
@@ -482,7 +690,7 @@ func TestService_GetPoliciesByType(t *testing.T) {
 	orgID, appID := fixtures.CreateTestOrgAndApp(t)
 
 	policiesRepo := policiesrepo.NewPostgresRepo(pg.Queries)
-	svc := policies.NewService(policiesRepo)
+	svc := policies.NewService(policiesRepo, nil, nil)
 
 	// Create multiple policies of different types
 	rateLimitPolicyID := fixtures.CreateTestPolicy(t, orgID, appID, model.PolicyTypeRateLimit, `{"requests_per_minute": 60}`)
@@ -520,7 +728,7 @@ func TestService_GetAppsForPolicy(t *testing.T) {
 	_, appID2 := fixtures.CreateTestOrgAndApp(t)
 
 	policiesRepo := policiesrepo.NewPostgresRepo(pg.Queries)
-	svc := policies.NewService(policiesRepo)
+	svc := policies.NewService(policiesRepo, nil, nil)
 
 	// Create a policy and attach it to both apps
 	policyID := fixtures.CreateTestPolicy(t, orgID, appID1, model.PolicyTypeRateLimit, `{"requests_per_minute": 60}`)
@@ -2,8 +2,11 @@ package policies
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"net/http"
 
+	gwpolicies "github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
 	"github.com/WebDeveloperBen/ai-gateway/internal/model"
 	"github.com/WebDeveloperBen/ai-gateway/internal/repository/policies"
 	"github.com/google/uuid"
@@ -22,14 +25,31 @@ type PoliciesService interface {
 	AttachPolicyToApp(ctx context.Context, policyID, appID uuid.UUID) error
 	DetachPolicyFromApp(ctx context.Context, policyID, appID uuid.UUID) error
 	GetAppsForPolicy(ctx context.Context, policyID uuid.UUID) ([]*model.Application, error)
+	AttachPolicy(ctx context.Context, orgID, policyID uuid.UUID, scope model.PolicyScope, targetID uuid.UUID) error
+	DetachPolicy(ctx context.Context, orgID, policyID uuid.UUID, scope model.PolicyScope, targetID uuid.UUID) error
+	SimulatePolicies(ctx context.Context, orgID string, req SimulatePolicyBody) (*PolicySimulation, error)
+}
+
+// Simulator runs sample requests through the gateway's policies without
+// sending them
+type Simulator interface {
+	Simulate(ctx context.Context, orgID, appID, keyID, endpoint string, header http.Header, body []byte) (*gwpolicies.Simulation, error)
+}
+
+// CacheInvalidator drops the gateway's cached policies for a scope so
+// attachment changes apply to the next request
+type CacheInvalidator interface {
+	InvalidateCache(ctx context.Context, level model.PolicyScope, id string) error
 }
 
 type policiesService struct {
-	repo policies.Repository
+	repo      policies.Repository
+	simulator Simulator
+	cache     CacheInvalidator
 }
 
-func NewService(repo policies.Repository) PoliciesService {
-	return &policiesService{repo: repo}
+func NewService(repo policies.Repository, simulator Simulator, cache CacheInvalidator) PoliciesService {
+	return &policiesService{repo: repo, simulator: simulator, cache: cache}
 }
 
 func (s *policiesService) CreatePolicy(ctx context.Context, orgID uuid.UUID, req CreatePolicyBody) (*Policy, error) {
@@ -40,7 +60,7 @@ func (s *policiesService) CreatePolicy(ctx context.Context, orgID uuid.UUID, req
 		return nil, errors.New("config is required")
 	}
 
-	policy, err := s.repo.Create(ctx, orgID, req.PolicyType, req.Config, req.Enabled)
+	policy, err := s.repo.Create(ctx, orgID, req.PolicyType, req.Config, req.Enabled, req.Mode)
 	if err != nil {
 		return nil, err
 	}
@@ -115,7 +135,11 @@ func (s *policiesService) UpdatePolicy(ctx context.Context, id uuid.UUID, req Up
 		return nil, errors.New("config is required")
 	}
 
-	policy, err := s.repo.Update(ctx, id, req.PolicyType, req.Config, req.Enabled)
+	var policy *model.Policy
+	err := s.changePolicy(ctx, id, func() (err error) {
+		policy, err = s.repo.Update(ctx, id, req.PolicyType, req.Config, req.Enabled, req.Mode)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -124,29 +148,148 @@ func (s *policiesService) UpdatePolicy(ctx context.Context, id uuid.UUID, req Up
 }
 
 func (s *policiesService) DeletePolicy(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Delete(ctx, id)
+	return s.changePolicy(ctx, id, func() error { return s.repo.Delete(ctx, id) })
 }
 
 func (s *policiesService) EnablePolicy(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Enable(ctx, id)
+	return s.changePolicy(ctx, id, func() error { return s.repo.Enable(ctx, id) })
 }
 
 func (s *policiesService) DisablePolicy(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Disable(ctx, id)
+	return s.changePolicy(ctx, id, func() error { return s.repo.Disable(ctx, id) })
+}
+
+// changePolicy applies change to a policy, then drops the cached policies of
+// every scope it is attached to so the change applies to the next request.
+// Attachments are read first because deleting a policy removes them.
+func (s *policiesService) changePolicy(ctx context.Context, id uuid.UUID, change func() error) error {
+	var attachments []model.PolicyAttachment
+	if s.cache != nil {
+		var err error
+		if attachments, err = s.repo.ListAttachments(ctx, id); err != nil {
+			return err
+		}
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		if err := s.invalidate(ctx, a.Scope, a.TargetID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *policiesService) AttachPolicyToApp(ctx context.Context, policyID, appID uuid.UUID) error {
-	return s.repo.AttachToApp(ctx, policyID, appID)
+	if err := s.repo.AttachToApp(ctx, policyID, appID); err != nil {
+		return err
+	}
+	return s.invalidate(ctx, model.PolicyScopeApp, appID)
 }
 
 func (s *policiesService) DetachPolicyFromApp(ctx context.Context, policyID, appID uuid.UUID) error {
-	return s.repo.DetachFromApp(ctx, policyID, appID)
+	if err := s.repo.DetachFromApp(ctx, policyID, appID); err != nil {
+		return err
+	}
+	return s.invalidate(ctx, model.PolicyScopeApp, appID)
 }
 
 func (s *policiesService) GetAppsForPolicy(ctx context.Context, policyID uuid.UUID) ([]*model.Application, error) {
 	return s.repo.GetAppsForPolicy(ctx, policyID)
 }
 
+func (s *policiesService) AttachPolicy(ctx context.Context, orgID, policyID uuid.UUID, scope model.PolicyScope, targetID uuid.UUID) error {
+	if err := s.repo.AttachToScope(ctx, orgID, policyID, scope, targetID); err != nil {
+		return err
+	}
+	return s.invalidate(ctx, scope, targetID)
+}
+
+func (s *policiesService) DetachPolicy(ctx context.Context, orgID, policyID uuid.UUID, scope model.PolicyScope, targetID uuid.UUID) error {
+	if err := s.repo.DetachFromScope(ctx, orgID, policyID, scope, targetID); err != nil {
+		return err
+	}
+	return s.invalidate(ctx, scope, targetID)
+}
+
+// invalidate drops the cached policies of every scope that includes targetID.
+// The gateway caches an API key's policies under its prefix, not its ID.
+func (s *policiesService) invalidate(ctx context.Context, scope model.PolicyScope, targetID uuid.UUID) error {
+	if s.cache == nil {
+		return nil
+	}
+	id := targetID.String()
+	if scope == model.PolicyScopeKey {
+		prefix, err := s.repo.GetKeyPrefix(ctx, targetID)
+		if err != nil {
+			return err
+		}
+		id = prefix
+	}
+	return s.cache.InvalidateCache(ctx, scope, id)
+}
+
+func (s *policiesService) SimulatePolicies(ctx context.Context, orgID string, req SimulatePolicyBody) (*PolicySimulation, error) {
+	if s.simulator == nil {
+		return nil, errors.New("policy simulation is not available")
+	}
+	if req.AppID == "" && req.KeyID == "" {
+		return nil, errors.New("app_id or key_id is required")
+	}
+	if req.AppID != "" {
+		if _, err := uuid.Parse(req.AppID); err != nil {
+			return nil, errors.New("invalid app_id")
+		}
+	}
+	body, err := json.Marshal(req.Request)
+	if err != nil {
+		return nil, errors.New("invalid request")
+	}
+	header := http.Header{}
+	for name, value := range req.Headers {
+		header.Set(name, value)
+	}
+
+	sim, err := s.simulator.Simulate(ctx, orgID, req.AppID, req.KeyID, req.Endpoint, header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PolicySimulation{
+		OrgID:           sim.Scope.OrgID,
+		AppID:           sim.Scope.AppID,
+		UserID:          sim.Scope.UserID,
+		KeyID:           sim.Scope.KeyID,
+		Allowed:         sim.Allowed,
+		Status:          sim.Status,
+		Reason:          sim.Reason,
+		Model:           sim.Model,
+		RoutedModel:     sim.RoutedModel,
+		Priority:        sim.Priority,
+		EstimatedTokens: sim.EstimatedTokens,
+		BodyRewritten:   sim.BodyRewritten,
+		Tags:            sim.Tags,
+		Policies:        make([]SimulatedPolicy, len(sim.Policies)),
+	}
+	for i, outcome := range sim.Policies {
+		result.Policies[i] = SimulatedPolicy{
+			PolicyType:    outcome.Type,
+			Mode:          outcome.Mode,
+			Passed:        outcome.Passed,
+			Phase:         outcome.Phase,
+			Status:        outcome.Status,
+			Reason:        outcome.Reason,
+			ModelOverride: outcome.ModelOverride,
+			Priority:      outcome.Priority,
+			Tags:          outcome.Tags,
+			Metadata:      outcome.Metadata,
+			BodyRewritten: outcome.BodyRewritten,
+		}
+	}
+	return result, nil
+}
+
 func (s *policiesService) convertToAPI(policy *model.Policy) *Policy {
 	return &Policy{
 		ID:         policy.ID.String(),
@@ -154,6 +297,7 @@ func (s *policiesService) convertToAPI(policy *model.Policy) *Policy {
 		PolicyType: policy.PolicyType,
 		Config:     policy.Config,
 		Enabled:    policy.Enabled,
+		Mode:       policy.Mode,
 		CreatedAt:  policy.CreatedAt,
 		UpdatedAt:  policy.UpdatedAt,
 	}
@@ -0,0 +1,56 @@
+package pricing
+
+import (
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+type ModelPrice struct {
+	ID                    string    `json:"id"`
+	OrgID                 string    `json:"org_id"`
+	Provider              string    `json:"provider"`
+	ModelName             string    `json:"model_name"`
+	DeploymentType        string    `json:"deployment_type"`
+	Currency              string    `json:"currency"`
+	InputPerMillion       float64   `json:"input_per_million"`
+	CachedInputPerMillion float64   `json:"cached_input_per_million"`
+	OutputPerMillion      float64   `json:"output_per_million"`
+	PerImage              float64   `json:"per_image"`
+	PerAudioSecond        float64   `json:"per_audio_second"`
+	EffectiveFrom         time.Time `json:"effective_from"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+type CreateModelPriceBody struct {
+	Provider              string     `json:"provider" required:"true"`
+	ModelName             string     `json:"model_name" required:"true"`
+	DeploymentType        string     `json:"deployment_type,omitempty" doc:"standard, global, provisioned or batch; defaults to standard"`
+	Currency              string     `json:"currency,omitempty" doc:"ISO 4217 code; defaults to USD"`
+	InputPerMillion       float64    `json:"input_per_million" minimum:"0" doc:"Price per million uncached prompt tokens"`
+	CachedInputPerMillion float64    `json:"cached_input_per_million,omitempty" minimum:"0" doc:"Price per million cached prompt tokens; defaults to the input price"`
+	OutputPerMillion      float64    `json:"output_per_million" minimum:"0" doc:"Price per million completion tokens"`
+	PerImage              float64    `json:"per_image,omitempty" minimum:"0"`
+	PerAudioSecond        float64    `json:"per_audio_second,omitempty" minimum:"0"`
+	EffectiveFrom         *time.Time `json:"effective_from,omitempty" doc:"When this price takes effect; defaults to now"`
+}
+
+type CreateModelPriceRequest struct {
+	Body CreateModelPriceBody `json:"body"`
+}
+
+type CreateModelPriceResponse struct {
+	Body *ModelPrice `json:"body"`
+}
+
+type ListModelPricesRequest struct {
+	model.ListRequest
+}
+
+type ListModelPricesResponse struct {
+	Body []*ModelPrice `json:"body"`
+}
+
+type GetModelPriceResponse struct {
+	Body *ModelPrice `json:"body"`
+}
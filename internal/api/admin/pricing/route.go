@@ -0,0 +1,119 @@
+package pricing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/exceptions"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+type PricingRouter struct {
+	Pricing PricingService
+}
+
+func NewRouter(pricing PricingService) *PricingRouter {
+	return &PricingRouter{Pricing: pricing}
+}
+
+func (r *PricingRouter) RegisterRoutes(grp *huma.Group) {
+	// POST /pricing
+	huma.Register(grp, huma.Operation{
+		OperationID:   "admin-create-model-price",
+		Method:        http.MethodPost,
+		Path:          "/pricing",
+		Summary:       "Add a model price",
+		Description:   "Adds a new version of a model's price. Usage recorded from effective_from onwards is costed at this price; earlier usage keeps the price it was recorded with.",
+		DefaultStatus: http.StatusCreated,
+		Tags:          []string{"Pricing"},
+	}, exceptions.Handle(func(ctx context.Context, in *CreateModelPriceRequest) (*CreateModelPriceResponse, error) {
+		// Get org ID from context (set by middleware)
+		orgID, ok := ctx.Value("org_id").(uuid.UUID)
+		if !ok {
+			return nil, huma.Error401Unauthorized("organization not found in context")
+		}
+
+		price, err := r.Pricing.CreatePrice(ctx, orgID, in.Body)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+
+		return &CreateModelPriceResponse{Body: price}, nil
+	}))
+
+	// GET /pricing
+	huma.Register(grp, huma.Operation{
+		OperationID: "admin-list-model-prices",
+		Method:      http.MethodGet,
+		Path:        "/pricing",
+		Summary:     "List model prices",
+		Description: "Retrieves every price version configured for the organization, newest first per model.",
+		Tags:        []string{"Pricing"},
+	}, exceptions.Handle(func(ctx context.Context, in *ListModelPricesRequest) (*ListModelPricesResponse, error) {
+		// Get org ID from context (set by middleware)
+		orgID, ok := ctx.Value("org_id").(uuid.UUID)
+		if !ok {
+			return nil, huma.Error401Unauthorized("organization not found in context")
+		}
+
+		normalized := model.NormalizePagination(model.ListRequest{Limit: in.Limit, Offset: in.Offset})
+		prices, err := r.Pricing.ListPrices(ctx, orgID, normalized.Limit, normalized.Offset)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to list model prices")
+		}
+
+		return &ListModelPricesResponse{Body: prices}, nil
+	}))
+
+	// GET /pricing/{id}
+	huma.Register(grp, huma.Operation{
+		OperationID: "admin-get-model-price",
+		Method:      http.MethodGet,
+		Path:        "/pricing/{id}",
+		Summary:     "Get model price",
+		Description: "Retrieves a single price version by its ID.",
+		Tags:        []string{"Pricing"},
+	}, exceptions.Handle(func(ctx context.Context, in *struct {
+		ID string `path:"id" required:"true"`
+	},
+	) (*GetModelPriceResponse, error) {
+		id, err := uuid.Parse(in.ID)
+		if err != nil {
+			return nil, huma.Error400BadRequest("invalid price ID")
+		}
+
+		price, err := r.Pricing.GetPrice(ctx, id)
+		if err != nil {
+			return nil, huma.Error404NotFound("model price not found")
+		}
+
+		return &GetModelPriceResponse{Body: price}, nil
+	}))
+
+	// DELETE /pricing/{id}
+	huma.Register(grp, huma.Operation{
+		OperationID:   "admin-delete-model-price",
+		Method:        http.MethodDelete,
+		Path:          "/pricing/{id}",
+		Summary:       "Delete model price",
+		Description:   "Deletes a price version. Usage already recorded keeps its cost.",
+		DefaultStatus: http.StatusNoContent,
+		Tags:          []string{"Pricing"},
+	}, exceptions.Handle(func(ctx context.Context, in *struct {
+		ID string `path:"id" required:"true"`
+	},
+	) (*struct{}, error) {
+		id, err := uuid.Parse(in.ID)
+		if err != nil {
+			return nil, huma.Error400BadRequest("invalid price ID")
+		}
+
+		if err := r.Pricing.DeletePrice(ctx, id); err != nil {
+			return nil, huma.Error404NotFound("model price not found")
+		}
+
+		return &struct{}{}, nil
+	}))
+}
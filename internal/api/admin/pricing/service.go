@@ -0,0 +1,131 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/repository/pricing"
+	"github.com/google/uuid"
+)
+
+type PricingService interface {
+	CreatePrice(ctx context.Context, orgID uuid.UUID, req CreateModelPriceBody) (*ModelPrice, error)
+	GetPrice(ctx context.Context, id uuid.UUID) (*ModelPrice, error)
+	ListPrices(ctx context.Context, orgID uuid.UUID, limit, offset int) ([]*ModelPrice, error)
+	DeletePrice(ctx context.Context, id uuid.UUID) error
+}
+
+type pricingService struct {
+	repo pricing.Repository
+	now  func() time.Time
+}
+
+func NewService(repo pricing.Repository) PricingService {
+	return &pricingService{repo: repo, now: time.Now}
+}
+
+func (s *pricingService) CreatePrice(ctx context.Context, orgID uuid.UUID, req CreateModelPriceBody) (*ModelPrice, error) {
+	if req.Provider == "" {
+		return nil, errors.New("provider is required")
+	}
+	if req.ModelName == "" {
+		return nil, errors.New("model_name is required")
+	}
+
+	deploymentType := req.DeploymentType
+	if deploymentType == "" {
+		deploymentType = model.DeploymentTypeStandard
+	}
+	switch deploymentType {
+	case model.DeploymentTypeStandard, model.DeploymentTypeGlobal, model.DeploymentTypeProvisioned, model.DeploymentTypeBatch:
+		// Valid
+	default:
+		return nil, errors.New("deployment_type must be one of: standard, global, provisioned, batch")
+	}
+
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	if len(currency) != 3 {
+		return nil, errors.New("currency must be a three-letter ISO 4217 code")
+	}
+
+	for _, v := range []float64{req.InputPerMillion, req.CachedInputPerMillion, req.OutputPerMillion, req.PerImage, req.PerAudioSecond} {
+		if v < 0 {
+			return nil, errors.New("prices must not be negative")
+		}
+	}
+
+	effectiveFrom := s.now()
+	if req.EffectiveFrom != nil {
+		effectiveFrom = *req.EffectiveFrom
+	}
+
+	price, err := s.repo.Create(ctx, model.ModelPrice{
+		OrgID:                 orgID,
+		Provider:              req.Provider,
+		ModelName:             req.ModelName,
+		DeploymentType:        deploymentType,
+		Currency:              currency,
+		InputPerMillion:       req.InputPerMillion,
+		CachedInputPerMillion: req.CachedInputPerMillion,
+		OutputPerMillion:      req.OutputPerMillion,
+		PerImage:              req.PerImage,
+		PerAudioSecond:        req.PerAudioSecond,
+		EffectiveFrom:         effectiveFrom,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.convertToAPI(price), nil
+}
+
+func (s *pricingService) GetPrice(ctx context.Context, id uuid.UUID) (*ModelPrice, error) {
+	price, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.convertToAPI(price), nil
+}
+
+func (s *pricingService) ListPrices(ctx context.Context, orgID uuid.UUID, limit, offset int) ([]*ModelPrice, error) {
+	prices, err := s.repo.List(ctx, orgID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ModelPrice, len(prices))
+	for i, price := range prices {
+		result[i] = s.convertToAPI(price)
+	}
+	return result, nil
+}
+
+func (s *pricingService) DeletePrice(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *pricingService) convertToAPI(p *model.ModelPrice) *ModelPrice {
+	return &ModelPrice{
+		ID:                    p.ID.String(),
+		OrgID:                 p.OrgID.String(),
+		Provider:              p.Provider,
+		ModelName:             p.ModelName,
+		DeploymentType:        p.DeploymentType,
+		Currency:              p.Currency,
+		InputPerMillion:       p.InputPerMillion,
+		CachedInputPerMillion: p.CachedInputPerMillion,
+		OutputPerMillion:      p.OutputPerMillion,
+		PerImage:              p.PerImage,
+		PerAudioSecond:        p.PerAudioSecond,
+		EffectiveFrom:         p.EffectiveFrom,
+		CreatedAt:             p.CreatedAt,
+	}
+}
@@ -0,0 +1,77 @@
+package pricing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	pricingrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/pricing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRepo struct {
+	pricingrepo.Repository
+	created *model.ModelPrice
+}
+
+func (f *fakeRepo) Create(ctx context.Context, p model.ModelPrice) (*model.ModelPrice, error) {
+	p.ID = uuid.New()
+	f.created = &p
+	return &p, nil
+}
+
+func TestCreatePrice(t *testing.T) {
+	now := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Defaults", func(t *testing.T) {
+		repo := &fakeRepo{}
+		svc := &pricingService{repo: repo, now: func() time.Time { return now }}
+
+		price, err := svc.CreatePrice(context.Background(), uuid.New(), CreateModelPriceBody{
+			Provider:         "openai",
+			ModelName:        "gpt-4o",
+			InputPerMillion:  2.5,
+			OutputPerMillion: 10,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, model.DeploymentTypeStandard, price.DeploymentType)
+		assert.Equal(t, "USD", price.Currency)
+		assert.Equal(t, now, price.EffectiveFrom)
+	})
+
+	t.Run("ExplicitVersion", func(t *testing.T) {
+		repo := &fakeRepo{}
+		svc := &pricingService{repo: repo, now: func() time.Time { return now }}
+		from := now.AddDate(0, 1, 0)
+
+		price, err := svc.CreatePrice(context.Background(), uuid.New(), CreateModelPriceBody{
+			Provider:       "azureopenai",
+			ModelName:      "gpt-4o",
+			DeploymentType: model.DeploymentTypeBatch,
+			Currency:       "eur",
+			EffectiveFrom:  &from,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, model.DeploymentTypeBatch, price.DeploymentType)
+		assert.Equal(t, "EUR", price.Currency)
+		assert.Equal(t, from, price.EffectiveFrom)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		svc := &pricingService{repo: &fakeRepo{}, now: time.Now}
+		cases := map[string]CreateModelPriceBody{
+			"missing provider":   {ModelName: "gpt-4o"},
+			"missing model":      {Provider: "openai"},
+			"bad deployment":     {Provider: "openai", ModelName: "gpt-4o", DeploymentType: "spot"},
+			"bad currency":       {Provider: "openai", ModelName: "gpt-4o", Currency: "dollars"},
+			"negative per image": {Provider: "openai", ModelName: "gpt-4o", PerImage: -1},
+		}
+		for name, body := range cases {
+			_, err := svc.CreatePrice(context.Background(), uuid.New(), body)
+			assert.Error(t, err, name)
+		}
+	})
+}
@@ -7,26 +7,38 @@ import (
 )
 
 type UsageMetric struct {
-	ID                string    `json:"id"`
-	OrgID             string    `json:"org_id"`
-	AppID             string    `json:"app_id"`
-	APIKeyID          string    `json:"api_key_id"`
-	ModelID           *string   `json:"model_id,omitempty"`
-	Provider          string    `json:"provider"`
-	ModelName         string    `json:"model_name"`
-	PromptTokens      int       `json:"prompt_tokens"`
-	CompletionTokens  int       `json:"completion_tokens"`
-	TotalTokens       int       `json:"total_tokens"`
-	RequestSizeBytes  int       `json:"request_size_bytes"`
-	ResponseSizeBytes int       `json:"response_size_bytes"`
-	Timestamp         time.Time `json:"timestamp"`
+	ID                string         `json:"id"`
+	OrgID             string         `json:"org_id"`
+	AppID             string         `json:"app_id"`
+	APIKeyID          string         `json:"api_key_id"`
+	ModelID           *string        `json:"model_id,omitempty"`
+	Provider          string         `json:"provider"`
+	ModelName         string         `json:"model_name"`
+	PromptTokens      int            `json:"prompt_tokens"`
+	CompletionTokens  int            `json:"completion_tokens"`
+	TotalTokens       int            `json:"total_tokens"`
+	RequestSizeBytes  int            `json:"request_size_bytes"`
+	ResponseSizeBytes int            `json:"response_size_bytes"`
+	Timestamp         time.Time      `json:"timestamp"`
+	Estimated         bool           `json:"estimated" doc:"Token counts were estimated by the gateway because the provider reported no usage"`
+	Cost              *float64       `json:"cost,omitempty" doc:"Cost in currency units; omitted when no price was in force for the model"`
+	Currency          string         `json:"currency,omitempty"`
+	Metadata          map[string]any `json:"metadata,omitempty" doc:"Values recorded by policies, e.g. injection_score and tags"`
 }
 
 type TokenSummary struct {
-	TotalPromptTokens     int `json:"total_prompt_tokens"`
-	TotalCompletionTokens int `json:"total_completion_tokens"`
-	TotalTokens           int `json:"total_tokens"`
-	RequestCount          int `json:"request_count"`
+	TotalPromptTokens     int         `json:"total_prompt_tokens"`
+	TotalCompletionTokens int         `json:"total_completion_tokens"`
+	TotalTokens           int         `json:"total_tokens"`
+	RequestCount          int         `json:"request_count"`
+	Costs                 []CostTotal `json:"costs" doc:"Total cost of priced requests, one entry per currency"`
+	UnpricedRequestCount  int         `json:"unpriced_request_count" doc:"Requests with no price in force for their model when recorded"`
+}
+
+type CostTotal struct {
+	Currency     string  `json:"currency"`
+	Amount       float64 `json:"amount"`
+	AmountMicros int64   `json:"amount_micros" doc:"Exact total in millionths of the currency unit"`
 }
 
 type ModelUsageSummary struct {
@@ -71,12 +71,7 @@ func (s *usageService) GetTokenSummaryByAppID(ctx context.Context, appID uuid.UU
 		return nil, err
 	}
 
-	return &TokenSummary{
-		TotalPromptTokens:     summary.TotalPromptTokens,
-		TotalCompletionTokens: summary.TotalCompletionTokens,
-		TotalTokens:           summary.TotalTokens,
-		RequestCount:          summary.RequestCount,
-	}, nil
+	return s.convertSummaryToAPI(summary), nil
 }
 
 func (s *usageService) GetTokenSummaryByOrgID(ctx context.Context, orgID uuid.UUID, start, end time.Time) (*TokenSummary, error) {
@@ -85,12 +80,7 @@ func (s *usageService) GetTokenSummaryByOrgID(ctx context.Context, orgID uuid.UU
 		return nil, err
 	}
 
-	return &TokenSummary{
-		TotalPromptTokens:     summary.TotalPromptTokens,
-		TotalCompletionTokens: summary.TotalCompletionTokens,
-		TotalTokens:           summary.TotalTokens,
-		RequestCount:          summary.RequestCount,
-	}, nil
+	return s.convertSummaryToAPI(summary), nil
 }
 
 func (s *usageService) GetUsageByModel(ctx context.Context, appID uuid.UUID, start, end time.Time, limit, offset int) ([]*ModelUsageSummary, error) {
@@ -113,6 +103,30 @@ func (s *usageService) GetUsageByModel(ctx context.Context, appID uuid.UUID, sta
 	return result, nil
 }
 
+func (s *usageService) convertSummaryToAPI(summary *usage.TokenSummary) *TokenSummary {
+	costs := make([]CostTotal, len(summary.Costs))
+	for i, c := range summary.Costs {
+		costs[i] = CostTotal{
+			Currency:     c.Currency,
+			Amount:       microsToAmount(c.CostMicros),
+			AmountMicros: c.CostMicros,
+		}
+	}
+
+	return &TokenSummary{
+		TotalPromptTokens:     summary.TotalPromptTokens,
+		TotalCompletionTokens: summary.TotalCompletionTokens,
+		TotalTokens:           summary.TotalTokens,
+		RequestCount:          summary.RequestCount,
+		Costs:                 costs,
+		UnpricedRequestCount:  summary.UnpricedCount,
+	}
+}
+
+func microsToAmount(micros int64) float64 {
+	return float64(micros) / 1e6
+}
+
 func (s *usageService) convertToAPI(metric *model.UsageMetric) *UsageMetric {
 	var modelID *string
 	if metric.ModelID != nil {
@@ -120,6 +134,12 @@ func (s *usageService) convertToAPI(metric *model.UsageMetric) *UsageMetric {
 		modelID = &id
 	}
 
+	var cost *float64
+	if metric.CostMicros != nil {
+		amount := microsToAmount(*metric.CostMicros)
+		cost = &amount
+	}
+
 	return &UsageMetric{
 		ID:                metric.ID.String(),
 		OrgID:             metric.OrgID.String(),
@@ -134,5 +154,9 @@ func (s *usageService) convertToAPI(metric *model.UsageMetric) *UsageMetric {
 		RequestSizeBytes:  metric.RequestSizeBytes,
 		ResponseSizeBytes: metric.ResponseSizeBytes,
 		Timestamp:         metric.Timestamp,
+		Estimated:         metric.Estimated,
+		Cost:              cost,
+		Currency:          metric.Currency,
+		Metadata:          metric.Metadata,
 	}
 }
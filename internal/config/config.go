@@ -36,6 +36,15 @@ type Config struct {
 	AppRegistrationTenantID     string
 	AppRegistrationRedirectURL  string
 	EnableRedisCircuitBreaker   bool
+	BatchPollInterval           time.Duration
+	AsyncWorkers                int
+	AsyncMaxAttempts            int
+	AsyncJobRetention           time.Duration
+	AsyncWebhookSecret          string
+	AlertWebhookURL             string
+	AlertWebhookSecret          string
+	SchedulerMaxInFlight        int
+	SchedulerCooldown           time.Duration
 }
 
 // Loads all environment variables from the .env file
@@ -65,6 +74,15 @@ func loadConfig() Config {
 		AppRegistrationTenantID:     getEnv("AZURE_APP_REGISTRATION_TENANT_ID", "dummy-tenant-id"),
 		AppRegistrationRedirectURL:  getEnv("AZURE_APP_REGISTRATION_REDIRECT_URL", "http://localhost:3000/auth/callback"),
 		EnableRedisCircuitBreaker:   getEnvAsBoolean("REDIS_CIRCUIT_BREAKER_ENABLED", true),
+		BatchPollInterval:           getEnvAsDuration("BATCH_POLL_INTERVAL_IN_SECONDS", time.Minute),
+		AsyncWorkers:                int(GetEnvAsInt64("ASYNC_WORKERS", 4)),
+		AsyncMaxAttempts:            int(GetEnvAsInt64("ASYNC_MAX_ATTEMPTS", 5)),
+		AsyncJobRetention:           getEnvAsDuration("ASYNC_JOB_RETENTION_IN_SECONDS", 24*time.Hour),
+		AsyncWebhookSecret:          getEnv("ASYNC_WEBHOOK_SECRET", ""),
+		AlertWebhookURL:             getEnv("ALERT_WEBHOOK_URL", ""),
+		AlertWebhookSecret:          getEnv("ALERT_WEBHOOK_SECRET", ""),
+		SchedulerMaxInFlight:        int(GetEnvAsInt64("SCHEDULER_MAX_IN_FLIGHT", 0)),
+		SchedulerCooldown:           getEnvAsDuration("SCHEDULER_COOLDOWN_IN_SECONDS", 5*time.Second),
 	}
 }
 
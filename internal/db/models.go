@@ -43,6 +43,38 @@ type ApplicationConfig struct {
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
 }
 
+type BatchFile struct {
+	ID        uuid.UUID          `json:"id"`
+	OrgID     uuid.UUID          `json:"org_id"`
+	AppID     uuid.UUID          `json:"app_id"`
+	KeyPrefix string             `json:"key_prefix"`
+	Provider  string             `json:"provider"`
+	FileID    string             `json:"file_id"`
+	Purpose   string             `json:"purpose"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type BatchJob struct {
+	ID            uuid.UUID          `json:"id"`
+	OrgID         uuid.UUID          `json:"org_id"`
+	AppID         uuid.UUID          `json:"app_id"`
+	KeyPrefix     string             `json:"key_prefix"`
+	Provider      string             `json:"provider"`
+	BatchID       string             `json:"batch_id"`
+	Endpoint      string             `json:"endpoint"`
+	Status        string             `json:"status"`
+	InputFileID   string             `json:"input_file_id"`
+	OutputFileID  *string            `json:"output_file_id"`
+	ErrorFileID   *string            `json:"error_file_id"`
+	UsageRecorded bool               `json:"usage_recorded"`
+	CompletedAt   pgtype.Timestamptz `json:"completed_at"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+	PollFailures  int32              `json:"poll_failures"`
+	NextPollAt    pgtype.Timestamptz `json:"next_poll_at"`
+	PollError     *string            `json:"poll_error"`
+}
+
 type GooseDbVersion struct {
 	ID        int32            `json:"id"`
 	VersionID int64            `json:"version_id"`
@@ -65,6 +97,22 @@ type Model struct {
 	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
 }
 
+type ModelPrice struct {
+	ID                    uuid.UUID          `json:"id"`
+	OrgID                 uuid.UUID          `json:"org_id"`
+	Provider              string             `json:"provider"`
+	ModelName             string             `json:"model_name"`
+	DeploymentType        string             `json:"deployment_type"`
+	Currency              string             `json:"currency"`
+	InputPerMillion       float64            `json:"input_per_million"`
+	CachedInputPerMillion float64            `json:"cached_input_per_million"`
+	OutputPerMillion      float64            `json:"output_per_million"`
+	PerImage              float64            `json:"per_image"`
+	PerAudioSecond        float64            `json:"per_audio_second"`
+	EffectiveFrom         pgtype.Timestamptz `json:"effective_from"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+}
+
 type Organisation struct {
 	ID        uuid.UUID          `json:"id"`
 	Name      string             `json:"name"`
@@ -112,6 +160,7 @@ type Policy struct {
 	Enabled    bool               `json:"enabled"`
 	CreatedAt  pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+	Mode       string             `json:"mode"`
 }
 
 type PolicyApplication struct {
@@ -141,6 +190,10 @@ type UsageMetric struct {
 	RequestSizeBytes  int32              `json:"request_size_bytes"`
 	ResponseSizeBytes int32              `json:"response_size_bytes"`
 	Timestamp         pgtype.Timestamptz `json:"timestamp"`
+	Estimated         bool               `json:"estimated"`
+	CostMicros        pgtype.Int8        `json:"cost_micros"`
+	Currency          *string            `json:"currency"`
+	Metadata          []byte             `json:"metadata"`
 }
 
 type User struct {
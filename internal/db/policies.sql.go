@@ -9,8 +9,25 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const attachPolicyToAPIKey = `-- name: AttachPolicyToAPIKey :exec
+INSERT INTO policy_api_keys (policy_id, key_id)
+VALUES ($1, $2)
+ON CONFLICT (policy_id, key_id) DO NOTHING
+`
+
+type AttachPolicyToAPIKeyParams struct {
+	PolicyID uuid.UUID `json:"policy_id"`
+	KeyID    uuid.UUID `json:"key_id"`
+}
+
+func (q *Queries) AttachPolicyToAPIKey(ctx context.Context, arg AttachPolicyToAPIKeyParams) error {
+	_, err := q.db.Exec(ctx, attachPolicyToAPIKey, arg.PolicyID, arg.KeyID)
+	return err
+}
+
 const attachPolicyToApp = `-- name: AttachPolicyToApp :exec
 INSERT INTO policy_applications (policy_id, app_id)
 VALUES ($1, $2)
@@ -27,13 +44,45 @@ func (q *Queries) AttachPolicyToApp(ctx context.Context, arg AttachPolicyToAppPa
 	return err
 }
 
+const attachPolicyToOrg = `-- name: AttachPolicyToOrg :exec
+INSERT INTO policy_organisations (policy_id, org_id)
+VALUES ($1, $2)
+ON CONFLICT (policy_id, org_id) DO NOTHING
+`
+
+type AttachPolicyToOrgParams struct {
+	PolicyID uuid.UUID `json:"policy_id"`
+	OrgID    uuid.UUID `json:"org_id"`
+}
+
+func (q *Queries) AttachPolicyToOrg(ctx context.Context, arg AttachPolicyToOrgParams) error {
+	_, err := q.db.Exec(ctx, attachPolicyToOrg, arg.PolicyID, arg.OrgID)
+	return err
+}
+
+const attachPolicyToUser = `-- name: AttachPolicyToUser :exec
+INSERT INTO policy_users (policy_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (policy_id, user_id) DO NOTHING
+`
+
+type AttachPolicyToUserParams struct {
+	PolicyID uuid.UUID `json:"policy_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) AttachPolicyToUser(ctx context.Context, arg AttachPolicyToUserParams) error {
+	_, err := q.db.Exec(ctx, attachPolicyToUser, arg.PolicyID, arg.UserID)
+	return err
+}
+
 const createPolicy = `-- name: CreatePolicy :one
 INSERT INTO policies (
-  org_id, policy_type, config, enabled
+  org_id, policy_type, config, enabled, mode
 ) VALUES (
-  $1, $2, $3, $4
+  $1, $2, $3, $4, $5
 )
-RETURNING id, org_id, policy_type, config, enabled, created_at, updated_at
+RETURNING id, org_id, policy_type, config, enabled, created_at, updated_at, mode
 `
 
 type CreatePolicyParams struct {
@@ -41,6 +90,7 @@ type CreatePolicyParams struct {
 	PolicyType string    `json:"policy_type"`
 	Config     []byte    `json:"config"`
 	Enabled    bool      `json:"enabled"`
+	Mode       string    `json:"mode"`
 }
 
 func (q *Queries) CreatePolicy(ctx context.Context, arg CreatePolicyParams) (Policy, error) {
@@ -49,6 +99,7 @@ func (q *Queries) CreatePolicy(ctx context.Context, arg CreatePolicyParams) (Pol
 		arg.PolicyType,
 		arg.Config,
 		arg.Enabled,
+		arg.Mode,
 	)
 	var i Policy
 	err := row.Scan(
@@ -59,6 +110,7 @@ func (q *Queries) CreatePolicy(ctx context.Context, arg CreatePolicyParams) (Pol
 		&i.Enabled,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Mode,
 	)
 	return i, err
 }
@@ -73,6 +125,21 @@ func (q *Queries) DeletePolicy(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const detachPolicyFromAPIKey = `-- name: DetachPolicyFromAPIKey :exec
+DELETE FROM policy_api_keys
+WHERE policy_id = $1 AND key_id = $2
+`
+
+type DetachPolicyFromAPIKeyParams struct {
+	PolicyID uuid.UUID `json:"policy_id"`
+	KeyID    uuid.UUID `json:"key_id"`
+}
+
+func (q *Queries) DetachPolicyFromAPIKey(ctx context.Context, arg DetachPolicyFromAPIKeyParams) error {
+	_, err := q.db.Exec(ctx, detachPolicyFromAPIKey, arg.PolicyID, arg.KeyID)
+	return err
+}
+
 const detachPolicyFromApp = `-- name: DetachPolicyFromApp :exec
 DELETE FROM policy_applications
 WHERE policy_id = $1 AND app_id = $2
@@ -88,6 +155,36 @@ func (q *Queries) DetachPolicyFromApp(ctx context.Context, arg DetachPolicyFromA
 	return err
 }
 
+const detachPolicyFromOrg = `-- name: DetachPolicyFromOrg :exec
+DELETE FROM policy_organisations
+WHERE policy_id = $1 AND org_id = $2
+`
+
+type DetachPolicyFromOrgParams struct {
+	PolicyID uuid.UUID `json:"policy_id"`
+	OrgID    uuid.UUID `json:"org_id"`
+}
+
+func (q *Queries) DetachPolicyFromOrg(ctx context.Context, arg DetachPolicyFromOrgParams) error {
+	_, err := q.db.Exec(ctx, detachPolicyFromOrg, arg.PolicyID, arg.OrgID)
+	return err
+}
+
+const detachPolicyFromUser = `-- name: DetachPolicyFromUser :exec
+DELETE FROM policy_users
+WHERE policy_id = $1 AND user_id = $2
+`
+
+type DetachPolicyFromUserParams struct {
+	PolicyID uuid.UUID `json:"policy_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) DetachPolicyFromUser(ctx context.Context, arg DetachPolicyFromUserParams) error {
+	_, err := q.db.Exec(ctx, detachPolicyFromUser, arg.PolicyID, arg.UserID)
+	return err
+}
+
 const disablePolicy = `-- name: DisablePolicy :exec
 UPDATE policies
 SET enabled = false,
@@ -147,7 +244,7 @@ func (q *Queries) GetAppsForPolicy(ctx context.Context, policyID uuid.UUID) ([]A
 }
 
 const getPoliciesByType = `-- name: GetPoliciesByType :many
-SELECT p.id, p.org_id, p.policy_type, p.config, p.enabled, p.created_at, p.updated_at FROM policies p
+SELECT p.id, p.org_id, p.policy_type, p.config, p.enabled, p.created_at, p.updated_at, p.mode FROM policies p
 JOIN policy_applications pa ON p.id = pa.policy_id
 WHERE pa.app_id = $1 AND p.policy_type = $2
 ORDER BY p.created_at
@@ -175,6 +272,7 @@ func (q *Queries) GetPoliciesByType(ctx context.Context, arg GetPoliciesByTypePa
 			&i.Enabled,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Mode,
 		); err != nil {
 			return nil, err
 		}
@@ -187,7 +285,7 @@ func (q *Queries) GetPoliciesByType(ctx context.Context, arg GetPoliciesByTypePa
 }
 
 const getPoliciesForApp = `-- name: GetPoliciesForApp :many
-SELECT p.id, p.org_id, p.policy_type, p.config, p.enabled, p.created_at, p.updated_at FROM policies p
+SELECT p.id, p.org_id, p.policy_type, p.config, p.enabled, p.created_at, p.updated_at, p.mode FROM policies p
 JOIN policy_applications pa ON p.id = pa.policy_id
 WHERE pa.app_id = $1
 ORDER BY p.policy_type
@@ -210,6 +308,7 @@ func (q *Queries) GetPoliciesForApp(ctx context.Context, appID uuid.UUID) ([]Pol
 			&i.Enabled,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Mode,
 		); err != nil {
 			return nil, err
 		}
@@ -222,7 +321,7 @@ func (q *Queries) GetPoliciesForApp(ctx context.Context, appID uuid.UUID) ([]Pol
 }
 
 const getPolicy = `-- name: GetPolicy :one
-SELECT id, org_id, policy_type, config, enabled, created_at, updated_at FROM policies
+SELECT id, org_id, policy_type, config, enabled, created_at, updated_at, mode FROM policies
 WHERE id = $1 LIMIT 1
 `
 
@@ -237,12 +336,13 @@ func (q *Queries) GetPolicy(ctx context.Context, id uuid.UUID) (Policy, error) {
 		&i.Enabled,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Mode,
 	)
 	return i, err
 }
 
 const listEnabledPolicies = `-- name: ListEnabledPolicies :many
-SELECT p.id, p.org_id, p.policy_type, p.config, p.enabled, p.created_at, p.updated_at FROM policies p
+SELECT p.id, p.org_id, p.policy_type, p.config, p.enabled, p.created_at, p.updated_at, p.mode FROM policies p
 JOIN policy_applications pa ON p.id = pa.policy_id
 WHERE pa.app_id = $1 AND p.enabled = true
 ORDER BY p.policy_type
@@ -272,6 +372,76 @@ func (q *Queries) ListEnabledPolicies(ctx context.Context, arg ListEnabledPolici
 			&i.Enabled,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Mode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEnabledPoliciesForScope = `-- name: ListEnabledPoliciesForScope :many
+SELECT p.id, p.org_id, p.policy_type, p.config, p.enabled, p.created_at, p.updated_at, p.mode, s.scope FROM (
+  SELECT po.policy_id, 'org'::text AS scope FROM policy_organisations po WHERE po.org_id = $1
+  UNION ALL
+  SELECT pa.policy_id, 'app'::text AS scope FROM policy_applications pa WHERE pa.app_id = $2
+  UNION ALL
+  SELECT pu.policy_id, 'user'::text AS scope FROM policy_users pu WHERE pu.user_id = $3
+  UNION ALL
+  SELECT pk.policy_id, 'key'::text AS scope FROM policy_api_keys pk WHERE pk.key_id = $4
+) s
+JOIN policies p ON p.id = s.policy_id
+WHERE p.enabled = true
+ORDER BY p.policy_type, p.created_at
+`
+
+type ListEnabledPoliciesForScopeParams struct {
+	OrgID  uuid.UUID `json:"org_id"`
+	AppID  uuid.UUID `json:"app_id"`
+	UserID uuid.UUID `json:"user_id"`
+	KeyID  uuid.UUID `json:"key_id"`
+}
+
+type ListEnabledPoliciesForScopeRow struct {
+	ID         uuid.UUID          `json:"id"`
+	OrgID      uuid.UUID          `json:"org_id"`
+	PolicyType string             `json:"policy_type"`
+	Config     []byte             `json:"config"`
+	Enabled    bool               `json:"enabled"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+	Mode       string             `json:"mode"`
+	Scope      string             `json:"scope"`
+}
+
+func (q *Queries) ListEnabledPoliciesForScope(ctx context.Context, arg ListEnabledPoliciesForScopeParams) ([]ListEnabledPoliciesForScopeRow, error) {
+	rows, err := q.db.Query(ctx, listEnabledPoliciesForScope,
+		arg.OrgID,
+		arg.AppID,
+		arg.UserID,
+		arg.KeyID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEnabledPoliciesForScopeRow
+	for rows.Next() {
+		var i ListEnabledPoliciesForScopeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrgID,
+			&i.PolicyType,
+			&i.Config,
+			&i.Enabled,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Mode,
+			&i.Scope,
 		); err != nil {
 			return nil, err
 		}
@@ -284,7 +454,7 @@ func (q *Queries) ListEnabledPolicies(ctx context.Context, arg ListEnabledPolici
 }
 
 const listPolicies = `-- name: ListPolicies :many
-SELECT p.id, p.org_id, p.policy_type, p.config, p.enabled, p.created_at, p.updated_at FROM policies p
+SELECT p.id, p.org_id, p.policy_type, p.config, p.enabled, p.created_at, p.updated_at, p.mode FROM policies p
 JOIN policy_applications pa ON p.id = pa.policy_id
 WHERE pa.app_id = $1
 ORDER BY p.policy_type
@@ -314,6 +484,7 @@ func (q *Queries) ListPolicies(ctx context.Context, arg ListPoliciesParams) ([]P
 			&i.Enabled,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Mode,
 		); err != nil {
 			return nil, err
 		}
@@ -325,14 +496,84 @@ func (q *Queries) ListPolicies(ctx context.Context, arg ListPoliciesParams) ([]P
 	return items, nil
 }
 
+const listPolicyAttachments = `-- name: ListPolicyAttachments :many
+SELECT 'org'::text AS scope, po.org_id AS target_id FROM policy_organisations po WHERE po.policy_id = $1
+UNION ALL
+SELECT 'app'::text AS scope, pa.app_id AS target_id FROM policy_applications pa WHERE pa.policy_id = $1
+UNION ALL
+SELECT 'user'::text AS scope, pu.user_id AS target_id FROM policy_users pu WHERE pu.policy_id = $1
+UNION ALL
+SELECT 'key'::text AS scope, pk.key_id AS target_id FROM policy_api_keys pk WHERE pk.policy_id = $1
+`
+
+type ListPolicyAttachmentsRow struct {
+	Scope    string    `json:"scope"`
+	TargetID uuid.UUID `json:"target_id"`
+}
+
+// Every org, app, user and API key a policy is attached to
+func (q *Queries) ListPolicyAttachments(ctx context.Context, policyID uuid.UUID) ([]ListPolicyAttachmentsRow, error) {
+	rows, err := q.db.Query(ctx, listPolicyAttachments, policyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPolicyAttachmentsRow
+	for rows.Next() {
+		var i ListPolicyAttachmentsRow
+		if err := rows.Scan(&i.Scope, &i.TargetID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const policyScopeInOrg = `-- name: PolicyScopeInOrg :one
+SELECT EXISTS (
+  SELECT 1 FROM policies p WHERE p.id = $1 AND p.org_id = $2
+) AND CASE $3::text
+  WHEN 'org' THEN $4::uuid = $2
+  WHEN 'app' THEN EXISTS (SELECT 1 FROM applications a WHERE a.id = $4 AND a.org_id = $2)
+  WHEN 'user' THEN EXISTS (SELECT 1 FROM users u WHERE u.id = $4 AND u.org_id = $2)
+  WHEN 'key' THEN EXISTS (SELECT 1 FROM api_keys k WHERE k.id = $4 AND k.org_id = $2)
+  ELSE false
+END AS in_org
+`
+
+type PolicyScopeInOrgParams struct {
+	PolicyID uuid.UUID `json:"policy_id"`
+	OrgID    uuid.UUID `json:"org_id"`
+	Scope    string    `json:"scope"`
+	TargetID uuid.UUID `json:"target_id"`
+}
+
+// Whether a policy and the org, app, user or API key it is attached to both
+// belong to the organisation
+func (q *Queries) PolicyScopeInOrg(ctx context.Context, arg PolicyScopeInOrgParams) (bool, error) {
+	row := q.db.QueryRow(ctx, policyScopeInOrg,
+		arg.PolicyID,
+		arg.OrgID,
+		arg.Scope,
+		arg.TargetID,
+	)
+	var in_org bool
+	err := row.Scan(&in_org)
+	return in_org, err
+}
+
 const updatePolicy = `-- name: UpdatePolicy :one
 UPDATE policies
 SET policy_type = $2,
     config = $3,
     enabled = $4,
+    mode = $5,
     updated_at = now()
 WHERE id = $1
-RETURNING id, org_id, policy_type, config, enabled, created_at, updated_at
+RETURNING id, org_id, policy_type, config, enabled, created_at, updated_at, mode
 `
 
 type UpdatePolicyParams struct {
@@ -340,6 +581,7 @@ type UpdatePolicyParams struct {
 	PolicyType string    `json:"policy_type"`
 	Config     []byte    `json:"config"`
 	Enabled    bool      `json:"enabled"`
+	Mode       string    `json:"mode"`
 }
 
 func (q *Queries) UpdatePolicy(ctx context.Context, arg UpdatePolicyParams) (Policy, error) {
@@ -348,6 +590,7 @@ func (q *Queries) UpdatePolicy(ctx context.Context, arg UpdatePolicyParams) (Pol
 		arg.PolicyType,
 		arg.Config,
 		arg.Enabled,
+		arg.Mode,
 	)
 	var i Policy
 	err := row.Scan(
@@ -358,6 +601,7 @@ func (q *Queries) UpdatePolicy(ctx context.Context, arg UpdatePolicyParams) (Pol
 		&i.Enabled,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Mode,
 	)
 	return i, err
 }
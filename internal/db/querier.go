@@ -13,10 +13,16 @@ import (
 type Querier interface {
 	AssignRoleToOrg(ctx context.Context, arg AssignRoleToOrgParams) error
 	AssignRoleToUser(ctx context.Context, arg AssignRoleToUserParams) error
+	AttachPolicyToAPIKey(ctx context.Context, arg AttachPolicyToAPIKeyParams) error
 	AttachPolicyToApp(ctx context.Context, arg AttachPolicyToAppParams) error
+	AttachPolicyToOrg(ctx context.Context, arg AttachPolicyToOrgParams) error
+	AttachPolicyToUser(ctx context.Context, arg AttachPolicyToUserParams) error
 	CreateApplication(ctx context.Context, arg CreateApplicationParams) (Application, error)
 	CreateApplicationConfig(ctx context.Context, arg CreateApplicationConfigParams) (ApplicationConfig, error)
+	CreateBatchFile(ctx context.Context, arg CreateBatchFileParams) error
+	CreateBatchJob(ctx context.Context, arg CreateBatchJobParams) (BatchJob, error)
 	CreateModel(ctx context.Context, arg CreateModelParams) (Model, error)
+	CreateModelPrice(ctx context.Context, arg CreateModelPriceParams) (ModelPrice, error)
 	CreateOrg(ctx context.Context, name string) (Organisation, error)
 	CreatePolicy(ctx context.Context, arg CreatePolicyParams) (Policy, error)
 	CreateRole(ctx context.Context, arg CreateRoleParams) (Role, error)
@@ -26,9 +32,13 @@ type Querier interface {
 	DeleteApplication(ctx context.Context, id uuid.UUID) error
 	DeleteApplicationConfig(ctx context.Context, id uuid.UUID) error
 	DeleteModel(ctx context.Context, id uuid.UUID) error
+	DeleteModelPrice(ctx context.Context, id uuid.UUID) error
 	DeletePolicy(ctx context.Context, id uuid.UUID) error
 	DeleteRole(ctx context.Context, id uuid.UUID) error
+	DetachPolicyFromAPIKey(ctx context.Context, arg DetachPolicyFromAPIKeyParams) error
 	DetachPolicyFromApp(ctx context.Context, arg DetachPolicyFromAppParams) error
+	DetachPolicyFromOrg(ctx context.Context, arg DetachPolicyFromOrgParams) error
+	DetachPolicyFromUser(ctx context.Context, arg DetachPolicyFromUserParams) error
 	DisableModel(ctx context.Context, id uuid.UUID) error
 	DisablePolicy(ctx context.Context, id uuid.UUID) error
 	EnableModel(ctx context.Context, id uuid.UUID) error
@@ -45,8 +55,14 @@ type Querier interface {
 	GetApplicationConfig(ctx context.Context, id uuid.UUID) (ApplicationConfig, error)
 	GetApplicationConfigByEnv(ctx context.Context, arg GetApplicationConfigByEnvParams) (ApplicationConfig, error)
 	GetAppsForPolicy(ctx context.Context, policyID uuid.UUID) ([]Application, error)
+	GetBatchFile(ctx context.Context, arg GetBatchFileParams) (BatchFile, error)
+	GetBatchJob(ctx context.Context, arg GetBatchJobParams) (BatchJob, error)
+	// Picks the latest version in force at the given time, preferring an exact
+	// deployment type match over the 'standard' fallback.
+	GetEffectiveModelPrice(ctx context.Context, arg GetEffectiveModelPriceParams) (ModelPrice, error)
 	GetModel(ctx context.Context, id uuid.UUID) (Model, error)
 	GetModelByProviderAndName(ctx context.Context, arg GetModelByProviderAndNameParams) (Model, error)
+	GetModelPrice(ctx context.Context, id uuid.UUID) (ModelPrice, error)
 	GetPoliciesByType(ctx context.Context, arg GetPoliciesByTypeParams) ([]Policy, error)
 	GetPoliciesForApp(ctx context.Context, appID uuid.UUID) ([]Policy, error)
 	GetPolicy(ctx context.Context, id uuid.UUID) (Policy, error)
@@ -62,15 +78,29 @@ type Querier interface {
 	ListApplications(ctx context.Context, arg ListApplicationsParams) ([]Application, error)
 	ListEnabledModels(ctx context.Context, arg ListEnabledModelsParams) ([]Model, error)
 	ListEnabledPolicies(ctx context.Context, arg ListEnabledPoliciesParams) ([]Policy, error)
+	ListEnabledPoliciesForScope(ctx context.Context, arg ListEnabledPoliciesForScopeParams) ([]ListEnabledPoliciesForScopeRow, error)
+	ListModelPrices(ctx context.Context, arg ListModelPricesParams) ([]ModelPrice, error)
 	ListModels(ctx context.Context, arg ListModelsParams) ([]Model, error)
+	// Jobs that have failed max_failures polls in a row are left for an operator
+	ListPendingBatchJobs(ctx context.Context, arg ListPendingBatchJobsParams) ([]BatchJob, error)
 	ListPolicies(ctx context.Context, arg ListPoliciesParams) ([]Policy, error)
+	// Every org, app, user and API key a policy is attached to
+	ListPolicyAttachments(ctx context.Context, policyID uuid.UUID) ([]ListPolicyAttachmentsRow, error)
 	ListRoles(ctx context.Context) ([]Role, error)
+	MarkBatchJobUsageRecorded(ctx context.Context, id uuid.UUID) (int64, error)
+	// Whether a policy and the org, app, user or API key it is attached to both
+	// belong to the organisation
+	PolicyScopeInOrg(ctx context.Context, arg PolicyScopeInOrgParams) (bool, error)
+	SetBatchJobPollState(ctx context.Context, arg SetBatchJobPollStateParams) error
+	SumCostByApp(ctx context.Context, arg SumCostByAppParams) ([]SumCostByAppRow, error)
+	SumCostByOrg(ctx context.Context, arg SumCostByOrgParams) ([]SumCostByOrgRow, error)
 	SumTokensByApp(ctx context.Context, arg SumTokensByAppParams) (SumTokensByAppRow, error)
 	SumTokensByOrg(ctx context.Context, arg SumTokensByOrgParams) (SumTokensByOrgRow, error)
 	UpdateAPIKeyLastUsed(ctx context.Context, keyPrefix string) (int64, error)
 	UpdateAPIKeyStatus(ctx context.Context, arg UpdateAPIKeyStatusParams) (int64, error)
 	UpdateApplication(ctx context.Context, arg UpdateApplicationParams) (Application, error)
 	UpdateApplicationConfig(ctx context.Context, arg UpdateApplicationConfigParams) (ApplicationConfig, error)
+	UpdateBatchJobStatus(ctx context.Context, arg UpdateBatchJobStatusParams) error
 	UpdateModel(ctx context.Context, arg UpdateModelParams) (Model, error)
 	UpdatePolicy(ctx context.Context, arg UpdatePolicyParams) (Policy, error)
 	UpdateRole(ctx context.Context, arg UpdateRoleParams) (Role, error)
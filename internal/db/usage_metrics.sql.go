@@ -16,11 +16,13 @@ const createUsageMetric = `-- name: CreateUsageMetric :one
 INSERT INTO usage_metrics (
   org_id, app_id, api_key_id, model_id, provider, model_name,
   prompt_tokens, completion_tokens, total_tokens,
-  request_size_bytes, response_size_bytes, timestamp
+  request_size_bytes, response_size_bytes, timestamp, estimated,
+  cost_micros, currency, metadata
 ) VALUES (
-  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
+  COALESCE($16::jsonb, '{}'::jsonb)
 )
-RETURNING id, org_id, app_id, api_key_id, model_id, provider, model_name, prompt_tokens, completion_tokens, total_tokens, request_size_bytes, response_size_bytes, timestamp
+RETURNING id, org_id, app_id, api_key_id, model_id, provider, model_name, prompt_tokens, completion_tokens, total_tokens, request_size_bytes, response_size_bytes, timestamp, estimated, cost_micros, currency, metadata
 `
 
 type CreateUsageMetricParams struct {
@@ -36,6 +38,10 @@ type CreateUsageMetricParams struct {
 	RequestSizeBytes  int32              `json:"request_size_bytes"`
 	ResponseSizeBytes int32              `json:"response_size_bytes"`
 	Timestamp         pgtype.Timestamptz `json:"timestamp"`
+	Estimated         bool               `json:"estimated"`
+	CostMicros        pgtype.Int8        `json:"cost_micros"`
+	Currency          *string            `json:"currency"`
+	Metadata          []byte             `json:"metadata"`
 }
 
 func (q *Queries) CreateUsageMetric(ctx context.Context, arg CreateUsageMetricParams) (UsageMetric, error) {
@@ -52,6 +58,10 @@ func (q *Queries) CreateUsageMetric(ctx context.Context, arg CreateUsageMetricPa
 		arg.RequestSizeBytes,
 		arg.ResponseSizeBytes,
 		arg.Timestamp,
+		arg.Estimated,
+		arg.CostMicros,
+		arg.Currency,
+		arg.Metadata,
 	)
 	var i UsageMetric
 	err := row.Scan(
@@ -68,6 +78,10 @@ func (q *Queries) CreateUsageMetric(ctx context.Context, arg CreateUsageMetricPa
 		&i.RequestSizeBytes,
 		&i.ResponseSizeBytes,
 		&i.Timestamp,
+		&i.Estimated,
+		&i.CostMicros,
+		&i.Currency,
+		&i.Metadata,
 	)
 	return i, err
 }
@@ -140,7 +154,7 @@ func (q *Queries) GetUsageByModel(ctx context.Context, arg GetUsageByModelParams
 }
 
 const getUsageMetricsByAPIKey = `-- name: GetUsageMetricsByAPIKey :many
-SELECT id, org_id, app_id, api_key_id, model_id, provider, model_name, prompt_tokens, completion_tokens, total_tokens, request_size_bytes, response_size_bytes, timestamp FROM usage_metrics
+SELECT id, org_id, app_id, api_key_id, model_id, provider, model_name, prompt_tokens, completion_tokens, total_tokens, request_size_bytes, response_size_bytes, timestamp, estimated, cost_micros, currency, metadata FROM usage_metrics
 WHERE api_key_id = $1
   AND timestamp >= $2
   AND timestamp <= $3
@@ -176,6 +190,10 @@ func (q *Queries) GetUsageMetricsByAPIKey(ctx context.Context, arg GetUsageMetri
 			&i.RequestSizeBytes,
 			&i.ResponseSizeBytes,
 			&i.Timestamp,
+			&i.Estimated,
+			&i.CostMicros,
+			&i.Currency,
+			&i.Metadata,
 		); err != nil {
 			return nil, err
 		}
@@ -188,7 +206,7 @@ func (q *Queries) GetUsageMetricsByAPIKey(ctx context.Context, arg GetUsageMetri
 }
 
 const getUsageMetricsByApp = `-- name: GetUsageMetricsByApp :many
-SELECT id, org_id, app_id, api_key_id, model_id, provider, model_name, prompt_tokens, completion_tokens, total_tokens, request_size_bytes, response_size_bytes, timestamp FROM usage_metrics
+SELECT id, org_id, app_id, api_key_id, model_id, provider, model_name, prompt_tokens, completion_tokens, total_tokens, request_size_bytes, response_size_bytes, timestamp, estimated, cost_micros, currency, metadata FROM usage_metrics
 WHERE app_id = $1
   AND timestamp >= $2
   AND timestamp <= $3
@@ -233,6 +251,10 @@ func (q *Queries) GetUsageMetricsByApp(ctx context.Context, arg GetUsageMetricsB
 			&i.RequestSizeBytes,
 			&i.ResponseSizeBytes,
 			&i.Timestamp,
+			&i.Estimated,
+			&i.CostMicros,
+			&i.Currency,
+			&i.Metadata,
 		); err != nil {
 			return nil, err
 		}
@@ -245,7 +267,7 @@ func (q *Queries) GetUsageMetricsByApp(ctx context.Context, arg GetUsageMetricsB
 }
 
 const getUsageMetricsByOrg = `-- name: GetUsageMetricsByOrg :many
-SELECT id, org_id, app_id, api_key_id, model_id, provider, model_name, prompt_tokens, completion_tokens, total_tokens, request_size_bytes, response_size_bytes, timestamp FROM usage_metrics
+SELECT id, org_id, app_id, api_key_id, model_id, provider, model_name, prompt_tokens, completion_tokens, total_tokens, request_size_bytes, response_size_bytes, timestamp, estimated, cost_micros, currency, metadata FROM usage_metrics
 WHERE org_id = $1
   AND timestamp >= $2
   AND timestamp <= $3
@@ -281,6 +303,10 @@ func (q *Queries) GetUsageMetricsByOrg(ctx context.Context, arg GetUsageMetricsB
 			&i.RequestSizeBytes,
 			&i.ResponseSizeBytes,
 			&i.Timestamp,
+			&i.Estimated,
+			&i.CostMicros,
+			&i.Currency,
+			&i.Metadata,
 		); err != nil {
 			return nil, err
 		}
@@ -292,12 +318,101 @@ func (q *Queries) GetUsageMetricsByOrg(ctx context.Context, arg GetUsageMetricsB
 	return items, nil
 }
 
+const sumCostByApp = `-- name: SumCostByApp :many
+SELECT
+  currency,
+  COALESCE(SUM(cost_micros), 0)::bigint as total_cost_micros
+FROM usage_metrics
+WHERE app_id = $1
+  AND timestamp >= $2
+  AND timestamp <= $3
+  AND cost_micros IS NOT NULL
+GROUP BY currency
+ORDER BY currency
+`
+
+type SumCostByAppParams struct {
+	AppID       uuid.UUID          `json:"app_id"`
+	Timestamp   pgtype.Timestamptz `json:"timestamp"`
+	Timestamp_2 pgtype.Timestamptz `json:"timestamp_2"`
+}
+
+type SumCostByAppRow struct {
+	Currency        *string `json:"currency"`
+	TotalCostMicros int64   `json:"total_cost_micros"`
+}
+
+func (q *Queries) SumCostByApp(ctx context.Context, arg SumCostByAppParams) ([]SumCostByAppRow, error) {
+	rows, err := q.db.Query(ctx, sumCostByApp, arg.AppID, arg.Timestamp, arg.Timestamp_2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SumCostByAppRow
+	for rows.Next() {
+		var i SumCostByAppRow
+		if err := rows.Scan(&i.Currency, &i.TotalCostMicros); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sumCostByOrg = `-- name: SumCostByOrg :many
+SELECT
+  currency,
+  COALESCE(SUM(cost_micros), 0)::bigint as total_cost_micros
+FROM usage_metrics
+WHERE org_id = $1
+  AND timestamp >= $2
+  AND timestamp <= $3
+  AND cost_micros IS NOT NULL
+GROUP BY currency
+ORDER BY currency
+`
+
+type SumCostByOrgParams struct {
+	OrgID       uuid.UUID          `json:"org_id"`
+	Timestamp   pgtype.Timestamptz `json:"timestamp"`
+	Timestamp_2 pgtype.Timestamptz `json:"timestamp_2"`
+}
+
+type SumCostByOrgRow struct {
+	Currency        *string `json:"currency"`
+	TotalCostMicros int64   `json:"total_cost_micros"`
+}
+
+func (q *Queries) SumCostByOrg(ctx context.Context, arg SumCostByOrgParams) ([]SumCostByOrgRow, error) {
+	rows, err := q.db.Query(ctx, sumCostByOrg, arg.OrgID, arg.Timestamp, arg.Timestamp_2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SumCostByOrgRow
+	for rows.Next() {
+		var i SumCostByOrgRow
+		if err := rows.Scan(&i.Currency, &i.TotalCostMicros); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const sumTokensByApp = `-- name: SumTokensByApp :one
 SELECT
   COALESCE(SUM(prompt_tokens), 0) as total_prompt_tokens,
   COALESCE(SUM(completion_tokens), 0) as total_completion_tokens,
   COALESCE(SUM(total_tokens), 0) as total_tokens,
-  COUNT(*) as request_count
+  COUNT(*) as request_count,
+  COUNT(*) FILTER (WHERE cost_micros IS NULL) as unpriced_count
 FROM usage_metrics
 WHERE app_id = $1
   AND timestamp >= $2
@@ -315,6 +430,7 @@ type SumTokensByAppRow struct {
 	TotalCompletionTokens interface{} `json:"total_completion_tokens"`
 	TotalTokens           interface{} `json:"total_tokens"`
 	RequestCount          int64       `json:"request_count"`
+	UnpricedCount         int64       `json:"unpriced_count"`
 }
 
 func (q *Queries) SumTokensByApp(ctx context.Context, arg SumTokensByAppParams) (SumTokensByAppRow, error) {
@@ -325,6 +441,7 @@ func (q *Queries) SumTokensByApp(ctx context.Context, arg SumTokensByAppParams)
 		&i.TotalCompletionTokens,
 		&i.TotalTokens,
 		&i.RequestCount,
+		&i.UnpricedCount,
 	)
 	return i, err
 }
@@ -334,7 +451,8 @@ SELECT
   COALESCE(SUM(prompt_tokens), 0) as total_prompt_tokens,
   COALESCE(SUM(completion_tokens), 0) as total_completion_tokens,
   COALESCE(SUM(total_tokens), 0) as total_tokens,
-  COUNT(*) as request_count
+  COUNT(*) as request_count,
+  COUNT(*) FILTER (WHERE cost_micros IS NULL) as unpriced_count
 FROM usage_metrics
 WHERE org_id = $1
   AND timestamp >= $2
@@ -352,6 +470,7 @@ type SumTokensByOrgRow struct {
 	TotalCompletionTokens interface{} `json:"total_completion_tokens"`
 	TotalTokens           interface{} `json:"total_tokens"`
 	RequestCount          int64       `json:"request_count"`
+	UnpricedCount         int64       `json:"unpriced_count"`
 }
 
 func (q *Queries) SumTokensByOrg(ctx context.Context, arg SumTokensByOrgParams) (SumTokensByOrgRow, error) {
@@ -362,6 +481,7 @@ func (q *Queries) SumTokensByOrg(ctx context.Context, arg SumTokensByOrgParams)
 		&i.TotalCompletionTokens,
 		&i.TotalTokens,
 		&i.RequestCount,
+		&i.UnpricedCount,
 	)
 	return i, err
 }
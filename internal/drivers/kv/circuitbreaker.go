@@ -86,6 +86,17 @@ func (cb *CircuitBreakerStore) Set(ctx context.Context, key string, value string
 	return err
 }
 
+// SetNX stores a value if the key is new through the circuit breaker
+func (cb *CircuitBreakerStore) SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	result, err := cb.breaker.Execute(func() (any, error) {
+		return cb.store.SetNX(ctx, key, value, ttl)
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.(bool), nil
+}
+
 // Del deletes a key through the circuit breaker
 func (cb *CircuitBreakerStore) Del(ctx context.Context, key string) error {
 	_, err := cb.breaker.Execute(func() (any, error) {
@@ -138,6 +149,139 @@ func (cb *CircuitBreakerStore) Expire(ctx context.Context, key string, ttl time.
 	return result.(bool), nil
 }
 
+// IncrAllWithinLimits applies a multi-counter limit check through the circuit breaker
+func (cb *CircuitBreakerStore) IncrAllWithinLimits(ctx context.Context, counters []LimitedCounter) (bool, []int64, error) {
+	var totals []int64
+	result, err := cb.breaker.Execute(func() (any, error) {
+		applied, t, err := cb.store.IncrAllWithinLimits(ctx, counters)
+		totals = t
+		return applied, err
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return result.(bool), totals, nil
+}
+
+// SlidingWindow applies sliding window limits through the circuit breaker
+func (cb *CircuitBreakerStore) SlidingWindow(ctx context.Context, limits []RateLimit) (bool, []RateLimitState, error) {
+	var states []RateLimitState
+	result, err := cb.breaker.Execute(func() (any, error) {
+		allowed, s, err := cb.store.SlidingWindow(ctx, limits)
+		states = s
+		return allowed, err
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return result.(bool), states, nil
+}
+
+// TokenBucket applies token bucket limits through the circuit breaker
+func (cb *CircuitBreakerStore) TokenBucket(ctx context.Context, limits []RateLimit) (bool, []RateLimitState, error) {
+	var states []RateLimitState
+	result, err := cb.breaker.Execute(func() (any, error) {
+		allowed, s, err := cb.store.TokenBucket(ctx, limits)
+		states = s
+		return allowed, err
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return result.(bool), states, nil
+}
+
+// AdjustSlidingWindow corrects a sliding window entry through the circuit breaker
+func (cb *CircuitBreakerStore) AdjustSlidingWindow(ctx context.Context, limit RateLimit, age time.Duration) error {
+	_, err := cb.breaker.Execute(func() (any, error) {
+		return nil, cb.store.AdjustSlidingWindow(ctx, limit, age)
+	})
+	return err
+}
+
+// AdjustTokenBucket adjusts a token bucket through the circuit breaker
+func (cb *CircuitBreakerStore) AdjustTokenBucket(ctx context.Context, limit RateLimit) error {
+	_, err := cb.breaker.Execute(func() (any, error) {
+		return nil, cb.store.AdjustTokenBucket(ctx, limit)
+	})
+	return err
+}
+
+// AcquireSemaphores takes semaphore slots through the circuit breaker
+func (cb *CircuitBreakerStore) AcquireSemaphores(ctx context.Context, holder string, sems []Semaphore) (bool, []int64, error) {
+	var counts []int64
+	result, err := cb.breaker.Execute(func() (any, error) {
+		ok, c, err := cb.store.AcquireSemaphores(ctx, holder, sems)
+		counts = c
+		return ok, err
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return result.(bool), counts, nil
+}
+
+// RenewSemaphores extends semaphore leases through the circuit breaker
+func (cb *CircuitBreakerStore) RenewSemaphores(ctx context.Context, holder string, sems []Semaphore) error {
+	_, err := cb.breaker.Execute(func() (any, error) {
+		return nil, cb.store.RenewSemaphores(ctx, holder, sems)
+	})
+	return err
+}
+
+// ReleaseSemaphores gives up semaphore slots through the circuit breaker
+func (cb *CircuitBreakerStore) ReleaseSemaphores(ctx context.Context, holder string, sems []Semaphore) error {
+	_, err := cb.breaker.Execute(func() (any, error) {
+		return nil, cb.store.ReleaseSemaphores(ctx, holder, sems)
+	})
+	return err
+}
+
+// readWindow reads a sliding window log through the circuit breaker
+func (cb *CircuitBreakerStore) readWindow(ctx context.Context, key string) ([]windowEntry, error) {
+	reader, ok := cb.store.(stateReader)
+	if !ok {
+		return nil, nil
+	}
+	result, err := cb.breaker.Execute(func() (any, error) {
+		return reader.readWindow(ctx, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]windowEntry), nil
+}
+
+// readTokenBucket reads a token bucket through the circuit breaker
+func (cb *CircuitBreakerStore) readTokenBucket(ctx context.Context, key string) (time.Time, error) {
+	reader, ok := cb.store.(stateReader)
+	if !ok {
+		return time.Time{}, nil
+	}
+	result, err := cb.breaker.Execute(func() (any, error) {
+		return reader.readTokenBucket(ctx, key)
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return result.(time.Time), nil
+}
+
+// readSemaphore reads a semaphore's holders through the circuit breaker
+func (cb *CircuitBreakerStore) readSemaphore(ctx context.Context, key string) (map[string]time.Time, error) {
+	reader, ok := cb.store.(stateReader)
+	if !ok {
+		return nil, nil
+	}
+	result, err := cb.breaker.Execute(func() (any, error) {
+		return reader.readSemaphore(ctx, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(map[string]time.Time), nil
+}
+
 // ScanGetAll scans keys matching a pattern through the circuit breaker
 func (cb *CircuitBreakerStore) ScanGetAll(ctx context.Context, pattern string, count int64) (map[string]string, error) {
 	result, err := cb.breaker.Execute(func() (any, error) {
@@ -11,6 +11,11 @@ import (
 type KvStore interface {
 	Get(ctx context.Context, key string) (string, error)
 	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+
+	// SetNX atomically sets key to value with a TTL only if key doesn't
+	// exist, and reports whether it was set
+	SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error)
+
 	Del(ctx context.Context, key string) error
 	Exists(ctx context.Context, key string) (bool, error)
 
@@ -26,12 +31,83 @@ type KvStore interface {
 	// Returns true if TTL was set, false if key doesn't exist
 	Expire(ctx context.Context, key string, ttl time.Duration) (bool, error)
 
+	// IncrAllWithinLimits atomically adds each counter's Amount to its key, but
+	// only if no counter would exceed its Limit; otherwise nothing changes.
+	// Returns whether the increments were applied and each counter's total
+	// (the new value if applied, the value it would have reached if not).
+	IncrAllWithinLimits(ctx context.Context, counters []LimitedCounter) (bool, []int64, error)
+
+	// SlidingWindow admits a request against sliding-window-log limits: each
+	// limit allows Limit units of cost in any trailing Window. Cost is
+	// recorded against every limit only if all of them admit it.
+	SlidingWindow(ctx context.Context, limits []RateLimit) (bool, []RateLimitState, error)
+
+	// TokenBucket admits a request against GCRA token buckets: each limit
+	// refills at Limit per Window up to a capacity of Burst. Cost is taken
+	// from every bucket only if all of them can cover it.
+	TokenBucket(ctx context.Context, limits []RateLimit) (bool, []RateLimitState, error)
+
+	// AdjustSlidingWindow corrects the cost of a request admitted age ago by
+	// limit.Cost (negative to refund), expiring with the original entry.
+	// Does nothing once the request has slid out of the window.
+	AdjustSlidingWindow(ctx context.Context, limit RateLimit, age time.Duration) error
+
+	// AdjustTokenBucket takes limit.Cost more tokens from the bucket without
+	// a limit check, or returns them when negative
+	AdjustTokenBucket(ctx context.Context, limit RateLimit) error
+
+	// AcquireSemaphores takes a slot for holder in every semaphore, but only
+	// if none would go over its Limit; otherwise nothing changes. Slots
+	// expire after Lease unless renewed, so a crashed holder can't leak them.
+	// Returns whether the slots were taken and each semaphore's slots in use
+	// (including holder's, or the count it would have reached if refused).
+	AcquireSemaphores(ctx context.Context, holder string, sems []Semaphore) (bool, []int64, error)
+
+	// RenewSemaphores extends holder's slots by another Lease. Slots that
+	// already expired are not taken again.
+	RenewSemaphores(ctx context.Context, holder string, sems []Semaphore) error
+
+	// ReleaseSemaphores gives up holder's slots
+	ReleaseSemaphores(ctx context.Context, holder string, sems []Semaphore) error
+
 	ScanGetAll(ctx context.Context, pattern string, count int64) (map[string]string, error)
 	ScanAll(ctx context.Context, pattern string, count int64) ([]string, error)
 
 	Close(ctx context.Context) error
 }
 
+// LimitedCounter is one counter in an IncrAllWithinLimits call
+type LimitedCounter struct {
+	Key    string
+	Amount int64
+	Limit  int64         // <= 0 means unlimited
+	TTL    time.Duration // applied when the key has no expiry yet
+}
+
+// RateLimit is one limit in a SlidingWindow or TokenBucket call
+type RateLimit struct {
+	Key    string
+	Cost   int64
+	Limit  int64 // units allowed per Window
+	Window time.Duration
+	Burst  int64 // token bucket capacity; defaults to Limit
+}
+
+// RateLimitState is a limit's quota after a SlidingWindow or TokenBucket call
+type RateLimitState struct {
+	Allowed    bool          // this limit on its own admits the request
+	Remaining  int64         // units still available now
+	ResetAfter time.Duration // until the full quota is available again
+	RetryAfter time.Duration // when not Allowed, until the cost would fit
+}
+
+// Semaphore is one counting semaphore in an AcquireSemaphores call
+type Semaphore struct {
+	Key   string
+	Limit int64 // <= 0 means unlimited
+	Lease time.Duration
+}
+
 /* ---------- Constants (namespaces) ---------- */
 
 const (
@@ -116,6 +192,18 @@ var (
 	KSCache    = NewKeyspace(KeyCachePrefix)
 	KSUser     = NewKeyspace("user:")
 	KSAPI      = NewKeyspace("api:")
+
+	// Async request queue: job records, pending markers and worker leases
+	KSAsyncJob   = NewKeyspace("asyncjob:")
+	KSAsyncQueue = NewKeyspace("asyncq:")
+	KSAsyncLease = NewKeyspace("asynclease:")
+
+	// Spend ledger counters and once-per-period budget alert markers
+	KSSpend       = NewKeyspace("spend:")
+	KSBudgetAlert = NewKeyspace("budgetalert:")
+
+	// Concurrency limit semaphores (sorted sets of holders scored by lease expiry)
+	KSConcurrency = NewKeyspace("concurrency:")
 )
 
 // Thin wrappers
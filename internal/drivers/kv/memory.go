@@ -3,7 +3,9 @@ package kv
 import (
 	"context"
 	"fmt"
+	"maps"
 	"path"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
@@ -16,13 +18,27 @@ type memoryItem struct {
 	expires time.Time
 }
 
+// windowEntry is one admitted request in a sliding window log
+type windowEntry struct {
+	at   time.Time
+	cost int64
+}
+
 type MemoryStore struct {
-	mu    sync.RWMutex
-	store map[string]memoryItem
+	mu      sync.RWMutex
+	store   map[string]memoryItem
+	windows map[string][]windowEntry        // sliding window logs, kept apart from string values
+	sems    map[string]map[string]time.Time // semaphore holders and their lease expiry
+	now     func() time.Time
 }
 
 func NewMemoryStore() *MemoryStore {
-	return &MemoryStore{store: make(map[string]memoryItem)}
+	return &MemoryStore{
+		store:   make(map[string]memoryItem),
+		windows: make(map[string][]windowEntry),
+		sems:    make(map[string]map[string]time.Time),
+		now:     time.Now,
+	}
 }
 
 func (m *MemoryStore) Get(ctx context.Context, key string) (string, error) {
@@ -46,6 +62,21 @@ func (m *MemoryStore) Set(ctx context.Context, key string, value string, ttl tim
 	return nil
 }
 
+func (m *MemoryStore) SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if item, ok := m.store[key]; ok && (item.expires.IsZero() || now.Before(item.expires)) {
+		return false, nil
+	}
+	expires := time.Time{}
+	if ttl > 0 {
+		expires = now.Add(ttl)
+	}
+	m.store[key] = memoryItem{value: value, expires: expires}
+	return true, nil
+}
+
 func (m *MemoryStore) Del(ctx context.Context, key string) error {
 	m.mu.Lock()
 	delete(m.store, key)
@@ -107,6 +138,281 @@ func (m *MemoryStore) Expire(ctx context.Context, key string, ttl time.Duration)
 	return true, nil
 }
 
+func (m *MemoryStore) IncrAllWithinLimits(ctx context.Context, counters []LimitedCounter) (bool, []int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	totals := make([]int64, len(counters))
+	allowed := true
+	for i, c := range counters {
+		var current int64
+		if item, ok := m.store[c.Key]; ok && (item.expires.IsZero() || now.Before(item.expires)) {
+			val, err := strconv.ParseInt(item.value, 10, 64)
+			if err != nil {
+				return false, nil, fmt.Errorf("value is not an integer")
+			}
+			current = val
+		}
+		totals[i] = current + c.Amount
+		if c.Limit > 0 && totals[i] > c.Limit {
+			allowed = false
+		}
+	}
+	if !allowed {
+		return false, totals, nil
+	}
+
+	for i, c := range counters {
+		item, ok := m.store[c.Key]
+		if !ok || (!item.expires.IsZero() && now.After(item.expires)) {
+			item = memoryItem{}
+		}
+		item.value = strconv.FormatInt(totals[i], 10)
+		if item.expires.IsZero() && c.TTL > 0 {
+			item.expires = now.Add(c.TTL)
+		}
+		m.store[c.Key] = item
+	}
+	return true, totals, nil
+}
+
+func (m *MemoryStore) SlidingWindow(ctx context.Context, limits []RateLimit) (bool, []RateLimitState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	states := make([]RateLimitState, len(limits))
+	used := make([]int64, len(limits))
+	allowed := true
+	for i, l := range limits {
+		// Drop entries that have slid out of the window
+		entries := m.windows[l.Key]
+		start := now.Add(-l.Window)
+		kept := entries[:0]
+		for _, e := range entries {
+			if e.at.After(start) {
+				kept = append(kept, e)
+				used[i] += e.cost
+			}
+		}
+		m.windows[l.Key] = kept
+
+		states[i].Allowed = used[i]+l.Cost <= l.Limit
+		if !states[i].Allowed {
+			allowed = false
+			// Wait until enough of the oldest entries expire to fit the cost
+			states[i].RetryAfter = l.Window
+			var freed int64
+			for _, e := range kept {
+				freed += e.cost
+				if used[i]-freed+l.Cost <= l.Limit {
+					states[i].RetryAfter = e.at.Add(l.Window).Sub(now)
+					break
+				}
+			}
+		}
+	}
+
+	for i, l := range limits {
+		kept := m.windows[l.Key]
+		if allowed {
+			m.windows[l.Key] = append(kept, windowEntry{at: now, cost: l.Cost})
+			states[i].Remaining = l.Limit - used[i] - l.Cost
+			states[i].ResetAfter = l.Window
+			continue
+		}
+		states[i].Remaining = max(0, l.Limit-used[i])
+		if len(kept) > 0 {
+			states[i].ResetAfter = kept[len(kept)-1].at.Add(l.Window).Sub(now)
+		}
+		if len(kept) == 0 {
+			delete(m.windows, l.Key)
+		}
+	}
+	return allowed, states, nil
+}
+
+func (m *MemoryStore) TokenBucket(ctx context.Context, limits []RateLimit) (bool, []RateLimitState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	nowNs := float64(now.UnixNano())
+	states := make([]RateLimitState, len(limits))
+	tats := make([]float64, len(limits))
+	newTats := make([]float64, len(limits))
+	emissions := make([]float64, len(limits))
+	tolerances := make([]float64, len(limits))
+	allowed := true
+	for i, l := range limits {
+		burst := l.Burst
+		if burst <= 0 {
+			burst = l.Limit
+		}
+		// GCRA: each unit of cost pushes the theoretical arrival time (TAT)
+		// forward by one emission interval; a request fits while the TAT
+		// stays within the burst tolerance of now
+		emissions[i] = float64(l.Window.Nanoseconds()) / float64(l.Limit)
+		tolerances[i] = emissions[i] * float64(burst)
+
+		tats[i] = nowNs
+		if item, ok := m.store[l.Key]; ok && (item.expires.IsZero() || now.Before(item.expires)) {
+			if tat, err := strconv.ParseFloat(item.value, 64); err == nil && tat > nowNs {
+				tats[i] = tat
+			}
+		}
+		newTats[i] = tats[i] + float64(l.Cost)*emissions[i]
+		allowAt := newTats[i] - tolerances[i]
+		states[i].Allowed = nowNs >= allowAt
+		if !states[i].Allowed {
+			allowed = false
+			states[i].RetryAfter = time.Duration(allowAt - nowNs)
+		}
+	}
+
+	for i, l := range limits {
+		tat := tats[i]
+		if allowed {
+			tat = newTats[i]
+			ttl := time.Duration(tat - nowNs)
+			m.store[l.Key] = memoryItem{value: strconv.FormatFloat(tat, 'f', 0, 64), expires: now.Add(max(ttl, time.Millisecond))}
+		}
+		states[i].Remaining = max(0, int64((nowNs+tolerances[i]-tat)/emissions[i]))
+		states[i].ResetAfter = time.Duration(tat - nowNs)
+	}
+	return allowed, states, nil
+}
+
+func (m *MemoryStore) AdjustSlidingWindow(ctx context.Context, limit RateLimit, age time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if age >= limit.Window || limit.Cost == 0 {
+		return nil
+	}
+	// Keep the log ordered by admission time so pruning and retry maths hold
+	at := now.Add(-age)
+	entries := m.windows[limit.Key]
+	i := len(entries)
+	for i > 0 && entries[i-1].at.After(at) {
+		i--
+	}
+	entries = append(entries, windowEntry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = windowEntry{at: at, cost: limit.Cost}
+	m.windows[limit.Key] = entries
+	return nil
+}
+
+func (m *MemoryStore) AdjustTokenBucket(ctx context.Context, limit RateLimit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	nowNs := float64(now.UnixNano())
+	tat := nowNs
+	if item, ok := m.store[limit.Key]; ok && (item.expires.IsZero() || now.Before(item.expires)) {
+		if v, err := strconv.ParseFloat(item.value, 64); err == nil && v > nowNs {
+			tat = v
+		}
+	}
+	tat += float64(limit.Cost) * float64(limit.Window.Nanoseconds()) / float64(limit.Limit)
+	if tat <= nowNs {
+		delete(m.store, limit.Key)
+		return nil
+	}
+	m.store[limit.Key] = memoryItem{value: strconv.FormatFloat(tat, 'f', 0, 64), expires: now.Add(max(time.Duration(tat-nowNs), time.Millisecond))}
+	return nil
+}
+
+func (m *MemoryStore) AcquireSemaphores(ctx context.Context, holder string, sems []Semaphore) (bool, []int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	ok := true
+	counts := make([]int64, len(sems))
+	for i, s := range sems {
+		holders := m.sems[s.Key]
+		for h, expires := range holders {
+			if !now.Before(expires) {
+				delete(holders, h)
+			}
+		}
+		counts[i] = int64(len(holders))
+		if _, held := holders[holder]; !held {
+			counts[i]++
+		}
+		if s.Limit > 0 && counts[i] > s.Limit {
+			ok = false
+		}
+	}
+	if !ok {
+		return false, counts, nil
+	}
+	for _, s := range sems {
+		if m.sems[s.Key] == nil {
+			m.sems[s.Key] = make(map[string]time.Time)
+		}
+		m.sems[s.Key][holder] = now.Add(s.Lease)
+	}
+	return true, counts, nil
+}
+
+func (m *MemoryStore) RenewSemaphores(ctx context.Context, holder string, sems []Semaphore) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	for _, s := range sems {
+		if expires, ok := m.sems[s.Key][holder]; ok && now.Before(expires) {
+			m.sems[s.Key][holder] = now.Add(s.Lease)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) ReleaseSemaphores(ctx context.Context, holder string, sems []Semaphore) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range sems {
+		delete(m.sems[s.Key], holder)
+		if len(m.sems[s.Key]) == 0 {
+			delete(m.sems, s.Key)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) readWindow(ctx context.Context, key string) ([]windowEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.windows[key]), nil
+}
+
+func (m *MemoryStore) readTokenBucket(ctx context.Context, key string) (time.Time, error) {
+	m.mu.RLock()
+	item, ok := m.store[key]
+	m.mu.RUnlock()
+	if !ok || (!item.expires.IsZero() && !m.now().Before(item.expires)) {
+		return time.Time{}, nil
+	}
+	tat, err := strconv.ParseFloat(item.value, 64)
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return time.Unix(0, int64(tat)), nil
+}
+
+func (m *MemoryStore) readSemaphore(ctx context.Context, key string) (map[string]time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return maps.Clone(m.sems[key]), nil
+}
+
 func (m *MemoryStore) Keys(ctx context.Context, pattern string) ([]string, error) {
 	// only supports simple suffix * as wildcard
 	m.mu.RLock()
@@ -125,6 +431,8 @@ func (m *MemoryStore) Close(ctx context.Context) error {
 	for k := range m.store {
 		delete(m.store, k)
 	}
+	clear(m.windows)
+	clear(m.sems)
 	m.mu.Unlock()
 	return nil
 }
@@ -69,6 +69,29 @@ func TestMemoryStore_Set(t *testing.T) {
 	})
 }
 
+func TestMemoryStore_SetNX(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	ok, err := store.SetNX(ctx, "lease", "first", 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = store.SetNX(ctx, "lease", "second", 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	value, _ := store.Get(ctx, "lease")
+	assert.Equal(t, "first", value)
+
+	// Free again once it expires
+	time.Sleep(150 * time.Millisecond)
+	ok, err = store.SetNX(ctx, "lease", "second", 0)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	value, _ = store.Get(ctx, "lease")
+	assert.Equal(t, "second", value)
+}
+
 func TestMemoryStore_Del(t *testing.T) {
 	ctx := context.Background()
 	store := NewMemoryStore()
@@ -159,6 +182,182 @@ func TestMemoryStore_Incr_IncrBy(t *testing.T) {
 	})
 }
 
+func TestMemoryStore_IncrAllWithinLimits(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	counters := func(amount int64) []LimitedCounter {
+		return []LimitedCounter{
+			{Key: "parent", Amount: amount, Limit: 10, TTL: time.Minute},
+			{Key: "child", Amount: amount, Limit: 4, TTL: time.Minute},
+			{Key: "unlimited", Amount: amount},
+		}
+	}
+
+	t.Run("applies all when within limits", func(t *testing.T) {
+		applied, totals, err := store.IncrAllWithinLimits(ctx, counters(3))
+		require.NoError(t, err)
+		assert.True(t, applied)
+		assert.Equal(t, []int64{3, 3, 3}, totals)
+	})
+
+	t.Run("applies none when any limit would be exceeded", func(t *testing.T) {
+		applied, totals, err := store.IncrAllWithinLimits(ctx, counters(2))
+		require.NoError(t, err)
+		assert.False(t, applied)
+		assert.Equal(t, []int64{5, 5, 5}, totals)
+
+		value, _ := store.Get(ctx, "parent")
+		assert.Equal(t, "3", value)
+	})
+
+	t.Run("sets ttl on new keys", func(t *testing.T) {
+		_, _, err := store.IncrAllWithinLimits(ctx, []LimitedCounter{{Key: "short", Amount: 1, TTL: time.Millisecond}})
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+
+		exists, _ := store.Exists(ctx, "short")
+		assert.False(t, exists)
+	})
+}
+
+func TestMemoryStore_SlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Unix(1_700_000_000, 0)
+	store.now = func() time.Time { return now }
+
+	limit := func(cost int64) []RateLimit {
+		return []RateLimit{{Key: "sw", Cost: cost, Limit: 3, Window: time.Minute}}
+	}
+
+	allowed, states, err := store.SlidingWindow(ctx, limit(2))
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, int64(1), states[0].Remaining)
+	assert.Equal(t, time.Minute, states[0].ResetAfter)
+
+	now = now.Add(40 * time.Second)
+	allowed, _, err = store.SlidingWindow(ctx, limit(1))
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	t.Run("denies across a window edge", func(t *testing.T) {
+		// A fixed window would have reset at the minute; the log still holds 3
+		now = now.Add(10 * time.Second)
+		allowed, states, err := store.SlidingWindow(ctx, limit(1))
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		assert.Equal(t, int64(0), states[0].Remaining)
+		assert.Equal(t, 10*time.Second, states[0].RetryAfter)
+	})
+
+	t.Run("admits once old entries slide out", func(t *testing.T) {
+		now = now.Add(10 * time.Second)
+		allowed, states, err := store.SlidingWindow(ctx, limit(2))
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, int64(0), states[0].Remaining)
+	})
+
+	t.Run("all or nothing across limits", func(t *testing.T) {
+		limits := []RateLimit{
+			{Key: "sw-a", Cost: 1, Limit: 10, Window: time.Minute},
+			{Key: "sw-b", Cost: 1, Limit: 0, Window: time.Minute},
+		}
+		allowed, states, err := store.SlidingWindow(ctx, limits)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		assert.True(t, states[0].Allowed)
+		assert.Equal(t, int64(10), states[0].Remaining)
+	})
+}
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Unix(1_700_000_000, 0)
+	store.now = func() time.Time { return now }
+
+	// 60 per minute: one token per second, bursting to 60
+	bucket := func(cost int64) []RateLimit {
+		return []RateLimit{{Key: "tb", Cost: cost, Limit: 60, Window: time.Minute}}
+	}
+
+	allowed, states, err := store.TokenBucket(ctx, bucket(60))
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, int64(0), states[0].Remaining)
+	assert.Equal(t, time.Minute, states[0].ResetAfter)
+
+	allowed, states, err = store.TokenBucket(ctx, bucket(5))
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 5*time.Second, states[0].RetryAfter)
+
+	t.Run("refills continuously", func(t *testing.T) {
+		now = now.Add(5 * time.Second)
+		allowed, states, err := store.TokenBucket(ctx, bucket(5))
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, int64(0), states[0].Remaining)
+	})
+
+	t.Run("burst caps the bucket", func(t *testing.T) {
+		limits := []RateLimit{{Key: "tb-burst", Cost: 11, Limit: 60, Window: time.Minute, Burst: 10}}
+		allowed, _, err := store.TokenBucket(ctx, limits)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+
+		value, _ := store.Get(ctx, "tb-burst")
+		assert.Empty(t, value)
+	})
+}
+
+func TestMemoryStore_AdjustRateLimits(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Unix(1_700_000_000, 0)
+	store.now = func() time.Time { return now }
+
+	t.Run("sliding window correction expires with the original", func(t *testing.T) {
+		limit := RateLimit{Key: "sw", Cost: 8, Limit: 10, Window: time.Minute}
+		allowed, _, err := store.SlidingWindow(ctx, []RateLimit{limit})
+		require.NoError(t, err)
+		require.True(t, allowed)
+
+		now = now.Add(30 * time.Second)
+		require.NoError(t, store.AdjustSlidingWindow(ctx, RateLimit{Key: "sw", Cost: -6, Window: time.Minute}, 30*time.Second))
+
+		limit.Cost = 8
+		allowed, states, err := store.SlidingWindow(ctx, []RateLimit{limit})
+		require.NoError(t, err)
+		assert.True(t, allowed, "2 used after the refund")
+		assert.Equal(t, int64(0), states[0].Remaining)
+
+		// Both the original and its correction slide out together
+		now = now.Add(31 * time.Second)
+		_, states, err = store.SlidingWindow(ctx, []RateLimit{{Key: "sw", Cost: 0, Limit: 10, Window: time.Minute}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), states[0].Remaining)
+	})
+
+	t.Run("token bucket charges and refunds", func(t *testing.T) {
+		bucket := RateLimit{Key: "tb", Cost: 30, Limit: 60, Window: time.Minute}
+		_, _, err := store.TokenBucket(ctx, []RateLimit{bucket})
+		require.NoError(t, err)
+
+		require.NoError(t, store.AdjustTokenBucket(ctx, RateLimit{Key: "tb", Cost: 30, Limit: 60, Window: time.Minute}))
+		allowed, _, err := store.TokenBucket(ctx, []RateLimit{{Key: "tb", Cost: 1, Limit: 60, Window: time.Minute}})
+		require.NoError(t, err)
+		assert.False(t, allowed)
+
+		require.NoError(t, store.AdjustTokenBucket(ctx, RateLimit{Key: "tb", Cost: -60, Limit: 60, Window: time.Minute}))
+		value, _ := store.Get(ctx, "tb")
+		assert.Empty(t, value, "a fully refunded bucket is removed")
+	})
+}
+
 func TestMemoryStore_Expire(t *testing.T) {
 	ctx := context.Background()
 	store := NewMemoryStore()
@@ -305,3 +504,59 @@ func TestMatchPattern(t *testing.T) {
 		})
 	}
 }
+
+func TestMemoryStore_Semaphores(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Unix(1_700_000_000, 0)
+	store.now = func() time.Time { return now }
+
+	inFlight := Semaphore{Key: "inflight", Limit: 2, Lease: time.Minute}
+	streams := Semaphore{Key: "streams", Limit: 1, Lease: time.Minute}
+
+	ok, counts, err := store.AcquireSemaphores(ctx, "a", []Semaphore{inFlight, streams})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []int64{1, 1}, counts)
+
+	// b fits in flight but not as a stream, so takes neither slot
+	ok, counts, err = store.AcquireSemaphores(ctx, "b", []Semaphore{inFlight, streams})
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, []int64{2, 2}, counts)
+
+	ok, counts, err = store.AcquireSemaphores(ctx, "c", []Semaphore{inFlight})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []int64{2}, counts)
+
+	// Re-acquiring a held slot doesn't take another
+	ok, _, err = store.AcquireSemaphores(ctx, "a", []Semaphore{inFlight})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, store.ReleaseSemaphores(ctx, "c", []Semaphore{inFlight}))
+	ok, _, err = store.AcquireSemaphores(ctx, "d", []Semaphore{inFlight})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Renewed leases survive; a holder that stops renewing loses its slot
+	now = now.Add(40 * time.Second)
+	require.NoError(t, store.RenewSemaphores(ctx, "a", []Semaphore{inFlight, streams}))
+	now = now.Add(40 * time.Second)
+	ok, counts, err = store.AcquireSemaphores(ctx, "e", []Semaphore{inFlight})
+	require.NoError(t, err)
+	assert.True(t, ok, "d's lease expired")
+	assert.Equal(t, []int64{2}, counts)
+
+	ok, _, err = store.AcquireSemaphores(ctx, "f", []Semaphore{streams})
+	require.NoError(t, err)
+	assert.False(t, ok, "a still holds the stream slot")
+
+	// Renewing an expired slot doesn't take it back
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, store.RenewSemaphores(ctx, "a", []Semaphore{streams}))
+	ok, _, err = store.AcquireSemaphores(ctx, "f", []Semaphore{streams})
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -2,8 +2,12 @@ package kv
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -31,6 +35,10 @@ func (r *RedisStore) Set(ctx context.Context, key string, value string, ttl time
 	return r.client.Set(ctx, key, value, ttl).Err()
 }
 
+func (r *RedisStore) SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, ttl).Result()
+}
+
 func (r *RedisStore) Del(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
@@ -52,6 +60,388 @@ func (r *RedisStore) Expire(ctx context.Context, key string, ttl time.Duration)
 	return r.client.Expire(ctx, key, ttl).Result()
 }
 
+// incrAllWithinLimitsScript checks every counter before touching any of them.
+// KEYS are the counters; ARGV holds amount, limit and TTL (ms) per key.
+// Returns {applied, total1, total2, ...}.
+var incrAllWithinLimitsScript = redis.NewScript(`
+local totals = {}
+local allowed = 1
+for i, key in ipairs(KEYS) do
+	local amount = tonumber(ARGV[i*3-2])
+	local limit = tonumber(ARGV[i*3-1])
+	totals[i] = tonumber(redis.call('GET', key) or '0') + amount
+	if limit > 0 and totals[i] > limit then
+		allowed = 0
+	end
+end
+if allowed == 1 then
+	for i, key in ipairs(KEYS) do
+		totals[i] = redis.call('INCRBY', key, ARGV[i*3-2])
+		local ttl = tonumber(ARGV[i*3])
+		if ttl > 0 and redis.call('PTTL', key) < 0 then
+			redis.call('PEXPIRE', key, ttl)
+		end
+	end
+end
+table.insert(totals, 1, allowed)
+return totals
+`)
+
+func (r *RedisStore) IncrAllWithinLimits(ctx context.Context, counters []LimitedCounter) (bool, []int64, error) {
+	if len(counters) == 0 {
+		return true, nil, nil
+	}
+	keys := make([]string, len(counters))
+	args := make([]any, 0, len(counters)*3)
+	for i, c := range counters {
+		keys[i] = c.Key
+		args = append(args, c.Amount, c.Limit, c.TTL.Milliseconds())
+	}
+	res, err := incrAllWithinLimitsScript.Run(ctx, r.client, keys, args...).Int64Slice()
+	if err != nil {
+		return false, nil, err
+	}
+	return res[0] == 1, res[1:], nil
+}
+
+// slidingWindowScript keeps one sorted set per limit, scored by admission time
+// in microseconds (server clock), with members "<time>:<nonce>:<cost>"
+// (cost is negative for corrections added by AdjustSlidingWindow).
+// KEYS are the limits; ARGV[1] is a per-call nonce, then cost, limit and
+// window (us) per key. Returns {allowed, then ok, remaining, reset_us,
+// retry_us per key}.
+var slidingWindowScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local allowed = 1
+local used, oks, resets, retries = {}, {}, {}, {}
+for i, key in ipairs(KEYS) do
+	local cost = tonumber(ARGV[i*3-1])
+	local limit = tonumber(ARGV[i*3])
+	local window = tonumber(ARGV[i*3+1])
+	redis.call('ZREMRANGEBYSCORE', key, '-inf', string.format('%.0f', now - window))
+	local entries = redis.call('ZRANGE', key, 0, -1, 'WITHSCORES')
+	local u = 0
+	for j = 1, #entries, 2 do
+		u = u + tonumber(string.match(entries[j], ':(-?%d+)$'))
+	end
+	used[i], oks[i], retries[i], resets[i] = u, 1, 0, 0
+	if #entries > 0 then
+		resets[i] = tonumber(entries[#entries]) + window - now
+	end
+	if u + cost > limit then
+		oks[i], allowed, retries[i] = 0, 0, window
+		local freed = 0
+		for j = 1, #entries, 2 do
+			freed = freed + tonumber(string.match(entries[j], ':(-?%d+)$'))
+			if u - freed + cost <= limit then
+				retries[i] = tonumber(entries[j+1]) + window - now
+				break
+			end
+		end
+	end
+end
+local out = {allowed}
+for i, key in ipairs(KEYS) do
+	local cost = tonumber(ARGV[i*3-1])
+	local window = tonumber(ARGV[i*3+1])
+	local remaining = tonumber(ARGV[i*3]) - used[i]
+	if allowed == 1 then
+		local at = string.format('%.0f', now)
+		redis.call('ZADD', key, at, at .. ':' .. ARGV[1] .. ':' .. ARGV[i*3-1])
+		redis.call('PEXPIRE', key, math.ceil(window / 1000))
+		remaining = remaining - cost
+		resets[i] = window
+	end
+	if remaining < 0 then remaining = 0 end
+	table.insert(out, oks[i])
+	table.insert(out, remaining)
+	table.insert(out, resets[i])
+	table.insert(out, retries[i])
+end
+return out
+`)
+
+// tokenBucketScript implements GCRA: each key stores the bucket's theoretical
+// arrival time (TAT) in microseconds. ARGV holds cost, limit, window (us) and
+// burst per key. Returns {allowed, then ok, remaining, reset_us, retry_us per key}.
+var tokenBucketScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local allowed = 1
+local tats, newtats, emissions, tolerances, oks, retries = {}, {}, {}, {}, {}, {}
+for i, key in ipairs(KEYS) do
+	local cost = tonumber(ARGV[i*4-3])
+	emissions[i] = tonumber(ARGV[i*4-1]) / tonumber(ARGV[i*4-2])
+	tolerances[i] = emissions[i] * tonumber(ARGV[i*4])
+	local tat = tonumber(redis.call('GET', key) or '0') or 0
+	if tat < now then tat = now end
+	tats[i] = tat
+	newtats[i] = tat + cost * emissions[i]
+	local allow_at = newtats[i] - tolerances[i]
+	oks[i], retries[i] = 1, 0
+	if now < allow_at then
+		oks[i], allowed, retries[i] = 0, 0, allow_at - now
+	end
+end
+local out = {allowed}
+for i, key in ipairs(KEYS) do
+	local tat = tats[i]
+	if allowed == 1 then
+		tat = newtats[i]
+		redis.call('SET', key, string.format('%.0f', tat), 'PX', math.max(1, math.ceil((tat - now) / 1000)))
+	end
+	local remaining = math.floor((now + tolerances[i] - tat) / emissions[i])
+	if remaining < 0 then remaining = 0 end
+	table.insert(out, oks[i])
+	table.insert(out, remaining)
+	table.insert(out, tat - now)
+	table.insert(out, retries[i])
+end
+return out
+`)
+
+// adjustSlidingWindowScript adds a correction entry (cost may be negative)
+// scored at the original admission time so both expire together.
+// ARGV: cost, age (us), window (us), nonce.
+var adjustSlidingWindowScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local window = tonumber(ARGV[3])
+local at = now - tonumber(ARGV[2])
+if at <= now - window then
+	return 0
+end
+local score = string.format('%.0f', at)
+redis.call('ZADD', KEYS[1], score, score .. ':' .. ARGV[4] .. ':' .. ARGV[1])
+redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+return 1
+`)
+
+// adjustTokenBucketScript moves a GCRA bucket's TAT by cost emission
+// intervals. ARGV: cost, limit, window (us).
+var adjustTokenBucketScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local tat = tonumber(redis.call('GET', KEYS[1]) or '0') or 0
+if tat < now then tat = now end
+tat = tat + tonumber(ARGV[1]) * tonumber(ARGV[3]) / tonumber(ARGV[2])
+if tat <= now then
+	redis.call('DEL', KEYS[1])
+	return 0
+end
+redis.call('SET', KEYS[1], string.format('%.0f', tat), 'PX', math.max(1, math.ceil((tat - now) / 1000)))
+return 1
+`)
+
+// acquireSemaphoresScript takes a slot in every semaphore or none. Each is a
+// sorted set of holders scored by lease expiry (ms); expired holders are
+// dropped first. ARGV: holder, then limit and lease (ms) per key.
+// Returns {ok, in_use...}.
+var acquireSemaphoresScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local holder = ARGV[1]
+local ok = 1
+local res = {0}
+for i, key in ipairs(KEYS) do
+	redis.call('ZREMRANGEBYSCORE', key, '-inf', string.format('%.0f', now))
+	local count = redis.call('ZCARD', key)
+	if not redis.call('ZSCORE', key, holder) then
+		count = count + 1
+	end
+	local limit = tonumber(ARGV[i * 2])
+	if limit > 0 and count > limit then
+		ok = 0
+	end
+	res[i + 1] = count
+end
+if ok == 1 then
+	for i, key in ipairs(KEYS) do
+		local lease = tonumber(ARGV[i * 2 + 1])
+		redis.call('ZADD', key, string.format('%.0f', now + lease), holder)
+		redis.call('PEXPIRE', key, lease)
+	end
+end
+res[1] = ok
+return res
+`)
+
+// renewSemaphoresScript extends holder's unexpired slots.
+// ARGV: holder, then lease (ms) per key.
+var renewSemaphoresScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+for i, key in ipairs(KEYS) do
+	local expires = tonumber(redis.call('ZSCORE', KEYS[i], ARGV[1]) or '0') or 0
+	if expires > now then
+		local lease = tonumber(ARGV[i + 1])
+		redis.call('ZADD', key, 'XX', string.format('%.0f', now + lease), ARGV[1])
+		if redis.call('PTTL', key) < lease then
+			redis.call('PEXPIRE', key, lease)
+		end
+	end
+end
+return 1
+`)
+
+func (r *RedisStore) AcquireSemaphores(ctx context.Context, holder string, sems []Semaphore) (bool, []int64, error) {
+	if len(sems) == 0 {
+		return true, nil, nil
+	}
+	keys := make([]string, len(sems))
+	args := make([]any, 0, 1+len(sems)*2)
+	args = append(args, holder)
+	for i, s := range sems {
+		keys[i] = s.Key
+		args = append(args, s.Limit, s.Lease.Milliseconds())
+	}
+	res, err := acquireSemaphoresScript.Run(ctx, r.client, keys, args...).Int64Slice()
+	if err != nil {
+		return false, nil, err
+	}
+	return res[0] == 1, res[1:], nil
+}
+
+func (r *RedisStore) RenewSemaphores(ctx context.Context, holder string, sems []Semaphore) error {
+	if len(sems) == 0 {
+		return nil
+	}
+	keys := make([]string, len(sems))
+	args := make([]any, 0, 1+len(sems))
+	args = append(args, holder)
+	for i, s := range sems {
+		keys[i] = s.Key
+		args = append(args, s.Lease.Milliseconds())
+	}
+	return renewSemaphoresScript.Run(ctx, r.client, keys, args...).Err()
+}
+
+func (r *RedisStore) ReleaseSemaphores(ctx context.Context, holder string, sems []Semaphore) error {
+	if len(sems) == 0 {
+		return nil
+	}
+	pipe := r.client.Pipeline()
+	for _, s := range sems {
+		pipe.ZRem(ctx, s.Key, holder)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisStore) AdjustSlidingWindow(ctx context.Context, limit RateLimit, age time.Duration) error {
+	if limit.Cost == 0 || age >= limit.Window {
+		return nil
+	}
+	args := []any{limit.Cost, age.Microseconds(), limit.Window.Microseconds(), uuid.NewString()}
+	return adjustSlidingWindowScript.Run(ctx, r.client, []string{limit.Key}, args...).Err()
+}
+
+func (r *RedisStore) AdjustTokenBucket(ctx context.Context, limit RateLimit) error {
+	if limit.Cost == 0 {
+		return nil
+	}
+	args := []any{limit.Cost, limit.Limit, limit.Window.Microseconds()}
+	return adjustTokenBucketScript.Run(ctx, r.client, []string{limit.Key}, args...).Err()
+}
+
+func (r *RedisStore) SlidingWindow(ctx context.Context, limits []RateLimit) (bool, []RateLimitState, error) {
+	if len(limits) == 0 {
+		return true, nil, nil
+	}
+	keys := make([]string, len(limits))
+	args := make([]any, 0, 1+len(limits)*3)
+	args = append(args, uuid.NewString())
+	for i, l := range limits {
+		keys[i] = l.Key
+		args = append(args, l.Cost, l.Limit, l.Window.Microseconds())
+	}
+	res, err := slidingWindowScript.Run(ctx, r.client, keys, args...).Int64Slice()
+	if err != nil {
+		return false, nil, err
+	}
+	return res[0] == 1, decodeRateLimitStates(res[1:]), nil
+}
+
+func (r *RedisStore) TokenBucket(ctx context.Context, limits []RateLimit) (bool, []RateLimitState, error) {
+	if len(limits) == 0 {
+		return true, nil, nil
+	}
+	keys := make([]string, len(limits))
+	args := make([]any, 0, len(limits)*4)
+	for i, l := range limits {
+		burst := l.Burst
+		if burst <= 0 {
+			burst = l.Limit
+		}
+		keys[i] = l.Key
+		args = append(args, l.Cost, l.Limit, l.Window.Microseconds(), burst)
+	}
+	res, err := tokenBucketScript.Run(ctx, r.client, keys, args...).Int64Slice()
+	if err != nil {
+		return false, nil, err
+	}
+	return res[0] == 1, decodeRateLimitStates(res[1:]), nil
+}
+
+func (r *RedisStore) readWindow(ctx context.Context, key string) ([]windowEntry, error) {
+	members, err := r.client.ZRangeWithScores(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]windowEntry, 0, len(members))
+	for _, z := range members {
+		member, _ := z.Member.(string)
+		cost, err := strconv.ParseInt(member[strings.LastIndexByte(member, ':')+1:], 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, windowEntry{at: time.UnixMicro(int64(z.Score)), cost: cost})
+	}
+	return entries, nil
+}
+
+func (r *RedisStore) readTokenBucket(ctx context.Context, key string) (time.Time, error) {
+	tat, err := r.client.Get(ctx, key).Float64()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMicro(int64(tat)), nil
+}
+
+func (r *RedisStore) readSemaphore(ctx context.Context, key string) (map[string]time.Time, error) {
+	members, err := r.client.ZRangeWithScores(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	holders := make(map[string]time.Time, len(members))
+	for _, z := range members {
+		if holder, ok := z.Member.(string); ok {
+			holders[holder] = time.UnixMilli(int64(z.Score))
+		}
+	}
+	return holders, nil
+}
+
+// decodeRateLimitStates unpacks the {ok, remaining, reset_us, retry_us}
+// groups returned by the rate limit scripts
+func decodeRateLimitStates(vals []int64) []RateLimitState {
+	states := make([]RateLimitState, len(vals)/4)
+	for i := range states {
+		v := vals[i*4 : i*4+4]
+		states[i] = RateLimitState{
+			Allowed:    v[0] == 1,
+			Remaining:  v[1],
+			ResetAfter: time.Duration(v[2]) * time.Microsecond,
+			RetryAfter: time.Duration(v[3]) * time.Microsecond,
+		}
+	}
+	return states
+}
+
 // ScanAll is a Non-blocking, incremental SCAN over a pattern.
 // 'count' is a hint; 512–2048 is a good starting range.
 func (r *RedisStore) ScanAll(ctx context.Context, key string, count int64) ([]string, error) {
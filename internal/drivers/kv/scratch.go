@@ -0,0 +1,331 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ScratchStore reads through to another store but keeps every write to
+// itself, so callers can run code that changes state (rate limits, counters)
+// against live values without changing them. Counters, sliding window logs,
+// token buckets and semaphores are copied from the base store the first time
+// they are used. Base stores that can't read that state without changing it
+// (see stateReader) leave it empty.
+type ScratchStore struct {
+	base    KvStore
+	scratch *MemoryStore
+
+	mu      sync.Mutex
+	touched map[string]bool // keys whose value now lives in scratch
+}
+
+// NewScratchStore creates a scratch store over base
+func NewScratchStore(base KvStore) *ScratchStore {
+	return &ScratchStore{base: base, scratch: NewMemoryStore(), touched: make(map[string]bool)}
+}
+
+// stateReader is implemented by stores that can read the state behind
+// sliding windows, token buckets and semaphores without changing it
+type stateReader interface {
+	// readWindow lists a sliding window log's entries, oldest first
+	readWindow(ctx context.Context, key string) ([]windowEntry, error)
+	// readTokenBucket returns a bucket's theoretical arrival time; zero when empty
+	readTokenBucket(ctx context.Context, key string) (time.Time, error)
+	// readSemaphore returns a semaphore's holders and their lease expiry
+	readSemaphore(ctx context.Context, key string) (map[string]time.Time, error)
+}
+
+var (
+	_ stateReader = (*MemoryStore)(nil)
+	_ stateReader = (*RedisStore)(nil)
+	_ stateReader = (*CircuitBreakerStore)(nil)
+)
+
+// copyOnWrite copies key's integer value from the base store before its
+// first change. Missing and non-integer values are left behind; the scratch
+// store treats the key as new.
+func (s *ScratchStore) copyOnWrite(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		if s.touched[key] {
+			continue
+		}
+		s.touched[key] = true
+		value, err := s.base.Get(ctx, key)
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			continue
+		}
+		// Scratch stores are short-lived; the TTL only bounds a stale copy
+		if err := s.scratch.Set(ctx, key, value, 24*time.Hour); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyState copies the live state behind keys into scratch with load before
+// their first use. Nothing is copied when the base store can't read it.
+func (s *ScratchStore) copyState(ctx context.Context, keys []string, load func(reader stateReader, key string) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reader, ok := s.base.(stateReader)
+	for _, key := range keys {
+		if s.touched[key] {
+			continue
+		}
+		s.touched[key] = true
+		if !ok {
+			continue
+		}
+		if err := load(reader, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ScratchStore) copyWindows(ctx context.Context, keys ...string) error {
+	return s.copyState(ctx, keys, func(reader stateReader, key string) error {
+		entries, err := reader.readWindow(ctx, key)
+		if err != nil || len(entries) == 0 {
+			return err
+		}
+		s.scratch.mu.Lock()
+		s.scratch.windows[key] = entries
+		s.scratch.mu.Unlock()
+		return nil
+	})
+}
+
+func (s *ScratchStore) copyTokenBuckets(ctx context.Context, keys ...string) error {
+	return s.copyState(ctx, keys, func(reader stateReader, key string) error {
+		tat, err := reader.readTokenBucket(ctx, key)
+		if err != nil || tat.IsZero() {
+			return err
+		}
+		s.scratch.mu.Lock()
+		s.scratch.store[key] = memoryItem{value: strconv.FormatInt(tat.UnixNano(), 10), expires: tat}
+		s.scratch.mu.Unlock()
+		return nil
+	})
+}
+
+func (s *ScratchStore) copySemaphores(ctx context.Context, keys ...string) error {
+	return s.copyState(ctx, keys, func(reader stateReader, key string) error {
+		holders, err := reader.readSemaphore(ctx, key)
+		if err != nil || len(holders) == 0 {
+			return err
+		}
+		s.scratch.mu.Lock()
+		s.scratch.sems[key] = holders
+		s.scratch.mu.Unlock()
+		return nil
+	})
+}
+
+// mark records that key's value now lives in scratch
+func (s *ScratchStore) mark(key string) {
+	s.mu.Lock()
+	s.touched[key] = true
+	s.mu.Unlock()
+}
+
+func (s *ScratchStore) isTouched(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.touched[key]
+}
+
+func (s *ScratchStore) Get(ctx context.Context, key string) (string, error) {
+	if s.isTouched(key) {
+		return s.scratch.Get(ctx, key)
+	}
+	return s.base.Get(ctx, key)
+}
+
+func (s *ScratchStore) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	s.mark(key)
+	return s.scratch.Set(ctx, key, value, ttl)
+}
+
+func (s *ScratchStore) SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	if !s.isTouched(key) {
+		exists, err := s.base.Exists(ctx, key)
+		if err != nil || exists {
+			return false, err
+		}
+	}
+	s.mark(key)
+	return s.scratch.SetNX(ctx, key, value, ttl)
+}
+
+func (s *ScratchStore) Del(ctx context.Context, key string) error {
+	s.mark(key)
+	return s.scratch.Del(ctx, key)
+}
+
+func (s *ScratchStore) Exists(ctx context.Context, key string) (bool, error) {
+	if s.isTouched(key) {
+		return s.scratch.Exists(ctx, key)
+	}
+	return s.base.Exists(ctx, key)
+}
+
+func (s *ScratchStore) Incr(ctx context.Context, key string) (int64, error) {
+	return s.IncrBy(ctx, key, 1)
+}
+
+func (s *ScratchStore) IncrBy(ctx context.Context, key string, amount int64) (int64, error) {
+	if err := s.copyOnWrite(ctx, key); err != nil {
+		return 0, err
+	}
+	return s.scratch.IncrBy(ctx, key, amount)
+}
+
+func (s *ScratchStore) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if err := s.copyOnWrite(ctx, key); err != nil {
+		return false, err
+	}
+	return s.scratch.Expire(ctx, key, ttl)
+}
+
+func (s *ScratchStore) IncrAllWithinLimits(ctx context.Context, counters []LimitedCounter) (bool, []int64, error) {
+	keys := make([]string, len(counters))
+	for i, c := range counters {
+		keys[i] = c.Key
+	}
+	if err := s.copyOnWrite(ctx, keys...); err != nil {
+		return false, nil, err
+	}
+	return s.scratch.IncrAllWithinLimits(ctx, counters)
+}
+
+func (s *ScratchStore) SlidingWindow(ctx context.Context, limits []RateLimit) (bool, []RateLimitState, error) {
+	if err := s.copyWindows(ctx, limitKeys(limits)...); err != nil {
+		return false, nil, err
+	}
+	return s.scratch.SlidingWindow(ctx, limits)
+}
+
+func (s *ScratchStore) TokenBucket(ctx context.Context, limits []RateLimit) (bool, []RateLimitState, error) {
+	if err := s.copyTokenBuckets(ctx, limitKeys(limits)...); err != nil {
+		return false, nil, err
+	}
+	return s.scratch.TokenBucket(ctx, limits)
+}
+
+func (s *ScratchStore) AdjustSlidingWindow(ctx context.Context, limit RateLimit, age time.Duration) error {
+	if err := s.copyWindows(ctx, limit.Key); err != nil {
+		return err
+	}
+	return s.scratch.AdjustSlidingWindow(ctx, limit, age)
+}
+
+func (s *ScratchStore) AdjustTokenBucket(ctx context.Context, limit RateLimit) error {
+	if err := s.copyTokenBuckets(ctx, limit.Key); err != nil {
+		return err
+	}
+	return s.scratch.AdjustTokenBucket(ctx, limit)
+}
+
+func (s *ScratchStore) AcquireSemaphores(ctx context.Context, holder string, sems []Semaphore) (bool, []int64, error) {
+	if err := s.copySemaphores(ctx, semaphoreKeys(sems)...); err != nil {
+		return false, nil, err
+	}
+	return s.scratch.AcquireSemaphores(ctx, holder, sems)
+}
+
+func (s *ScratchStore) RenewSemaphores(ctx context.Context, holder string, sems []Semaphore) error {
+	if err := s.copySemaphores(ctx, semaphoreKeys(sems)...); err != nil {
+		return err
+	}
+	return s.scratch.RenewSemaphores(ctx, holder, sems)
+}
+
+func (s *ScratchStore) ReleaseSemaphores(ctx context.Context, holder string, sems []Semaphore) error {
+	if err := s.copySemaphores(ctx, semaphoreKeys(sems)...); err != nil {
+		return err
+	}
+	return s.scratch.ReleaseSemaphores(ctx, holder, sems)
+}
+
+func limitKeys(limits []RateLimit) []string {
+	keys := make([]string, len(limits))
+	for i, l := range limits {
+		keys[i] = l.Key
+	}
+	return keys
+}
+
+func semaphoreKeys(sems []Semaphore) []string {
+	keys := make([]string, len(sems))
+	for i, s := range sems {
+		keys[i] = s.Key
+	}
+	return keys
+}
+
+func (s *ScratchStore) ScanGetAll(ctx context.Context, pattern string, count int64) (map[string]string, error) {
+	values, err := s.base.ScanGetAll(ctx, pattern, count)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = make(map[string]string)
+	}
+	for _, key := range s.touchedMatching(pattern) {
+		delete(values, key)
+	}
+	changed, err := s.scratch.ScanGetAll(ctx, pattern, count)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(values, changed)
+	return values, nil
+}
+
+func (s *ScratchStore) ScanAll(ctx context.Context, pattern string, count int64) ([]string, error) {
+	keys, err := s.base.ScanAll(ctx, pattern, count)
+	if err != nil {
+		return nil, err
+	}
+	touched := s.touchedMatching(pattern)
+	keys = slices.DeleteFunc(keys, func(key string) bool { return slices.Contains(touched, key) })
+	changed, err := s.scratch.ScanAll(ctx, pattern, count)
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, changed...), nil
+}
+
+// touchedMatching lists the keys matching pattern whose value lives in scratch
+func (s *ScratchStore) touchedMatching(pattern string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for key := range s.touched {
+		if matchPattern(key, pattern) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Close releases the scratch values; the base store is left open
+func (s *ScratchStore) Close(ctx context.Context) error {
+	return s.scratch.Close(ctx)
+}
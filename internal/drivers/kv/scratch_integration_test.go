@@ -0,0 +1,106 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/testkit"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScratchStoreIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	_, redisAddr := testkit.SetupTestContainers(t, testkit.DefaultContainerConfig())
+
+	ctx := context.Background()
+	base := kv.NewRedisStore(redisAddr, "", 0)
+	prefix := "scratch-test:" + uuid.NewString() + ":"
+
+	t.Run("MissingCounterStartsAtZero", func(t *testing.T) {
+		store := kv.NewScratchStore(base)
+
+		n, err := store.Incr(ctx, prefix+"missing")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		ok, totals, err := store.IncrAllWithinLimits(ctx, []kv.LimitedCounter{
+			{Key: prefix + "also-missing", Amount: 1, Limit: 5, TTL: time.Minute},
+		})
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, []int64{1}, totals)
+
+		exists, err := base.Exists(ctx, prefix+"missing")
+		require.NoError(t, err)
+		assert.False(t, exists, "the base store is never written")
+	})
+
+	t.Run("CountersStartFromLiveValues", func(t *testing.T) {
+		_, err := base.IncrBy(ctx, prefix+"requests", 4)
+		require.NoError(t, err)
+
+		n, err := kv.NewScratchStore(base).Incr(ctx, prefix+"requests")
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), n)
+
+		live, err := base.Get(ctx, prefix+"requests")
+		require.NoError(t, err)
+		assert.Equal(t, "4", live)
+	})
+
+	t.Run("SlidingWindowStartsFromLiveEntries", func(t *testing.T) {
+		limits := []kv.RateLimit{{Key: prefix + "window", Cost: 1, Limit: 2, Window: time.Minute}}
+		ok, _, err := base.SlidingWindow(ctx, limits)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		store := kv.NewScratchStore(base)
+		ok, _, err = store.SlidingWindow(ctx, limits)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, _, err = store.SlidingWindow(ctx, limits)
+		require.NoError(t, err)
+		assert.False(t, ok, "the live entry counts against the limit")
+
+		ok, _, err = base.SlidingWindow(ctx, limits)
+		require.NoError(t, err)
+		assert.True(t, ok, "the live window still has room")
+	})
+
+	t.Run("TokenBucketStartsFromLiveState", func(t *testing.T) {
+		limits := []kv.RateLimit{{Key: prefix + "bucket", Cost: 1, Limit: 2, Window: time.Minute}}
+		ok, _, err := base.TokenBucket(ctx, limits)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		store := kv.NewScratchStore(base)
+		ok, _, err = store.TokenBucket(ctx, limits)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, _, err = store.TokenBucket(ctx, limits)
+		require.NoError(t, err)
+		assert.False(t, ok, "the live token counts against the bucket")
+
+		ok, _, err = base.TokenBucket(ctx, limits)
+		require.NoError(t, err)
+		assert.True(t, ok, "the live bucket still has a token")
+	})
+
+	t.Run("SemaphoresStartFromLiveHolders", func(t *testing.T) {
+		sems := []kv.Semaphore{{Key: prefix + "inflight", Limit: 1, Lease: time.Minute}}
+		ok, _, err := base.AcquireSemaphores(ctx, "live", sems)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		ok, _, err = kv.NewScratchStore(base).AcquireSemaphores(ctx, "simulated", sems)
+		require.NoError(t, err)
+		assert.False(t, ok, "the live holder still has the only slot")
+	})
+}
@@ -0,0 +1,136 @@
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScratchStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reads through and keeps writes", func(t *testing.T) {
+		base := NewMemoryStore()
+		require.NoError(t, base.Set(ctx, "name", "live", 0))
+		store := NewScratchStore(base)
+
+		value, err := store.Get(ctx, "name")
+		require.NoError(t, err)
+		assert.Equal(t, "live", value)
+
+		require.NoError(t, store.Set(ctx, "name", "scratch", 0))
+		require.NoError(t, store.Del(ctx, "other"))
+		value, _ = store.Get(ctx, "name")
+		assert.Equal(t, "scratch", value)
+
+		value, _ = base.Get(ctx, "name")
+		assert.Equal(t, "live", value, "the base store is never written")
+	})
+
+	t.Run("counters start from live values", func(t *testing.T) {
+		base := NewMemoryStore()
+		_, err := base.IncrBy(ctx, "requests", 4)
+		require.NoError(t, err)
+		store := NewScratchStore(base)
+
+		n, err := store.Incr(ctx, "requests")
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), n)
+
+		ok, totals, err := store.IncrAllWithinLimits(ctx, []LimitedCounter{
+			{Key: "requests", Amount: 1, Limit: 5, TTL: time.Minute},
+		})
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, []int64{6}, totals)
+
+		live, _ := base.Get(ctx, "requests")
+		assert.Equal(t, "4", live)
+	})
+
+	t.Run("scans merge both stores", func(t *testing.T) {
+		base := NewMemoryStore()
+		require.NoError(t, base.Set(ctx, "k:1", "a", 0))
+		require.NoError(t, base.Set(ctx, "k:2", "b", 0))
+		store := NewScratchStore(base)
+		require.NoError(t, store.Set(ctx, "k:2", "changed", 0))
+		require.NoError(t, store.Set(ctx, "k:3", "c", 0))
+		require.NoError(t, store.Del(ctx, "k:1"))
+
+		values, err := store.ScanGetAll(ctx, "k:*", 100)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"k:2": "changed", "k:3": "c"}, values)
+
+		keys, err := store.ScanAll(ctx, "k:*", 100)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"k:2", "k:3"}, keys)
+	})
+
+	t.Run("semaphores start from live holders", func(t *testing.T) {
+		base := NewMemoryStore()
+		sems := []Semaphore{{Key: "inflight", Limit: 1, Lease: time.Minute}}
+		ok, _, err := base.AcquireSemaphores(ctx, "live", sems)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		store := NewScratchStore(base)
+		ok, counts, err := store.AcquireSemaphores(ctx, "simulated", sems)
+		require.NoError(t, err)
+		assert.False(t, ok, "the live holder still has the only slot")
+		assert.Equal(t, []int64{2}, counts)
+
+		require.NoError(t, store.ReleaseSemaphores(ctx, "live", sems))
+		ok, _, err = store.AcquireSemaphores(ctx, "simulated", sems)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, _, err = base.AcquireSemaphores(ctx, "other", sems)
+		require.NoError(t, err)
+		assert.False(t, ok, "the live holder is never released")
+	})
+
+	t.Run("sliding windows start from live entries", func(t *testing.T) {
+		base := NewMemoryStore()
+		limits := []RateLimit{{Key: "window", Cost: 1, Limit: 2, Window: time.Minute}}
+		ok, _, err := base.SlidingWindow(ctx, limits)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		store := NewScratchStore(base)
+		ok, states, err := store.SlidingWindow(ctx, limits)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, int64(0), states[0].Remaining)
+		ok, _, err = store.SlidingWindow(ctx, limits)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		ok, _, err = base.SlidingWindow(ctx, limits)
+		require.NoError(t, err)
+		assert.True(t, ok, "the live window still has room")
+	})
+
+	t.Run("token buckets start from live state", func(t *testing.T) {
+		base := NewMemoryStore()
+		limits := []RateLimit{{Key: "bucket", Cost: 1, Limit: 2, Window: time.Minute}}
+		ok, _, err := base.TokenBucket(ctx, limits)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		store := NewScratchStore(base)
+		ok, states, err := store.TokenBucket(ctx, limits)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, int64(0), states[0].Remaining)
+		ok, _, err = store.TokenBucket(ctx, limits)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		ok, _, err = base.TokenBucket(ctx, limits)
+		require.NoError(t, err)
+		assert.True(t, ok, "the live bucket still has a token")
+	})
+}
@@ -0,0 +1,184 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/async"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// submitAsync queues a "Prefer: respond-async" request and answers 202 with
+// the job ID. The caller is authenticated here because the job later runs
+// without client credentials.
+func (c *Core) submitAsync(hctx huma.Context, r *http.Request) {
+	inURL := hctx.URL()
+	base, endpoint := c.asyncTarget(inURL.Path)
+	if !async.SupportsEndpoint(endpoint) {
+		fail(hctx, http.StatusBadRequest, `{"title":"Bad Request","status":400,"detail":"async mode is only supported for chat completions, completions and embeddings"}`)
+		return
+	}
+
+	keyID, keyData, err := c.Authenticator.Authenticate(r)
+	if err != nil || keyData == nil {
+		fail(hctx, http.StatusUnauthorized, `{"title":"Unauthorized","status":401,"detail":"unauthorized"}`)
+		return
+	}
+
+	var raw []byte
+	if br := hctx.BodyReader(); br != nil {
+		raw, _ = io.ReadAll(io.LimitReader(br, int64(c.MaxBody)+1))
+	}
+	if len(raw) > c.MaxBody {
+		fail(hctx, http.StatusRequestEntityTooLarge, `{"title":"Request Entity Too Large","status":413,"detail":"request body too large"}`)
+		return
+	}
+	var opts struct {
+		Stream bool `json:"stream"`
+	}
+	if json.Unmarshal(raw, &opts) == nil && opts.Stream {
+		fail(hctx, http.StatusBadRequest, `{"title":"Bad Request","status":400,"detail":"streaming is not supported in async mode"}`)
+		return
+	}
+
+	callback := r.Header.Get(async.CallbackHeader)
+	if callback != "" {
+		if err := async.ValidateCallback(hctx.Context(), callback); err != nil {
+			fail(hctx, http.StatusBadRequest, fmt.Sprintf(`{"title":"Bad Request","status":400,"detail":%q}`, err.Error()))
+			return
+		}
+	}
+
+	job := &async.Job{
+		Method:      hctx.Method(),
+		Path:        inURL.Path,
+		Query:       inURL.RawQuery,
+		Header:      async.CaptureHeader(r.Header),
+		Body:        raw,
+		KeyID:       keyID,
+		OrgID:       keyData.OrgID,
+		AppID:       keyData.AppID,
+		UserID:      keyData.UserID,
+		CallbackURL: callback,
+	}
+	if err := c.Async.Enqueue(hctx.Context(), job); err != nil {
+		logger.GetLogger(hctx.Context()).Error().Err(err).Msg("async: failed to enqueue job")
+		fail(hctx, http.StatusServiceUnavailable, `{"title":"Service Unavailable","status":503,"detail":"failed to queue request"}`)
+		return
+	}
+
+	out, _ := json.Marshal(job.View())
+	hctx.SetHeader("Content-Type", "application/json")
+	hctx.SetHeader("Location", base+"/jobs/"+job.ID)
+	hctx.SetStatus(http.StatusAccepted)
+	_, _ = hctx.BodyWriter().Write(out)
+}
+
+// asyncTarget resolves the "/v1/..." endpoint of path and the gateway base
+// path preceding the provider prefix, under which the job status route lives.
+func (c *Core) asyncTarget(path string) (base, endpoint string) {
+	ad, prefix, prefixPos := c.matchAdapter(path)
+	if ad == nil {
+		return "", ""
+	}
+	tail := path
+	if prefixPos >= 0 {
+		base, tail = path[:prefixPos], path[prefixPos+len(prefix):]
+	}
+	if j := strings.Index(tail, "/v1/"); j >= 0 {
+		endpoint = tail[j:]
+	}
+	return base, endpoint
+}
+
+// Execute replays a queued job through the gateway transport as the identity
+// it was accepted under. It implements async.Executor.
+func (c *Core) Execute(ctx context.Context, job *async.Job) (*async.Result, error) {
+	ctx = auth.WithPreAuthenticated(ctx, auth.KeyData{
+		KeyID:  job.KeyID,
+		OrgID:  job.OrgID,
+		AppID:  job.AppID,
+		UserID: job.UserID,
+	})
+	ctx = context.WithValue(context.WithValue(ctx, ctxTenantKey{}, job.KeyID), ctxAppKey{}, job.AppID)
+
+	req, err := http.NewRequestWithContext(ctx, job.Method, "http://placeholder", nil)
+	if err != nil {
+		return nil, async.Permanent(err)
+	}
+	for k, vs := range job.Header {
+		req.Header[k] = append([]string(nil), vs...)
+	}
+
+	c.direct(ctx, req, job.Method, url.URL{Path: job.Path, RawQuery: job.Query}, bytes.NewReader(job.Body))
+	if cause := req.Header.Get("X-RP-Error"); cause != "" {
+		return nil, async.Permanent(errors.New(cause))
+	}
+
+	resp, err := c.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	result := async.NewResult(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	result.RetryAfter = async.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return result, nil
+}
+
+type JobStatusInput struct {
+	JobID         string `path:"job_id" doc:"Async job ID returned when the request was accepted"`
+	Authorization string `header:"Authorization"`
+	APIKey        string `header:"X-API-Key"`
+}
+
+type JobStatusOutput struct {
+	Body async.View
+}
+
+// JobStatusHandler reports the state and, once finished, the result of an
+// async job. Jobs are only visible to the application that submitted them.
+func (c *Core) JobStatusHandler() func(ctx context.Context, in *JobStatusInput) (*JobStatusOutput, error) {
+	return func(ctx context.Context, in *JobStatusInput) (*JobStatusOutput, error) {
+		r := &http.Request{Header: http.Header{}}
+		if in.Authorization != "" {
+			r.Header.Set("Authorization", in.Authorization)
+		}
+		if in.APIKey != "" {
+			r.Header.Set("X-API-Key", in.APIKey)
+		}
+		_, keyData, err := c.Authenticator.Authenticate(r)
+		if err != nil || keyData == nil {
+			return nil, huma.Error401Unauthorized("unauthorized")
+		}
+		if c.Async == nil {
+			return nil, huma.Error404NotFound("job not found")
+		}
+
+		job, err := c.Async.Get(ctx, in.JobID)
+		if err != nil {
+			if errors.Is(err, async.ErrNotFound) {
+				return nil, huma.Error404NotFound("job not found")
+			}
+			return nil, huma.Error500InternalServerError("failed to load job", err)
+		}
+		if job.AppID != keyData.AppID {
+			return nil, huma.Error404NotFound("job not found")
+		}
+		return &JobStatusOutput{Body: job.View()}, nil
+	}
+}
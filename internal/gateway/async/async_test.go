@@ -0,0 +1,294 @@
+package async
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubExecutor struct {
+	results []*Result
+	errs    []error
+	calls   int
+}
+
+func (s *stubExecutor) Execute(context.Context, *Job) (*Result, error) {
+	i := s.calls
+	s.calls++
+	var err error
+	if i < len(s.errs) {
+		err = s.errs[i]
+	}
+	if i < len(s.results) {
+		return s.results[i], err
+	}
+	return nil, err
+}
+
+func newTestQueue(now *time.Time) *Queue {
+	q := NewQueue(kv.NewMemoryStore(), time.Hour, time.Minute)
+	q.now = func() time.Time { return *now }
+	return q
+}
+
+func TestRequested(t *testing.T) {
+	assert.True(t, Requested(http.Header{"Prefer": {"respond-async"}}))
+	assert.True(t, Requested(http.Header{"Prefer": {"return=minimal, Respond-Async"}}))
+	assert.False(t, Requested(http.Header{"Prefer": {"return=minimal"}}))
+	assert.False(t, Requested(http.Header{}))
+}
+
+func TestCaptureHeaderDropsCredentials(t *testing.T) {
+	h := CaptureHeader(http.Header{
+		"Content-Type":  {"application/json"},
+		"Authorization": {"Bearer secret"},
+		"X-Api-Key":     {"secret"},
+	})
+	assert.Equal(t, http.Header{"Content-Type": {"application/json"}}, h)
+}
+
+func TestQueue_ClaimIsExclusive(t *testing.T) {
+	now := time.Now()
+	q := newTestQueue(&now)
+	ctx := context.Background()
+
+	job := &Job{Method: http.MethodPost, Path: "/v1/chat/completions", AppID: "app"}
+	require.NoError(t, q.Enqueue(ctx, job))
+	require.NotEmpty(t, job.ID)
+
+	claimed, err := q.Claim(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, StatusRunning, claimed[0].Status)
+	assert.Equal(t, 1, claimed[0].Attempts)
+
+	// Leased: a second worker gets nothing
+	again, err := q.Claim(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+}
+
+// scanHookStore runs afterScan once, between a worker scanning the queue
+// markers and leasing the jobs it found
+type scanHookStore struct {
+	kv.KvStore
+	afterScan func()
+}
+
+func (s *scanHookStore) ScanGetAll(ctx context.Context, pattern string, count int64) (map[string]string, error) {
+	out, err := s.KvStore.ScanGetAll(ctx, pattern, count)
+	if hook := s.afterScan; hook != nil {
+		s.afterScan = nil
+		hook()
+	}
+	return out, err
+}
+
+func TestQueue_ClaimSkipsJobCompletedAfterScan(t *testing.T) {
+	ctx := context.Background()
+	store := &scanHookStore{KvStore: kv.NewMemoryStore()}
+	q := NewQueue(store, time.Hour, time.Minute)
+
+	require.NoError(t, q.Enqueue(ctx, &Job{}))
+	claimed, err := q.Claim(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	job := claimed[0]
+
+	// The first worker finishes the job after the second has seen its marker
+	store.afterScan = func() {
+		require.NoError(t, q.Complete(ctx, job, StatusSucceeded, &Result{StatusCode: 200}, ""))
+	}
+	again, err := q.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	stored, err := q.Get(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	leased, err := store.Exists(ctx, kv.KSAsyncLease.Key(job.ID))
+	require.NoError(t, err)
+	assert.False(t, leased, "the skipped claim releases its lease")
+}
+
+func TestQueue_KeepLease(t *testing.T) {
+	ctx := context.Background()
+	q := NewQueue(kv.NewMemoryStore(), time.Hour, 60*time.Millisecond)
+
+	require.NoError(t, q.Enqueue(ctx, &Job{}))
+	claimed, err := q.Claim(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	// Renewed while the job runs past its lease TTL
+	stop := q.KeepLease(ctx, claimed[0])
+	time.Sleep(200 * time.Millisecond)
+	again, err := q.Claim(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	// A worker that stops renewing loses the job
+	stop()
+	time.Sleep(100 * time.Millisecond)
+	again, err = q.Claim(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, 2, again[0].Attempts)
+
+	// and can't take the new claim's lease back
+	assert.ErrorIs(t, q.renew(ctx, claimed[0]), errLeaseLost)
+	require.NoError(t, q.renew(ctx, again[0]))
+}
+
+func TestQueue_RetryWaitsUntilDue(t *testing.T) {
+	now := time.Now()
+	q := newTestQueue(&now)
+	ctx := context.Background()
+
+	require.NoError(t, q.Enqueue(ctx, &Job{}))
+	claimed, _ := q.Claim(ctx, 1)
+	require.Len(t, claimed, 1)
+	require.NoError(t, q.Retry(ctx, claimed[0], now.Add(time.Minute), "upstream returned 429"))
+
+	early, _ := q.Claim(ctx, 1)
+	assert.Empty(t, early)
+
+	now = now.Add(2 * time.Minute)
+	due, _ := q.Claim(ctx, 1)
+	require.Len(t, due, 1)
+	assert.Equal(t, 2, due[0].Attempts)
+}
+
+func TestPool_RetriesThenSucceeds(t *testing.T) {
+	now := time.Now()
+	q := newTestQueue(&now)
+	ctx := context.Background()
+
+	exec := &stubExecutor{
+		results: []*Result{{StatusCode: 429, RetryAfter: 30 * time.Second}, nil, NewResult(200, "application/json", []byte(`{"ok":true}`))},
+		errs:    []error{nil, errors.New("connection reset"), nil},
+	}
+	p := NewPool(q, exec, Options{MaxAttempts: 5, BaseBackoff: time.Second})
+	p.now = q.now
+
+	job := &Job{}
+	require.NoError(t, q.Enqueue(ctx, job))
+	for i := 0; i < 3; i++ {
+		claimed, err := q.Claim(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1, "attempt %d", i+1)
+		p.Process(ctx, claimed[0])
+		now = now.Add(time.Minute)
+	}
+
+	stored, err := q.Get(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, stored.Status)
+	assert.Equal(t, 3, stored.Attempts)
+	assert.JSONEq(t, `{"ok":true}`, string(stored.Result.Body))
+
+	// Completed jobs leave the queue
+	rest, _ := q.Claim(ctx, 1)
+	assert.Empty(t, rest)
+}
+
+func TestPool_PermanentFailure(t *testing.T) {
+	now := time.Now()
+	q := newTestQueue(&now)
+	ctx := context.Background()
+
+	exec := &stubExecutor{errs: []error{Permanent(errors.New("no_adapter_for_path"))}}
+	p := NewPool(q, exec, Options{})
+
+	job := &Job{}
+	require.NoError(t, q.Enqueue(ctx, job))
+	claimed, _ := q.Claim(ctx, 1)
+	p.Process(ctx, claimed[0])
+
+	stored, _ := q.Get(ctx, job.ID)
+	assert.Equal(t, StatusFailed, stored.Status)
+	assert.Equal(t, "no_adapter_for_path", stored.View().Error)
+	assert.Equal(t, 1, exec.calls)
+}
+
+func TestPool_DeliversSignedWebhook(t *testing.T) {
+	now := time.Now()
+	q := newTestQueue(&now)
+	ctx := context.Background()
+
+	var gotSig string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSig = r.Header.Get(SignatureHeader)
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	exec := &stubExecutor{results: []*Result{NewResult(200, "application/json", []byte(`{"id":"chatcmpl-1"}`))}}
+	p := NewPool(q, exec, Options{WebhookSecret: "s3cret", WebhookClient: srv.Client()})
+	p.now = q.now
+
+	job := &Job{CallbackURL: srv.URL}
+	require.NoError(t, q.Enqueue(ctx, job))
+	claimed, _ := q.Claim(ctx, 1)
+	p.Process(ctx, claimed[0])
+
+	require.NotEmpty(t, gotBody)
+	assert.Equal(t, Sign("s3cret", now, gotBody), gotSig)
+
+	var view View
+	require.NoError(t, json.Unmarshal(gotBody, &view))
+	assert.Equal(t, job.ID, view.ID)
+	assert.Equal(t, StatusSucceeded, view.Status)
+}
+
+func TestValidateCallback(t *testing.T) {
+	ctx := context.Background()
+
+	assert.NoError(t, ValidateCallback(ctx, "https://8.8.8.8/hooks/ai"))
+	for _, raw := range []string{
+		"http://8.8.8.8/hooks/ai",
+		"https://user:pw@8.8.8.8/",
+		"/relative",
+		"https://127.0.0.1/",
+		"https://[::1]/",
+		"https://10.0.0.5/",
+		"https://192.168.1.1/",
+		"https://169.254.169.254/latest/meta-data/",
+		"https://100.64.0.1/",
+		"https://0.0.0.0/",
+		"https://[::ffff:127.0.0.1]/",
+	} {
+		assert.Error(t, ValidateCallback(ctx, raw), raw)
+	}
+}
+
+func TestPool_DefaultClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("webhook reached a loopback server")
+	}))
+	defer srv.Close()
+
+	p := NewPool(nil, nil, Options{})
+	_, err := p.opts.WebhookClient.Post(srv.URL, "application/json", nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errBlockedAddress)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 10*time.Second, ParseRetryAfter("10", now))
+	assert.Equal(t, time.Minute, ParseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now))
+	assert.Zero(t, ParseRetryAfter("", now))
+	assert.Zero(t, ParseRetryAfter("soon", now))
+}
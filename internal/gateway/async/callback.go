@@ -0,0 +1,79 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var errBlockedAddress = errors.New("callback address is not publicly routable")
+
+// ValidateCallback checks that raw is an absolute https URL whose host
+// resolves only to public addresses, so a caller cannot point webhook
+// delivery at the gateway's own network or a cloud metadata endpoint.
+func ValidateCallback(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+		return fmt.Errorf("%s must be an absolute https URL", CallbackHeader)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("%s host could not be resolved", CallbackHeader)
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return fmt.Errorf("%s must not resolve to a private address", CallbackHeader)
+		}
+	}
+	return nil
+}
+
+// publicAddr reports whether addr is routable on the public internet.
+// Loopback, private, link-local (including 169.254.169.254 metadata),
+// CGNAT and unspecified addresses are rejected.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	switch {
+	case !addr.IsValid(), addr.IsUnspecified(), addr.IsLoopback(), addr.IsPrivate(),
+		addr.IsLinkLocalUnicast(), addr.IsLinkLocalMulticast(), addr.IsInterfaceLocalMulticast(),
+		addr.IsMulticast():
+		return false
+	}
+	return !sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace is the RFC 6598 carrier-grade NAT range, which
+// netip does not classify as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// newWebhookClient returns the default delivery client. Its dialer re-checks
+// every address it connects to, so a callback host that re-resolves to a
+// private address after submission is still refused.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil || !publicAddr(ap.Addr()) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
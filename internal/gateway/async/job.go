@@ -0,0 +1,175 @@
+// Package async implements the gateway's asynchronous request mode: requests
+// sent with "Prefer: respond-async" are queued in the KV store, executed by a
+// background worker pool with retries, and their results delivered through a
+// signed webhook or the job status endpoint.
+package async
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PreferHeaderValue is the RFC 7240 preference that opts a request into async mode.
+const PreferHeaderValue = "respond-async"
+
+// CallbackHeader optionally carries the webhook URL the result is POSTed to.
+const CallbackHeader = "X-Callback-URL"
+
+// SignatureHeader carries the webhook HMAC signature ("t=<unix>,v1=<hex>").
+const SignatureHeader = "X-Gateway-Signature"
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Job is a queued request plus the identity it was authenticated as.
+type Job struct {
+	ID          string      `json:"id"`
+	Status      Status      `json:"status"`
+	Method      string      `json:"method"`
+	Path        string      `json:"path"`
+	Query       string      `json:"query,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+	KeyID       string      `json:"key_id"`
+	OrgID       string      `json:"org_id"`
+	AppID       string      `json:"app_id"`
+	UserID      string      `json:"user_id,omitempty"`
+	CallbackURL string      `json:"callback_url,omitempty"`
+	Attempts    int         `json:"attempts"`
+	LastError   string      `json:"last_error,omitempty"`
+	Result      *Result     `json:"result,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+
+	lease string // the token of the claim this worker holds, set by Claim
+}
+
+// claimable reports whether the job may still run: it is waiting, or its last
+// worker stopped renewing the lease before finishing it
+func (j *Job) claimable() bool {
+	return j.Status == StatusQueued || j.Status == StatusRunning
+}
+
+// Result is the upstream response captured for a finished job.
+type Result struct {
+	StatusCode  int             `json:"status_code"`
+	ContentType string          `json:"content_type,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+
+	// RetryAfter is the upstream's requested back-off for 429/5xx responses.
+	RetryAfter time.Duration `json:"-"`
+}
+
+// NewResult captures an upstream response body, storing non-JSON bodies as a JSON string.
+func NewResult(statusCode int, contentType string, body []byte) *Result {
+	raw := json.RawMessage(body)
+	if len(body) > 0 && !json.Valid(body) {
+		raw, _ = json.Marshal(string(body))
+	}
+	return &Result{StatusCode: statusCode, ContentType: contentType, Body: raw}
+}
+
+// ParseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func ParseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// View is the client-facing representation of a job.
+type View struct {
+	ID          string     `json:"id"`
+	Object      string     `json:"object"`
+	Status      Status     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+	Result      *Result    `json:"result,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// View returns the client-facing representation of the job.
+func (j *Job) View() View {
+	v := View{
+		ID:          j.ID,
+		Object:      "gateway.job",
+		Status:      j.Status,
+		Attempts:    j.Attempts,
+		Result:      j.Result,
+		CreatedAt:   j.CreatedAt,
+		CompletedAt: j.CompletedAt,
+	}
+	if j.Status == StatusFailed {
+		v.Error = j.LastError
+	}
+	return v
+}
+
+// Requested reports whether the caller asked for async processing.
+func Requested(h http.Header) bool {
+	for _, v := range h.Values("Prefer") {
+		for _, pref := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(pref), PreferHeaderValue) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SupportsEndpoint reports whether a "/v1/..." endpoint can run asynchronously.
+func SupportsEndpoint(endpoint string) bool {
+	switch endpoint {
+	case "/v1/chat/completions", "/v1/completions", "/v1/embeddings":
+		return true
+	}
+	return false
+}
+
+// forwardedHeaders are the caller headers replayed when the job executes.
+// Credentials are deliberately excluded; the job runs as the stored identity.
+var forwardedHeaders = []string{"Content-Type", "Accept", "User-Agent", "OpenAI-Beta"}
+
+// CaptureHeader copies the replayable subset of the caller's headers.
+func CaptureHeader(h http.Header) http.Header {
+	out := http.Header{}
+	for _, k := range forwardedHeaders {
+		if vs := h.Values(k); len(vs) > 0 {
+			out[http.CanonicalHeaderKey(k)] = append([]string(nil), vs...)
+		}
+	}
+	return out
+}
+
+// permanentError marks a failure that retrying will not fix.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the worker fails the job without retrying.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func isPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
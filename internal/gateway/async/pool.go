@@ -0,0 +1,212 @@
+package async
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+)
+
+// Executor runs a job's request through the gateway and returns the upstream
+// response. Errors wrapped with Permanent are not retried.
+type Executor interface {
+	Execute(ctx context.Context, job *Job) (*Result, error)
+}
+
+// Options tunes the worker pool; zero values fall back to defaults.
+type Options struct {
+	Workers       int
+	MaxAttempts   int
+	BaseBackoff   time.Duration
+	MaxBackoff    time.Duration
+	PollInterval  time.Duration
+	WebhookSecret string
+	WebhookClient *http.Client
+}
+
+// Pool executes queued jobs with retries and delivers results by webhook.
+type Pool struct {
+	queue *Queue
+	exec  Executor
+	opts  Options
+	now   func() time.Time
+}
+
+func NewPool(queue *Queue, exec Executor, opts Options) *Pool {
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = 2 * time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 5 * time.Minute
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.WebhookClient == nil {
+		opts.WebhookClient = newWebhookClient()
+	}
+	return &Pool{queue: queue, exec: exec, opts: opts, now: time.Now}
+}
+
+// Run starts the workers and blocks until ctx is cancelled.
+func (p *Pool) Run(ctx context.Context) {
+	jobs := make(chan *Job)
+	var wg sync.WaitGroup
+	for i := 0; i < p.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				p.Process(ctx, job)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(p.opts.PollInterval)
+	defer func() {
+		ticker.Stop()
+		close(jobs)
+		wg.Wait()
+	}()
+
+	for {
+		claimed, err := p.queue.Claim(ctx, p.opts.Workers)
+		if err != nil {
+			logger.GetLogger(ctx).Error().Err(err).Msg("async: failed to claim jobs")
+		}
+		for _, job := range claimed {
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process executes a claimed job once and either completes or reschedules it.
+func (p *Pool) Process(ctx context.Context, job *Job) {
+	log := logger.GetLogger(ctx).With().Str("job_id", job.ID).Int("attempt", job.Attempts).Logger()
+	defer p.queue.KeepLease(ctx, job)()
+
+	result, err := p.exec.Execute(ctx, job)
+	retryable, wait, cause := p.classify(result, err)
+
+	if retryable && job.Attempts < p.opts.MaxAttempts {
+		if wait <= 0 {
+			wait = p.backoff(job.Attempts)
+		}
+		log.Warn().Str("cause", cause).Dur("retry_in", wait).Msg("async: job attempt failed, retrying")
+		if err := p.queue.Retry(ctx, job, p.now().Add(wait), cause); err != nil {
+			log.Error().Err(err).Msg("async: failed to reschedule job")
+		}
+		return
+	}
+
+	status := StatusSucceeded
+	if cause != "" {
+		status = StatusFailed
+	}
+	if err := p.queue.Complete(ctx, job, status, result, cause); err != nil {
+		log.Error().Err(err).Msg("async: failed to store job result")
+		return
+	}
+	if job.CallbackURL != "" {
+		if err := p.deliver(ctx, job); err != nil {
+			log.Warn().Err(err).Str("callback_url", job.CallbackURL).Msg("async: webhook delivery failed")
+		}
+	}
+}
+
+// classify decides whether an attempt should be retried and how long to wait.
+func (p *Pool) classify(result *Result, err error) (retryable bool, wait time.Duration, cause string) {
+	if err != nil {
+		return !isPermanent(err), 0, err.Error()
+	}
+	switch {
+	case result.StatusCode == http.StatusTooManyRequests:
+		return true, result.RetryAfter, "upstream returned 429"
+	case result.StatusCode >= 500:
+		return true, result.RetryAfter, fmt.Sprintf("upstream returned %d", result.StatusCode)
+	case result.StatusCode >= 400:
+		return false, 0, fmt.Sprintf("upstream returned %d", result.StatusCode)
+	}
+	return false, 0, ""
+}
+
+// backoff is exponential in the attempt number, capped at MaxBackoff.
+func (p *Pool) backoff(attempt int) time.Duration {
+	d := p.opts.BaseBackoff
+	for i := 1; i < attempt && d < p.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, p.opts.MaxBackoff)
+}
+
+// deliver POSTs the job view to its callback URL, signed with the webhook secret.
+func (p *Pool) deliver(ctx context.Context, job *Job) error {
+	payload, err := json.Marshal(job.View())
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= 3; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.CallbackURL, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if p.opts.WebhookSecret != "" {
+			req.Header.Set(SignatureHeader, Sign(p.opts.WebhookSecret, p.now(), payload))
+		}
+
+		resp, err := p.opts.WebhookClient.Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return nil
+			}
+			err = fmt.Errorf("callback returned %d", resp.StatusCode)
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.backoff(attempt)):
+		}
+	}
+	return lastErr
+}
+
+// Sign returns the signature header value for a webhook payload:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<payload>">".
+func Sign(secret string, at time.Time, payload []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,237 @@
+package async
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when a job does not exist or has expired.
+var ErrNotFound = errors.New("async job not found")
+
+// errLeaseLost is returned when a job's lease expired or was taken by another worker.
+var errLeaseLost = errors.New("async job lease lost")
+
+const (
+	// DefaultRetention is how long job records (and results) are kept.
+	DefaultRetention = 24 * time.Hour
+	// DefaultLeaseTTL bounds how long a crashed worker can hold a job.
+	DefaultLeaseTTL = 5 * time.Minute
+)
+
+// Queue stores jobs in the KV store so any gateway replica can execute them.
+//
+// Each job has a record under KSAsyncJob, a pending marker under KSAsyncQueue
+// (whose value is the earliest unix-ms it may run) and, while executing, a
+// lease under KSAsyncLease claimed with an atomic SetNX. The lease expires
+// after leaseTTL unless renewed (see KeepLease).
+type Queue struct {
+	store     kv.KvStore
+	retention time.Duration
+	leaseTTL  time.Duration
+	now       func() time.Time
+}
+
+func NewQueue(store kv.KvStore, retention, leaseTTL time.Duration) *Queue {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	if leaseTTL <= 0 {
+		leaseTTL = DefaultLeaseTTL
+	}
+	return &Queue{store: store, retention: retention, leaseTTL: leaseTTL, now: time.Now}
+}
+
+// Enqueue assigns the job an ID and makes it available to workers.
+func (q *Queue) Enqueue(ctx context.Context, job *Job) error {
+	job.ID = "job_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	job.Status = StatusQueued
+	job.CreatedAt = q.now().UTC()
+	if err := q.save(ctx, job); err != nil {
+		return err
+	}
+	return q.schedule(ctx, job.ID, job.CreatedAt)
+}
+
+// Get returns the job with the given ID.
+func (q *Queue) Get(ctx context.Context, id string) (*Job, error) {
+	raw, err := q.store.Get(ctx, kv.KSAsyncJob.Key(id))
+	if err != nil || raw == "" {
+		// Stores differ on how a miss is reported; treat both as not found
+		return nil, ErrNotFound
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		return nil, fmt.Errorf("decode async job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+// Claim leases up to limit due jobs, oldest first, and marks them running.
+func (q *Queue) Claim(ctx context.Context, limit int) ([]*Job, error) {
+	pending, err := q.store.ScanGetAll(ctx, kv.KSAsyncQueue.PatternAll(), 100)
+	if err != nil {
+		return nil, err
+	}
+
+	type due struct {
+		id string
+		at int64
+	}
+	now := q.now().UnixMilli()
+	var ready []due
+	for key, val := range pending {
+		at, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || at > now {
+			continue
+		}
+		ready = append(ready, due{id: strings.TrimPrefix(key, kv.KSAsyncQueue.Prefix), at: at})
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i].at < ready[j].at })
+
+	var claimed []*Job
+	for _, d := range ready {
+		if len(claimed) >= limit {
+			break
+		}
+		token, ok, err := q.lease(ctx, d.id)
+		if err != nil {
+			return claimed, err
+		}
+		if !ok {
+			continue
+		}
+		job, err := q.Get(ctx, d.id)
+		if err != nil {
+			// Record expired underneath the marker; drop it
+			_ = q.store.Del(ctx, kv.KSAsyncQueue.Key(d.id))
+			_ = q.store.Del(ctx, kv.KSAsyncLease.Key(d.id))
+			continue
+		}
+		// The marker was scanned before the lease was taken, so another worker
+		// may have completed the job in between and released its lease
+		queued, err := q.store.Exists(ctx, kv.KSAsyncQueue.Key(d.id))
+		if err != nil {
+			_ = q.store.Del(ctx, kv.KSAsyncLease.Key(d.id))
+			return claimed, err
+		}
+		if !queued || !job.claimable() {
+			_ = q.store.Del(ctx, kv.KSAsyncLease.Key(d.id))
+			continue
+		}
+		job.lease = token
+		job.Status = StatusRunning
+		job.Attempts++
+		if err := q.save(ctx, job); err != nil {
+			return claimed, err
+		}
+		claimed = append(claimed, job)
+	}
+	return claimed, nil
+}
+
+// Retry releases the job so it runs again no earlier than at.
+func (q *Queue) Retry(ctx context.Context, job *Job, at time.Time, cause string) error {
+	job.Status = StatusQueued
+	job.LastError = cause
+	if err := q.save(ctx, job); err != nil {
+		return err
+	}
+	if err := q.schedule(ctx, job.ID, at); err != nil {
+		return err
+	}
+	return q.store.Del(ctx, kv.KSAsyncLease.Key(job.ID))
+}
+
+// Complete stores the final outcome and removes the job from the queue.
+func (q *Queue) Complete(ctx context.Context, job *Job, status Status, result *Result, cause string) error {
+	now := q.now().UTC()
+	job.Status = status
+	job.Result = result
+	job.LastError = cause
+	job.CompletedAt = &now
+	if err := q.save(ctx, job); err != nil {
+		return err
+	}
+	if err := q.store.Del(ctx, kv.KSAsyncQueue.Key(job.ID)); err != nil {
+		return err
+	}
+	return q.store.Del(ctx, kv.KSAsyncLease.Key(job.ID))
+}
+
+func (q *Queue) save(ctx context.Context, job *Job) error {
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.store.Set(ctx, kv.KSAsyncJob.Key(job.ID), string(raw), q.retention)
+}
+
+func (q *Queue) schedule(ctx context.Context, id string, at time.Time) error {
+	return q.store.Set(ctx, kv.KSAsyncQueue.Key(id), strconv.FormatInt(at.UnixMilli(), 10), q.retention)
+}
+
+// lease atomically claims a job with a TTL; only the first SetNX after
+// expiry wins. The token identifies this claim of the job.
+func (q *Queue) lease(ctx context.Context, id string) (string, bool, error) {
+	token := uuid.NewString()
+	ok, err := q.store.SetNX(ctx, kv.KSAsyncLease.Key(id), token, q.leaseTTL)
+	if err != nil {
+		return "", false, err
+	}
+	return token, ok, nil
+}
+
+// KeepLease renews a claimed job's lease every third of the lease TTL until
+// the returned stop is called, so a job that runs longer than the TTL isn't
+// claimed by another worker. Renewal ends early if the lease is lost.
+func (q *Queue) KeepLease(ctx context.Context, job *Job) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(q.leaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := q.renew(ctx, job)
+				if err == nil {
+					continue
+				}
+				logger.GetLogger(ctx).Warn().Err(err).Str("job_id", job.ID).Msg("async: failed to renew job lease")
+				if errors.Is(err, errLeaseLost) {
+					return
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// renew extends job's lease by another TTL if it still holds it
+func (q *Queue) renew(ctx context.Context, job *Job) error {
+	key := kv.KSAsyncLease.Key(job.ID)
+	if holder, err := q.store.Get(ctx, key); err == nil && holder != "" && holder != job.lease {
+		return errLeaseLost
+	}
+	ok, err := q.store.Expire(ctx, key, q.leaseTTL)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errLeaseLost
+	}
+	return nil
+}
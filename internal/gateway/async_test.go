@@ -0,0 +1,61 @@
+package gateway_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/async"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
+	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
+	"github.com/stretchr/testify/require"
+)
+
+type stubAdapter struct{}
+
+func (stubAdapter) Prefix() string { return provider.OpenAIPrefix }
+
+func (stubAdapter) Rewrite(req *http.Request, suffix string, _ provider.ReqInfo) error {
+	req.URL.Scheme, req.URL.Host, req.URL.Path = "https", "upstream.test", suffix
+	return nil
+}
+
+func TestCore_Execute(t *testing.T) {
+	var seen *http.Request
+	var body string
+	base := gateway.RTFunc(func(r *http.Request) (*http.Response, error) {
+		seen = r
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		h := http.Header{}
+		h.Set("Content-Type", "application/json")
+		h.Set("Retry-After", "7")
+		return &http.Response{StatusCode: 429, Header: h, Body: io.NopCloser(strings.NewReader(`{"error":"slow down"}`))}, nil
+	})
+	transport := gateway.Chain(base, gateway.WithAuth(&mockAuthenticator{shouldFail: true}))
+	core := gateway.NewCoreWithAdapters(transport, &mockAuthenticator{shouldFail: true}, stubAdapter{})
+
+	job := &async.Job{
+		Method: http.MethodPost,
+		Path:   "/api/providers/openai/v1/chat/completions",
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   []byte(`{"model":"gpt-4o"}`),
+		KeyID:  "key_prefix",
+		OrgID:  "org",
+		AppID:  "app",
+	}
+	result, err := core.Execute(context.Background(), job)
+	require.NoError(t, err)
+	require.Equal(t, 429, result.StatusCode)
+	require.JSONEq(t, `{"error":"slow down"}`, string(result.Body))
+	require.Equal(t, 7.0, result.RetryAfter.Seconds())
+
+	require.Equal(t, "upstream.test", seen.URL.Host)
+	require.Equal(t, "/v1/chat/completions", auth.GetEndpoint(seen.Context()))
+	require.Equal(t, "app", auth.GetAppID(seen.Context()))
+	require.Equal(t, "gpt-4o", auth.GetModelName(seen.Context()))
+	require.Equal(t, `{"model":"gpt-4o"}`, body)
+}
@@ -1,6 +1,9 @@
 package auth
 
-import "context"
+import (
+	"context"
+	"net/http"
+)
 
 // Context keys for storing auth information
 type contextKey string
@@ -13,14 +16,20 @@ const (
 	contextKeyProvider contextKey = "provider"
 	contextKeyModel    contextKey = "model_name"
 	contextKeyPolicies contextKey = "policies"
+	contextKeyEndpoint contextKey = "endpoint"
+	contextKeyPreAuth  contextKey = "pre_authenticated"
+	contextKeyKeyMeta  contextKey = "api_key_metadata"
+	contextKeyHeader   contextKey = "caller_header"
+	contextKeyRouter   contextKey = "router"
 )
 
 // KeyData contains authenticated key information
 type KeyData struct {
-	KeyID  string
-	OrgID  string
-	AppID  string
-	UserID string
+	KeyID    string
+	OrgID    string
+	AppID    string
+	UserID   string
+	Metadata []byte // the key's raw JSON metadata, e.g. {"tags": ["intern"]}
 }
 
 // WithKeyID adds API key ID to context
@@ -38,6 +47,21 @@ func GetKeyID(ctx context.Context) string {
 	return ""
 }
 
+// WithKeyMetadata adds the API key's raw JSON metadata to context
+func WithKeyMetadata(ctx context.Context, metadata []byte) context.Context {
+	return context.WithValue(ctx, contextKeyKeyMeta, metadata)
+}
+
+// GetKeyMetadata retrieves the API key's raw JSON metadata from context
+func GetKeyMetadata(ctx context.Context) []byte {
+	if val := ctx.Value(contextKeyKeyMeta); val != nil {
+		if raw, ok := val.([]byte); ok {
+			return raw
+		}
+	}
+	return nil
+}
+
 // WithOrgID adds organization ID to context
 func WithOrgID(ctx context.Context, orgID string) context.Context {
 	return context.WithValue(ctx, contextKeyOrgID, orgID)
@@ -113,6 +137,74 @@ func GetModelName(ctx context.Context) string {
 	return ""
 }
 
+// WithEndpoint adds the normalized "/v1/..." request path to context
+func WithEndpoint(ctx context.Context, endpoint string) context.Context {
+	return context.WithValue(ctx, contextKeyEndpoint, endpoint)
+}
+
+// GetEndpoint retrieves the normalized "/v1/..." request path from context
+func GetEndpoint(ctx context.Context) string {
+	if val := ctx.Value(contextKeyEndpoint); val != nil {
+		if str, ok := val.(string); ok {
+			return str
+		}
+	}
+	return ""
+}
+
+// WithCallerHeader stores a copy of the headers the caller sent, taken before
+// the adapter replaces credentials with the provider's
+func WithCallerHeader(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, contextKeyHeader, header.Clone())
+}
+
+// GetCallerHeader retrieves the headers the caller sent from context
+func GetCallerHeader(ctx context.Context) http.Header {
+	if val := ctx.Value(contextKeyHeader); val != nil {
+		if header, ok := val.(http.Header); ok {
+			return header
+		}
+	}
+	return nil
+}
+
+// Router points an upstream request at a deployment of model, as the
+// provider adapter first did for the requested model
+type Router func(r *http.Request, model string) error
+
+// WithRouter adds the request's provider routing to context
+func WithRouter(ctx context.Context, route Router) context.Context {
+	return context.WithValue(ctx, contextKeyRouter, route)
+}
+
+// GetRouter retrieves the request's provider routing from context
+func GetRouter(ctx context.Context) Router {
+	if val := ctx.Value(contextKeyRouter); val != nil {
+		if route, ok := val.(Router); ok {
+			return route
+		}
+	}
+	return nil
+}
+
+// WithPreAuthenticated stores an identity established earlier (e.g. when an
+// async job was accepted) so the auth middleware trusts it instead of
+// requiring client credentials on the request.
+func WithPreAuthenticated(ctx context.Context, data KeyData) context.Context {
+	ctx = WithKeyID(ctx, data.KeyID)
+	ctx = WithOrgID(ctx, data.OrgID)
+	ctx = WithAppID(ctx, data.AppID)
+	ctx = WithUserID(ctx, data.UserID)
+	ctx = WithKeyMetadata(ctx, data.Metadata)
+	return context.WithValue(ctx, contextKeyPreAuth, true)
+}
+
+// IsPreAuthenticated reports whether the context carries a trusted identity
+func IsPreAuthenticated(ctx context.Context) bool {
+	v, _ := ctx.Value(contextKeyPreAuth).(bool)
+	return v
+}
+
 // WithPolicies adds loaded policies to context
 func WithPolicies(ctx context.Context, policies interface{}) context.Context {
 	return context.WithValue(ctx, contextKeyPolicies, policies)
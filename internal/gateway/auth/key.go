@@ -55,10 +55,11 @@ func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (keyID string, keyDa
 
 	// Build KeyData from record
 	data := &KeyData{
-		KeyID:  keyID,
-		OrgID:  rec.OrgID.String(),
-		AppID:  rec.AppID.String(),
-		UserID: rec.UserID.String(),
+		KeyID:    keyID,
+		OrgID:    rec.OrgID.String(),
+		AppID:    rec.AppID.String(),
+		UserID:   rec.UserID.String(),
+		Metadata: rec.Metadata,
 	}
 
 	return keyID, data, nil
@@ -1,22 +1,63 @@
 package auth
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+)
 
 // ParsedRequest contains pre-parsed LLM request data to avoid multiple JSON unmarshals
 type ParsedRequest struct {
 	Model           string
 	EstimatedTokens int
 	RequestSize     int
+	Stream          bool // the caller asked for a streamed response
+
+	// Unreadable is set when the body is JSON whose fields don't have the
+	// expected types, so its messages and prompt are unknown
+	Unreadable bool
 
 	// Raw messages for advanced policies that need full content
 	Messages []Message
 	Prompt   string
+
+	// Tools offered to the model. ToolChoice is "none", "auto" or "required"
+	// (empty when unset); ForcedTool names the one tool the model must call.
+	Tools      []Tool
+	ToolChoice string
+	ForcedTool string
+
+	// Body is the buffered upstream request body. It is shared with the
+	// upstream reader, so treat it as read-only.
+	Body []byte
+
+	// BatchModels lists the models referenced by a Batch API input file upload
+	BatchModels []string
+
+	// StreamUsageInjected is set when the gateway added stream_options.include_usage
+	// upstream, so the usage-only chunk must be stripped from the client stream
+	StreamUsageInjected bool
 }
 
-// Message represents a chat message
+// Message represents a chat message. Content holds its text; the text parts
+// joined with newlines when the content is an array of parts.
 type Message struct {
 	Role    string
 	Content string
+	Images  []Image
+}
+
+// Image is an image part of a chat message
+type Image struct {
+	URL    string // remote URL, or the data URI of an inline image
+	Inline bool   // embedded as a base64 data URI
+	Size   int    // decoded bytes of an inline image; unknown (0) for remote ones
+	Detail string // low, high or auto
+}
+
+// Tool is a tool offered to the model, from tools or the legacy functions field
+type Tool struct {
+	Name       string
+	Parameters json.RawMessage // JSON Schema of the tool's arguments
 }
 
 // Context helpers for parsed request
@@ -0,0 +1,174 @@
+// Package batch tracks upstream Batch API jobs created through the gateway:
+// it keeps ownership and status in sync and records token usage once a job's
+// output file is available.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"slices"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/repository/batches"
+)
+
+// Object is the subset of the upstream batch resource the gateway tracks.
+type Object struct {
+	ID           string `json:"id"`
+	Endpoint     string `json:"endpoint"`
+	Status       string `json:"status"`
+	InputFileID  string `json:"input_file_id"`
+	OutputFileID string `json:"output_file_id"`
+	ErrorFileID  string `json:"error_file_id"`
+	CompletedAt  *int64 `json:"completed_at"`
+}
+
+// ParseObject decodes an upstream batch resource.
+func ParseObject(raw []byte) (*Object, error) {
+	var obj Object
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	return &obj, nil
+}
+
+// Sync applies the upstream state to the tracked job and grants the owning app
+// access to any output and error files the upstream has produced.
+func Sync(ctx context.Context, repo batches.Writer, job *model.BatchJob, obj *Object) error {
+	status := model.BatchStatus(obj.Status)
+	if status == job.Status && obj.OutputFileID == job.OutputFileID && obj.ErrorFileID == job.ErrorFileID {
+		return nil
+	}
+
+	var completedAt *time.Time
+	if obj.CompletedAt != nil && *obj.CompletedAt > 0 {
+		t := time.Unix(*obj.CompletedAt, 0)
+		completedAt = &t
+	} else if status.IsTerminal() {
+		t := time.Now()
+		completedAt = &t
+	}
+	if err := repo.UpdateJobStatus(ctx, job.ID, status, obj.OutputFileID, obj.ErrorFileID, completedAt); err != nil {
+		return err
+	}
+
+	for _, f := range []struct{ id, purpose string }{
+		{obj.OutputFileID, "batch_output"},
+		{obj.ErrorFileID, "batch_error"},
+	} {
+		if f.id == "" {
+			continue
+		}
+		if err := repo.CreateFile(ctx, model.BatchFile{
+			OrgID:     job.OrgID,
+			AppID:     job.AppID,
+			KeyPrefix: job.KeyPrefix,
+			Provider:  job.Provider,
+			FileID:    f.id,
+			Purpose:   f.purpose,
+		}); err != nil {
+			return err
+		}
+	}
+
+	job.Status = status
+	job.OutputFileID = obj.OutputFileID
+	job.ErrorFileID = obj.ErrorFileID
+	job.CompletedAt = completedAt
+	return nil
+}
+
+// outputLine is a single JSONL record from a batch output file.
+type outputLine struct {
+	Response *struct {
+		StatusCode int `json:"status_code"`
+		Body       struct {
+			Model string `json:"model"`
+			Usage *struct {
+				PromptTokens     int `json:"prompt_tokens"`
+				CompletionTokens int `json:"completion_tokens"`
+				TotalTokens      int `json:"total_tokens"`
+			} `json:"usage"`
+		} `json:"body"`
+	} `json:"response"`
+}
+
+// ParseOutputUsage sums token usage per model across a batch output file.
+// Lines without a usage block (errors, malformed records) are skipped.
+func ParseOutputUsage(r io.Reader) (map[string]*model.TokenUsage, error) {
+	totals := map[string]*model.TokenUsage{}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var rec outputLine
+		if err := json.Unmarshal(line, &rec); err != nil {
+			continue
+		}
+		if rec.Response == nil || rec.Response.Body.Usage == nil {
+			continue
+		}
+		u := rec.Response.Body.Usage
+		t, ok := totals[rec.Response.Body.Model]
+		if !ok {
+			t = &model.TokenUsage{}
+			totals[rec.Response.Body.Model] = t
+		}
+		t.PromptTokens += u.PromptTokens
+		t.CompletionTokens += u.CompletionTokens
+		total := u.TotalTokens
+		if total == 0 {
+			total = u.PromptTokens + u.CompletionTokens
+		}
+		t.TotalTokens += total
+	}
+	return totals, sc.Err()
+}
+
+// InputModels returns the distinct models referenced by a batch input file
+// carried in a multipart /v1/files upload. It returns nil for any other body.
+func InputModels(contentType string, body []byte) []string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return nil
+	}
+
+	var models []string
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		sc := bufio.NewScanner(part)
+		sc.Buffer(make([]byte, 0, 64*1024), 16<<20)
+		for sc.Scan() {
+			var rec struct {
+				Body struct {
+					Model string `json:"model"`
+				} `json:"body"`
+			}
+			if json.Unmarshal(sc.Bytes(), &rec) != nil || rec.Body.Model == "" {
+				continue
+			}
+			if !slices.Contains(models, rec.Body.Model) {
+				models = append(models, rec.Body.Model)
+			}
+		}
+	}
+	slices.Sort(models)
+	return models
+}
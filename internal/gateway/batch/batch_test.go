@@ -0,0 +1,51 @@
+package batch_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/batch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutputUsage(t *testing.T) {
+	output := strings.Join([]string{
+		`{"custom_id":"1","response":{"status_code":200,"body":{"model":"gpt-4o","usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}}}`,
+		`{"custom_id":"2","response":{"status_code":200,"body":{"model":"gpt-4o","usage":{"prompt_tokens":20,"completion_tokens":10}}}}`,
+		`{"custom_id":"3","response":{"status_code":200,"body":{"model":"gpt-4o-mini","usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}}}`,
+		`{"custom_id":"4","response":null,"error":{"code":"server_error"}}`,
+		`not json`,
+		``,
+	}, "\n")
+
+	totals, err := batch.ParseOutputUsage(strings.NewReader(output))
+	require.NoError(t, err)
+	require.Len(t, totals, 2)
+
+	assert.Equal(t, 30, totals["gpt-4o"].PromptTokens)
+	assert.Equal(t, 15, totals["gpt-4o"].CompletionTokens)
+	assert.Equal(t, 45, totals["gpt-4o"].TotalTokens)
+	assert.Equal(t, 2, totals["gpt-4o-mini"].TotalTokens)
+}
+
+func TestInputModels(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	require.NoError(t, mw.WriteField("purpose", "batch"))
+	fw, err := mw.CreateFormFile("file", "input.jsonl")
+	require.NoError(t, err)
+	_, _ = fw.Write([]byte(strings.Join([]string{
+		`{"custom_id":"1","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o","messages":[]}}`,
+		`{"custom_id":"2","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini","messages":[]}}`,
+		`{"custom_id":"3","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o","messages":[]}}`,
+	}, "\n")))
+	require.NoError(t, mw.Close())
+
+	models := batch.InputModels(mw.FormDataContentType(), buf.Bytes())
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o-mini"}, models)
+
+	assert.Nil(t, batch.InputModels("application/json", []byte(`{"model":"gpt-4o"}`)))
+}
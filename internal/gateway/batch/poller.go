@@ -0,0 +1,249 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway"
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
+	"github.com/WebDeveloperBen/ai-gateway/internal/repository/batches"
+	"github.com/WebDeveloperBen/ai-gateway/internal/repository/keys"
+	"github.com/google/uuid"
+)
+
+// maxPollFailures is how many polls in a row may fail before a job is left
+// for an operator, so jobs that can never succeed stop taking up each poll.
+const maxPollFailures = 10
+
+// maxPollBackoff caps the wait between polls of a failing job
+const maxPollBackoff = time.Hour
+
+// Pricer costs usage against the model price in force. It returns a nil
+// cost when the model has no price.
+type Pricer interface {
+	Cost(ctx context.Context, orgID uuid.UUID, provider, modelName, deploymentType string, usage model.BillableUsage) (*int64, string, error)
+}
+
+// Poller periodically refreshes tracked batches from the upstream provider and
+// records their token usage once the output file is available.
+type Poller struct {
+	repo      batches.Repository
+	keys      keys.Reader
+	pricing   Pricer
+	adapters  []provider.Adapter
+	transport http.RoundTripper
+	interval  time.Duration
+	batchSize int
+}
+
+// NewPoller creates a batch poller. Upstream calls go through rt directly
+// (not the gateway middleware chain) since they are not client traffic.
+// Usage is costed at batch prices; a nil pricing records it unpriced.
+func NewPoller(repo batches.Repository, keyReader keys.Reader, pricing Pricer, adapters []provider.Adapter, rt http.RoundTripper, interval time.Duration) *Poller {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Poller{
+		repo:      repo,
+		keys:      keyReader,
+		pricing:   pricing,
+		adapters:  adapters,
+		transport: rt,
+		interval:  interval,
+		batchSize: 100,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.PollOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PollOnce refreshes every due job whose usage has not yet been recorded.
+// A failed poll is retried with backoff, up to maxPollFailures in a row.
+func (p *Poller) PollOnce(ctx context.Context) {
+	jobs, err := p.repo.ListPendingJobs(ctx, p.batchSize, maxPollFailures)
+	if err != nil {
+		logger.GetLogger(ctx).Error().Err(err).Msg("Failed to list pending batch jobs")
+		return
+	}
+	for _, job := range jobs {
+		err := p.refresh(ctx, job)
+		if err == nil {
+			if job.PollFailures > 0 {
+				if err := p.repo.SetPollState(ctx, job.ID, 0, time.Now(), ""); err != nil {
+					logger.GetLogger(ctx).Error().Err(err).Str("batch_id", job.BatchID).Msg("Failed to reset batch job poll failures")
+				}
+			}
+			continue
+		}
+
+		failures := job.PollFailures + 1
+		event := logger.GetLogger(ctx).Warn()
+		if failures >= maxPollFailures {
+			event = logger.GetLogger(ctx).Error()
+		}
+		event.Err(err).
+			Str("batch_id", job.BatchID).
+			Str("provider", job.Provider).
+			Int("failures", failures).
+			Bool("given_up", failures >= maxPollFailures).
+			Msg("Failed to refresh batch job")
+		if err := p.repo.SetPollState(ctx, job.ID, failures, time.Now().Add(p.backoff(failures)), err.Error()); err != nil {
+			logger.GetLogger(ctx).Error().Err(err).Str("batch_id", job.BatchID).Msg("Failed to record batch job poll failure")
+		}
+	}
+}
+
+// backoff is how long to wait before polling a job again after failures
+// polls in a row have failed
+func (p *Poller) backoff(failures int) time.Duration {
+	d := p.interval
+	for i := 1; i < failures && d < maxPollBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxPollBackoff)
+}
+
+func (p *Poller) refresh(ctx context.Context, job *model.BatchJob) error {
+	raw, err := p.get(ctx, job, "/v1/batches/"+job.BatchID)
+	if err != nil {
+		return err
+	}
+	obj, err := ParseObject(raw)
+	if err != nil {
+		return fmt.Errorf("decode batch: %w", err)
+	}
+	if err := Sync(ctx, p.repo, job, obj); err != nil {
+		return err
+	}
+	if !job.Status.IsTerminal() {
+		return nil
+	}
+
+	// Completed, expired and cancelled batches may all have partial output.
+	var metrics []*model.UsageMetric
+	if job.OutputFileID != "" {
+		if metrics, err = p.usageMetrics(ctx, job); err != nil {
+			return err
+		}
+	}
+	return p.repo.RecordUsage(ctx, job.ID, metrics)
+}
+
+// usageMetrics reads the job's output file and returns a usage row per model
+func (p *Poller) usageMetrics(ctx context.Context, job *model.BatchJob) ([]*model.UsageMetric, error) {
+	raw, err := p.get(ctx, job, "/v1/files/"+job.OutputFileID+"/content")
+	if err != nil {
+		return nil, err
+	}
+	totals, err := ParseOutputUsage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("parse batch output: %w", err)
+	}
+
+	key, err := p.keys.GetByKeyPrefix(ctx, job.KeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("resolve api key: %w", err)
+	}
+
+	now := time.Now()
+	if job.CompletedAt != nil {
+		now = *job.CompletedAt
+	}
+	metrics := make([]*model.UsageMetric, 0, len(totals))
+	for modelName, u := range totals {
+		costMicros, currency := p.cost(ctx, job, modelName, u)
+		metrics = append(metrics, &model.UsageMetric{
+			OrgID:             job.OrgID,
+			AppID:             job.AppID,
+			APIKeyID:          key.ID,
+			Provider:          job.Provider,
+			ModelName:         modelName,
+			PromptTokens:      u.PromptTokens,
+			CompletionTokens:  u.CompletionTokens,
+			TotalTokens:       u.TotalTokens,
+			ResponseSizeBytes: len(raw),
+			Timestamp:         now,
+			CostMicros:        costMicros,
+			Currency:          currency,
+		})
+	}
+	return metrics, nil
+}
+
+// cost prices a model's batch usage; unpriced models record a NULL cost
+func (p *Poller) cost(ctx context.Context, job *model.BatchJob, modelName string, u *model.TokenUsage) (*int64, string) {
+	if p.pricing == nil {
+		return nil, ""
+	}
+	costMicros, currency, err := p.pricing.Cost(ctx, job.OrgID, job.Provider, modelName, model.DeploymentTypeBatch, model.BillableUsage{Tokens: *u})
+	if err != nil {
+		logger.GetLogger(ctx).Warn().
+			Err(err).
+			Str("provider", job.Provider).
+			Str("model", modelName).
+			Msg("Failed to resolve model price")
+		return nil, ""
+	}
+	return costMicros, currency
+}
+
+// get issues an upstream GET for suffix using the adapter that owns the job.
+func (p *Poller) get(ctx context.Context, job *model.BatchJob, suffix string) ([]byte, error) {
+	ad := p.adapterFor(job.Provider)
+	if ad == nil {
+		return nil, fmt.Errorf("no adapter for provider %q", job.Provider)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://placeholder"+suffix, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := ad.Rewrite(req, suffix, provider.ReqInfo{
+		Method: http.MethodGet,
+		Path:   suffix,
+		Tenant: job.KeyPrefix,
+	}); err != nil {
+		return nil, err
+	}
+	resp, err := p.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("upstream %s returned %d", suffix, resp.StatusCode)
+	}
+	return body, nil
+}
+
+func (p *Poller) adapterFor(name string) provider.Adapter {
+	for _, ad := range p.adapters {
+		if gateway.GetProviderName(ad) == name {
+			return ad
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package batch_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/batch"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
+	"github.com/WebDeveloperBen/ai-gateway/internal/repository/batches"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryRepo struct {
+	jobs     []*model.BatchJob
+	files    map[string]model.BatchFile
+	recorded map[uuid.UUID]bool
+	metrics  []*model.UsageMetric
+}
+
+func (m *memoryRepo) GetFile(_ context.Context, _, fileID string) (*model.BatchFile, error) {
+	if f, ok := m.files[fileID]; ok {
+		return &f, nil
+	}
+	return nil, batches.ErrNotFound
+}
+
+func (m *memoryRepo) GetJob(context.Context, string, string) (*model.BatchJob, error) {
+	return nil, batches.ErrNotFound
+}
+
+func (m *memoryRepo) ListPendingJobs(_ context.Context, _, maxFailures int) ([]*model.BatchJob, error) {
+	var out []*model.BatchJob
+	for _, j := range m.jobs {
+		if !m.recorded[j.ID] && j.PollFailures < maxFailures && !j.NextPollAt.After(time.Now()) {
+			out = append(out, j)
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryRepo) CreateFile(_ context.Context, f model.BatchFile) error {
+	m.files[f.FileID] = f
+	return nil
+}
+
+func (m *memoryRepo) CreateJob(_ context.Context, j model.BatchJob) (*model.BatchJob, error) {
+	return &j, nil
+}
+
+func (m *memoryRepo) UpdateJobStatus(context.Context, uuid.UUID, model.BatchStatus, string, string, *time.Time) error {
+	return nil
+}
+
+func (m *memoryRepo) RecordUsage(_ context.Context, id uuid.UUID, metrics []*model.UsageMetric) error {
+	if !m.recorded[id] {
+		m.recorded[id] = true
+		m.metrics = append(m.metrics, metrics...)
+	}
+	return nil
+}
+
+func (m *memoryRepo) SetPollState(_ context.Context, id uuid.UUID, failures int, nextPollAt time.Time, pollErr string) error {
+	for _, j := range m.jobs {
+		if j.ID == id {
+			j.PollFailures, j.NextPollAt, j.PollError = failures, nextPollAt, pollErr
+		}
+	}
+	return nil
+}
+
+type keyReader struct{ id uuid.UUID }
+
+func (k keyReader) GetByKeyPrefix(context.Context, string) (*model.Key, error) {
+	return &model.Key{ID: k.id}, nil
+}
+
+func (k keyReader) GetSecretPHCByPrefix(context.Context, string) (string, error) { return "", nil }
+
+func (k keyReader) TouchLastUsed(context.Context, string) error { return nil }
+
+// fixedPricer charges a micro per token, recording the deployment type asked for
+type fixedPricer struct{ deploymentTypes []string }
+
+func (f *fixedPricer) Cost(_ context.Context, _ uuid.UUID, _, _, deploymentType string, usage model.BillableUsage) (*int64, string, error) {
+	f.deploymentTypes = append(f.deploymentTypes, deploymentType)
+	cost := int64(usage.Tokens.TotalTokens)
+	return &cost, "USD", nil
+}
+
+// upstreamAdapter forwards the "/v1/..." suffix to a test server.
+type upstreamAdapter struct{ base string }
+
+func (a upstreamAdapter) Prefix() string { return provider.OpenAIPrefix }
+
+func (a upstreamAdapter) Rewrite(req *http.Request, suffix string, _ provider.ReqInfo) error {
+	u, err := url.Parse(a.base + suffix)
+	if err != nil {
+		return err
+	}
+	provider.SetUpstreamURL(req, u)
+	return nil
+}
+
+func TestPoller_RecordsUsageForCompletedBatch(t *testing.T) {
+	status := "in_progress"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/batches/batch_1":
+			_, _ = w.Write([]byte(`{"id":"batch_1","status":"` + status + `","output_file_id":"file-out","completed_at":1700000000}`))
+		case "/v1/files/file-out/content":
+			_, _ = w.Write([]byte(`{"response":{"status_code":200,"body":{"model":"gpt-4o","usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}}}` + "\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	job := &model.BatchJob{
+		ID:        uuid.New(),
+		OrgID:     uuid.New(),
+		AppID:     uuid.New(),
+		KeyPrefix: "key_prefix",
+		Provider:  "openai",
+		BatchID:   "batch_1",
+		Status:    model.BatchStatusValidating,
+	}
+	repo := &memoryRepo{jobs: []*model.BatchJob{job}, files: map[string]model.BatchFile{}, recorded: map[uuid.UUID]bool{}}
+	keyID := uuid.New()
+	pricer := &fixedPricer{}
+
+	p := batch.NewPoller(repo, keyReader{id: keyID}, pricer, []provider.Adapter{upstreamAdapter{base: srv.URL}}, nil, time.Minute)
+
+	// Still running: status is synced but nothing is recorded
+	p.PollOnce(context.Background())
+	assert.Equal(t, model.BatchStatusInProgress, job.Status)
+	assert.Empty(t, repo.metrics)
+	assert.False(t, repo.recorded[job.ID])
+
+	status = "completed"
+	p.PollOnce(context.Background())
+	require.Len(t, repo.metrics, 1)
+	m := repo.metrics[0]
+	assert.Equal(t, job.AppID, m.AppID)
+	assert.Equal(t, keyID, m.APIKeyID)
+	assert.Equal(t, "gpt-4o", m.ModelName)
+	assert.Equal(t, 15, m.TotalTokens)
+	require.NotNil(t, m.CostMicros)
+	assert.Equal(t, int64(15), *m.CostMicros)
+	assert.Equal(t, "USD", m.Currency)
+	assert.Equal(t, []string{model.DeploymentTypeBatch}, pricer.deploymentTypes)
+	assert.True(t, repo.recorded[job.ID])
+	assert.Equal(t, job.AppID, repo.files["file-out"].AppID)
+
+	// Recorded jobs are not polled again
+	p.PollOnce(context.Background())
+	assert.Len(t, repo.metrics, 1)
+}
+
+func TestPoller_BacksOffAndGivesUpOnFailingJobs(t *testing.T) {
+	failing := &model.BatchJob{ID: uuid.New(), Provider: "unknown", BatchID: "batch_gone", Status: model.BatchStatusInProgress}
+	repo := &memoryRepo{jobs: []*model.BatchJob{failing}, files: map[string]model.BatchFile{}, recorded: map[uuid.UUID]bool{}}
+	p := batch.NewPoller(repo, keyReader{id: uuid.New()}, nil, nil, nil, time.Minute)
+
+	p.PollOnce(context.Background())
+	assert.Equal(t, 1, failing.PollFailures)
+	assert.Contains(t, failing.PollError, "no adapter")
+	assert.WithinDuration(t, time.Now().Add(time.Minute), failing.NextPollAt, 5*time.Second)
+
+	// Not due again until the backoff has passed
+	pending, err := repo.ListPendingJobs(context.Background(), 100, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	failing.NextPollAt = time.Time{}
+	p.PollOnce(context.Background())
+	assert.Equal(t, 2, failing.PollFailures)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), failing.NextPollAt, 5*time.Second)
+
+	// After enough failures in a row the job is no longer polled
+	failing.PollFailures, failing.NextPollAt = 9, time.Time{}
+	p.PollOnce(context.Background())
+	assert.Equal(t, 10, failing.PollFailures)
+	failing.NextPollAt = time.Time{}
+	pending, err = repo.ListPendingJobs(context.Background(), 100, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	assert.False(t, repo.recorded[failing.ID])
+}
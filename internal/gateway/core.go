@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/async"
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
 	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
 	"github.com/danielgtaylor/huma/v2"
@@ -26,6 +27,11 @@ func (c *Core) StreamingHandler() func(ctx context.Context, _ *struct{}) (*huma.
 
 				hctx.EachHeader(func(n, v string) { r.Header.Add(n, v) })
 
+				if c.Async != nil && async.Requested(r.Header) {
+					c.submitAsync(hctx, r)
+					return
+				}
+
 				tenant, app, _ := c.Authenticator.Authenticate(r)
 
 				ctxWithTenant := context.WithValue(
@@ -65,100 +71,120 @@ func (c *Core) StreamingHandler() func(ctx context.Context, _ *struct{}) (*huma.
 func (c *Core) makeDirector(ctx context.Context, hctx huma.Context) func(*http.Request) {
 	return func(req *http.Request) {
 		// Use the real incoming path from Huma (not the placeholder).
-		inURL := hctx.URL()
-		path := inURL.Path
-		req.URL.Path = path
-		req.URL.RawQuery = inURL.RawQuery
+		c.direct(ctx, req, hctx.Method(), hctx.URL(), hctx.BodyReader())
+	}
+}
 
-		// 1) Try to find a matching adapter by Prefix() on segment boundaries.
-		var (
-			ad        provider.Adapter
-			prefix    string
-			prefixPos = -1
-		)
-		for _, a := range c.Adapters {
-			pfx := a.Prefix()
-			if pfx == "" || pfx == "/" {
-				continue
-			}
-			if i := IndexOfSegment(path, pfx); i >= 0 {
-				ad, prefix, prefixPos = a, pfx, i
-				break
-			}
+// matchAdapter finds the adapter whose Prefix() appears in path on segment
+// boundaries, falling back to the only adapter when exactly one is registered
+// (in which case prefixPos is -1).
+func (c *Core) matchAdapter(path string) (ad provider.Adapter, prefix string, prefixPos int) {
+	for _, a := range c.Adapters {
+		pfx := a.Prefix()
+		if pfx == "" || pfx == "/" {
+			continue
 		}
-		// Fallback: if exactly one adapter is registered, use it as default.
-		if ad == nil && len(c.Adapters) == 1 {
-			ad = c.Adapters[0]
-			prefix = ""
-			prefixPos = -1
-		}
-		if ad == nil {
-			req.Header.Set("X-RP-Error", "no_adapter_for_path:"+path)
-			req.Header.Set("X-RP-Adapters", strings.Join(ListPrefixes(c.Adapters), ","))
-			req.URL = mustParse("http://invalid/")
-			return
+		if i := IndexOfSegment(path, pfx); i >= 0 {
+			return a, pfx, i
 		}
+	}
+	if len(c.Adapters) == 1 {
+		return c.Adapters[0], "", -1
+	}
+	return nil, "", -1
+}
 
-		// 2) Compute suffix starting at /v1/... AFTER the matched prefix.
-		var tail string
-		if prefixPos >= 0 {
-			tail = path[prefixPos+len(prefix):]
-			if !strings.HasPrefix(tail, "/") {
-				tail = "/" + tail
-			}
-		} else {
-			// default adapter case: tail is entire path
-			tail = path
-		}
-		j := strings.Index(tail, "/v1/")
-		if j < 0 {
-			req.Header.Set("X-RP-Error", "no_v1_suffix after prefix "+prefix)
-			req.URL = mustParse("http://invalid/")
-			return
-		}
-		suffix := tail[j:] // "/v1/..."
+// direct rewrites req for the upstream serving inURL. It is shared by the
+// reverse proxy director and async job execution.
+func (c *Core) direct(ctx context.Context, req *http.Request, method string, inURL url.URL, body io.Reader) {
+	path := inURL.Path
+	req.URL.Path = path
+	req.URL.RawQuery = inURL.RawQuery
+
+	// 1) Try to find a matching adapter by Prefix() on segment boundaries.
+	ad, prefix, prefixPos := c.matchAdapter(path)
+	if ad == nil {
+		req.Header.Set("X-RP-Error", "no_adapter_for_path:"+path)
+		req.Header.Set("X-RP-Adapters", strings.Join(ListPrefixes(c.Adapters), ","))
+		req.URL = mustParse("http://invalid/")
+		return
+	}
 
-		// 3) Snapshot body (so retries/RTs can reread) + build ReqInfo.
-		var raw []byte
-		if br := hctx.BodyReader(); br != nil {
-			raw, _ = io.ReadAll(io.LimitReader(br, int64(c.MaxBody)))
+	// 2) Compute suffix starting at /v1/... AFTER the matched prefix.
+	var tail string
+	if prefixPos >= 0 {
+		tail = path[prefixPos+len(prefix):]
+		if !strings.HasPrefix(tail, "/") {
+			tail = "/" + tail
 		}
-		// Attach body and be explicit about length & TE.
-		req.Body = io.NopCloser(bytes.NewReader(raw))
-		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(raw)), nil }
-		req.ContentLength = int64(len(raw))
-		req.Header.Del("Transfer-Encoding")
-		req.Header.Set("Content-Length", strconv.Itoa(len(raw)))
-		// If you ever had an inbound Content-Encoding, drop it; we’re sending raw JSON upstream.
-		req.Header.Del("Content-Encoding")
+	} else {
+		// default adapter case: tail is entire path
+		tail = path
+	}
+	j := strings.Index(tail, "/v1/")
+	if j < 0 {
+		req.Header.Set("X-RP-Error", "no_v1_suffix after prefix "+prefix)
+		req.URL = mustParse("http://invalid/")
+		return
+	}
+	suffix := tail[j:] // "/v1/..."
 
-		model := ExtractModel(raw)
+	// 3) Snapshot body (so retries/RTs can reread) + build ReqInfo.
+	maxBody := c.MaxBody
+	if provider.IsAccountScopedPath(suffix) && c.MaxUploadBody > maxBody {
+		maxBody = c.MaxUploadBody
+	}
+	var raw []byte
+	if body != nil {
+		raw, _ = io.ReadAll(io.LimitReader(body, int64(maxBody)))
+	}
+	// Attach body and be explicit about length & TE.
+	req.Body = io.NopCloser(bytes.NewReader(raw))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(raw)), nil }
+	req.ContentLength = int64(len(raw))
+	req.Header.Del("Transfer-Encoding")
+	req.Header.Set("Content-Length", strconv.Itoa(len(raw)))
+	// If you ever had an inbound Content-Encoding, drop it; we’re sending raw JSON upstream.
+	req.Header.Del("Content-Encoding")
 
-		info := provider.ReqInfo{
-			Method: hctx.Method(),
-			Path:   suffix,
-			Query:  req.URL.RawQuery,
-			Model:  model,
-			Tenant: TenantFrom(ctx),
-			App:    AppFrom(ctx),
-		}
+	model := ExtractModel(raw)
 
-		// Set provider and model in context for middleware
-		ctx = auth.WithProvider(ctx, GetProviderName(ad))
-		ctx = auth.WithModelName(ctx, model)
-		req = req.WithContext(ctx)
+	info := provider.ReqInfo{
+		Method: method,
+		Path:   suffix,
+		Query:  req.URL.RawQuery,
+		Model:  model,
+		Tenant: TenantFrom(ctx),
+		App:    AppFrom(ctx),
+	}
 
-		// 4) Let the adapter rewrite to the real upstream.
-		if err := ad.Rewrite(req, suffix, info); err != nil {
-			req.Header.Set("X-RP-Error", "rewrite:"+Escape(err.Error()))
-			req.URL = mustParse("http://invalid/")
-			return
+	// Set provider, model, endpoint, the caller's headers and routing in
+	// context for middleware. The outgoing request is replaced in place so
+	// the transport sees them.
+	route := func(r *http.Request, model string) error {
+		routed := info
+		routed.Model = model
+		if err := ad.Rewrite(r, suffix, routed); err != nil {
+			return err
 		}
-
 		// Ensure Host aligns with upstream host
-		if req.URL.Host != "" {
-			req.Host = req.URL.Host
+		if r.URL.Host != "" {
+			r.Host = r.URL.Host
 		}
+		return nil
+	}
+	ctx = auth.WithProvider(ctx, GetProviderName(ad))
+	ctx = auth.WithModelName(ctx, model)
+	ctx = auth.WithEndpoint(ctx, suffix)
+	ctx = auth.WithCallerHeader(ctx, req.Header)
+	ctx = auth.WithRouter(ctx, route)
+	*req = *req.WithContext(ctx)
+
+	// 4) Let the adapter rewrite to the real upstream.
+	if err := route(req, model); err != nil {
+		req.Header.Set("X-RP-Error", "rewrite:"+Escape(err.Error()))
+		req.URL = mustParse("http://invalid/")
+		return
 	}
 }
 
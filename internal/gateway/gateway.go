@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/async"
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/loadbalancing"
 	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
@@ -15,9 +16,11 @@ import (
 
 type Core struct {
 	MaxBody       int
+	MaxUploadBody int // applies to Files/Batch API requests, which carry JSONL uploads
 	Transport     http.RoundTripper
 	Adapters      []provider.Adapter
 	Authenticator auth.KeyAuthenticator
+	Async         *async.Queue // nil disables "Prefer: respond-async"
 }
 
 func NewCoreWithAdapters(rt http.RoundTripper, auth auth.KeyAuthenticator, adapters ...provider.Adapter) *Core {
@@ -26,6 +29,7 @@ func NewCoreWithAdapters(rt http.RoundTripper, auth auth.KeyAuthenticator, adapt
 	}
 	return &Core{
 		MaxBody:       1 << 20,
+		MaxUploadBody: 200 << 20,
 		Transport:     rt,
 		Adapters:      adapters,
 		Authenticator: auth,
@@ -0,0 +1,255 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/batch"
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
+	"github.com/WebDeveloperBen/ai-gateway/internal/repository/batches"
+	"github.com/google/uuid"
+)
+
+// BatchTracker scopes Files and Batch API traffic to the calling app. Upstream
+// accounts are shared across apps, so every file and batch created through the
+// gateway is recorded and later reads are only allowed for the owning app.
+type BatchTracker struct {
+	repo batches.Repository
+}
+
+// NewBatchTracker creates a new batch tracking middleware
+func NewBatchTracker(repo batches.Repository) *BatchTracker {
+	return &BatchTracker{repo: repo}
+}
+
+type batchRouteKind int
+
+const (
+	batchRouteUnsupported batchRouteKind = iota
+	batchRouteFileUpload
+	batchRouteFileRead
+	batchRouteCreate
+	batchRouteRead
+)
+
+// parseBatchRoute classifies a "/v1/files..." or "/v1/batches..." request and
+// returns the referenced resource ID, if any.
+func parseBatchRoute(method, endpoint string) (batchRouteKind, string) {
+	parts := strings.Split(strings.Trim(endpoint, "/"), "/")
+	if len(parts) < 2 || parts[0] != "v1" {
+		return batchRouteUnsupported, ""
+	}
+	switch {
+	case parts[1] == "files" && len(parts) == 2 && method == http.MethodPost:
+		return batchRouteFileUpload, ""
+	case parts[1] == "files" && len(parts) == 3 && method == http.MethodGet:
+		return batchRouteFileRead, parts[2]
+	case parts[1] == "files" && len(parts) == 4 && parts[3] == "content" && method == http.MethodGet:
+		return batchRouteFileRead, parts[2]
+	case parts[1] == "batches" && len(parts) == 2 && method == http.MethodPost:
+		return batchRouteCreate, ""
+	case parts[1] == "batches" && len(parts) == 3 && method == http.MethodGet:
+		return batchRouteRead, parts[2]
+	case parts[1] == "batches" && len(parts) == 4 && parts[3] == "cancel" && method == http.MethodPost:
+		return batchRouteRead, parts[2]
+	}
+	return batchRouteUnsupported, ""
+}
+
+// Middleware returns a RoundTripper middleware that enforces batch ownership
+func (bt *BatchTracker) Middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		ctx := r.Context()
+		endpoint := auth.GetEndpoint(ctx)
+		if !provider.IsAccountScopedPath(endpoint) {
+			return next.RoundTrip(r)
+		}
+
+		orgID, errOrg := uuid.Parse(auth.GetOrgID(ctx))
+		appID, errApp := uuid.Parse(auth.GetAppID(ctx))
+		if errOrg != nil || errApp != nil {
+			return deny(401, "unauthorized"), nil
+		}
+		owner := model.BatchFile{
+			OrgID:     orgID,
+			AppID:     appID,
+			KeyPrefix: auth.GetKeyID(ctx),
+			Provider:  auth.GetProvider(ctx),
+		}
+
+		kind, resourceID := parseBatchRoute(r.Method, endpoint)
+		switch kind {
+		case batchRouteFileUpload:
+			return bt.handleFileUpload(next, r, owner)
+		case batchRouteFileRead:
+			if !bt.ownsFile(r, owner, resourceID) {
+				return deny(404, "not found"), nil
+			}
+			return next.RoundTrip(r)
+		case batchRouteCreate:
+			return bt.handleBatchCreate(next, r, owner)
+		case batchRouteRead:
+			return bt.handleBatchRead(next, r, owner, resourceID)
+		default:
+			// Listing would expose other apps' resources on the shared account.
+			return deny(404, "not found"), nil
+		}
+	})
+}
+
+func (bt *BatchTracker) handleFileUpload(next http.RoundTripper, r *http.Request, owner model.BatchFile) (*http.Response, error) {
+	resp, err := next.RoundTrip(r)
+	if err != nil || !isSuccess(resp) {
+		return resp, err
+	}
+
+	var file struct {
+		ID      string `json:"id"`
+		Purpose string `json:"purpose"`
+	}
+	if err := peekJSON(resp, &file); err != nil || file.ID == "" {
+		return resp, nil
+	}
+	owner.FileID = file.ID
+	owner.Purpose = file.Purpose
+	if err := bt.repo.CreateFile(r.Context(), owner); err != nil {
+		logger.GetLogger(r.Context()).Error().
+			Err(err).
+			Str("app_id", owner.AppID.String()).
+			Str("file_id", file.ID).
+			Msg("Failed to record batch file ownership")
+	}
+	return resp, nil
+}
+
+func (bt *BatchTracker) handleBatchCreate(next http.RoundTripper, r *http.Request, owner model.BatchFile) (*http.Response, error) {
+	body, err := readRequestBody(r)
+	if err != nil {
+		return deny(400, "failed to read request body"), nil
+	}
+	var req struct {
+		InputFileID string `json:"input_file_id"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.InputFileID == "" {
+		return deny(400, "input_file_id is required"), nil
+	}
+	if !bt.ownsFile(r, owner, req.InputFileID) {
+		return deny(404, "input file not found"), nil
+	}
+
+	resp, err := next.RoundTrip(r)
+	if err != nil || !isSuccess(resp) {
+		return resp, err
+	}
+
+	raw, err := peekBody(resp)
+	if err != nil {
+		return resp, nil
+	}
+	obj, err := batch.ParseObject(raw)
+	if err != nil || obj.ID == "" {
+		return resp, nil
+	}
+	if obj.InputFileID == "" {
+		obj.InputFileID = req.InputFileID
+	}
+	if _, err := bt.repo.CreateJob(r.Context(), model.BatchJob{
+		OrgID:       owner.OrgID,
+		AppID:       owner.AppID,
+		KeyPrefix:   owner.KeyPrefix,
+		Provider:    owner.Provider,
+		BatchID:     obj.ID,
+		Endpoint:    obj.Endpoint,
+		Status:      model.BatchStatus(obj.Status),
+		InputFileID: obj.InputFileID,
+	}); err != nil {
+		logger.GetLogger(r.Context()).Error().
+			Err(err).
+			Str("app_id", owner.AppID.String()).
+			Str("batch_id", obj.ID).
+			Msg("Failed to record batch job")
+	}
+	return resp, nil
+}
+
+func (bt *BatchTracker) handleBatchRead(next http.RoundTripper, r *http.Request, owner model.BatchFile, batchID string) (*http.Response, error) {
+	job, err := bt.repo.GetJob(r.Context(), owner.Provider, batchID)
+	if err != nil {
+		if !errors.Is(err, batches.ErrNotFound) {
+			logger.GetLogger(r.Context()).Error().Err(err).Str("batch_id", batchID).Msg("Failed to load batch job")
+		}
+		return deny(404, "not found"), nil
+	}
+	if job.AppID != owner.AppID {
+		return deny(404, "not found"), nil
+	}
+
+	resp, err := next.RoundTrip(r)
+	if err != nil || !isSuccess(resp) {
+		return resp, err
+	}
+
+	// Opportunistically sync so the caller can fetch output files straight away.
+	raw, err := peekBody(resp)
+	if err != nil {
+		return resp, nil
+	}
+	if obj, err := batch.ParseObject(raw); err == nil && obj.Status != "" {
+		if err := batch.Sync(r.Context(), bt.repo, job, obj); err != nil {
+			logger.GetLogger(r.Context()).Error().Err(err).Str("batch_id", batchID).Msg("Failed to sync batch job")
+		}
+	}
+	return resp, nil
+}
+
+func (bt *BatchTracker) ownsFile(r *http.Request, owner model.BatchFile, fileID string) bool {
+	f, err := bt.repo.GetFile(r.Context(), owner.Provider, fileID)
+	if err != nil {
+		if !errors.Is(err, batches.ErrNotFound) {
+			logger.GetLogger(r.Context()).Error().Err(err).Str("file_id", fileID).Msg("Failed to load batch file")
+		}
+		return false
+	}
+	return f.AppID == owner.AppID
+}
+
+func isSuccess(resp *http.Response) bool {
+	return resp != nil && resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// readRequestBody reads the request body and replaces it with a fresh reader
+func readRequestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// peekBody reads the response body and replaces it with a fresh reader
+func peekBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return body, err
+}
+
+func peekJSON(resp *http.Response, v any) error {
+	body, err := peekBody(resp)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/repository/batches"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryBatchRepo is an in-memory batches.Repository for testing
+type memoryBatchRepo struct {
+	files map[string]*model.BatchFile
+	jobs  map[string]*model.BatchJob
+}
+
+func newMemoryBatchRepo() *memoryBatchRepo {
+	return &memoryBatchRepo{files: map[string]*model.BatchFile{}, jobs: map[string]*model.BatchJob{}}
+}
+
+func (m *memoryBatchRepo) GetFile(_ context.Context, provider, fileID string) (*model.BatchFile, error) {
+	if f, ok := m.files[provider+"/"+fileID]; ok {
+		return f, nil
+	}
+	return nil, batches.ErrNotFound
+}
+
+func (m *memoryBatchRepo) GetJob(_ context.Context, provider, batchID string) (*model.BatchJob, error) {
+	if j, ok := m.jobs[provider+"/"+batchID]; ok {
+		return j, nil
+	}
+	return nil, batches.ErrNotFound
+}
+
+func (m *memoryBatchRepo) ListPendingJobs(context.Context, int, int) ([]*model.BatchJob, error) {
+	return nil, nil
+}
+
+func (m *memoryBatchRepo) CreateFile(_ context.Context, f model.BatchFile) error {
+	if _, ok := m.files[f.Provider+"/"+f.FileID]; !ok {
+		m.files[f.Provider+"/"+f.FileID] = &f
+	}
+	return nil
+}
+
+func (m *memoryBatchRepo) CreateJob(_ context.Context, j model.BatchJob) (*model.BatchJob, error) {
+	j.ID = uuid.New()
+	m.jobs[j.Provider+"/"+j.BatchID] = &j
+	return &j, nil
+}
+
+func (m *memoryBatchRepo) UpdateJobStatus(_ context.Context, id uuid.UUID, status model.BatchStatus, outputFileID, errorFileID string, completedAt *time.Time) error {
+	for _, j := range m.jobs {
+		if j.ID == id {
+			j.Status, j.OutputFileID, j.ErrorFileID, j.CompletedAt = status, outputFileID, errorFileID, completedAt
+		}
+	}
+	return nil
+}
+
+func (m *memoryBatchRepo) RecordUsage(context.Context, uuid.UUID, []*model.UsageMetric) error {
+	return nil
+}
+
+func (m *memoryBatchRepo) SetPollState(context.Context, uuid.UUID, int, time.Time, string) error {
+	return nil
+}
+
+func batchRequest(method, endpoint, appID string, body []byte) *http.Request {
+	req := httptest.NewRequest(method, "http://upstream"+endpoint, bytes.NewReader(body))
+	ctx := auth.WithEndpoint(req.Context(), endpoint)
+	ctx = auth.WithOrgID(ctx, uuid.NewString())
+	ctx = auth.WithAppID(ctx, appID)
+	ctx = auth.WithKeyID(ctx, "key_prefix")
+	ctx = auth.WithProvider(ctx, "openai")
+	return req.WithContext(ctx)
+}
+
+func TestParseBatchRoute(t *testing.T) {
+	tests := []struct {
+		method, endpoint string
+		kind             batchRouteKind
+		id               string
+	}{
+		{http.MethodPost, "/v1/files", batchRouteFileUpload, ""},
+		{http.MethodGet, "/v1/files/file-1", batchRouteFileRead, "file-1"},
+		{http.MethodGet, "/v1/files/file-1/content", batchRouteFileRead, "file-1"},
+		{http.MethodPost, "/v1/batches", batchRouteCreate, ""},
+		{http.MethodGet, "/v1/batches/batch_1", batchRouteRead, "batch_1"},
+		{http.MethodPost, "/v1/batches/batch_1/cancel", batchRouteRead, "batch_1"},
+		{http.MethodGet, "/v1/files", batchRouteUnsupported, ""},
+		{http.MethodGet, "/v1/batches", batchRouteUnsupported, ""},
+		{http.MethodDelete, "/v1/files/file-1", batchRouteUnsupported, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.endpoint, func(t *testing.T) {
+			kind, id := parseBatchRoute(tt.method, tt.endpoint)
+			assert.Equal(t, tt.kind, kind)
+			assert.Equal(t, tt.id, id)
+		})
+	}
+}
+
+func TestBatchTracker_Middleware(t *testing.T) {
+	appID := uuid.NewString()
+	otherApp := uuid.NewString()
+
+	t.Run("NonBatchPassesThrough", func(t *testing.T) {
+		repo := newMemoryBatchRepo()
+		next := &testMockRoundTripper{responseBody: []byte(`{}`)}
+		req := batchRequest(http.MethodPost, "/v1/chat/completions", appID, nil)
+
+		resp, err := NewBatchTracker(repo).Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+	})
+
+	t.Run("UploadRecordsOwnership", func(t *testing.T) {
+		repo := newMemoryBatchRepo()
+		next := &testMockRoundTripper{responseBody: []byte(`{"id":"file-1","purpose":"batch"}`)}
+		req := batchRequest(http.MethodPost, "/v1/files", appID, []byte("multipart"))
+
+		resp, err := NewBatchTracker(repo).Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.JSONEq(t, `{"id":"file-1","purpose":"batch"}`, string(body))
+
+		f, err := repo.GetFile(context.Background(), "openai", "file-1")
+		require.NoError(t, err)
+		assert.Equal(t, appID, f.AppID.String())
+		assert.Equal(t, "batch", f.Purpose)
+	})
+
+	t.Run("FileReadByOtherAppIsNotFound", func(t *testing.T) {
+		repo := newMemoryBatchRepo()
+		_ = repo.CreateFile(context.Background(), model.BatchFile{AppID: uuid.MustParse(otherApp), Provider: "openai", FileID: "file-1"})
+		called := false
+		next := &testMockRoundTripper{checkRequest: func(*http.Request) { called = true }}
+		req := batchRequest(http.MethodGet, "/v1/files/file-1/content", appID, nil)
+
+		resp, err := NewBatchTracker(repo).Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+		assert.False(t, called)
+	})
+
+	t.Run("CreateRequiresOwnedInputFile", func(t *testing.T) {
+		repo := newMemoryBatchRepo()
+		_ = repo.CreateFile(context.Background(), model.BatchFile{AppID: uuid.MustParse(otherApp), Provider: "openai", FileID: "file-1"})
+		next := &testMockRoundTripper{responseBody: []byte(`{"id":"batch_1"}`)}
+		req := batchRequest(http.MethodPost, "/v1/batches", appID, []byte(`{"input_file_id":"file-1"}`))
+
+		resp, err := NewBatchTracker(repo).Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+		assert.Empty(t, repo.jobs)
+	})
+
+	t.Run("CreateRecordsJob", func(t *testing.T) {
+		repo := newMemoryBatchRepo()
+		_ = repo.CreateFile(context.Background(), model.BatchFile{AppID: uuid.MustParse(appID), Provider: "openai", FileID: "file-1"})
+		var forwarded []byte
+		next := &testMockRoundTripper{
+			responseBody: []byte(`{"id":"batch_1","status":"validating","endpoint":"/v1/chat/completions","input_file_id":"file-1"}`),
+			checkRequest: func(r *http.Request) { forwarded, _ = io.ReadAll(r.Body) },
+		}
+		req := batchRequest(http.MethodPost, "/v1/batches", appID, []byte(`{"input_file_id":"file-1"}`))
+
+		resp, err := NewBatchTracker(repo).Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.JSONEq(t, `{"input_file_id":"file-1"}`, string(forwarded))
+
+		job, err := repo.GetJob(context.Background(), "openai", "batch_1")
+		require.NoError(t, err)
+		assert.Equal(t, appID, job.AppID.String())
+		assert.Equal(t, model.BatchStatusValidating, job.Status)
+		assert.Equal(t, "key_prefix", job.KeyPrefix)
+	})
+
+	t.Run("ReadSyncsStatusAndOutputOwnership", func(t *testing.T) {
+		repo := newMemoryBatchRepo()
+		_, _ = repo.CreateJob(context.Background(), model.BatchJob{AppID: uuid.MustParse(appID), Provider: "openai", BatchID: "batch_1", Status: model.BatchStatusInProgress})
+		next := &testMockRoundTripper{responseBody: []byte(`{"id":"batch_1","status":"completed","output_file_id":"file-out","completed_at":1700000000}`)}
+		req := batchRequest(http.MethodGet, "/v1/batches/batch_1", appID, nil)
+
+		resp, err := NewBatchTracker(repo).Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		job, _ := repo.GetJob(context.Background(), "openai", "batch_1")
+		assert.Equal(t, model.BatchStatusCompleted, job.Status)
+		require.NotNil(t, job.CompletedAt)
+		f, err := repo.GetFile(context.Background(), "openai", "file-out")
+		require.NoError(t, err)
+		assert.Equal(t, appID, f.AppID.String())
+	})
+
+	t.Run("ReadByOtherAppIsNotFound", func(t *testing.T) {
+		repo := newMemoryBatchRepo()
+		_, _ = repo.CreateJob(context.Background(), model.BatchJob{AppID: uuid.MustParse(otherApp), Provider: "openai", BatchID: "batch_1"})
+		next := &testMockRoundTripper{responseBody: []byte(`{}`)}
+		req := batchRequest(http.MethodPost, "/v1/batches/batch_1/cancel", appID, nil)
+
+		resp, err := NewBatchTracker(repo).Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+
+	t.Run("ListingIsRejected", func(t *testing.T) {
+		repo := newMemoryBatchRepo()
+		next := &testMockRoundTripper{responseBody: []byte(`{}`)}
+		req := batchRequest(http.MethodGet, "/v1/batches", appID, nil)
+
+		resp, err := NewBatchTracker(repo).Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+}
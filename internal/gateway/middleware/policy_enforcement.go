@@ -2,31 +2,38 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/tokens"
 	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
 )
 
 // PolicyLoader defines the interface for loading policies
 type PolicyLoader interface {
-	LoadPolicies(ctx context.Context, appID string) ([]policies.Policy, error)
+	LoadPolicies(ctx context.Context, scope policies.Scope) ([]policies.Policy, error)
 }
 
 // PolicyEnforcer provides policy enforcement for requests
 type PolicyEnforcer struct {
-	engine PolicyLoader
+	engine    PolicyLoader
+	estimator *tokens.Estimator
 }
 
 // NewPolicyEnforcer creates a new policy enforcer
 func NewPolicyEnforcer(engine PolicyLoader) *PolicyEnforcer {
 	return &PolicyEnforcer{
-		engine: engine,
+		engine:    engine,
+		estimator: tokens.NewEstimator(),
 	}
 }
 
@@ -42,8 +49,8 @@ func (pe *PolicyEnforcer) Middleware(next http.RoundTripper) http.RoundTripper {
 			return next.RoundTrip(r)
 		}
 
-		// Load policies for this app
-		policyList, err := pe.engine.LoadPolicies(ctx, appID)
+		// Load the policies attached to this app and its org, user and key
+		policyList, err := pe.engine.LoadPolicies(ctx, requestScope(ctx))
 		if err != nil {
 			logger.GetLogger(ctx).Error().
 				Err(err).
@@ -67,38 +74,352 @@ func (pe *PolicyEnforcer) Middleware(next http.RoundTripper) http.RoundTripper {
 			return deny(500, "internal error"), nil
 		}
 
-		// Build pre-request context using parsed data
-		preCtx := &policies.PreRequestContext{
-			Request:          r,
-			OrgID:            auth.GetOrgID(ctx),
-			AppID:            appID,
-			APIKeyID:         auth.GetKeyID(ctx),
-			Model:            parsedReq.Model,
-			EstimatedTokens:  parsedReq.EstimatedTokens,
-			RequestSizeBytes: parsedReq.RequestSize,
-			Body:             nil, // No longer needed - policies use parsed data
-		}
-
-		// Run pre-checks (blocking)
-		for _, policy := range policyList {
-			if err := policy.PreCheck(ctx, preCtx); err != nil {
-				logger.GetLogger(ctx).Warn().
-					Err(err).
-					Str("app_id", appID).
-					Str("org_id", auth.GetOrgID(ctx)).
-					Str("policy_type", string(policy.Type())).
-					Str("model", parsedReq.Model).
-					Int("estimated_tokens", parsedReq.EstimatedTokens).
-					Msg("Policy check failed")
-				return deny(429, "policy violation"), nil
+		// Content policies can't vouch for a body they couldn't read
+		if parsedReq.Unreadable && !provider.IsAccountScopedPath(auth.GetEndpoint(ctx)) {
+			for _, policy := range policyList {
+				if policies.InspectsContent(policy) {
+					logger.GetLogger(ctx).Warn().
+						Str("app_id", appID).
+						Str("policy_type", string(policy.Type())).
+						Msg("Request body could not be inspected")
+					return deny(400, "request body could not be inspected"), nil
+				}
 			}
 		}
 
-		// Continue with request
-		return next.RoundTrip(r)
+		// Files/Batch API calls carry no model of their own: uploaded batch
+		// input files are checked once per model they reference, and every
+		// other call runs the policies once with no model. Usage is recorded
+		// by the batch poller when the job completes.
+		models := []string{parsedReq.Model}
+		if provider.IsAccountScopedPath(auth.GetEndpoint(ctx)) && len(parsedReq.BatchModels) > 0 {
+			models = parsedReq.BatchModels
+		}
+
+		// Content policies may rewrite the body; later policies and models
+		// see their changes
+		body, messages, prompt := parsedReq.Body, toPolicyMessages(parsedReq.Messages), parsedReq.Prompt
+		tools := toPolicyTools(parsedReq.Tools)
+		estimatedTokens := parsedReq.EstimatedTokens
+		rewritten := false
+
+		// Transform phase: rewrite the request before any limits are checked
+		if !provider.IsAccountScopedPath(auth.GetEndpoint(ctx)) {
+			transformCtx := &policies.PreRequestContext{
+				Request:          r,
+				OrgID:            auth.GetOrgID(ctx),
+				AppID:            appID,
+				APIKeyID:         auth.GetKeyID(ctx),
+				UserID:           auth.GetUserID(ctx),
+				KeyMetadata:      auth.GetKeyMetadata(ctx),
+				Endpoint:         auth.GetEndpoint(ctx),
+				Model:            parsedReq.Model,
+				EstimatedTokens:  estimatedTokens,
+				RequestSizeBytes: parsedReq.RequestSize,
+				Stream:           parsedReq.Stream,
+				Messages:         messages,
+				Prompt:           prompt,
+				Tools:            tools,
+				ToolChoice:       parsedReq.ToolChoice,
+				ForcedTool:       parsedReq.ForcedTool,
+				Body:             body,
+				Header:           r.Header,
+				CallerHeader:     auth.GetCallerHeader(ctx),
+			}
+			for _, policy := range policyList {
+				transformer, ok := policy.(policies.Transformer)
+				if !ok {
+					continue
+				}
+				if err := transformer.Transform(ctx, transformCtx); err != nil {
+					logger.GetLogger(ctx).Warn().
+						Err(err).
+						Str("app_id", appID).
+						Str("policy_type", string(policy.Type())).
+						Msg("Policy transform failed")
+					return denyPolicy(policy.Type(), err), nil
+				}
+			}
+			body, messages, prompt = transformCtx.Body, transformCtx.Messages, transformCtx.Prompt
+			if transformCtx.BodyRewritten {
+				rewritten = true
+				if n, err := pe.estimator.EstimateRequest(ctx, parsedReq.Model, body); err == nil {
+					estimatedTokens = n
+				}
+			}
+		}
+
+		var override string
+		var priority *policies.Priority
+		var reservations []policies.TokenReservation
+		var leases []*policies.ConcurrencyLease
+		var tags []string
+		var metadata map[string]any
+		limits := rateLimitHeaders{}
+		for _, modelName := range models {
+			// Build pre-request context using parsed data
+			preCtx := &policies.PreRequestContext{
+				Request:          r,
+				OrgID:            auth.GetOrgID(ctx),
+				AppID:            appID,
+				APIKeyID:         auth.GetKeyID(ctx),
+				UserID:           auth.GetUserID(ctx),
+				KeyMetadata:      auth.GetKeyMetadata(ctx),
+				Endpoint:         auth.GetEndpoint(ctx),
+				Model:            modelName,
+				EstimatedTokens:  estimatedTokens,
+				RequestSizeBytes: parsedReq.RequestSize,
+				Stream:           parsedReq.Stream,
+				Messages:         messages,
+				Prompt:           prompt,
+				Tools:            tools,
+				ToolChoice:       parsedReq.ToolChoice,
+				ForcedTool:       parsedReq.ForcedTool,
+				Body:             body,
+				CallerHeader:     auth.GetCallerHeader(ctx),
+			}
+
+			// Run pre-checks (blocking)
+			for _, policy := range policyList {
+				if err := policy.PreCheck(ctx, preCtx); err != nil {
+					logger.GetLogger(ctx).Warn().
+						Err(err).
+						Str("app_id", appID).
+						Str("org_id", auth.GetOrgID(ctx)).
+						Str("policy_type", string(policy.Type())).
+						Str("model", modelName).
+						Int("estimated_tokens", estimatedTokens).
+						Msg("Policy check failed")
+					// Give back tokens and slots taken by policies that already passed
+					releaseReservations(ctx, pe.engine, append(reservations, preCtx.Reservations...))
+					policies.ReleaseLeases(ctx, append(leases, preCtx.Leases...))
+					resp := denyPolicy(policy.Type(), err)
+					limits.add(preCtx.RateLimits)
+					limits.apply(resp.Header)
+					return resp, nil
+				}
+			}
+			override = preCtx.ModelOverride
+			if preCtx.Priority != nil {
+				priority = preCtx.Priority
+			}
+			reservations = append(reservations, preCtx.Reservations...)
+			leases = append(leases, preCtx.Leases...)
+			limits.add(preCtx.RateLimits)
+			body, messages, prompt = preCtx.Body, preCtx.Messages, preCtx.Prompt
+			rewritten = rewritten || preCtx.BodyRewritten
+			for _, tag := range preCtx.Tags {
+				if !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+			for key, value := range preCtx.Metadata {
+				if metadata == nil {
+					metadata = map[string]any{}
+				}
+				metadata[key] = value
+			}
+		}
+		if len(reservations) > 0 {
+			ctx = policies.WithReservations(ctx, reservations)
+			r = r.WithContext(ctx)
+		}
+		if priority != nil {
+			ctx = policies.WithPriority(ctx, *priority)
+			r = r.WithContext(ctx)
+		}
+		if len(tags) > 0 {
+			logger.GetLogger(ctx).Info().
+				Str("app_id", appID).
+				Strs("tags", tags).
+				Msg("Policies tagged request")
+			ctx = policies.WithTags(ctx, tags)
+			r = r.WithContext(ctx)
+		}
+		if len(metadata) > 0 {
+			ctx = policies.WithMetadata(ctx, metadata)
+			r = r.WithContext(ctx)
+		}
+
+		// A policy rewrote the body (e.g. clamped max_tokens or masked PII)
+		if rewritten {
+			setRequestBody(r, body)
+			transformed := *parsedReq
+			transformed.Body = body
+			transformed.RequestSize = len(body)
+			transformed.EstimatedTokens = estimatedTokens
+			transformed.Messages = toAuthMessages(messages)
+			transformed.Prompt = prompt
+			parsedReq = &transformed
+			ctx = auth.WithParsedRequest(ctx, parsedReq)
+			r = r.WithContext(ctx)
+		}
+
+		// A policy asked for a different model (e.g. budget downgrade)
+		if override != "" && override != parsedReq.Model && !provider.IsAccountScopedPath(auth.GetEndpoint(ctx)) {
+			logger.GetLogger(ctx).Info().
+				Str("app_id", appID).
+				Str("model", parsedReq.Model).
+				Str("override", override).
+				Msg("Rerouting request to policy-selected model")
+			if err := rewriteModel(r, override); err != nil {
+				releaseReservations(ctx, pe.engine, reservations)
+				policies.ReleaseLeases(ctx, leases)
+				return deny(500, "failed to reroute request"), nil
+			}
+			rerouted := *parsedReq
+			rerouted.Model = override
+			ctx = auth.WithModelName(auth.WithParsedRequest(ctx, &rerouted), override)
+			r = r.WithContext(ctx)
+		}
+
+		// Continue with request, reporting the caller's remaining quota
+		resp, err := next.RoundTrip(r)
+		if err == nil && resp != nil && len(limits) > 0 {
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+			limits.apply(resp.Header)
+		}
+		holdLeases(ctx, resp, err, leases)
+		return resp, err
 	})
 }
 
+// requestScope collects the IDs policies can be attached to from the auth context
+func requestScope(ctx context.Context) policies.Scope {
+	return policies.Scope{
+		OrgID:  auth.GetOrgID(ctx),
+		AppID:  auth.GetAppID(ctx),
+		UserID: auth.GetUserID(ctx),
+		KeyID:  auth.GetKeyID(ctx),
+	}
+}
+
+// reservationSettler is implemented by policy engines that can release token
+// reservations (the production *policies.Engine)
+type reservationSettler interface {
+	SettleReservations(ctx context.Context, reservations []policies.TokenReservation, actualTokens int) error
+}
+
+// releaseReservations returns reservations for a request that will not be
+// sent upstream
+func releaseReservations(ctx context.Context, engine PolicyLoader, reservations []policies.TokenReservation) {
+	settler, ok := engine.(reservationSettler)
+	if !ok || len(reservations) == 0 {
+		return
+	}
+	if err := settler.SettleReservations(ctx, reservations, 0); err != nil {
+		logger.GetLogger(ctx).Warn().Err(err).Msg("Failed to release token reservations")
+	}
+}
+
+// holdLeases keeps concurrency slots until the response body has been fully
+// read or closed (the end of a stream), or releases them straight away when
+// there is no body to wait for
+func holdLeases(ctx context.Context, resp *http.Response, err error, leases []*policies.ConcurrencyLease) {
+	if len(leases) == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	if err != nil || resp == nil || resp.Body == nil {
+		policies.ReleaseLeases(ctx, leases)
+		return
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: func() { policies.ReleaseLeases(ctx, leases) }}
+}
+
+// releasingBody runs release once the body hits EOF or is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// rewriteModel points an already-rewritten upstream request at another model:
+// the JSON body's model field, then the provider's routing is run again so
+// the request goes to one of that model's deployments with its credentials.
+func rewriteModel(r *http.Request, to string) error {
+	if err := provider.RewriteJSONModel(r, to); err != nil {
+		return err
+	}
+	if r.Body != nil {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	}
+	if route := auth.GetRouter(r.Context()); route != nil {
+		return route(r, to)
+	}
+	return nil
+}
+
+// setRequestBody replaces the upstream request body and fixes Content-Length
+func setRequestBody(r *http.Request, body []byte) {
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	provider.ForceContentLength(r, len(body))
+}
+
+// toPolicyMessages copies parsed chat messages into the form policies see
+func toPolicyMessages(messages []auth.Message) []policies.Message {
+	if len(messages) == 0 {
+		return nil
+	}
+	out := make([]policies.Message, len(messages))
+	for i, msg := range messages {
+		out[i] = policies.Message{Role: msg.Role, Content: msg.Content}
+		for _, img := range msg.Images {
+			out[i].Images = append(out[i].Images, policies.Image(img))
+		}
+	}
+	return out
+}
+
+// toAuthMessages is the inverse of toPolicyMessages
+func toAuthMessages(messages []policies.Message) []auth.Message {
+	if len(messages) == 0 {
+		return nil
+	}
+	out := make([]auth.Message, len(messages))
+	for i, msg := range messages {
+		out[i] = auth.Message{Role: msg.Role, Content: msg.Content}
+		for _, img := range msg.Images {
+			out[i].Images = append(out[i].Images, auth.Image(img))
+		}
+	}
+	return out
+}
+
+// toPolicyTools copies parsed tool definitions into the form policies see
+func toPolicyTools(tools []auth.Tool) []policies.Tool {
+	if len(tools) == 0 {
+		return nil
+	}
+	out := make([]policies.Tool, len(tools))
+	for i, tool := range tools {
+		out[i] = policies.Tool{Name: tool.Name, Parameters: tool.Parameters}
+	}
+	return out
+}
+
 // roundTripFunc is a type adapter for http.RoundTripper
 type roundTripFunc func(*http.Request) (*http.Response, error)
 
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/tokens"
+	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
+)
+
+// defaultSimulatedEndpoint is the endpoint sample requests are sent to when
+// none is given
+const defaultSimulatedEndpoint = "/v1/chat/completions"
+
+// PolicySimulator shows how the gateway's policies would handle a sample
+// request, parsing it the way RequestBuffer and PolicyEnforcer do
+type PolicySimulator struct {
+	engine    *policies.Engine
+	buffer    *RequestBuffer
+	estimator *tokens.Estimator
+}
+
+// NewPolicySimulator creates a new policy simulator
+func NewPolicySimulator(engine *policies.Engine) *PolicySimulator {
+	return &PolicySimulator{
+		engine:    engine,
+		buffer:    NewRequestBuffer(),
+		estimator: tokens.NewEstimator(),
+	}
+}
+
+// Simulate runs body through the policies of keyID's scope, or appID's when
+// no key is given, as a request to endpoint with the given headers. The app
+// or key must belong to orgID. Nothing is sent upstream and no counters
+// change.
+func (ps *PolicySimulator) Simulate(ctx context.Context, orgID, appID, keyID, endpoint string, header http.Header, body []byte) (*policies.Simulation, error) {
+	if endpoint == "" {
+		endpoint = defaultSimulatedEndpoint
+	}
+	if provider.IsAccountScopedPath(endpoint) {
+		return nil, fmt.Errorf("files and batch requests can't be simulated")
+	}
+
+	scope, keyMetadata, err := ps.engine.ResolveScope(ctx, orgID, appID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if header != nil {
+		r.Header = header.Clone()
+	}
+
+	parsed := ps.buffer.parseRequest(ctx, body)
+	return ps.engine.Simulate(ctx, scope, &policies.PreRequestContext{
+		Request:          r,
+		OrgID:            scope.OrgID,
+		AppID:            scope.AppID,
+		APIKeyID:         scope.KeyID,
+		UserID:           scope.UserID,
+		KeyMetadata:      keyMetadata,
+		Endpoint:         endpoint,
+		Model:            parsed.Model,
+		EstimatedTokens:  parsed.EstimatedTokens,
+		RequestSizeBytes: parsed.RequestSize,
+		Stream:           parsed.Stream,
+		Messages:         toPolicyMessages(parsed.Messages),
+		Prompt:           parsed.Prompt,
+		Tools:            toPolicyTools(parsed.Tools),
+		ToolChoice:       parsed.ToolChoice,
+		ForcedTool:       parsed.ForcedTool,
+		Body:             body,
+		Header:           r.Header,
+		CallerHeader:     header,
+	}, ps.estimator.EstimateRequest)
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/async"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/scheduling"
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+)
+
+// PriorityScheduler admits requests to saturated deployments in priority
+// order. It runs after policy enforcement, which assigns each request a tier.
+type PriorityScheduler struct {
+	scheduler *scheduling.Scheduler
+	engine    PolicyLoader // releases token reservations of shed requests
+}
+
+// NewPriorityScheduler creates a new priority scheduler middleware
+func NewPriorityScheduler(scheduler *scheduling.Scheduler, engine PolicyLoader) *PriorityScheduler {
+	return &PriorityScheduler{scheduler: scheduler, engine: engine}
+}
+
+// Middleware returns a RoundTripper middleware that queues or sheds requests
+// for saturated deployments and notices when upstream throttles
+func (ps *PriorityScheduler) Middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		ctx := r.Context()
+
+		// Only model calls from authenticated apps are scheduled
+		appID := auth.GetAppID(ctx)
+		modelName := auth.GetModelName(ctx)
+		if appID == "" || modelName == "" {
+			return next.RoundTrip(r)
+		}
+
+		deployment := auth.GetProvider(ctx) + ":" + r.URL.Host + ":" + modelName
+		priority := policies.GetPriority(ctx)
+		release, err := ps.scheduler.Acquire(ctx, scheduling.Request{
+			Deployment: deployment,
+			App:        appID,
+			Rank:       slices.Index(policies.PriorityTiers, priority.Tier),
+			MaxWait:    priority.MaxWait,
+		})
+		if err != nil {
+			logger.GetLogger(ctx).Warn().
+				Err(err).
+				Str("app_id", appID).
+				Str("deployment", deployment).
+				Str("priority", priority.Tier).
+				Msg("Deployment saturated, shedding request")
+			releaseReservations(context.WithoutCancel(ctx), ps.engine, policies.GetReservations(ctx))
+			if !errors.Is(err, scheduling.ErrShed) {
+				return nil, err
+			}
+			return shed(priority.Tier), nil
+		}
+
+		resp, err := next.RoundTrip(r)
+		if err == nil && resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			ps.scheduler.Throttled(deployment, parseRetryAfter(resp.Header, time.Now()))
+		}
+		if err != nil || resp == nil || resp.Body == nil {
+			release()
+			return resp, err
+		}
+		resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+		return resp, nil
+	})
+}
+
+// shedRetryAfter is the retry hint given to shed requests
+const shedRetryAfter = 5 * time.Second
+
+// shed is the response for a request dropped because its deployment is saturated
+func shed(tier string) *http.Response {
+	resp := problemResponse(policyProblem{
+		Title:  "deployment saturated",
+		Status: http.StatusServiceUnavailable,
+		Detail: tier + " priority request shed while the deployment is saturated",
+	})
+	resp.Header.Set("Retry-After", retryAfterSeconds(shedRetryAfter))
+	return resp
+}
+
+// parseRetryAfter reads how long upstream asked us to back off: the
+// retry-after-ms header Azure OpenAI and OpenAI send, else Retry-After in
+// seconds or as an HTTP date. Missing or invalid values give zero.
+func parseRetryAfter(h http.Header, now time.Time) time.Duration {
+	if ms, err := strconv.Atoi(h.Get("Retry-After-Ms")); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return async.ParseRetryAfter(h.Get("Retry-After"), now)
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/scheduling"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+// statusRoundTripper answers every request with a fixed status and headers
+type statusRoundTripper struct {
+	status int
+	header http.Header
+	calls  int
+}
+
+func (s *statusRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	s.calls++
+	header := s.header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: s.status, Header: header, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func scheduledRequest(appID string, priority *policies.Priority, reservations ...policies.TokenReservation) *http.Request {
+	req := httptest.NewRequest("POST", "https://aoai.example.com/openai/deployments/gpt-4/chat/completions", strings.NewReader(`{}`))
+	ctx := auth.WithAppID(req.Context(), appID)
+	ctx = auth.WithProvider(ctx, "azure")
+	ctx = auth.WithModelName(ctx, "gpt-4")
+	if priority != nil {
+		ctx = policies.WithPriority(ctx, *priority)
+	}
+	if len(reservations) > 0 {
+		ctx = policies.WithReservations(ctx, reservations)
+	}
+	return req.WithContext(ctx)
+}
+
+func TestPriorityScheduler(t *testing.T) {
+	t.Run("ShedsLowTierWhenFull", func(t *testing.T) {
+		engine := &settlingPolicyEngine{}
+		ps := NewPriorityScheduler(scheduling.NewScheduler(len(policies.PriorityTiers), 1, time.Second), engine)
+		upstream := &statusRoundTripper{status: 200}
+
+		first, err := ps.Middleware(upstream).RoundTrip(scheduledRequest("app-1", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, first.StatusCode)
+
+		low := &policies.Priority{Tier: model.PriorityLow}
+		reservation := policies.TokenReservation{Algorithm: model.RateLimitFixedWindow}
+		resp, err := ps.Middleware(upstream).RoundTrip(scheduledRequest("app-2", low, reservation))
+		require.NoError(t, err)
+		assert.Equal(t, 503, resp.StatusCode)
+		assert.Equal(t, "5", resp.Header.Get("Retry-After"))
+		assert.Equal(t, 1, upstream.calls)
+		assert.Len(t, engine.settled, 1, "the shed request's token reservation is released")
+
+		// Closing the first response frees the slot
+		require.NoError(t, first.Body.Close())
+		resp, err = ps.Middleware(upstream).RoundTrip(scheduledRequest("app-2", low))
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+	})
+
+	t.Run("QueuedHigherTierGetsFreedSlot", func(t *testing.T) {
+		ps := NewPriorityScheduler(scheduling.NewScheduler(len(policies.PriorityTiers), 1, time.Second), &mockPolicyEngine{})
+		upstream := &statusRoundTripper{status: 200}
+
+		first, err := ps.Middleware(upstream).RoundTrip(scheduledRequest("app-1", nil))
+		require.NoError(t, err)
+
+		done := make(chan *http.Response)
+		go func() {
+			resp, _ := ps.Middleware(upstream).RoundTrip(scheduledRequest("app-2", &policies.Priority{Tier: model.PriorityHigh, MaxWait: 5 * time.Second}))
+			done <- resp
+		}()
+		time.Sleep(20 * time.Millisecond)
+		_, _ = io.ReadAll(first.Body) // EOF releases too
+
+		resp := <-done
+		require.NotNil(t, resp)
+		assert.Equal(t, 200, resp.StatusCode)
+	})
+
+	t.Run("UpstreamThrottlingNarrowsLowerTiers", func(t *testing.T) {
+		// During the cooldown critical may have 2 in flight, standard 1 and low none
+		ps := NewPriorityScheduler(scheduling.NewScheduler(len(policies.PriorityTiers), 2, time.Second), &mockPolicyEngine{})
+		throttled := &statusRoundTripper{status: 429, header: http.Header{"Retry-After": []string{"30"}}}
+
+		resp, err := ps.Middleware(throttled).RoundTrip(scheduledRequest("app-1", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 429, resp.StatusCode)
+		resp.Body.Close()
+
+		// Standard traffic keeps a share of the deployment...
+		standard := &policies.Priority{Tier: model.PriorityStandard}
+		resp, err = ps.Middleware(throttled).RoundTrip(scheduledRequest("app-2", standard))
+		require.NoError(t, err)
+		assert.Equal(t, 2, throttled.calls)
+		resp.Body.Close()
+
+		// ...low priority traffic sheds rather than hammering it...
+		low := &policies.Priority{Tier: model.PriorityLow}
+		resp, err = ps.Middleware(throttled).RoundTrip(scheduledRequest("app-3", low))
+		require.NoError(t, err)
+		assert.Equal(t, 503, resp.StatusCode)
+		assert.Equal(t, 2, throttled.calls)
+
+		// ...and critical traffic still goes through
+		critical := &policies.Priority{Tier: model.PriorityCritical}
+		resp, err = ps.Middleware(throttled).RoundTrip(scheduledRequest("app-4", critical))
+		require.NoError(t, err)
+		assert.Equal(t, 3, throttled.calls)
+		resp.Body.Close()
+	})
+
+	t.Run("UpstreamThrottlingWithoutCapShedsNothing", func(t *testing.T) {
+		ps := NewPriorityScheduler(scheduling.NewScheduler(len(policies.PriorityTiers), 0, time.Second), &mockPolicyEngine{})
+		throttled := &statusRoundTripper{status: 429, header: http.Header{"Retry-After": []string{"30"}}}
+
+		for _, app := range []string{"app-1", "app-2"} {
+			resp, err := ps.Middleware(throttled).RoundTrip(scheduledRequest(app, nil))
+			require.NoError(t, err)
+			assert.Equal(t, 429, resp.StatusCode)
+			resp.Body.Close()
+		}
+		assert.Equal(t, 2, throttled.calls)
+	})
+
+	t.Run("NonModelRequestsPassThrough", func(t *testing.T) {
+		ps := NewPriorityScheduler(scheduling.NewScheduler(len(policies.PriorityTiers), 1, time.Second), &mockPolicyEngine{})
+		upstream := &statusRoundTripper{status: 200}
+		req := httptest.NewRequest("GET", "https://aoai.example.com/openai/files", nil)
+		req = req.WithContext(auth.WithAppID(req.Context(), "app-1"))
+
+		for range 3 {
+			resp, err := ps.Middleware(upstream).RoundTrip(req)
+			require.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+		}
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 1500*time.Millisecond, parseRetryAfter(http.Header{"Retry-After-Ms": {"1500"}, "Retry-After": {"2"}}, now))
+	assert.Equal(t, 2*time.Second, parseRetryAfter(http.Header{"Retry-After": {"2"}}, now))
+	assert.Equal(t, time.Minute, parseRetryAfter(http.Header{"Retry-After": {now.Add(time.Minute).Format(http.TimeFormat)}}, now))
+	assert.Zero(t, parseRetryAfter(http.Header{"Retry-After": {"soon"}}, now))
+	assert.Zero(t, parseRetryAfter(http.Header{}, now))
+}
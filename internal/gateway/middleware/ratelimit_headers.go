@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+// rateLimitHeaders collects the tightest quota per metric across every rate
+// limit checked for a request, for OpenAI-compatible x-ratelimit-* headers
+type rateLimitHeaders map[string]policies.RateLimitStatus
+
+// add merges statuses, keeping the one with the least remaining per metric
+func (h rateLimitHeaders) add(statuses []policies.RateLimitStatus) {
+	for _, s := range statuses {
+		cur, ok := h[s.Metric]
+		if !ok || s.Remaining < cur.Remaining || (s.Remaining == cur.Remaining && s.ResetAfter > cur.ResetAfter) {
+			h[s.Metric] = s
+		}
+	}
+}
+
+// apply sets the gateway's limits on a response, replacing any the upstream
+// sent for its own account. Retry-After is added once a quota is used up.
+func (h rateLimitHeaders) apply(header http.Header) {
+	var retry time.Duration
+	for metric, s := range h {
+		header.Set("x-ratelimit-limit-"+metric, strconv.FormatInt(s.Limit, 10))
+		header.Set("x-ratelimit-remaining-"+metric, strconv.FormatInt(s.Remaining, 10))
+		header.Set("x-ratelimit-reset-"+metric, s.ResetAfter.Round(time.Millisecond).String())
+		if s.Remaining == 0 {
+			retry = max(retry, s.ResetAfter)
+		}
+	}
+	if retry > 0 && header.Get("Retry-After") == "" {
+		header.Set("Retry-After", retryAfterSeconds(retry))
+	}
+}
+
+// retryAfterSeconds formats d for a Retry-After header: whole seconds, rounded
+// up so callers never retry early
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(max(1, int(math.Ceil(d.Seconds()))))
+}
+
+// policyProblem is the problem+json body returned when the gateway itself
+// refuses a request, e.g. when a policy rejects it
+type policyProblem struct {
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Policy string `json:"policy,omitempty"`
+}
+
+// denyPolicy builds the response for a failed policy PreCheck. Policies pick
+// the status and retry hint by returning a *policies.PolicyError; anything
+// else is treated as a 429.
+func denyPolicy(policyType model.PolicyType, err error) *http.Response {
+	code := http.StatusTooManyRequests
+	var retryAfter time.Duration
+	var perr *policies.PolicyError
+	if errors.As(err, &perr) {
+		if perr.Status != 0 {
+			code = perr.Status
+		}
+		retryAfter = perr.RetryAfter
+	}
+
+	resp := problemResponse(policyProblem{
+		Title:  "policy violation",
+		Status: code,
+		Detail: err.Error(),
+		Policy: string(policyType),
+	})
+	if retryAfter > 0 {
+		resp.Header.Set("Retry-After", retryAfterSeconds(retryAfter))
+	}
+	return resp
+}
+
+// problemResponse builds a problem+json response for p
+func problemResponse(p policyProblem) *http.Response {
+	body, _ := json.Marshal(p)
+	return &http.Response{
+		StatusCode:    p.Status,
+		Status:        fmt.Sprintf("%d %s", p.Status, http.StatusText(p.Status)),
+		Header:        http.Header{"Content-Type": []string{"application/problem+json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
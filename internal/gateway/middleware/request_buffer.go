@@ -6,9 +6,12 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/batch"
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/tokens"
+	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
 )
 
 // RequestBuffer buffers and parses the request body once
@@ -37,13 +40,31 @@ func (rb *RequestBuffer) Middleware(next http.RoundTripper) http.RoundTripper {
 			r.Body.Close()
 		}
 
-		// Replace body with a fresh reader for upstream
-		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-
 		// Parse request ONCE and extract all needed data
+		endpoint := auth.GetEndpoint(r.Context())
 		parsed := rb.parseRequest(r.Context(), bodyBytes)
+		if provider.IsAccountScopedPath(endpoint) {
+			parsed.BatchModels = batch.InputModels(r.Header.Get("Content-Type"), bodyBytes)
+		}
+
+		// Ask for the final usage chunk on streams so they can be metered
+		if streamUsageEndpoints[endpoint] {
+			bodyBytes, parsed.StreamUsageInjected = injectStreamUsage(bodyBytes)
+			if parsed.StreamUsageInjected {
+				r.ContentLength = int64(len(bodyBytes))
+				r.Header.Set("Content-Length", strconv.Itoa(len(bodyBytes)))
+			}
+		}
+
+		parsed.Body = bodyBytes
 
-		// Store parsed request in context (small struct, not full body)
+		// Replace body with a fresh reader for upstream
+		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		if parsed.StreamUsageInjected {
+			r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(bodyBytes)), nil }
+		}
+
+		// Store parsed request in context
 		ctx := auth.WithParsedRequest(r.Context(), parsed)
 		r = r.WithContext(ctx)
 
@@ -65,19 +86,27 @@ func (rb *RequestBuffer) parseRequest(ctx context.Context, body []byte) *auth.Pa
 	var req struct {
 		Model    string `json:"model"`
 		Messages []struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role    string                `json:"role"`
+			Content tokens.MessageContent `json:"content"`
 		} `json:"messages,omitempty"`
-		Prompt string `json:"prompt,omitempty"`
+		Prompt       tokens.PromptContent `json:"prompt,omitempty"`
+		Stream       bool                 `json:"stream,omitempty"`
+		Tools        []requestTool        `json:"tools,omitempty"`
+		Functions    []requestToolSchema  `json:"functions,omitempty"`
+		ToolChoice   json.RawMessage      `json:"tool_choice,omitempty"`
+		FunctionCall json.RawMessage      `json:"function_call,omitempty"`
 	}
 
 	if err := json.Unmarshal(body, &req); err != nil {
-		// If parsing fails, return partial data
+		// If parsing fails, return partial data. A JSON body of an unexpected
+		// shape may still carry content policies can't see.
+		parsed.Unreadable = json.Valid(body)
 		return parsed
 	}
 
 	// Extract model
 	parsed.Model = req.Model
+	parsed.Stream = req.Stream
 
 	// Convert messages
 	if len(req.Messages) > 0 {
@@ -85,13 +114,34 @@ func (rb *RequestBuffer) parseRequest(ctx context.Context, body []byte) *auth.Pa
 		for i, msg := range req.Messages {
 			parsed.Messages[i] = auth.Message{
 				Role:    msg.Role,
-				Content: msg.Content,
+				Content: msg.Content.Text,
+			}
+			for _, img := range msg.Content.Images {
+				parsed.Messages[i].Images = append(parsed.Messages[i].Images, auth.Image{
+					URL:    img.URL,
+					Inline: img.Inline(),
+					Size:   img.Size(),
+					Detail: img.Detail,
+				})
 			}
 		}
 	}
 
 	// Extract prompt (for completion endpoints)
-	parsed.Prompt = req.Prompt
+	parsed.Prompt = string(req.Prompt)
+
+	// Tools, including the legacy functions/function_call fields
+	for _, tool := range req.Tools {
+		parsed.Tools = append(parsed.Tools, tool.parse())
+	}
+	for _, fn := range req.Functions {
+		parsed.Tools = append(parsed.Tools, auth.Tool{Name: fn.Name, Parameters: fn.Parameters})
+	}
+	choice := req.ToolChoice
+	if len(choice) == 0 {
+		choice = req.FunctionCall
+	}
+	parsed.ToolChoice, parsed.ForcedTool = parseToolChoice(choice)
 
 	// Estimate tokens using the parsed data
 	estimatedTokens, err := rb.estimator.EstimateRequest(ctx, parsed.Model, body)
@@ -101,3 +151,56 @@ func (rb *RequestBuffer) parseRequest(ctx context.Context, body []byte) *auth.Pa
 
 	return parsed
 }
+
+// requestToolSchema is a named tool definition: a function tool's function,
+// a custom tool's custom, or a legacy functions entry
+type requestToolSchema struct {
+	Name       string          `json:"name"`
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+}
+
+// requestTool is an entry of a chat request's tools array
+type requestTool struct {
+	Type     string            `json:"type"`
+	Function requestToolSchema `json:"function"`
+	Custom   requestToolSchema `json:"custom"`
+}
+
+// parse names the tool; built-in tools without a name go by their type
+func (t requestTool) parse() auth.Tool {
+	switch {
+	case t.Function.Name != "":
+		return auth.Tool{Name: t.Function.Name, Parameters: t.Function.Parameters}
+	case t.Custom.Name != "":
+		return auth.Tool{Name: t.Custom.Name}
+	default:
+		return auth.Tool{Name: t.Type}
+	}
+}
+
+// parseToolChoice reads tool_choice or function_call: a mode string, an
+// object naming the tool the model must call, or an allowed_tools choice
+func parseToolChoice(raw json.RawMessage) (choice, forced string) {
+	if len(raw) == 0 {
+		return "", ""
+	}
+	if err := json.Unmarshal(raw, &choice); err == nil {
+		return choice, ""
+	}
+	var named struct {
+		requestTool
+		Name         string `json:"name"`
+		AllowedTools struct {
+			Mode string `json:"mode"`
+		} `json:"allowed_tools"`
+	}
+	if err := json.Unmarshal(raw, &named); err != nil {
+		return "", ""
+	}
+	for _, name := range []string{named.Name, named.Function.Name, named.Custom.Name} {
+		if name != "" {
+			return "required", name
+		}
+	}
+	return named.AllowedTools.Mode, ""
+}
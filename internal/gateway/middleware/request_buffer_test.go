@@ -82,6 +82,30 @@ func TestRequestBuffer_Middleware(t *testing.T) {
 		assert.Greater(t, receivedParsed.EstimatedTokens, 0)
 	})
 
+	t.Run("StreamingRequestIncludesUsage", func(t *testing.T) {
+		body := `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hi"}]}`
+		req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader([]byte(body)))
+		req = req.WithContext(auth.WithEndpoint(req.Context(), "/v1/chat/completions"))
+
+		var forwarded []byte
+		var receivedParsed *auth.ParsedRequest
+		next := &testMockRoundTripper{
+			responseBody: []byte(`{}`),
+			checkRequest: func(r *http.Request) {
+				forwarded, _ = io.ReadAll(r.Body)
+				receivedParsed = auth.GetParsedRequest(r.Context())
+				assert.Equal(t, int64(len(forwarded)), r.ContentLength)
+			},
+		}
+
+		_, err := buffer.Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		assert.Contains(t, string(forwarded), `"stream_options":{"include_usage":true}`)
+		assert.True(t, receivedParsed.StreamUsageInjected)
+		assert.Equal(t, len(body), receivedParsed.RequestSize)
+		assert.Equal(t, forwarded, receivedParsed.Body)
+	})
+
 	t.Run("ValidCompletionRequest", func(t *testing.T) {
 		body := `{
 			"model": "text-davinci-003",
@@ -163,6 +187,7 @@ func TestRequestBuffer_parseRequest(t *testing.T) {
 		assert.Nil(t, parsed.Messages)
 		assert.Empty(t, parsed.Prompt)
 		assert.Equal(t, 0, parsed.EstimatedTokens)
+		assert.False(t, parsed.Unreadable, "invalid JSON is left to the provider to reject")
 	})
 
 	t.Run("ChatCompletionRequest", func(t *testing.T) {
@@ -195,6 +220,83 @@ func TestRequestBuffer_parseRequest(t *testing.T) {
 		assert.Greater(t, parsed.EstimatedTokens, 0)
 	})
 
+	t.Run("PromptArray", func(t *testing.T) {
+		parsed := buffer.parseRequest(ctx, []byte(`{"model": "gpt-3.5-turbo-instruct", "prompt": ["First", "Second"]}`))
+		assert.Equal(t, "First\nSecond", parsed.Prompt)
+		assert.False(t, parsed.Unreadable)
+	})
+
+	t.Run("TypeMismatch", func(t *testing.T) {
+		parsed := buffer.parseRequest(ctx, []byte(`{"model": "gpt-4", "messages": [{"role": "user", "content": "my card is 4111 1111 1111 1111"}], "prompt": [1, 2, 3]}`))
+		assert.True(t, parsed.Unreadable, "content that couldn't be decoded is flagged")
+		assert.Nil(t, parsed.Messages)
+	})
+
+	t.Run("StreamFlag", func(t *testing.T) {
+		parsed := buffer.parseRequest(ctx, []byte(`{"model": "gpt-4", "stream": true}`))
+		assert.True(t, parsed.Stream)
+		parsed = buffer.parseRequest(ctx, []byte(`{"model": "gpt-4"}`))
+		assert.False(t, parsed.Stream)
+	})
+
+	t.Run("MultimodalContent", func(t *testing.T) {
+		body := `{
+			"model": "gpt-4o",
+			"messages": [
+				{"role": "system", "content": "You describe images."},
+				{"role": "user", "content": [
+					{"type": "text", "text": "What is in these?"},
+					{"type": "image_url", "image_url": {"url": "https://example.com/cat.png", "detail": "low"}},
+					{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw0KGgo="}}
+				]}
+			]
+		}`
+		parsed := buffer.parseRequest(ctx, []byte(body))
+		assert.Equal(t, "gpt-4o", parsed.Model)
+		require.Len(t, parsed.Messages, 2)
+		assert.Equal(t, "You describe images.", parsed.Messages[0].Content)
+		assert.Equal(t, "What is in these?", parsed.Messages[1].Content)
+		assert.Equal(t, []auth.Image{
+			{URL: "https://example.com/cat.png", Detail: "low"},
+			{URL: "data:image/png;base64,iVBORw0KGgo=", Inline: true, Size: 8},
+		}, parsed.Messages[1].Images)
+	})
+
+	t.Run("Tools", func(t *testing.T) {
+		body := `{
+			"model": "gpt-4",
+			"messages": [{"role": "user", "content": "Weather in Paris?"}],
+			"tools": [
+				{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}},
+				{"type": "custom", "custom": {"name": "run_sql"}},
+				{"type": "web_search"}
+			],
+			"tool_choice": {"type": "function", "function": {"name": "get_weather"}}
+		}`
+		parsed := buffer.parseRequest(ctx, []byte(body))
+		require.Len(t, parsed.Tools, 3)
+		assert.Equal(t, "get_weather", parsed.Tools[0].Name)
+		assert.JSONEq(t, `{"type": "object"}`, string(parsed.Tools[0].Parameters))
+		assert.Equal(t, "run_sql", parsed.Tools[1].Name)
+		assert.Equal(t, "web_search", parsed.Tools[2].Name)
+		assert.Equal(t, "required", parsed.ToolChoice)
+		assert.Equal(t, "get_weather", parsed.ForcedTool)
+
+		parsed = buffer.parseRequest(ctx, []byte(`{"model": "gpt-4", "tools": [], "tool_choice": "none"}`))
+		assert.Empty(t, parsed.Tools)
+		assert.Equal(t, "none", parsed.ToolChoice)
+		assert.Empty(t, parsed.ForcedTool)
+	})
+
+	t.Run("LegacyFunctions", func(t *testing.T) {
+		body := `{"model": "gpt-4", "functions": [{"name": "lookup", "parameters": {"type": "object"}}], "function_call": {"name": "lookup"}}`
+		parsed := buffer.parseRequest(ctx, []byte(body))
+		require.Len(t, parsed.Tools, 1)
+		assert.Equal(t, "lookup", parsed.Tools[0].Name)
+		assert.Equal(t, "required", parsed.ToolChoice)
+		assert.Equal(t, "lookup", parsed.ForcedTool)
+	})
+
 	t.Run("InvalidJSON", func(t *testing.T) {
 		body := `{"invalid": json}`
 		parsed := buffer.parseRequest(ctx, []byte(body))
@@ -0,0 +1,585 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
+)
+
+// ResponseGuard runs response guard policies over completions before the
+// caller receives them: redacting text, replacing it with a refusal, or
+// ending a stream early with an SSE error event
+type ResponseGuard struct{}
+
+// NewResponseGuard creates a new response guard middleware
+func NewResponseGuard() *ResponseGuard {
+	return &ResponseGuard{}
+}
+
+// Middleware returns a RoundTripper middleware that guards completions
+func (rg *ResponseGuard) Middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		ctx := r.Context()
+		checker := newCompletionChecker(ctx)
+		if checker == nil || provider.IsAccountScopedPath(auth.GetEndpoint(ctx)) {
+			return next.RoundTrip(r)
+		}
+
+		// Ask for an uncompressed response so it can be inspected
+		r.Header.Del("Accept-Encoding")
+
+		resp, err := next.RoundTrip(r)
+		if err != nil || resp == nil || resp.Body == nil || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return resp, err
+		}
+
+		contentType := resp.Header.Get("Content-Type")
+		switch {
+		case strings.HasPrefix(contentType, "text/event-stream"):
+			resp.Body = newGuardedStream(checker, resp.Body)
+			resp.ContentLength = -1
+			resp.Header.Del("Content-Length")
+		case strings.HasPrefix(contentType, "application/json"):
+			if err := checker.guardJSON(resp); err != nil {
+				return nil, err
+			}
+		}
+		return resp, nil
+	})
+}
+
+// completionChecker runs a request's response guards over completion text
+type completionChecker struct {
+	ctx    context.Context
+	guards []policies.Policy
+	appID  string
+	model  string
+	stream bool
+}
+
+// newCompletionChecker returns a checker for the request's response guard
+// policies, or nil when it has none
+func newCompletionChecker(ctx context.Context) *completionChecker {
+	policyList, _ := auth.GetPolicies(ctx).([]policies.Policy)
+	var guards []policies.Policy
+	for _, policy := range policyList {
+		if _, ok := policy.(policies.ResponseGuard); ok {
+			guards = append(guards, policy)
+		}
+	}
+	if len(guards) == 0 {
+		return nil
+	}
+	c := &completionChecker{ctx: ctx, guards: guards, appID: auth.GetAppID(ctx), model: auth.GetModelName(ctx)}
+	if parsedReq := auth.GetParsedRequest(ctx); parsedReq != nil {
+		c.stream = parsedReq.Stream
+		if c.model == "" {
+			c.model = parsedReq.Model
+		}
+	}
+	return c
+}
+
+// check runs every guard over text, returning the (possibly redacted) text or
+// the violation that withholds it
+func (c *completionChecker) check(text string, final bool) (string, error) {
+	resp := &policies.ResponseContext{AppID: c.appID, Model: c.model, Stream: c.stream, Text: text, Final: final}
+	for _, policy := range c.guards {
+		if err := policy.(policies.ResponseGuard).CheckResponse(c.ctx, resp); err != nil {
+			c.logViolation(policy.Type(), err)
+			return "", err
+		}
+	}
+	if resp.Redacted {
+		logger.GetLogger(c.ctx).Info().
+			Str("app_id", c.appID).
+			Str("model", c.model).
+			Msg("Response guard redacted completion")
+	}
+	return resp.Text, nil
+}
+
+// logViolation records why a completion was withheld
+func (c *completionChecker) logViolation(policyType model.PolicyType, err error) {
+	logger.GetLogger(c.ctx).Warn().
+		Err(err).
+		Str("app_id", c.appID).
+		Str("model", c.model).
+		Str("policy_type", string(policyType)).
+		Bool("stream", c.stream).
+		Msg("Response guard withheld completion")
+}
+
+// guardJSON checks each choice of a non-streamed completion, rewriting the
+// body when text is redacted or refused
+func (c *completionChecker) guardJSON(resp *http.Response) error {
+	raw, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	body := raw
+	if doc, ok := decodeJSONObject(raw); ok {
+		changed := false
+		choices, _ := doc["choices"].([]any)
+		for _, item := range choices {
+			choice, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			redacted, refused := c.guardToolCalls(choice)
+			if refused {
+				changed = true
+				continue
+			}
+			changed = changed || redacted
+			text, set := choiceText(choice)
+			if set == nil {
+				continue
+			}
+			checked, err := c.check(text, true)
+			if err != nil {
+				set(refusalText(err))
+				choice["finish_reason"] = "content_filter"
+				changed = true
+			} else if checked != text {
+				set(checked)
+				changed = true
+			}
+		}
+		if changed {
+			if updated, err := json.Marshal(doc); err == nil {
+				body = updated
+			}
+		}
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+// guardToolCalls checks the arguments of a non-streamed choice's tool calls,
+// redacting them in place. When a call is refused its tool calls are replaced
+// with a refusal message.
+func (c *completionChecker) guardToolCalls(choice map[string]any) (redacted, refused bool) {
+	message, _ := choice["message"].(map[string]any)
+	toolCalls, _ := message["tool_calls"].([]any)
+	for _, item := range toolCalls {
+		call, _ := item.(map[string]any)
+		function, _ := call["function"].(map[string]any)
+		args, ok := function["arguments"].(string)
+		if !ok {
+			continue
+		}
+		// Tool call arguments are never the whole completion
+		checked, err := c.check(args, false)
+		if err != nil {
+			delete(message, "tool_calls")
+			message["content"] = refusalText(err)
+			choice["finish_reason"] = "content_filter"
+			return false, true
+		}
+		if checked != args {
+			function["arguments"] = checked
+			redacted = true
+		}
+	}
+	return redacted, false
+}
+
+// choiceText returns the completion text of a non-streamed choice (chat
+// message content or completions text) and a setter for it
+func choiceText(choice map[string]any) (string, func(string)) {
+	if message, ok := choice["message"].(map[string]any); ok {
+		if content, ok := message["content"].(string); ok {
+			return content, func(s string) { message["content"] = s }
+		}
+		return "", nil
+	}
+	if text, ok := choice["text"].(string); ok {
+		return text, func(s string) { choice["text"] = s }
+	}
+	return "", nil
+}
+
+// refusalText is the completion shown in place of withheld output
+func refusalText(err error) string {
+	var violation *policies.ResponseViolation
+	if errors.As(err, &violation) && violation.Refusal != "" {
+		return violation.Refusal
+	}
+	return policies.DefaultRefusalMessage
+}
+
+// decodeJSONObject decodes a JSON object, keeping numbers exact
+func decodeJSONObject(raw []byte) (map[string]any, bool) {
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil || doc == nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+// guardWindow bounds how much streamed text is held back and re-checked:
+// matches up to this many bytes long are redacted before any of them is sent.
+const guardWindow = 256
+
+// streamKey identifies a streamed text: a choice's content (tool -1) or the
+// arguments of one of its tool calls
+type streamKey struct {
+	choice int
+	tool   int
+}
+
+// streamedText tracks one text of a streamed completion
+type streamedText struct {
+	sent strings.Builder // content already passed to the caller, for the final check
+	tail string          // the end of the text already sent, for matches that need context
+	held string          // unchecked text held back until it is guardWindow from the end
+	chat bool            // chat chunks carry delta.content, completions chunks text
+}
+
+// guardedStream checks an SSE completion stream as it is read. The last
+// guardWindow bytes of each text are held back so matches are complete
+// before they are sent; a violation, or a match reaching text already sent,
+// ends the stream with an error event.
+type guardedStream struct {
+	checker *completionChecker
+	src     io.ReadCloser
+	buf     []byte
+	pending bytes.Buffer
+	out     bytes.Buffer
+	err     error
+	texts   map[streamKey]*streamedText
+}
+
+func newGuardedStream(checker *completionChecker, src io.ReadCloser) *guardedStream {
+	return &guardedStream{checker: checker, src: src, buf: make([]byte, 4096), texts: map[streamKey]*streamedText{}}
+}
+
+// Read implements io.Reader for the guarded stream
+func (g *guardedStream) Read(p []byte) (int, error) {
+	for g.out.Len() == 0 && g.err == nil {
+		n, err := g.src.Read(g.buf)
+		g.pending.Write(g.buf[:n])
+		g.drain()
+		if err != nil && g.err == nil {
+			if g.pending.Len() > 0 {
+				g.process(bytes.Clone(g.pending.Bytes()))
+				g.pending.Reset()
+			}
+			g.flush(func(streamKey) bool { return true })
+			if g.err == nil {
+				g.err = err
+			}
+		}
+	}
+	if g.out.Len() > 0 {
+		return g.out.Read(p)
+	}
+	return 0, g.err
+}
+
+// Close implements io.Closer for the guarded stream
+func (g *guardedStream) Close() error {
+	return g.src.Close()
+}
+
+// drain processes every complete event in pending
+func (g *guardedStream) drain() {
+	for g.err == nil {
+		data := g.pending.Bytes()
+		n := eventEnd(data)
+		if n < 0 {
+			return
+		}
+		event := bytes.Clone(data[:n])
+		g.pending.Next(n)
+		g.process(event)
+	}
+}
+
+// process checks the text in one SSE event and writes the event, rewritten
+// if anything was held back or redacted
+func (g *guardedStream) process(event []byte) {
+	payload, ok := eventPayload(event)
+	if !ok {
+		g.out.Write(event)
+		return
+	}
+	if string(payload) == "[DONE]" {
+		g.flush(func(streamKey) bool { return true })
+		if g.err == nil {
+			g.out.Write(event)
+		}
+		return
+	}
+	chunk, ok := decodeJSONObject(payload)
+	if !ok {
+		g.out.Write(event)
+		return
+	}
+
+	changed := false
+	choices, _ := chunk["choices"].([]any)
+	for _, item := range choices {
+		choice, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		index := jsonIndex(choice)
+		final := choice["finish_reason"] != nil
+
+		delta, chat := choice["delta"].(map[string]any)
+		if chat {
+			toolCalls, _ := delta["tool_calls"].([]any)
+			for _, item := range toolCalls {
+				call, _ := item.(map[string]any)
+				function, _ := call["function"].(map[string]any)
+				args, ok := function["arguments"].(string)
+				if !ok || (args == "" && !final) {
+					continue
+				}
+				release, err := g.advance(streamKey{choice: index, tool: jsonIndex(call)}, true, args, final)
+				if err != nil {
+					g.abort(err)
+					return
+				}
+				if release != args {
+					function["arguments"] = release
+					changed = true
+				}
+			}
+		}
+		if final {
+			// Release what other tool calls of the choice still hold first
+			g.flush(func(key streamKey) bool { return key.choice == index && key.tool >= 0 })
+			if g.err != nil {
+				return
+			}
+		}
+
+		var piece string
+		var set func(string)
+		if chat {
+			_, hasContent := delta["content"].(string)
+			piece, _ = delta["content"].(string)
+			set = func(s string) {
+				if s != "" || hasContent {
+					delta["content"] = s
+				}
+			}
+		} else if text, ok := choice["text"].(string); ok {
+			piece = text
+			set = func(s string) { choice["text"] = s }
+		} else {
+			continue
+		}
+		if piece == "" && !final {
+			continue
+		}
+
+		release, err := g.advance(streamKey{choice: index, tool: -1}, chat, piece, final)
+		if err != nil {
+			g.abort(err)
+			return
+		}
+		if release != piece {
+			set(release)
+			changed = true
+		}
+	}
+
+	if !changed {
+		g.out.Write(event)
+		return
+	}
+	updated, err := json.Marshal(chunk)
+	if err != nil {
+		g.out.Write(event)
+		return
+	}
+	g.out.WriteString("data: ")
+	g.out.Write(updated)
+	g.out.WriteString("\n\n")
+}
+
+// jsonIndex reads the "index" field of a choice or tool call
+func jsonIndex(item map[string]any) int {
+	if n, ok := item["index"].(json.Number); ok {
+		if v, err := n.Int64(); err == nil {
+			return int(v)
+		}
+	}
+	return 0
+}
+
+// advance adds a piece of streamed text and returns the text that may be
+// passed on now. Only the tail of the text already sent and the text held
+// back are checked, except for the final check of a choice's content, which
+// guards like the JSON schema need whole.
+func (g *guardedStream) advance(key streamKey, chat bool, piece string, final bool) (string, error) {
+	st, ok := g.texts[key]
+	if !ok {
+		st = &streamedText{chat: chat}
+		g.texts[key] = st
+	}
+	raw := st.held + piece
+	before := st.tail
+	if final && key.tool < 0 {
+		before = st.sent.String()
+	}
+	// Tool call arguments are never the whole completion
+	checked, err := g.checker.check(before+raw, final && key.tool < 0)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(checked, before) {
+		err := &policies.ResponseViolation{Reason: "response guard matched text that was already streamed"}
+		g.checker.logViolation(model.PolicyTypeResponseGuard, err)
+		return "", err
+	}
+
+	rest := checked[len(before):]
+	release := rest
+	st.held = ""
+	if !final {
+		cut := releasable(raw, rest)
+		release, st.held = rest[:cut], raw
+		if cut > 0 {
+			st.held = raw[len(raw)-(len(rest)-cut):]
+		}
+	}
+	st.tail = lookBehind(st.tail + release)
+	if key.tool < 0 {
+		st.sent.WriteString(release)
+	}
+	if final {
+		delete(g.texts, key)
+	}
+	return release, nil
+}
+
+// releasable returns how much of checked, the guarded form of raw, can be
+// sent: everything up to the last word boundary guardWindow from the end,
+// provided no redaction falls in the part held back. That part is kept
+// unredacted so it is checked again whole once the rest of a match arrives.
+func releasable(raw, checked string) int {
+	cut := len(checked) - guardWindow
+	if cut <= 0 {
+		return 0
+	}
+	if i := strings.LastIndexAny(checked[:cut], " \t\r\n"); i >= 0 {
+		cut = i + 1
+	} else {
+		for cut > 0 && !utf8.RuneStart(checked[cut]) {
+			cut--
+		}
+	}
+	kept := len(checked) - cut
+	if kept > len(raw) || checked[cut:] != raw[len(raw)-kept:] {
+		return 0
+	}
+	return cut
+}
+
+// lookBehind keeps the last guardWindow bytes of sent text, starting at a
+// word boundary where there is one
+func lookBehind(sent string) string {
+	if len(sent) <= guardWindow {
+		return sent
+	}
+	sent = sent[len(sent)-guardWindow:]
+	if i := strings.IndexAny(sent, " \t\r\n"); i >= 0 {
+		return sent[i+1:]
+	}
+	for len(sent) > 0 && !utf8.RuneStart(sent[0]) {
+		sent = sent[1:]
+	}
+	return sent
+}
+
+// flush releases the text still held back for the matching keys, as when
+// the stream ends without a finish_reason for every choice
+func (g *guardedStream) flush(match func(streamKey) bool) {
+	var keys []streamKey
+	for key := range g.texts {
+		if match(key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b streamKey) int {
+		if a.choice != b.choice {
+			return a.choice - b.choice
+		}
+		return a.tool - b.tool
+	})
+	for _, key := range keys {
+		st := g.texts[key]
+		if st.held == "" {
+			delete(g.texts, key)
+			continue
+		}
+		release, err := g.advance(key, st.chat, "", true)
+		if err != nil {
+			g.abort(err)
+			return
+		}
+		choice := map[string]any{"index": key.choice, "text": release}
+		switch {
+		case key.tool >= 0:
+			choice = map[string]any{"index": key.choice, "delta": map[string]any{"tool_calls": []any{
+				map[string]any{"index": key.tool, "function": map[string]any{"arguments": release}},
+			}}}
+		case st.chat:
+			choice = map[string]any{"index": key.choice, "delta": map[string]any{"content": release}}
+		}
+		updated, _ := json.Marshal(map[string]any{"choices": []any{choice}})
+		g.out.WriteString("data: ")
+		g.out.Write(updated)
+		g.out.WriteString("\n\n")
+	}
+}
+
+// abort ends the stream with a terminal error event
+func (g *guardedStream) abort(err error) {
+	payload, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message": err.Error(),
+			"type":    "policy_violation",
+			"policy":  string(model.PolicyTypeResponseGuard),
+		},
+	})
+	g.out.WriteString("event: error\ndata: ")
+	g.out.Write(payload)
+	g.out.WriteString("\n\n")
+	g.pending.Reset()
+	g.err = io.EOF
+}
+
+// eventPayload returns the data of an SSE event
+func eventPayload(event []byte) ([]byte, bool) {
+	for _, line := range eventLines(event) {
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, []byte("data:")) {
+			return bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:"))), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,283 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sseRoundTripper streams chat completion chunks, one byte per read so events
+// arrive split across reads. Lines end in crlf when set.
+type sseRoundTripper struct {
+	deltas []string
+	crlf   bool
+}
+
+func (m *sseRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body strings.Builder
+	for _, delta := range m.deltas {
+		content, _ := json.Marshal(delta)
+		fmt.Fprintf(&body, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%s},\"finish_reason\":null}]}\n\n", content)
+	}
+	body.WriteString("data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+	body.WriteString("data: [DONE]\n\n")
+	stream := body.String()
+	if m.crlf {
+		stream = strings.ReplaceAll(stream, "\n", "\r\n")
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(iotest.OneByteReader(strings.NewReader(stream))),
+	}, nil
+}
+
+// streamedContent joins the delta contents of an SSE chat stream
+func streamedContent(t *testing.T, stream string) string {
+	t.Helper()
+	var content strings.Builder
+	for _, event := range strings.Split(strings.ReplaceAll(stream, "\r\n", "\n"), "\n\n") {
+		payload, ok := strings.CutPrefix(event, "data: ")
+		if !ok || payload == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(payload), &chunk), payload)
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+		}
+	}
+	return content.String()
+}
+
+// streamedArguments joins the tool call arguments of an SSE chat stream
+func streamedArguments(t *testing.T, stream string) string {
+	t.Helper()
+	var args strings.Builder
+	for _, event := range strings.Split(stream, "\n\n") {
+		payload, ok := strings.CutPrefix(event, "data: ")
+		if !ok || payload == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					ToolCalls []struct {
+						Function struct {
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(payload), &chunk), payload)
+		for _, choice := range chunk.Choices {
+			for _, call := range choice.Delta.ToolCalls {
+				args.WriteString(call.Function.Arguments)
+			}
+		}
+	}
+	return args.String()
+}
+
+func TestResponseGuard_Middleware(t *testing.T) {
+	engine := policies.NewEngine(nil, kv.NewMemoryStore())
+
+	guardedRequest := func(t *testing.T, config string, stream bool) *http.Request {
+		t.Helper()
+		guard, err := engine.NewPolicy(model.PolicyTypeResponseGuard, []byte(config))
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		ctx := auth.WithAppID(req.Context(), "app-456")
+		ctx = auth.WithPolicies(ctx, []policies.Policy{guard})
+		ctx = auth.WithParsedRequest(ctx, &auth.ParsedRequest{Model: "gpt-4", Stream: stream})
+		return req.WithContext(ctx)
+	}
+
+	t.Run("RedactsCompletion", func(t *testing.T) {
+		var acceptEncoding string
+		next := &testMockRoundTripper{
+			responseBody: []byte(`{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"Mail jane@example.com"},"finish_reason":"stop"}]}`),
+			checkRequest: func(r *http.Request) { acceptEncoding = r.Header.Get("Accept-Encoding") },
+		}
+		req := guardedRequest(t, `{"pii_detectors":["email"],"action":"redact"}`, false)
+
+		resp, err := NewResponseGuard().Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.JSONEq(t, `{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"Mail [REDACTED_EMAIL]"},"finish_reason":"stop"}]}`, string(body))
+		assert.Equal(t, int64(len(body)), resp.ContentLength)
+		assert.Empty(t, acceptEncoding, "upstream response must be inspectable")
+	})
+
+	t.Run("RefusesCompletion", func(t *testing.T) {
+		next := &testMockRoundTripper{
+			responseBody: []byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"The password is hunter2"},"finish_reason":"stop"}]}`),
+		}
+		req := guardedRequest(t, `{"keywords":["hunter2"]}`, false)
+
+		resp, err := NewResponseGuard().Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		expected := fmt.Sprintf(`{"choices":[{"index":0,"message":{"role":"assistant","content":%q},"finish_reason":"content_filter"}]}`, policies.DefaultRefusalMessage)
+		assert.JSONEq(t, expected, string(body))
+	})
+
+	t.Run("StreamRedactsMatchSplitAcrossChunks", func(t *testing.T) {
+		next := &sseRoundTripper{deltas: []string{"Contact ", "jane", "@example", ".com", " today", "."}}
+		req := guardedRequest(t, `{"pii_detectors":["email"],"action":"redact"}`, true)
+
+		resp, err := NewResponseGuard().Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		assert.Equal(t, int64(-1), resp.ContentLength)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "Contact [REDACTED_EMAIL] today.", streamedContent(t, string(body)))
+		assert.True(t, strings.HasSuffix(string(body), "data: [DONE]\n\n"))
+	})
+
+	t.Run("StreamRedactsCRLFEvents", func(t *testing.T) {
+		next := &sseRoundTripper{deltas: []string{"Contact ", "jane", "@example", ".com", " today", "."}, crlf: true}
+		req := guardedRequest(t, `{"pii_detectors":["email"],"action":"redact"}`, true)
+
+		resp, err := NewResponseGuard().Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "Contact [REDACTED_EMAIL] today.", streamedContent(t, string(body)))
+		assert.True(t, strings.HasSuffix(string(body), "data: [DONE]\r\n\r\n"))
+	})
+
+	t.Run("StreamAbortedOnViolation", func(t *testing.T) {
+		intro := strings.Repeat("All is well here. ", 20)
+		next := &sseRoundTripper{deltas: []string{intro, "The ", "password ", "is ", "hunter2", " ok"}}
+		req := guardedRequest(t, `{"keywords":["hunter2"]}`, true)
+
+		resp, err := NewResponseGuard().Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		stream := string(body)
+
+		assert.NotContains(t, stream, "hunter2")
+		assert.NotContains(t, stream, "[DONE]")
+		assert.True(t, strings.HasSuffix(stream, "\n\n"))
+		events := strings.Split(strings.TrimSuffix(stream, "\n\n"), "\n\n")
+		last := events[len(events)-1]
+		assert.True(t, strings.HasPrefix(last, "event: error\ndata: "), last)
+		assert.Contains(t, last, `"type":"policy_violation"`)
+		sent := streamedContent(t, strings.Join(events[:len(events)-1], "\n\n"))
+		assert.NotEmpty(t, sent, "text well before the match is released")
+		assert.True(t, strings.HasPrefix(intro, sent), sent)
+	})
+
+	t.Run("StreamRedactsPhraseAcrossWords", func(t *testing.T) {
+		intro := strings.Repeat("Here is the plan. ", 20)
+		next := &sseRoundTripper{deltas: []string{intro, "It ", "is ", "project ", "blue ", "falcon", " for ", "now."}}
+		req := guardedRequest(t, `{"keywords":["project blue falcon"],"action":"redact"}`, true)
+
+		resp, err := NewResponseGuard().Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, intro+"It is [REDACTED_KEYWORD] for now.", streamedContent(t, string(body)))
+		assert.True(t, strings.HasSuffix(string(body), "data: [DONE]\n\n"))
+	})
+
+	t.Run("StreamRedactsLongCompletion", func(t *testing.T) {
+		var deltas []string
+		var want strings.Builder
+		for i := range 200 {
+			word := fmt.Sprintf("word%d ", i)
+			deltas = append(deltas, word)
+			want.WriteString(word)
+		}
+		deltas = append(deltas, "mail ", "jane", "@example.com", " now")
+		want.WriteString("mail [REDACTED_EMAIL] now")
+		next := &sseRoundTripper{deltas: deltas}
+		req := guardedRequest(t, `{"pii_detectors":["email"],"action":"redact"}`, true)
+
+		resp, err := NewResponseGuard().Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, want.String(), streamedContent(t, string(body)))
+	})
+
+	t.Run("RedactsToolCallArguments", func(t *testing.T) {
+		next := &testMockRoundTripper{
+			responseBody: []byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"send","arguments":"{\"to\":\"jane@example.com\"}"}}]},"finish_reason":"tool_calls"}]}`),
+		}
+		req := guardedRequest(t, `{"pii_detectors":["email"],"action":"redact"}`, false)
+
+		resp, err := NewResponseGuard().Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.JSONEq(t, `{"choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"send","arguments":"{\"to\":\"[REDACTED_EMAIL]\"}"}}]},"finish_reason":"tool_calls"}]}`, string(body))
+	})
+
+	t.Run("RefusesToolCallArguments", func(t *testing.T) {
+		next := &testMockRoundTripper{
+			responseBody: []byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"login","arguments":"{\"password\":\"hunter2\"}"}}]},"finish_reason":"tool_calls"}]}`),
+		}
+		req := guardedRequest(t, `{"keywords":["hunter2"]}`, false)
+
+		resp, err := NewResponseGuard().Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		expected := fmt.Sprintf(`{"choices":[{"index":0,"message":{"role":"assistant","content":%q},"finish_reason":"content_filter"}]}`, policies.DefaultRefusalMessage)
+		assert.JSONEq(t, expected, string(body))
+	})
+
+	t.Run("StreamRedactsToolCallArguments", func(t *testing.T) {
+		var body strings.Builder
+		body.WriteString("data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"send\",\"arguments\":\"\"}}]},\"finish_reason\":null}]}\n\n")
+		for _, piece := range []string{`{"to":"`, "jane", "@example", `.com"}`} {
+			args, _ := json.Marshal(piece)
+			fmt.Fprintf(&body, "data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":%s}}]},\"finish_reason\":null}]}\n\n", args)
+		}
+		body.WriteString("data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"tool_calls\"}]}\n\n")
+		body.WriteString("data: [DONE]\n\n")
+		next := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+				Body:       io.NopCloser(strings.NewReader(body.String())),
+			}, nil
+		})
+		req := guardedRequest(t, `{"pii_detectors":["email"],"action":"redact"}`, true)
+
+		resp, err := NewResponseGuard().Middleware(next).RoundTrip(req)
+		require.NoError(t, err)
+		out, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, `{"to":"[REDACTED_EMAIL]"}`, streamedArguments(t, string(out)))
+		assert.True(t, strings.HasSuffix(string(out), "data: [DONE]\n\n"))
+	})
+
+	t.Run("NoGuardsPassThrough", func(t *testing.T) {
+		responseBody := []byte(`{"choices":[{"message":{"content":"jane@example.com"}}]}`)
+		next := &testMockRoundTripper{responseBody: responseBody}
+		req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		ctx := auth.WithPolicies(req.Context(), []policies.Policy{&mockPolicy{policyType: model.PolicyTypeRateLimit}})
+
+		resp, err := NewResponseGuard().Middleware(next).RoundTrip(req.WithContext(ctx))
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		assert.True(t, bytes.Equal(responseBody, body))
+	})
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// streamUsageEndpoints accept stream_options.include_usage upstream
+var streamUsageEndpoints = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+}
+
+// injectStreamUsage adds stream_options.include_usage=true to a streaming
+// request that did not ask for it, so the final usage chunk is always sent
+// and the call can be metered. It reports whether the body was changed.
+func injectStreamUsage(body []byte) ([]byte, bool) {
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(body, &req); err != nil {
+		return body, false
+	}
+	var stream bool
+	if raw, ok := req["stream"]; !ok || json.Unmarshal(raw, &stream) != nil || !stream {
+		return body, false
+	}
+
+	opts := map[string]json.RawMessage{}
+	if raw, ok := req["stream_options"]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &opts); err != nil {
+			return body, false
+		}
+	}
+	var includeUsage bool
+	if raw, ok := opts["include_usage"]; ok && json.Unmarshal(raw, &includeUsage) == nil && includeUsage {
+		return body, false
+	}
+
+	opts["include_usage"] = json.RawMessage("true")
+	rawOpts, err := json.Marshal(opts)
+	if err != nil {
+		return body, false
+	}
+	req["stream_options"] = rawOpts
+	out, err := json.Marshal(req)
+	if err != nil {
+		return body, false
+	}
+	return out, true
+}
+
+// streamUsageFilter removes the usage-only chunk (empty choices, non-null
+// usage) from an SSE stream whose caller did not request it. Events are
+// passed through as soon as they are complete so streaming is not delayed.
+type streamUsageFilter struct {
+	src     io.ReadCloser
+	buf     []byte
+	pending bytes.Buffer
+	out     bytes.Buffer
+	err     error
+}
+
+func newStreamUsageFilter(src io.ReadCloser) *streamUsageFilter {
+	return &streamUsageFilter{src: src, buf: make([]byte, 4096)}
+}
+
+// Read implements io.Reader for the filtered stream
+func (f *streamUsageFilter) Read(p []byte) (int, error) {
+	for f.out.Len() == 0 && f.err == nil {
+		n, err := f.src.Read(f.buf)
+		f.pending.Write(f.buf[:n])
+		f.drain()
+		if err != nil {
+			f.err = err
+			// Flush a trailing event without its blank-line terminator
+			if f.pending.Len() > 0 && !isUsageOnlyEvent(f.pending.Bytes()) {
+				f.out.Write(f.pending.Bytes())
+			}
+			f.pending.Reset()
+		}
+	}
+	if f.out.Len() > 0 {
+		return f.out.Read(p)
+	}
+	return 0, f.err
+}
+
+// Close implements io.Closer for the filtered stream
+func (f *streamUsageFilter) Close() error {
+	return f.src.Close()
+}
+
+// drain moves every complete event from pending to out, dropping usage-only ones
+func (f *streamUsageFilter) drain() {
+	for {
+		data := f.pending.Bytes()
+		n := eventEnd(data)
+		if n < 0 {
+			return
+		}
+		event := data[:n]
+		if !isUsageOnlyEvent(event) {
+			f.out.Write(event)
+		}
+		f.pending.Next(n)
+	}
+}
+
+// eventEnd returns the length of the first complete SSE event in data, or -1
+// if there is none yet. An event ends at a blank line; lines may end in CRLF,
+// LF or CR.
+func eventEnd(data []byte) int {
+	lineStart := true
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c != '\n' && c != '\r' {
+			lineStart = false
+			continue
+		}
+		end := i + 1
+		if c == '\r' {
+			if end == len(data) {
+				return -1 // the LF of a CRLF may not have arrived yet
+			}
+			if data[end] == '\n' {
+				end++
+			}
+		}
+		if lineStart {
+			return end
+		}
+		lineStart = true
+		i = end - 1
+	}
+	return -1
+}
+
+// eventLines splits an SSE event into its non-empty lines
+func eventLines(event []byte) [][]byte {
+	return bytes.FieldsFunc(event, func(r rune) bool { return r == '\n' || r == '\r' })
+}
+
+// isUsageOnlyEvent reports whether an SSE event is the chunk added by include_usage
+func isUsageOnlyEvent(event []byte) bool {
+	for _, line := range eventLines(event) {
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		payload := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+		var chunk struct {
+			Choices []json.RawMessage `json:"choices"`
+			Usage   json.RawMessage   `json:"usage"`
+		}
+		if json.Unmarshal(payload, &chunk) != nil {
+			return false
+		}
+		return len(chunk.Choices) == 0 && len(chunk.Usage) > 0 && string(chunk.Usage) != "null"
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectStreamUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		injected bool
+		expected string
+	}{
+		{"NotStreaming", `{"model":"gpt-4o"}`, false, `{"model":"gpt-4o"}`},
+		{"StreamFalse", `{"model":"gpt-4o","stream":false}`, false, `{"model":"gpt-4o","stream":false}`},
+		{"StreamWithoutOptions", `{"model":"gpt-4o","stream":true}`, true, `{"model":"gpt-4o","stream":true,"stream_options":{"include_usage":true}}`},
+		{"StreamOptionsNull", `{"stream":true,"stream_options":null}`, true, `{"stream":true,"stream_options":{"include_usage":true}}`},
+		{"IncludeUsageFalse", `{"stream":true,"stream_options":{"include_usage":false}}`, true, `{"stream":true,"stream_options":{"include_usage":true}}`},
+		{"KeepsOtherOptions", `{"stream":true,"stream_options":{"foo":1}}`, true, `{"stream":true,"stream_options":{"foo":1,"include_usage":true}}`},
+		{"AlreadyRequested", `{"stream":true,"stream_options":{"include_usage":true}}`, false, `{"stream":true,"stream_options":{"include_usage":true}}`},
+		{"InvalidJSON", `not json`, false, `not json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, injected := injectStreamUsage([]byte(tt.body))
+			assert.Equal(t, tt.injected, injected)
+			if tt.injected {
+				assert.JSONEq(t, tt.expected, string(out))
+			} else {
+				assert.Equal(t, tt.expected, string(out))
+			}
+		})
+	}
+}
+
+func TestStreamUsageFilter(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"choices":[{"delta":{"content":"Hi"}}],"usage":null}`,
+		`data: {"choices":[{"delta":{},"finish_reason":"stop"}],"usage":null}`,
+		`data: {"choices":[],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`,
+		`data: [DONE]`,
+	}, "\n\n") + "\n\n"
+
+	// One byte at a time exercises events split across reads
+	src := io.NopCloser(iotest.OneByteReader(strings.NewReader(stream)))
+	out, err := io.ReadAll(newStreamUsageFilter(src))
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(out), "prompt_tokens")
+	assert.Contains(t, string(out), `"content":"Hi"`)
+	assert.True(t, strings.HasSuffix(string(out), "data: [DONE]\n\n"))
+
+	// Servers may end lines in CRLF
+	src = io.NopCloser(iotest.OneByteReader(strings.NewReader(strings.ReplaceAll(stream, "\n", "\r\n"))))
+	out, err = io.ReadAll(newStreamUsageFilter(src))
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(out), "prompt_tokens")
+	assert.Contains(t, string(out), `"content":"Hi"`)
+	assert.True(t, strings.HasSuffix(string(out), "data: [DONE]\r\n\r\n"))
+}
@@ -119,7 +119,7 @@ func TestStreamingResponseWithUsageRecordingAndPolicies(t *testing.T) {
 		ctx = auth.WithModelName(ctx, "gpt-4")
 
 		// Load policies and set in context
-		policiesList, err := engine.LoadPolicies(ctx, testAppID)
+		policiesList, err := engine.LoadPolicies(ctx, policies.Scope{AppID: testAppID})
 		require.NoError(t, err)
 		ctx = auth.WithPolicies(ctx, policiesList)
 
@@ -286,7 +286,7 @@ func TestStreamingResponseWithUsageRecordingAndPolicies(t *testing.T) {
 		ctx = auth.WithModelName(ctx, "gpt-4")
 
 		// Load policies (includes our token limit policy)
-		policiesList, err := engine.LoadPolicies(ctx, testAppID)
+		policiesList, err := engine.LoadPolicies(ctx, policies.Scope{AppID: testAppID})
 		require.NoError(t, err)
 		ctx = auth.WithPolicies(ctx, policiesList)
 
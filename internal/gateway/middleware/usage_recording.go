@@ -1,8 +1,8 @@
 package middleware
 
 import (
-	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"strings"
@@ -11,19 +11,23 @@ import (
 	"github.com/WebDeveloperBen/ai-gateway/internal/db"
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/pricing"
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/tokens"
 	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
 	"github.com/WebDeveloperBen/ai-gateway/internal/model"
 	"github.com/WebDeveloperBen/ai-gateway/internal/observability"
+	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
+	pricingrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/pricing"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-// responseBodyWrapper wraps the response body to capture the full content before async processing
+// responseBodyWrapper tees the response body into an incremental decoder as
+// the client reads it, and fires onClose once the body is finished
 type responseBodyWrapper struct {
 	originalBody io.ReadCloser
-	onClose      func([]byte)
-	body         *bytes.Buffer
+	decoder      io.Writer
+	onClose      func()
 	closed       bool
 }
 
@@ -34,7 +38,7 @@ func (rbw *responseBodyWrapper) Read(p []byte) (n int, err error) {
 	}
 	n, err = rbw.originalBody.Read(p)
 	if n > 0 {
-		rbw.body.Write(p[:n])
+		_, _ = rbw.decoder.Write(p[:n])
 	}
 	if err != nil {
 		rbw.close()
@@ -51,29 +55,32 @@ func (rbw *responseBodyWrapper) Close() error {
 	return rbw.originalBody.Close()
 }
 
-// close captures the full body and calls the onClose callback
+// close marks the body finished and calls the onClose callback
 func (rbw *responseBodyWrapper) close() {
 	if rbw.closed {
 		return
 	}
 	rbw.closed = true
-	bodyBytes := rbw.body.Bytes()
-	rbw.onClose(bodyBytes)
+	rbw.onClose()
 }
 
 // UsageRecorder records usage metrics and runs post-check policies
 type UsageRecorder struct {
-	db     *db.Queries
-	parser *tokens.Parser
-	engine *policies.Engine
+	db        *db.Queries
+	parser    *tokens.Parser
+	estimator *tokens.Estimator
+	pricing   *pricing.Calculator
+	engine    *policies.Engine
 }
 
 // NewUsageRecorder creates a new usage recorder
 func NewUsageRecorder(database *db.Queries, engine *policies.Engine) *UsageRecorder {
 	return &UsageRecorder{
-		db:     database,
-		parser: tokens.NewParser(),
-		engine: engine,
+		db:        database,
+		parser:    tokens.NewParser(),
+		estimator: tokens.NewEstimator(),
+		pricing:   pricing.NewCalculator(pricingrepo.NewPostgresRepo(database), pricing.DefaultCacheTTL),
+		engine:    engine,
 	}
 }
 
@@ -83,93 +90,157 @@ func (ur *UsageRecorder) Middleware(next http.RoundTripper) http.RoundTripper {
 		// Capture request metadata
 		ctx := r.Context()
 
+		// Files/Batch API usage is recorded by the batch poller on completion
+		if provider.IsAccountScopedPath(auth.GetEndpoint(ctx)) {
+			return next.RoundTrip(r)
+		}
+
 		// Get request size from parsed request data
 		parsedReq := auth.GetParsedRequest(ctx)
 		requestSizeBytes := 0
+		estimatedPromptTokens := 0
 		if parsedReq != nil {
 			requestSizeBytes = parsedReq.RequestSize
+			estimatedPromptTokens = parsedReq.EstimatedTokens
 		}
 
 		// Capture start time
 		startTime := time.Now()
 
+		reservations := policies.GetReservations(ctx)
+
 		// Execute request
 		resp, err := next.RoundTrip(r)
 		if err != nil {
+			// Nothing was consumed upstream; release the reserved tokens
+			if len(reservations) > 0 && ur.engine != nil {
+				go ur.settle(detachContext(ctx), reservations, 0)
+			}
 			return resp, err
 		}
 
 		// Capture latency
 		latencyMs := time.Since(startTime).Milliseconds()
 
-		// Extract provider and model from context
+		// Extract provider, model and the routed deployment's pricing tier from context
+		deploymentType := provider.DeploymentType(ctx)
 		provider := auth.GetProvider(ctx)
 		modelName := auth.GetModelName(ctx)
 
 		// Create detached context for async processing
 		detachedCtx := detachContext(ctx)
 
-		// Wrap the response body to capture the full content before async processing
+		// Decode the body incrementally as the client reads it
+		decoder := tokens.NewResponseDecoder(ur.parser, provider, startTime)
 		resp.Body = &responseBodyWrapper{
 			originalBody: resp.Body,
-			body:         &bytes.Buffer{},
-			onClose: func(bodyBytes []byte) {
+			decoder:      decoder,
+			onClose: func() {
 				// Launch async processing after response is fully consumed
 				go ur.recordAsync(detachedCtx, &asyncRecordParams{
-					provider:         provider,
-					modelName:        modelName,
-					requestSizeBytes: requestSizeBytes,
-					latencyMs:        latencyMs,
-					request:          r,
-					response:         resp,
-					capturedBytes:    bytes.NewBuffer(bodyBytes),
+					provider:              provider,
+					modelName:             modelName,
+					deploymentType:        deploymentType,
+					requestSizeBytes:      requestSizeBytes,
+					estimatedPromptTokens: estimatedPromptTokens,
+					latencyMs:             latencyMs,
+					request:               r,
+					response:              resp,
+					summary:               decoder.Summary(),
+					reservations:          reservations,
 				})
 			},
 		}
 
+		// Hide the usage chunk the gateway requested on the caller's behalf.
+		// The recorder above still captures it from the unfiltered stream.
+		if parsedReq != nil && parsedReq.StreamUsageInjected &&
+			strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			resp.Body = newStreamUsageFilter(resp.Body)
+			resp.ContentLength = -1
+			resp.Header.Del("Content-Length")
+		}
+
 		// Return the response with wrapped body
 		return resp, nil
 	})
 }
 
+// usageMetadata collects the values and tags policies recorded for a request
+func usageMetadata(r *http.Request) []byte {
+	if r == nil {
+		return nil
+	}
+	metadata := map[string]any{}
+	for key, value := range policies.GetMetadata(r.Context()) {
+		metadata[key] = value
+	}
+	if tags := policies.GetTags(r.Context()); len(tags) > 0 {
+		metadata["tags"] = tags
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
 // asyncRecordParams holds parameters for async recording
 type asyncRecordParams struct {
-	provider         string
-	modelName        string
+	provider  string
+	modelName string
+	// deploymentType is the pricing tier of the deployment the request was
+	// routed to; empty means standard
+	deploymentType   string
 	requestSizeBytes int
-	latencyMs        int64
-	request          *http.Request
-	response         *http.Response
-	capturedBytes    *bytes.Buffer
+	// estimatedPromptTokens is the request-side estimate, used when the
+	// response carries no usage block
+	estimatedPromptTokens int
+	latencyMs             int64
+	request               *http.Request
+	response              *http.Response
+	summary               *tokens.ResponseSummary
+	reservations          []policies.TokenReservation
+}
+
+func getDetachedUserID(ctx context.Context) string {
+	if data, ok := ctx.Value(detachedContextKey{}).(*detachedData); ok {
+		return data.UserID
+	}
+	return auth.GetUserID(ctx)
 }
 
 // recordAsync performs the actual recording in a goroutine
 // This runs AFTER the response has been consumed by the client
 func (ur *UsageRecorder) recordAsync(ctx context.Context, params *asyncRecordParams) {
-	// Wait a bit to ensure response body has been consumed
-	// This allows TeeReader to capture the full response
-	time.Sleep(10 * time.Millisecond)
-
 	// Extract IDs from detached context
 	orgID := getDetachedOrgID(ctx)
 	appID := getDetachedAppID(ctx)
 	apiKeyID := getDetachedKeyID(ctx)
 
-	// Get captured response bytes
-	respBodyBytes := params.capturedBytes.Bytes()
-	responseSizeBytes := len(respBodyBytes)
-
-	// Parse token usage from captured response
-	tokenUsage, err := ur.parseTokenUsage(params.provider, respBodyBytes)
-	if err != nil {
-		// If parsing fails, use zeros (some responses may not have usage)
-		tokenUsage = &model.TokenUsage{
-			PromptTokens:     0,
-			CompletionTokens: 0,
-			TotalTokens:      0,
+	summary := params.summary
+	responseSizeBytes := summary.Size
+
+	tokenUsage := summary.Usage
+	estimated := false
+	if tokenUsage == nil {
+		// No usage reported (older API versions, self-hosted servers): count
+		// successful responses ourselves rather than recording zero tokens
+		tokenUsage = &model.TokenUsage{}
+		if params.response != nil && params.response.StatusCode < 300 {
+			tokenUsage = ur.estimateUsage(ctx, params.modelName, params.estimatedPromptTokens, summary.Text)
+			estimated = tokenUsage.TotalTokens > 0
 		}
 	}
 
+	// Replace the rate limit estimates with what was actually used
+	if len(params.reservations) > 0 && ur.engine != nil {
+		ur.settle(ctx, params.reservations, tokenUsage.TotalTokens)
+	}
+
 	// Parse UUIDs
 	orgUUID, err := uuid.Parse(orgID)
 	if err != nil {
@@ -198,6 +269,25 @@ func (ur *UsageRecorder) recordAsync(ctx context.Context, params *asyncRecordPar
 		return
 	}
 
+	// Cost the request against the price in force; unpriced models record a NULL cost
+	var cost pgtype.Int8
+	var currency *string
+	costMicros, costCurrency, err := ur.pricing.Cost(ctx, orgUUID, params.provider, params.modelName, params.deploymentType, model.BillableUsage{
+		Tokens:       *tokenUsage,
+		Images:       summary.Images,
+		AudioSeconds: summary.AudioSeconds,
+	})
+	if err != nil {
+		logger.GetLogger(ctx).Warn().
+			Err(err).
+			Str("provider", params.provider).
+			Str("model", params.modelName).
+			Msg("Failed to resolve model price")
+	} else if costMicros != nil {
+		cost = pgtype.Int8{Int64: *costMicros, Valid: true}
+		currency = &costCurrency
+	}
+
 	// Insert usage metric
 	_, err = ur.db.CreateUsageMetric(ctx, db.CreateUsageMetricParams{
 		OrgID:             orgUUID,
@@ -215,6 +305,10 @@ func (ur *UsageRecorder) recordAsync(ctx context.Context, params *asyncRecordPar
 			Time:  time.Now(),
 			Valid: true,
 		},
+		Estimated:  estimated,
+		CostMicros: cost,
+		Currency:   currency,
+		Metadata:   usageMetadata(params.request),
 	})
 	if err != nil {
 		logger.GetLogger(ctx).Error().
@@ -228,13 +322,35 @@ func (ur *UsageRecorder) recordAsync(ctx context.Context, params *asyncRecordPar
 		return
 	}
 
+	// Update the spend ledger once, before budget policies read it
+	if cost.Valid && ur.engine != nil {
+		if err := ur.engine.RecordSpend(ctx, policies.SpendEntry{
+			OrgID:      orgID,
+			AppID:      appID,
+			APIKeyID:   apiKeyID,
+			Currency:   *currency,
+			CostMicros: cost.Int64,
+			At:         time.Now(),
+		}); err != nil {
+			logger.GetLogger(ctx).Error().
+				Err(err).
+				Str("app_id", appID).
+				Msg("Failed to record spend")
+		}
+	}
+
 	// Get policies from context (already loaded in enforcement middleware)
 	policyListInterface := auth.GetPolicies(ctx)
 	if policyListInterface == nil {
 		// Fallback: load policies if not in context
 		// This shouldn't normally happen but provides safety
 		var err error
-		policyListInterface, err = ur.engine.LoadPolicies(ctx, appID)
+		policyListInterface, err = ur.engine.LoadPolicies(ctx, policies.Scope{
+			OrgID:  orgID,
+			AppID:  appID,
+			UserID: getDetachedUserID(ctx),
+			KeyID:  apiKeyID,
+		})
 		if err != nil {
 			logger.GetLogger(ctx).Error().
 				Err(err).
@@ -266,6 +382,17 @@ func (ur *UsageRecorder) recordAsync(ctx context.Context, params *asyncRecordPar
 		RequestSizeBytes:  params.requestSizeBytes,
 		ResponseSizeBytes: responseSizeBytes,
 		LatencyMs:         params.latencyMs,
+		FinishReason:      summary.FinishReason,
+		TimeToFirstToken:  summary.TimeToFirstToken,
+		Reservations:      params.reservations,
+	}
+	if cost.Valid {
+		postCtx.CostMicros = cost.Int64
+		postCtx.Currency = *currency
+	}
+	if params.request != nil {
+		postCtx.Tags = policies.GetTags(params.request.Context())
+		postCtx.Metadata = policies.GetMetadata(params.request.Context())
 	}
 
 	// Record LLM token metrics
@@ -278,61 +405,39 @@ func (ur *UsageRecorder) recordAsync(ctx context.Context, params *asyncRecordPar
 		tokenUsage.TotalTokens,
 	)
 
+	if summary.Streamed && summary.TimeToFirstToken > 0 {
+		observability.FromContext(ctx).RecordTimeToFirstToken(ctx, params.provider, params.modelName, summary.TimeToFirstToken)
+	}
+
 	// Run post-checks (non-blocking, for logging/metrics)
 	for _, policy := range policyList {
 		policy.PostCheck(ctx, postCtx)
 	}
 }
 
-// parseTokenUsage handles both regular and streaming responses
-func (ur *UsageRecorder) parseTokenUsage(provider string, respBodyBytes []byte) (*model.TokenUsage, error) {
-	// First try parsing as a regular response
-	usage, err := ur.parser.ParseResponse(provider, respBodyBytes)
-	if err == nil {
-		return usage, nil
-	}
-
-	// If that fails, try parsing as streaming response
-	// Split by SSE event boundaries
-	bodyStr := string(respBodyBytes)
-	lines := strings.Split(bodyStr, "\n")
-
-	var chunks [][]byte
-	var currentChunk []string
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			// Empty line indicates end of chunk
-			if len(currentChunk) > 0 {
-				chunkData := strings.Join(currentChunk, "\n")
-				if strings.HasPrefix(chunkData, "data: ") {
-					// Extract JSON part after "data: "
-					jsonData := strings.TrimPrefix(chunkData, "data: ")
-					if jsonData != "[DONE]" {
-						chunks = append(chunks, []byte(jsonData))
-					}
-				}
-				currentChunk = nil
-			}
-		} else {
-			currentChunk = append(currentChunk, line)
-		}
+// settle applies actual token usage to the reservations made at pre-check
+func (ur *UsageRecorder) settle(ctx context.Context, reservations []policies.TokenReservation, actualTokens int) {
+	if err := ur.engine.SettleReservations(ctx, reservations, actualTokens); err != nil {
+		logger.GetLogger(ctx).Warn().
+			Err(err).
+			Int("actual_tokens", actualTokens).
+			Msg("Failed to settle token reservations")
 	}
+}
 
-	// Handle any remaining chunk
-	if len(currentChunk) > 0 {
-		chunkData := strings.Join(currentChunk, "\n")
-		if strings.HasPrefix(chunkData, "data: ") {
-			jsonData := strings.TrimPrefix(chunkData, "data: ")
-			if jsonData != "[DONE]" {
-				chunks = append(chunks, []byte(jsonData))
-			}
+// estimateUsage tokenizes the generated text of a response that reported no
+// usage and pairs it with the request-side prompt estimate
+func (ur *UsageRecorder) estimateUsage(ctx context.Context, modelName string, promptTokens int, text string) *model.TokenUsage {
+	usage := &model.TokenUsage{PromptTokens: promptTokens}
+	if text != "" {
+		if n, err := ur.estimator.EstimateText(ctx, modelName, text); err == nil {
+			usage.CompletionTokens = n
+		} else {
+			logger.GetLogger(ctx).Warn().Err(err).Str("model", modelName).Msg("Failed to estimate completion tokens")
 		}
 	}
-
-	// Try parsing chunks for streaming usage
-	return ur.parser.ParseStreamedResponse(provider, chunks)
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
 }
 
 type detachedContextKey struct{}
@@ -7,13 +7,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/auth"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/tokens"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestUsageRecorder_parseTokenUsage(t *testing.T) {
+func TestUsageRecorder_decodeUsage(t *testing.T) {
 	recorder := NewUsageRecorder(nil, nil)
 
 	t.Run("OpenAI_RegularResponse", func(t *testing.T) {
@@ -27,8 +31,7 @@ func TestUsageRecorder_parseTokenUsage(t *testing.T) {
 			}
 		}`)
 
-		usage, err := recorder.parseTokenUsage("openai", body)
-		require.NoError(t, err)
+		usage := decodeUsage(recorder, "openai", body)
 		assert.NotNil(t, usage)
 		assert.Equal(t, 10, usage.PromptTokens)
 		assert.Equal(t, 20, usage.CompletionTokens)
@@ -44,8 +47,7 @@ data: [DONE]
 
 `
 
-		usage, err := recorder.parseTokenUsage("openai", []byte(body))
-		require.NoError(t, err)
+		usage := decodeUsage(recorder, "openai", []byte(body))
 		assert.NotNil(t, usage)
 		assert.Equal(t, 5, usage.PromptTokens)
 		assert.Equal(t, 10, usage.CompletionTokens)
@@ -59,17 +61,15 @@ data: [DONE]
 			"choices": [{"message": {"content": "Hello"}}]
 		}`)
 
-		usage, err := recorder.parseTokenUsage("openai", body)
-		require.Error(t, err) // Should error because no usage data
-		assert.Nil(t, usage)
+		usage := decodeUsage(recorder, "openai", body)
+		assert.Nil(t, usage) // No usage block reported
 	})
 
 	t.Run("InvalidJSON", func(t *testing.T) {
 		body := []byte(`invalid json`)
 
-		usage, err := recorder.parseTokenUsage("openai", body)
-		require.Error(t, err) // Should error because invalid JSON
-		assert.Nil(t, usage)
+		usage := decodeUsage(recorder, "openai", body)
+		assert.Nil(t, usage) // Unparseable body
 	})
 
 	t.Run("StreamingResponseMalformed", func(t *testing.T) {
@@ -84,9 +84,8 @@ data: [DONE]
 
 `
 
-		usage, err := recorder.parseTokenUsage("openai", []byte(body))
-		// Should still succeed because the valid chunk with usage is parsed
-		require.NoError(t, err)
+		// Malformed chunks are skipped; the valid usage chunk is still found
+		usage := decodeUsage(recorder, "openai", []byte(body))
 		assert.NotNil(t, usage)
 		assert.Equal(t, 5, usage.PromptTokens)
 		assert.Equal(t, 10, usage.CompletionTokens)
@@ -103,18 +102,16 @@ data: [DONE]
 
 `
 
-		usage, err := recorder.parseTokenUsage("openai", []byte(body))
-		require.Error(t, err) // Should error because no valid chunks
-		assert.Nil(t, usage)
+		usage := decodeUsage(recorder, "openai", []byte(body))
+		assert.Nil(t, usage) // No valid chunks
 	})
 
 	t.Run("StreamingResponseEmpty", func(t *testing.T) {
 		// Test empty streaming response
 		body := ``
 
-		usage, err := recorder.parseTokenUsage("openai", []byte(body))
-		require.Error(t, err) // Should error because no data
-		assert.Nil(t, usage)
+		usage := decodeUsage(recorder, "openai", []byte(body))
+		assert.Nil(t, usage) // Empty body
 	})
 
 	t.Run("StreamingResponseNoDataPrefix", func(t *testing.T) {
@@ -126,12 +123,18 @@ data: [DONE]
 
 `
 
-		usage, err := recorder.parseTokenUsage("openai", []byte(body))
-		require.Error(t, err) // Should error because no valid data chunks
-		assert.Nil(t, usage)
+		usage := decodeUsage(recorder, "openai", []byte(body))
+		assert.Nil(t, usage) // No data events
 	})
 }
 
+// decodeUsage runs a whole body through the recorder's incremental decoder
+func decodeUsage(recorder *UsageRecorder, provider string, body []byte) *model.TokenUsage {
+	d := tokens.NewResponseDecoder(recorder.parser, provider, time.Now())
+	_, _ = d.Write(body)
+	return d.Summary().Usage
+}
+
 // testNopCloser implements io.ReadCloser for testing
 type testNopCloser struct {
 	io.Reader
@@ -159,17 +162,49 @@ func (ert *errorRoundTripper) RoundTrip(r *http.Request) (*http.Response, error)
 	return nil, ert.err
 }
 
+func TestUsageRecorder_estimateUsage(t *testing.T) {
+	recorder := NewUsageRecorder(nil, nil)
+	ctx := context.Background()
+
+	t.Run("NoGeneratedText", func(t *testing.T) {
+		usage := recorder.estimateUsage(ctx, "gpt-4o", 12, "")
+		assert.Equal(t, 12, usage.PromptTokens)
+		assert.Equal(t, 0, usage.CompletionTokens)
+		assert.Equal(t, 12, usage.TotalTokens)
+	})
+
+	t.Run("StreamWithoutUsageChunk", func(t *testing.T) {
+		if _, err := recorder.estimator.EstimateText(ctx, "gpt-4o", "probe"); err != nil {
+			t.Skip("tokenizer data unavailable: ", err)
+		}
+		body := "data: {\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n" +
+			"data: {\"choices\":[{\"delta\":{\"content\":\" world\"}}]}\n\n" +
+			"data: [DONE]\n\n"
+
+		d := tokens.NewResponseDecoder(recorder.parser, "openai", time.Now())
+		_, _ = d.Write([]byte(body))
+		summary := d.Summary()
+		require.Nil(t, summary.Usage)
+
+		usage := recorder.estimateUsage(ctx, "gpt-4o", 8, summary.Text)
+		assert.Equal(t, 8, usage.PromptTokens)
+		assert.Equal(t, 2, usage.CompletionTokens)
+		assert.Equal(t, 10, usage.TotalTokens)
+	})
+}
+
 func TestResponseBodyWrapper(t *testing.T) {
 	t.Run("ReadAndClose", func(t *testing.T) {
 		originalBody := &testNopCloser{bytes.NewReader([]byte("test response"))}
+		decoded := &bytes.Buffer{}
 		var capturedBody []byte
-		onClose := func(body []byte) {
-			capturedBody = body
+		onClose := func() {
+			capturedBody = decoded.Bytes()
 		}
 
 		wrapper := &responseBodyWrapper{
 			originalBody: originalBody,
-			body:         &bytes.Buffer{},
+			decoder:      decoded,
 			onClose:      onClose,
 		}
 
@@ -184,7 +219,7 @@ func TestResponseBodyWrapper(t *testing.T) {
 		err = wrapper.Close()
 		require.NoError(t, err)
 
-		// Check that onClose was called with captured body
+		// Check that onClose was called after the decoder saw the body
 		assert.Equal(t, []byte("test response"), capturedBody)
 
 		// Subsequent reads should fail
@@ -195,33 +230,35 @@ func TestResponseBodyWrapper(t *testing.T) {
 
 	t.Run("CloseWithoutRead", func(t *testing.T) {
 		originalBody := &testNopCloser{bytes.NewReader([]byte("test"))}
-		var capturedBody []byte
-		onClose := func(body []byte) {
-			capturedBody = body
+		decoded := &bytes.Buffer{}
+		onCloseCalled := false
+		onClose := func() {
+			onCloseCalled = true
 		}
 
 		wrapper := &responseBodyWrapper{
 			originalBody: originalBody,
-			body:         &bytes.Buffer{},
+			decoder:      decoded,
 			onClose:      onClose,
 		}
 
 		err := wrapper.Close()
 		require.NoError(t, err)
-		// Since nothing was read, the buffer is empty
-		assert.Nil(t, capturedBody)
+		assert.True(t, onCloseCalled)
+		// Since nothing was read, the decoder saw nothing
+		assert.Zero(t, decoded.Len())
 	})
 
 	t.Run("MultipleCloseCalls", func(t *testing.T) {
 		originalBody := &testNopCloser{bytes.NewReader([]byte("test"))}
 		callCount := 0
-		onClose := func(body []byte) {
+		onClose := func() {
 			callCount++
 		}
 
 		wrapper := &responseBodyWrapper{
 			originalBody: originalBody,
-			body:         &bytes.Buffer{},
+			decoder:      &bytes.Buffer{},
 			onClose:      onClose,
 		}
 
@@ -234,16 +271,15 @@ func TestResponseBodyWrapper(t *testing.T) {
 	t.Run("ReadWithError", func(t *testing.T) {
 		// Create a reader that returns an error
 		originalBody := &errorReader{err: io.EOF}
+		decoded := &bytes.Buffer{}
 		var onCloseCalled bool
-		var capturedBody []byte
-		onClose := func(body []byte) {
+		onClose := func() {
 			onCloseCalled = true
-			capturedBody = body
 		}
 
 		wrapper := &responseBodyWrapper{
 			originalBody: originalBody,
-			body:         &bytes.Buffer{},
+			decoder:      decoded,
 			onClose:      onClose,
 		}
 
@@ -255,7 +291,7 @@ func TestResponseBodyWrapper(t *testing.T) {
 
 		// Check that onClose was called (since Read called close on error)
 		assert.True(t, onCloseCalled)
-		assert.Nil(t, capturedBody) // No data was read
+		assert.Zero(t, decoded.Len()) // No data was read
 
 		// Subsequent reads should fail
 		n, err = wrapper.Read(buf)
@@ -372,3 +408,13 @@ func TestDetachedContext(t *testing.T) {
 		assert.Equal(t, "", getDetachedKeyID(emptyDetachedCtx))
 	})
 }
+
+func TestUsageMetadata(t *testing.T) {
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	assert.Nil(t, usageMetadata(req))
+	assert.Nil(t, usageMetadata(nil))
+
+	ctx := policies.WithMetadata(req.Context(), map[string]any{"injection_score": 0.74})
+	ctx = policies.WithTags(ctx, []string{"prompt_injection"})
+	assert.JSONEq(t, `{"injection_score":0.74,"tags":["prompt_injection"]}`, string(usageMetadata(req.WithContext(ctx))))
+}
@@ -0,0 +1,230 @@
+package policies
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/db"
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/observability"
+)
+
+// Post-check action types
+const (
+	ActionFlag       = "flag"        // mark the API key (or app) for a while; pre-checks test it with flagged()
+	ActionAlert      = "alert"       // send an alert to the alert sink
+	ActionDisableKey = "disable_key" // revoke the API key that made the request
+	ActionPenalty    = "penalty"     // charge extra tokens to the request's token quotas
+	ActionEvent      = "event"       // log a named event and count it in the policy events metric
+)
+
+// DefaultFlagTTL is how long a flag lasts when the action sets no ttl
+const DefaultFlagTTL = 24 * time.Hour
+
+// Action is something a post-check asks the engine to do about a completed
+// request, beyond logging it
+type Action struct {
+	Type    string         `json:"action"`
+	Name    string         `json:"name,omitempty"` // flag, alert or event name
+	Message string         `json:"message,omitempty"`
+	Tokens  int            `json:"tokens,omitempty"` // penalty size
+	TTL     string         `json:"ttl,omitempty"`    // how long a flag lasts, e.g. "1h"
+	Data    map[string]any `json:"data,omitempty"`   // extra fields for alerts and events
+}
+
+// Validate checks the action's type and parameters
+func (a Action) Validate() error {
+	switch a.Type {
+	case ActionAlert, ActionDisableKey:
+	case ActionFlag:
+		if a.Name == "" {
+			return fmt.Errorf("flag action needs a name")
+		}
+		if a.TTL != "" {
+			if ttl, err := time.ParseDuration(a.TTL); err != nil || ttl <= 0 {
+				return fmt.Errorf("flag action has invalid ttl %q", a.TTL)
+			}
+		}
+	case ActionPenalty:
+		if a.Tokens <= 0 {
+			return fmt.Errorf("penalty action needs a positive number of tokens")
+		}
+	case ActionEvent:
+		if a.Name == "" {
+			return fmt.Errorf("event action needs a name")
+		}
+	default:
+		return fmt.Errorf("unknown action %q", a.Type)
+	}
+	return nil
+}
+
+// ParseActions reads the actions a post-check returned: an action name, an
+// action object, or a list of either. null, "", {}, [] and booleans mean no
+// action.
+func ParseActions(value any) ([]Action, error) {
+	switch v := value.(type) {
+	case nil, bool:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		action := Action{Type: v}
+		return []Action{action}, action.Validate()
+	case map[string]any:
+		if len(v) == 0 {
+			return nil, nil
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var action Action
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&action); err != nil {
+			return nil, fmt.Errorf("invalid action: %w", err)
+		}
+		return []Action{action}, action.Validate()
+	case []any:
+		var actions []Action
+		for _, item := range v {
+			parsed, err := ParseActions(item)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, parsed...)
+		}
+		return actions, nil
+	default:
+		return nil, fmt.Errorf("post-check must return a boolean, an action or a list of actions, got %T", value)
+	}
+}
+
+// ActionExecutor carries out post-check actions. The Engine implements it.
+type ActionExecutor interface {
+	ExecuteActions(ctx context.Context, source model.PolicyType, req *PostRequestContext, actions []Action)
+}
+
+// FlagKey is where a flag set on an API key (or app) lives in the cache
+func FlagKey(orgID, subject, name string) string {
+	return fmt.Sprintf("policyflag:%s:%s:%s", orgID, subject, name)
+}
+
+// flagSubject is what flags attach to: the API key, or the app for requests
+// made without one
+func flagSubject(apiKeyID, appID string) string {
+	if apiKeyID != "" {
+		return "key:" + apiKeyID
+	}
+	return "app:" + appID
+}
+
+// ExecuteActions implements ActionExecutor. Failures are logged; they never
+// affect the request, which has already completed.
+func (e *Engine) ExecuteActions(ctx context.Context, source model.PolicyType, req *PostRequestContext, actions []Action) {
+	for _, action := range actions {
+		if err := e.executeAction(ctx, source, req, action); err != nil {
+			logger.GetLogger(ctx).Error().
+				Err(err).
+				Str("action", action.Type).
+				Str("policy_type", string(source)).
+				Str("app_id", req.AppID).
+				Msg("Failed to execute policy action")
+			continue
+		}
+		logger.GetLogger(ctx).Info().
+			Str("action", action.Type).
+			Str("name", action.Name).
+			Str("policy_type", string(source)).
+			Str("app_id", req.AppID).
+			Str("api_key_id", req.APIKeyID).
+			Msg("Executed policy action")
+	}
+}
+
+func (e *Engine) executeAction(ctx context.Context, source model.PolicyType, req *PostRequestContext, action Action) error {
+	switch action.Type {
+	case ActionFlag:
+		ttl := DefaultFlagTTL
+		if action.TTL != "" {
+			ttl, _ = time.ParseDuration(action.TTL)
+		}
+		if e.cache == nil {
+			return fmt.Errorf("no cache configured")
+		}
+		value := action.Message
+		if value == "" {
+			value = "1"
+		}
+		return e.cache.Set(ctx, FlagKey(req.OrgID, flagSubject(req.APIKeyID, req.AppID), action.Name), value, ttl)
+
+	case ActionAlert:
+		alertType := action.Name
+		if alertType == "" {
+			alertType = "post_check"
+		}
+		message := action.Message
+		if message == "" {
+			message = "Post-check alert raised"
+		}
+		e.alerts.Emit(ctx, Alert{
+			Type:       alertType,
+			PolicyType: string(source),
+			OrgID:      req.OrgID,
+			AppID:      req.AppID,
+			APIKeyID:   req.APIKeyID,
+			Message:    message,
+			Data:       action.Data,
+			At:         time.Now(),
+		})
+		return nil
+
+	case ActionDisableKey:
+		if req.APIKeyID == "" {
+			return fmt.Errorf("request was not made with an API key")
+		}
+		if e.db == nil {
+			return fmt.Errorf("no database configured")
+		}
+		affected, err := e.db.UpdateAPIKeyStatus(ctx, db.UpdateAPIKeyStatusParams{
+			KeyPrefix: req.APIKeyID,
+			Status:    string(model.KeyRevoked),
+		})
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return fmt.Errorf("key not found")
+		}
+		return nil
+
+	case ActionPenalty:
+		if len(req.Reservations) == 0 {
+			return fmt.Errorf("request was not charged to any token quota")
+		}
+		return NewRateLimiter(e.cache).Charge(ctx, req.Reservations, action.Tokens)
+
+	case ActionEvent:
+		message := action.Message
+		if message == "" {
+			message = "Policy event"
+		}
+		observability.FromContext(ctx).RecordPolicyEvent(ctx, string(source), action.Name)
+		logger.GetLogger(ctx).Info().
+			Str("event", action.Name).
+			Str("policy_type", string(source)).
+			Str("org_id", req.OrgID).
+			Str("app_id", req.AppID).
+			Str("api_key_id", req.APIKeyID).
+			Interface("data", action.Data).
+			Msg(message)
+		return nil
+	}
+	return fmt.Errorf("unknown action %q", action.Type)
+}
@@ -0,0 +1,152 @@
+package policies_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseActions(t *testing.T) {
+	t.Run("NoAction", func(t *testing.T) {
+		for _, value := range []any{nil, true, false, "", map[string]any{}, []any{}} {
+			actions, err := policies.ParseActions(value)
+			require.NoError(t, err)
+			assert.Empty(t, actions)
+		}
+	})
+
+	t.Run("Forms", func(t *testing.T) {
+		actions, err := policies.ParseActions([]any{
+			"alert",
+			map[string]any{"action": "penalty", "tokens": float64(500)},
+			map[string]any{"action": "flag", "name": "heavy_user", "ttl": "1h"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []policies.Action{
+			{Type: policies.ActionAlert},
+			{Type: policies.ActionPenalty, Tokens: 500},
+			{Type: policies.ActionFlag, Name: "heavy_user", TTL: "1h"},
+		}, actions)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for name, value := range map[string]any{
+			"unknown action":     "shutdown",
+			"unknown field":      map[string]any{"action": "alert", "severity": "high"},
+			"flag without name":  map[string]any{"action": "flag"},
+			"bad ttl":            map[string]any{"action": "flag", "name": "x", "ttl": "soon"},
+			"penalty size":       map[string]any{"action": "penalty", "tokens": float64(0)},
+			"event without name": "event",
+			"number":             float64(3),
+		} {
+			_, err := policies.ParseActions(value)
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestCELPostCheckActions(t *testing.T) {
+	ctx := context.Background()
+	heavy := &policies.PostRequestContext{
+		OrgID: "org-1", AppID: "app-1", APIKeyID: "key-1",
+		ActualTokens: model.TokenUsage{CompletionTokens: 25000, TotalTokens: 26000},
+	}
+	light := &policies.PostRequestContext{
+		OrgID: "org-1", AppID: "app-1", APIKeyID: "key-1",
+		ActualTokens: model.TokenUsage{CompletionTokens: 100, TotalTokens: 200},
+	}
+
+	newPolicy := func(t *testing.T, engine *policies.Engine, config string) policies.Policy {
+		t.Helper()
+		policy, err := engine.NewPolicy(model.PolicyTypeCustomCEL, []byte(config))
+		require.NoError(t, err)
+		return policy
+	}
+
+	t.Run("Alert", func(t *testing.T) {
+		engine := policies.NewEngine(nil, kv.NewMemoryStore())
+		sink := &recordingAlertSink{}
+		engine.SetAlertSink(sink)
+		policy := newPolicy(t, engine, `{
+			"post_check_expression": "completion_tokens > 20000 ? {'action': 'alert', 'name': 'long_completion', 'message': 'Completion over 20k tokens', 'data': {'tokens': completion_tokens}} : {}"
+		}`)
+
+		policy.PostCheck(ctx, light)
+		assert.Empty(t, sink.alerts)
+
+		policy.PostCheck(ctx, heavy)
+		require.Len(t, sink.alerts, 1)
+		assert.Equal(t, "long_completion", sink.alerts[0].Type)
+		assert.Equal(t, string(model.PolicyTypeCustomCEL), sink.alerts[0].PolicyType)
+		assert.Equal(t, "key-1", sink.alerts[0].APIKeyID)
+		assert.Equal(t, float64(25000), sink.alerts[0].Data["tokens"])
+	})
+
+	t.Run("FlagReadByPreCheck", func(t *testing.T) {
+		engine := policies.NewEngine(nil, kv.NewMemoryStore())
+		flagger := newPolicy(t, engine, `{
+			"post_check_expression": "completion_tokens > 20000 ? [{'action': 'flag', 'name': 'heavy_user', 'ttl': '1h'}] : []"
+		}`)
+		guard := newPolicy(t, engine, `{"pre_check_expression": "!flagged('heavy_user')"}`)
+
+		key1 := &policies.PreRequestContext{OrgID: "org-1", AppID: "app-1", APIKeyID: "key-1"}
+		require.NoError(t, guard.PreCheck(ctx, key1))
+
+		flagger.PostCheck(ctx, light)
+		require.NoError(t, guard.PreCheck(ctx, key1))
+
+		flagger.PostCheck(ctx, heavy)
+		assert.Error(t, guard.PreCheck(ctx, key1))
+		assert.NoError(t, guard.PreCheck(ctx, &policies.PreRequestContext{OrgID: "org-1", AppID: "app-1", APIKeyID: "key-2"}))
+		assert.NoError(t, guard.PreCheck(ctx, &policies.PreRequestContext{OrgID: "org-2", AppID: "app-1", APIKeyID: "key-1"}))
+	})
+
+	t.Run("Penalty", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		engine := policies.NewEngine(nil, cache)
+		limit := policies.NewRateLimitPolicy(model.RateLimitConfig{TokensPerMinute: 1000}, cache)
+		policy := newPolicy(t, engine, `{
+			"post_check_expression": "completion_tokens > 20000 ? {'action': 'penalty', 'tokens': 600} : {}"
+		}`)
+
+		req := &policies.PreRequestContext{AppID: "app-1", EstimatedTokens: 200}
+		require.NoError(t, limit.PreCheck(ctx, req))
+
+		post := *heavy
+		post.Reservations = req.Reservations
+		policy.PostCheck(ctx, &post)
+
+		assert.Error(t, limit.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1", EstimatedTokens: 300}),
+			"200 reserved plus a 600 penalty leave no room for 300 more")
+		assert.NoError(t, limit.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1", EstimatedTokens: 200}))
+	})
+
+	t.Run("FailuresDoNotPanic", func(t *testing.T) {
+		// No database to revoke the key in and no reservations to penalise
+		engine := policies.NewEngine(nil, kv.NewMemoryStore())
+		policy := newPolicy(t, engine, `{
+			"post_check_expression": "['disable_key', {'action': 'penalty', 'tokens': 10}, {'action': 'event', 'name': 'abuse'}]"
+		}`)
+		policy.PostCheck(ctx, heavy)
+	})
+
+	t.Run("FalseRunsNothing", func(t *testing.T) {
+		engine := policies.NewEngine(nil, kv.NewMemoryStore())
+		sink := &recordingAlertSink{}
+		engine.SetAlertSink(sink)
+		policy := newPolicy(t, engine, `{"post_check_expression": "total_tokens < 1000"}`)
+		policy.PostCheck(ctx, heavy)
+		assert.Empty(t, sink.alerts)
+	})
+
+	t.Run("InvalidResultType", func(t *testing.T) {
+		_, err := policies.NewEngine(nil, kv.NewMemoryStore()).NewPolicy(model.PolicyTypeCustomCEL,
+			[]byte(`{"post_check_expression": "total_tokens * 2"}`))
+		assert.Error(t, err)
+	})
+}
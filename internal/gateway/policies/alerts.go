@@ -0,0 +1,98 @@
+package policies
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/async"
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+	"github.com/WebDeveloperBen/ai-gateway/internal/observability"
+)
+
+// Alert is an event raised by a policy that operators should hear about
+// without the request itself failing (e.g. a budget threshold was reached).
+type Alert struct {
+	Type       string         `json:"type"`
+	PolicyType string         `json:"policy_type"`
+	OrgID      string         `json:"org_id"`
+	AppID      string         `json:"app_id,omitempty"`
+	APIKeyID   string         `json:"api_key_id,omitempty"`
+	Message    string         `json:"message"`
+	Data       map[string]any `json:"data,omitempty"`
+	At         time.Time      `json:"at"`
+}
+
+// AlertSink receives policy alerts. Emit is called from the async post-check
+// path and must not block for long.
+type AlertSink interface {
+	Emit(ctx context.Context, alert Alert)
+}
+
+// LogAlertSink writes alerts to the structured log and the alert metric
+type LogAlertSink struct{}
+
+// Emit implements AlertSink
+func (LogAlertSink) Emit(ctx context.Context, alert Alert) {
+	observability.FromContext(ctx).RecordPolicyAlert(ctx, alert.PolicyType, alert.Type)
+	logger.GetLogger(ctx).Warn().
+		Str("alert_type", alert.Type).
+		Str("policy_type", alert.PolicyType).
+		Str("org_id", alert.OrgID).
+		Str("app_id", alert.AppID).
+		Interface("data", alert.Data).
+		Msg(alert.Message)
+}
+
+// WebhookAlertSink logs alerts and POSTs them as JSON to a URL, signed the
+// same way as async job callbacks when a secret is configured.
+type WebhookAlertSink struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// NewWebhookAlertSink creates a sink posting to url
+func NewWebhookAlertSink(url, secret string) *WebhookAlertSink {
+	return &WebhookAlertSink{URL: url, Secret: secret, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Emit implements AlertSink
+func (s *WebhookAlertSink) Emit(ctx context.Context, alert Alert) {
+	LogAlertSink{}.Emit(ctx, alert)
+	if err := s.post(ctx, alert); err != nil {
+		logger.GetLogger(ctx).Error().
+			Err(err).
+			Str("alert_type", alert.Type).
+			Str("org_id", alert.OrgID).
+			Msg("Failed to deliver policy alert webhook")
+	}
+}
+
+func (s *WebhookAlertSink) post(ctx context.Context, alert Alert) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Secret != "" {
+		req.Header.Set(async.SignatureHeader, async.Sign(s.Secret, time.Now(), payload))
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
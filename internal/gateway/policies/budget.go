@@ -0,0 +1,189 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+// Alert types raised by the budget policy
+const (
+	AlertBudgetThreshold = "budget.threshold"
+	AlertBudgetExceeded  = "budget.exceeded"
+)
+
+func init() {
+	Register(model.PolicyTypeBudget, func(config []byte, deps PolicyDependencies) (Policy, error) {
+		var cfg model.BudgetConfig
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid budget config: %w", err)
+		}
+		if cfg.Limit <= 0 {
+			return nil, fmt.Errorf("invalid budget config: limit must be greater than zero")
+		}
+		if cfg.Action == model.BudgetActionDowngrade && cfg.DowngradeModel == "" {
+			return nil, fmt.Errorf("invalid budget config: downgrade_model is required for the downgrade action")
+		}
+		return NewBudgetPolicy(cfg, deps.Cache, deps.Alerts), nil
+	})
+}
+
+// BudgetPolicy caps spend over a day or month. Spend is read from the ledger
+// kept by RecordSpend; the policy itself only raises alerts as thresholds are
+// crossed and blocks or downgrades requests once the cap is reached.
+type BudgetPolicy struct {
+	config     model.BudgetConfig
+	cache      kv.KvStore
+	alerts     AlertSink
+	limitMicro int64
+	now        func() time.Time
+}
+
+// NewBudgetPolicy creates a new budget policy, applying config defaults
+func NewBudgetPolicy(config model.BudgetConfig, cache kv.KvStore, alerts AlertSink) *BudgetPolicy {
+	if config.Scope == "" {
+		config.Scope = model.BudgetScopeApp
+	}
+	if config.Period == "" {
+		config.Period = model.BudgetPeriodMonthly
+	}
+	if config.Currency == "" {
+		config.Currency = model.DefaultCurrency
+	}
+	if len(config.AlertThresholds) == 0 {
+		config.AlertThresholds = []int{80, 100}
+	}
+	if config.Action == "" {
+		config.Action = model.BudgetActionBlock
+	}
+	if alerts == nil {
+		alerts = LogAlertSink{}
+	}
+	return &BudgetPolicy{
+		config:     config,
+		cache:      cache,
+		alerts:     alerts,
+		limitMicro: int64(math.Round(config.Limit * 1e6)),
+		now:        time.Now,
+	}
+}
+
+// Type returns the policy type
+func (p *BudgetPolicy) Type() model.PolicyType {
+	return model.PolicyTypeBudget
+}
+
+// scopeID picks the ID spend is tracked under for this policy's scope
+func (p *BudgetPolicy) scopeID(orgID, appID, apiKeyID string) string {
+	switch p.config.Scope {
+	case model.BudgetScopeOrg:
+		return orgID
+	case model.BudgetScopeKey:
+		return apiKeyID
+	default:
+		return appID
+	}
+}
+
+// PreCheck blocks or downgrades the request once the budget is used up
+func (p *BudgetPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error {
+	scopeID := p.scopeID(req.OrgID, req.AppID, req.APIKeyID)
+	if scopeID == "" {
+		return nil
+	}
+
+	spent, err := GetSpend(ctx, p.cache, p.config.Scope, scopeID, p.config.Currency, p.config.Period, p.now())
+	if err != nil {
+		// Fail open - a ledger outage shouldn't take down traffic
+		logger.GetLogger(ctx).Error().Err(err).Str("app_id", req.AppID).Msg("Failed to read budget spend")
+		return nil
+	}
+	if spent < p.limitMicro {
+		return nil
+	}
+
+	if p.config.Action == model.BudgetActionDowngrade {
+		if req.Model != p.config.DowngradeModel {
+			logger.GetLogger(ctx).Info().
+				Str("app_id", req.AppID).
+				Str("model", req.Model).
+				Str("downgrade_model", p.config.DowngradeModel).
+				Msg("Budget exhausted, downgrading model")
+			req.ModelOverride = p.config.DowngradeModel
+		}
+		return nil
+	}
+
+	logger.GetLogger(ctx).Warn().
+		Int64("spent_micros", spent).
+		Int64("limit_micros", p.limitMicro).
+		Str("scope", p.config.Scope).
+		Str("app_id", req.AppID).
+		Msg("Budget exceeded")
+	now := p.now()
+	_, periodEnd := periodBucket(p.config.Period, now)
+	return throttlef(periodEnd.Sub(now), "%s budget exceeded", p.config.Period)
+}
+
+// PostCheck raises an alert the first time spend crosses each threshold in a period
+func (p *BudgetPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {
+	if req.CostMicros <= 0 || req.Currency != p.config.Currency {
+		return
+	}
+	scopeID := p.scopeID(req.OrgID, req.AppID, req.APIKeyID)
+	if scopeID == "" {
+		return
+	}
+
+	now := p.now()
+	spent, err := GetSpend(ctx, p.cache, p.config.Scope, scopeID, p.config.Currency, p.config.Period, now)
+	if err != nil {
+		logger.GetLogger(ctx).Error().Err(err).Str("app_id", req.AppID).Msg("Failed to read budget spend")
+		return
+	}
+
+	bucket, end := periodBucket(p.config.Period, now)
+	for _, threshold := range p.config.AlertThresholds {
+		if spent*100 < p.limitMicro*int64(threshold) {
+			continue
+		}
+		// Only the first request past a threshold raises the alert
+		key := kv.KSBudgetAlert.Key(p.config.Scope, scopeID, p.config.Period, fmt.Sprint(p.limitMicro), fmt.Sprint(threshold), bucket)
+		n, err := p.cache.Incr(ctx, key)
+		if err != nil || n != 1 {
+			continue
+		}
+		_, _ = p.cache.Expire(ctx, key, end.Sub(now)+24*time.Hour)
+
+		alertType := AlertBudgetThreshold
+		if threshold >= 100 {
+			alertType = AlertBudgetExceeded
+		}
+		p.alerts.Emit(ctx, Alert{
+			Type:       alertType,
+			PolicyType: string(model.PolicyTypeBudget),
+			OrgID:      req.OrgID,
+			AppID:      req.AppID,
+			APIKeyID:   req.APIKeyID,
+			Message:    fmt.Sprintf("%s %s budget reached %d%% of %.2f %s", p.config.Scope, p.config.Period, threshold, p.config.Limit, p.config.Currency),
+			Data: map[string]any{
+				"scope":        p.config.Scope,
+				"scope_id":     scopeID,
+				"period":       p.config.Period,
+				"threshold":    threshold,
+				"limit":        p.config.Limit,
+				"spent":        float64(spent) / 1e6,
+				"currency":     p.config.Currency,
+				"action":       p.config.Action,
+				"period_start": bucket,
+			},
+			At: now,
+		})
+	}
+}
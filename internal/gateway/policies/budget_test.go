@@ -0,0 +1,126 @@
+package policies_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAlertSink collects emitted alerts
+type recordingAlertSink struct {
+	mu     sync.Mutex
+	alerts []policies.Alert
+}
+
+func (s *recordingAlertSink) Emit(_ context.Context, alert policies.Alert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts = append(s.alerts, alert)
+}
+
+// spend records costMicros against app-1 and runs the policy's post-check
+func spend(t *testing.T, cache kv.KvStore, p policies.Policy, costMicros int64) {
+	t.Helper()
+	ctx := context.Background()
+	require.NoError(t, policies.RecordSpend(ctx, cache, policies.SpendEntry{
+		OrgID: "org-1", AppID: "app-1", APIKeyID: "key-1",
+		Currency: model.DefaultCurrency, CostMicros: costMicros, At: time.Now(),
+	}))
+	p.PostCheck(ctx, &policies.PostRequestContext{
+		OrgID: "org-1", AppID: "app-1", APIKeyID: "key-1",
+		CostMicros: costMicros, Currency: model.DefaultCurrency,
+	})
+}
+
+func TestBudgetPolicy(t *testing.T) {
+	ctx := context.Background()
+	pre := func() *policies.PreRequestContext {
+		return &policies.PreRequestContext{OrgID: "org-1", AppID: "app-1", APIKeyID: "key-1", Model: "gpt-4o"}
+	}
+
+	t.Run("BlocksAtHardCap", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		p := policies.NewBudgetPolicy(model.BudgetConfig{Limit: 1}, cache, &recordingAlertSink{})
+
+		require.NoError(t, p.PreCheck(ctx, pre()))
+		spend(t, cache, p, 1_000_000)
+		assert.Error(t, p.PreCheck(ctx, pre()))
+	})
+
+	t.Run("DowngradesAtHardCap", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		p := policies.NewBudgetPolicy(model.BudgetConfig{
+			Limit: 1, Action: model.BudgetActionDowngrade, DowngradeModel: "gpt-4o-mini",
+		}, cache, &recordingAlertSink{})
+		spend(t, cache, p, 2_000_000)
+
+		req := pre()
+		require.NoError(t, p.PreCheck(ctx, req))
+		assert.Equal(t, "gpt-4o-mini", req.ModelOverride)
+
+		already := pre()
+		already.Model = "gpt-4o-mini"
+		require.NoError(t, p.PreCheck(ctx, already))
+		assert.Empty(t, already.ModelOverride)
+	})
+
+	t.Run("AlertsOncePerThreshold", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		sink := &recordingAlertSink{}
+		p := policies.NewBudgetPolicy(model.BudgetConfig{Limit: 10}, cache, sink)
+
+		spend(t, cache, p, 5_000_000) // 50%
+		assert.Empty(t, sink.alerts)
+		spend(t, cache, p, 3_000_000) // 80%
+		spend(t, cache, p, 1_000_000) // 90%
+		require.Len(t, sink.alerts, 1)
+		assert.Equal(t, policies.AlertBudgetThreshold, sink.alerts[0].Type)
+		assert.Equal(t, 80, sink.alerts[0].Data["threshold"])
+
+		spend(t, cache, p, 1_000_000) // 100%
+		spend(t, cache, p, 1_000_000) // 110%
+		require.Len(t, sink.alerts, 2)
+		assert.Equal(t, policies.AlertBudgetExceeded, sink.alerts[1].Type)
+	})
+
+	t.Run("ScopesAreIndependent", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		orgBudget := policies.NewBudgetPolicy(model.BudgetConfig{Scope: model.BudgetScopeOrg, Limit: 1}, cache, nil)
+		spend(t, cache, orgBudget, 1_000_000)
+
+		require.Error(t, orgBudget.PreCheck(ctx, pre()))
+		other := pre()
+		other.OrgID = "org-2"
+		assert.NoError(t, orgBudget.PreCheck(ctx, other))
+	})
+
+	t.Run("IgnoresOtherCurrencies", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		sink := &recordingAlertSink{}
+		p := policies.NewBudgetPolicy(model.BudgetConfig{Limit: 1, Currency: "EUR"}, cache, sink)
+		spend(t, cache, p, 5_000_000)
+
+		assert.NoError(t, p.PreCheck(ctx, pre()))
+		assert.Empty(t, sink.alerts)
+	})
+}
+
+func TestBudgetPolicyFactory(t *testing.T) {
+	engine := policies.NewEngine(nil, kv.NewMemoryStore())
+
+	_, err := engine.NewPolicy(model.PolicyTypeBudget, []byte(`{"limit":100,"period":"daily"}`))
+	require.NoError(t, err)
+
+	_, err = engine.NewPolicy(model.PolicyTypeBudget, []byte(`{"limit":0}`))
+	assert.Error(t, err)
+
+	_, err = engine.NewPolicy(model.PolicyTypeBudget, []byte(`{"limit":100,"action":"downgrade"}`))
+	assert.Error(t, err)
+}
@@ -15,18 +15,36 @@ const (
 	PolicyCacheTTL = 5 * time.Minute
 
 	// Cache key prefixes
-	PolicyCachePrefix = "policy:app:"
+	PolicyCachePrefix = "policy:"
 )
 
-// CacheKey generates a Redis key for caching policies
-func CacheKey(appID string) string {
-	return fmt.Sprintf("%s%s:policies", PolicyCachePrefix, appID)
+// CacheKey generates a Redis key for caching the merged policies of a scope
+func CacheKey(scope Scope) string {
+	return fmt.Sprintf("%sorg:%s:app:%s:user:%s:key:%s:policies",
+		PolicyCachePrefix, scope.OrgID, scope.AppID, scope.UserID, scope.KeyID)
+}
+
+// CachePattern matches the cache keys of every scope that includes the given
+// org, app, user or key
+func CachePattern(level model.PolicyScope, id string) string {
+	scope := Scope{OrgID: "*", AppID: "*", UserID: "*", KeyID: "*"}
+	switch level {
+	case model.PolicyScopeOrg:
+		scope.OrgID = id
+	case model.PolicyScopeApp:
+		scope.AppID = id
+	case model.PolicyScopeUser:
+		scope.UserID = id
+	case model.PolicyScopeKey:
+		scope.KeyID = id
+	}
+	return CacheKey(scope)
 }
 
 // GetCachedPolicies retrieves policies from cache
 // Note: Returns cached policy data that must be reconstructed with Engine.NewPolicy
-func GetCachedPolicies(ctx context.Context, cache kv.KvStore, appID string) ([]CachedPolicy, bool, error) {
-	key := CacheKey(appID)
+func GetCachedPolicies(ctx context.Context, cache kv.KvStore, scope Scope) ([]CachedPolicy, bool, error) {
+	key := CacheKey(scope)
 
 	data, err := cache.Get(ctx, key)
 	if err != nil {
@@ -48,24 +66,34 @@ func GetCachedPolicies(ctx context.Context, cache kv.KvStore, appID string) ([]C
 }
 
 // SetCachedPoliciesRaw stores policies in cache from their raw DB representation
-func SetCachedPoliciesRaw(ctx context.Context, cache kv.KvStore, appID string, policies []CachedPolicy) error {
+func SetCachedPoliciesRaw(ctx context.Context, cache kv.KvStore, scope Scope, policies []CachedPolicy) error {
 	data, err := json.Marshal(policies)
 	if err != nil {
 		return fmt.Errorf("failed to marshal policies: %w", err)
 	}
 
-	key := CacheKey(appID)
+	key := CacheKey(scope)
 	return cache.Set(ctx, key, string(data), PolicyCacheTTL)
 }
 
-// InvalidatePolicyCache removes cached policies for an app
-func InvalidatePolicyCache(ctx context.Context, cache kv.KvStore, appID string) error {
-	key := CacheKey(appID)
-	return cache.Del(ctx, key)
+// InvalidatePolicyCache removes cached policies for every scope that includes
+// the given org, app, user or key
+func InvalidatePolicyCache(ctx context.Context, cache kv.KvStore, level model.PolicyScope, id string) error {
+	keys, err := cache.ScanAll(ctx, CachePattern(level, id), 100)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := cache.Del(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // CachedPolicy represents a policy in cache-friendly format
 type CachedPolicy struct {
 	Type   model.PolicyType `json:"type"`
 	Config []byte           `json:"config"`
+	Mode   model.PolicyMode `json:"mode,omitempty"` // empty means enforce
 }
@@ -2,6 +2,7 @@ package policies_test
 
 import (
 	"context"
+	"path"
 	"testing"
 	"time"
 
@@ -47,11 +48,11 @@ func TestCacheIntegrationWithRedis(t *testing.T) {
 		}
 
 		// Store in cache
-		err := policies.SetCachedPoliciesRaw(ctx, cache, appID, cachedPolicies)
+		err := policies.SetCachedPoliciesRaw(ctx, cache, policies.Scope{AppID: appID}, cachedPolicies)
 		require.NoError(t, err)
 
 		// Retrieve from cache
-		retrieved, found, err := policies.GetCachedPolicies(ctx, cache, appID)
+		retrieved, found, err := policies.GetCachedPolicies(ctx, cache, policies.Scope{AppID: appID})
 		require.NoError(t, err)
 		require.True(t, found, "Policies should be found in cache")
 		require.Len(t, retrieved, 2, "Should have 2 cached policies")
@@ -67,7 +68,7 @@ func TestCacheIntegrationWithRedis(t *testing.T) {
 		appID := "nonexistent-app"
 
 		// Try to get policies for non-existent app
-		retrieved, found, err := policies.GetCachedPolicies(ctx, cache, appID)
+		retrieved, found, err := policies.GetCachedPolicies(ctx, cache, policies.Scope{AppID: appID})
 		// Redis returns "redis: nil" for non-existent keys, which we treat as cache miss
 		if err != nil && err.Error() == "redis: nil" {
 			require.False(t, found, "Should not find policies for non-existent app")
@@ -90,20 +91,20 @@ func TestCacheIntegrationWithRedis(t *testing.T) {
 			},
 		}
 
-		err := policies.SetCachedPoliciesRaw(ctx, cache, appID, cachedPolicies)
+		err := policies.SetCachedPoliciesRaw(ctx, cache, policies.Scope{AppID: appID}, cachedPolicies)
 		require.NoError(t, err)
 
 		// Verify they're cached
-		_, found, err := policies.GetCachedPolicies(ctx, cache, appID)
+		_, found, err := policies.GetCachedPolicies(ctx, cache, policies.Scope{AppID: appID})
 		require.NoError(t, err)
 		require.True(t, found, "Policies should be cached")
 
 		// Invalidate cache
-		err = policies.InvalidatePolicyCache(ctx, cache, appID)
+		err = policies.InvalidatePolicyCache(ctx, cache, model.PolicyScopeApp, appID)
 		require.NoError(t, err)
 
 		// Verify they're gone
-		_, found, err = policies.GetCachedPolicies(ctx, cache, appID)
+		_, found, err = policies.GetCachedPolicies(ctx, cache, policies.Scope{AppID: appID})
 		// Redis returns "redis: nil" for non-existent keys, which we treat as cache miss
 		if err != nil && err.Error() == "redis: nil" {
 			require.False(t, found, "Policies should be removed after invalidation")
@@ -114,11 +115,15 @@ func TestCacheIntegrationWithRedis(t *testing.T) {
 	})
 
 	t.Run("CacheKeyGeneration", func(t *testing.T) {
-		appID := "test-app-key"
-		expectedKey := "policy:app:test-app-key:policies"
+		scope := policies.Scope{OrgID: "org-1", AppID: "test-app-key", UserID: "user-1", KeyID: "key-1"}
+		expectedKey := "policy:org:org-1:app:test-app-key:user:user-1:key:key-1:policies"
 
-		key := policies.CacheKey(appID)
+		key := policies.CacheKey(scope)
 		require.Equal(t, expectedKey, key)
+
+		matched, err := path.Match(policies.CachePattern(model.PolicyScopeUser, "user-1"), key)
+		require.NoError(t, err)
+		require.True(t, matched, "invalidating the user must cover every scope that includes it")
 	})
 
 	t.Run("CacheExpiration", func(t *testing.T) {
@@ -132,11 +137,11 @@ func TestCacheIntegrationWithRedis(t *testing.T) {
 			},
 		}
 
-		err := policies.SetCachedPoliciesRaw(ctx, cache, appID, cachedPolicies)
+		err := policies.SetCachedPoliciesRaw(ctx, cache, policies.Scope{AppID: appID}, cachedPolicies)
 		require.NoError(t, err)
 
 		// Verify they're cached immediately
-		_, found, err := policies.GetCachedPolicies(ctx, cache, appID)
+		_, found, err := policies.GetCachedPolicies(ctx, cache, policies.Scope{AppID: appID})
 		require.NoError(t, err)
 		require.True(t, found, "Policies should be cached immediately")
 
@@ -146,7 +151,7 @@ func TestCacheIntegrationWithRedis(t *testing.T) {
 		time.Sleep(1 * time.Second)
 
 		// Should still be cached (since TTL is 5 minutes)
-		_, found, err = policies.GetCachedPolicies(ctx, cache, appID)
+		_, found, err = policies.GetCachedPolicies(ctx, cache, policies.Scope{AppID: appID})
 		require.NoError(t, err)
 		require.True(t, found, "Policies should still be cached after 1 second")
 	})
@@ -4,46 +4,81 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
 	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
 	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 // CELPolicy represents a policy that uses CEL expressions for evaluation
 type CELPolicy struct {
 	policyType    model.PolicyType
-	preCheckExpr  cel.Program // Compiled CEL expression for pre-check
-	postCheckExpr cel.Program // Compiled CEL expression for post-check (optional)
-	config        []byte      // Original config for reference
+	preCheckExpr  cel.Program    // Compiled CEL expression for pre-check
+	postCheckExpr cel.Program    // Compiled CEL expression for post-check (optional)
+	config        []byte         // Original config for reference
+	location      *time.Location // timezone for hour and weekday
+	now           func() time.Time
+	actions       ActionExecutor // runs the actions the post-check returns
 }
 
-// NewCELPolicy creates a new CEL-based policy
-func NewCELPolicy(policyType model.PolicyType, config []byte) (*CELPolicy, error) {
+// celOrgVar and celSubjectVar hold the org ID and flag subject counter() and
+// flagged() are scoped to. Their names can't be written in an expression, so
+// they can't be shadowed or spoofed.
+const (
+	celOrgVar     = "@org_id"
+	celSubjectVar = "@flag_subject"
+)
+
+// NewCELPolicy creates a new CEL-based policy. deps.Cache backs counter() and
+// flagged(); without one, expressions calling them fail to evaluate.
+// deps.Actions runs the actions a post-check returns.
+func NewCELPolicy(policyType model.PolicyType, config []byte, deps PolicyDependencies) (*CELPolicy, error) {
 	// Parse config to get CEL expressions
 	type celConfig struct {
 		PreCheckExpression  string `json:"pre_check_expression"`
 		PostCheckExpression string `json:"post_check_expression,omitempty"`
+		Timezone            string `json:"timezone,omitempty"`
 	}
 
 	var cfg celConfig
 	if err := json.Unmarshal(config, &cfg); err != nil {
 		return nil, fmt.Errorf("invalid CEL policy config: %w", err)
 	}
+	location := time.UTC
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CEL policy config: timezone: %w", err)
+		}
+		location = loc
+	}
 
-	// Create CEL environment with available variables
-	env, err := cel.NewEnv(
-		cel.Variable("request_size_bytes", cel.IntType),
-		cel.Variable("estimated_tokens", cel.IntType),
-		cel.Variable("model", cel.StringType),
-		cel.Variable("org_id", cel.StringType),
-		cel.Variable("app_id", cel.StringType),
-		cel.Variable("prompt_tokens", cel.IntType),
-		cel.Variable("completion_tokens", cel.IntType),
-		cel.Variable("total_tokens", cel.IntType),
-		cel.Variable("latency_ms", cel.IntType),
-		cel.Variable("response_size_bytes", cel.IntType),
-	)
+	// Count dry-run traffic separately
+	counterPrefix := ""
+	if deps.DryRun {
+		counterPrefix = DryRunKeyPrefix
+	}
+
+	// Each phase gets its own environment, so an expression reading a variable
+	// its phase doesn't have fails to compile instead of failing every request
+	functions := celFunctions(deps.Cache, counterPrefix)
+	preEnv, err := cel.NewEnv(append(celPreCheckVars(), functions)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	postEnv, err := cel.NewEnv(append(celPostCheckVars(), functions)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
 	}
@@ -51,11 +86,11 @@ func NewCELPolicy(policyType model.PolicyType, config []byte) (*CELPolicy, error
 	// Compile pre-check expression
 	var preCheck cel.Program
 	if cfg.PreCheckExpression != "" {
-		ast, issues := env.Compile(cfg.PreCheckExpression)
+		ast, issues := preEnv.Compile(cfg.PreCheckExpression)
 		if issues != nil && issues.Err() != nil {
 			return nil, fmt.Errorf("failed to compile pre-check expression: %w", issues.Err())
 		}
-		preCheck, err = env.Program(ast)
+		preCheck, err = preEnv.Program(ast)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create pre-check program: %w", err)
 		}
@@ -64,11 +99,16 @@ func NewCELPolicy(policyType model.PolicyType, config []byte) (*CELPolicy, error
 	// Compile post-check expression (optional)
 	var postCheck cel.Program
 	if cfg.PostCheckExpression != "" {
-		ast, issues := env.Compile(cfg.PostCheckExpression)
+		ast, issues := postEnv.Compile(cfg.PostCheckExpression)
 		if issues != nil && issues.Err() != nil {
 			return nil, fmt.Errorf("failed to compile post-check expression: %w", issues.Err())
 		}
-		postCheck, err = env.Program(ast)
+		switch ast.OutputType().Kind() {
+		case types.BoolKind, types.StringKind, types.MapKind, types.ListKind, types.DynKind:
+		default:
+			return nil, fmt.Errorf("post-check expression must return a boolean, an action or a list of actions, got %s", ast.OutputType())
+		}
+		postCheck, err = postEnv.Program(ast)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create post-check program: %w", err)
 		}
@@ -79,9 +119,55 @@ func NewCELPolicy(policyType model.PolicyType, config []byte) (*CELPolicy, error
 		preCheckExpr:  preCheck,
 		postCheckExpr: postCheck,
 		config:        config,
+		location:      location,
+		now:           time.Now,
+		actions:       deps.Actions,
 	}, nil
 }
 
+// celSharedVars are available to both pre-check and post-check expressions
+func celSharedVars() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Variable("request_size_bytes", cel.IntType),
+		cel.Variable("model", cel.StringType),
+		cel.Variable("org_id", cel.StringType),
+		cel.Variable("app_id", cel.StringType),
+		cel.Variable("api_key_id", cel.StringType),
+		cel.Variable("now", cel.TimestampType),
+		cel.Variable("hour", cel.IntType),
+		cel.Variable("weekday", cel.StringType),
+		cel.Variable(celOrgVar, cel.StringType),
+		cel.Variable(celSubjectVar, cel.StringType),
+	}
+}
+
+// celPreCheckVars describe the request a pre-check expression sees
+func celPreCheckVars() []cel.EnvOption {
+	return append(celSharedVars(),
+		cel.Variable("estimated_tokens", cel.IntType),
+		cel.Variable("user_id", cel.StringType),
+		cel.Variable("key_metadata", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("headers", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("path", cel.StringType),
+		cel.Variable("endpoint", cel.StringType),
+		cel.Variable("stream", cel.BoolType),
+		cel.Variable("messages", cel.ListType(cel.MapType(cel.StringType, cel.StringType))),
+		cel.Variable("prompt", cel.StringType),
+		cel.Variable("tools", cel.ListType(cel.StringType)),
+	)
+}
+
+// celPostCheckVars describe the response a post-check expression sees
+func celPostCheckVars() []cel.EnvOption {
+	return append(celSharedVars(),
+		cel.Variable("prompt_tokens", cel.IntType),
+		cel.Variable("completion_tokens", cel.IntType),
+		cel.Variable("total_tokens", cel.IntType),
+		cel.Variable("latency_ms", cel.IntType),
+		cel.Variable("response_size_bytes", cel.IntType),
+	)
+}
+
 // Type returns the policy type
 func (p *CELPolicy) Type() model.PolicyType {
 	return p.policyType
@@ -100,7 +186,20 @@ func (p *CELPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error
 		"model":              req.Model,
 		"org_id":             req.OrgID,
 		"app_id":             req.AppID,
+		"api_key_id":         req.APIKeyID,
+		"user_id":            req.UserID,
+		"key_metadata":       celKeyMetadata(req.KeyMetadata),
+		"headers":            celHeaders(req),
+		"path":               req.Endpoint,
+		"endpoint":           provider.EndpointKind(req.Endpoint),
+		"stream":             req.Stream,
+		"messages":           celMessages(req.Messages),
+		"prompt":             req.Prompt,
+		"tools":              celToolNames(req.Tools),
+		celOrgVar:            req.OrgID,
+		celSubjectVar:        flagSubject(req.APIKeyID, req.AppID),
 	}
+	p.addTime(vars)
 
 	// Evaluate expression
 	out, _, err := p.preCheckExpr.Eval(vars)
@@ -121,7 +220,8 @@ func (p *CELPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error
 	return nil
 }
 
-// PostCheck evaluates the CEL post-check expression (async)
+// PostCheck evaluates the CEL post-check expression (async) and hands any
+// actions it returns to the engine. A false result is only logged.
 func (p *CELPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {
 	if p.postCheckExpr == nil {
 		return // No post-check expression
@@ -134,21 +234,254 @@ func (p *CELPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {
 		"total_tokens":        req.ActualTokens.TotalTokens,
 		"latency_ms":          req.LatencyMs,
 		"response_size_bytes": req.ResponseSizeBytes,
+		"request_size_bytes":  req.RequestSizeBytes,
 		"model":               req.ModelName,
 		"org_id":              req.OrgID,
 		"app_id":              req.AppID,
+		"api_key_id":          req.APIKeyID,
+		celOrgVar:             req.OrgID,
+		celSubjectVar:         flagSubject(req.APIKeyID, req.AppID),
 	}
+	p.addTime(vars)
 
 	// Evaluate expression (errors are logged but don't block)
-	_, _, err := p.postCheckExpr.Eval(vars)
-	if err != nil {
-		logger.GetLogger(ctx).Error().
-			Err(err).
+	out, _, err := p.postCheckExpr.Eval(vars)
+	if err == nil {
+		var actions []Action
+		if actions, err = celActions(out); err == nil {
+			p.runActions(ctx, req, out, actions)
+			return
+		}
+	}
+	logger.GetLogger(ctx).Error().
+		Err(err).
+		Str("app_id", req.AppID).
+		Str("policy_type", string(p.policyType)).
+		Msg("CEL post-check evaluation failed")
+}
+
+// runActions logs a failed condition and passes actions on to the executor
+func (p *CELPolicy) runActions(ctx context.Context, req *PostRequestContext, out ref.Val, actions []Action) {
+	if out == types.False {
+		logger.GetLogger(ctx).Warn().
 			Str("app_id", req.AppID).
 			Str("policy_type", string(p.policyType)).
-			Msg("CEL post-check evaluation failed")
+			Msg("CEL post-check returned false")
+	}
+	if len(actions) == 0 {
+		return
+	}
+	if p.actions == nil {
+		logger.GetLogger(ctx).Warn().
+			Int("actions", len(actions)).
+			Str("policy_type", string(p.policyType)).
+			Msg("No executor configured for CEL post-check actions")
 		return
 	}
+	p.actions.ExecuteActions(ctx, p.policyType, req, actions)
+}
 
-	// Post-check is for logging/metrics, not enforcement
+// celActions converts a post-check result to actions
+func celActions(out ref.Val) ([]Action, error) {
+	native, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, err
+	}
+	return ParseActions(native.(*structpb.Value).AsInterface())
+}
+
+// addTime sets now, and hour and weekday in the policy's timezone
+func (p *CELPolicy) addTime(vars map[string]any) {
+	now := p.now().In(p.location)
+	vars["now"] = now
+	vars["hour"] = now.Hour()
+	vars["weekday"] = strings.ToLower(now.Weekday().String())
+}
+
+// celCredentialHeaders are never shown to CEL expressions: a policy could
+// otherwise test a caller's or the gateway's provider key one character at a
+// time
+var celCredentialHeaders = map[string]bool{
+	"authorization":             true,
+	"proxy-authorization":       true,
+	"api-key":                   true,
+	"x-api-key":                 true,
+	"cookie":                    true,
+	"ocp-apim-subscription-key": true,
+	"x-goog-api-key":            true,
+}
+
+// celHeaders flattens the caller's headers into a map keyed by lowercase
+// name, leaving out credentials
+func celHeaders(req *PreRequestContext) map[string]string {
+	out := make(map[string]string, len(req.CallerHeader))
+	for name, values := range req.CallerHeader {
+		name = strings.ToLower(name)
+		if celCredentialHeaders[name] {
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// celMessages exposes chat messages as role/content maps
+func celMessages(messages []Message) []map[string]string {
+	out := make([]map[string]string, len(messages))
+	for i, msg := range messages {
+		out[i] = map[string]string{"role": msg.Role, "content": msg.Content}
+	}
+	return out
+}
+
+// celToolNames lists the names of the tools offered to the model
+func celToolNames(tools []Tool) []string {
+	out := make([]string, len(tools))
+	for i, tool := range tools {
+		out[i] = tool.Name
+	}
+	return out
+}
+
+// celKeyMetadata decodes the API key's metadata; anything but a JSON object
+// is exposed as an empty map
+func celKeyMetadata(raw []byte) map[string]any {
+	metadata := map[string]any{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &metadata); err != nil || metadata == nil {
+			return map[string]any{}
+		}
+	}
+	return metadata
+}
+
+// celPIIDetectors are the detectors contains_pii() runs
+var celPIIDetectors, _ = builtinDetectors(PIIDetectors)
+
+// celRegexes caches the patterns regex() compiles
+var celRegexes sync.Map
+
+// celFunctions declares the custom functions available to expressions:
+//
+//	regex(text, pattern)   whether text matches the RE2 pattern
+//	contains_pii(text)     whether a built-in PII detector finds anything
+//	counter(key, window)   increments the org's named counter for the current
+//	                       window (e.g. "1h") and returns its new value
+//	flagged(name)          whether a post-check flag action has marked the
+//	                       request's API key (or app) with name
+//
+// counterPrefix is put in front of counter keys so dry-run policies don't
+// share counts with enforced ones.
+func celFunctions(cache kv.KvStore, counterPrefix string) cel.EnvOption {
+	return cel.Lib(celLibrary{cache: cache, counterPrefix: counterPrefix})
+}
+
+type celLibrary struct {
+	cache         kv.KvStore
+	counterPrefix string
+}
+
+func (l celLibrary) LibraryName() string {
+	return "gateway"
+}
+
+func (l celLibrary) ProgramOptions() []cel.ProgramOption {
+	return nil
+}
+
+func (l celLibrary) CompileOptions() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("regex",
+			cel.Overload("regex_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(func(text, pattern ref.Val) ref.Val {
+					re, err := celRegex(string(pattern.(types.String)))
+					if err != nil {
+						return types.NewErr("regex: %v", err)
+					}
+					return types.Bool(re.MatchString(string(text.(types.String))))
+				}))),
+		cel.Function("contains_pii",
+			cel.Overload("contains_pii_string", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(text ref.Val) ref.Val {
+					found := map[string]bool{}
+					redactMatches(celPIIDetectors, string(text.(types.String)), found)
+					return types.Bool(len(found) > 0)
+				}))),
+		// counter(key, window) expands to @counter(@org_id, key, window) so
+		// counters are always scoped to the caller's org
+		cel.Macros(cel.GlobalMacro("counter", 2, func(eh cel.MacroExprFactory, target ast.Expr, args []ast.Expr) (ast.Expr, *common.Error) {
+			return eh.NewCall("@counter", append([]ast.Expr{eh.NewIdent(celOrgVar)}, args...)...), nil
+		})),
+		// flagged(name) expands to @flagged(@org_id, @flag_subject, name)
+		cel.Macros(cel.GlobalMacro("flagged", 1, func(eh cel.MacroExprFactory, target ast.Expr, args []ast.Expr) (ast.Expr, *common.Error) {
+			return eh.NewCall("@flagged", eh.NewIdent(celOrgVar), eh.NewIdent(celSubjectVar), args[0]), nil
+		})),
+		cel.Function("@flagged",
+			cel.Overload("flagged_string_string_string", []*cel.Type{cel.StringType, cel.StringType, cel.StringType}, cel.BoolType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					org, subject, name := string(args[0].(types.String)), string(args[1].(types.String)), string(args[2].(types.String))
+					return l.flagged(org, subject, name)
+				}))),
+		cel.Function("@counter",
+			cel.Overload("counter_string_string_string", []*cel.Type{cel.StringType, cel.StringType, cel.StringType}, cel.IntType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					org, key, window := string(args[0].(types.String)), string(args[1].(types.String)), string(args[2].(types.String))
+					return l.counter(org, key, window)
+				}))),
+	}
+}
+
+// counter increments a fixed-window counter. Store errors fail open, like the
+// rate limiter: the count reads as 0.
+func (l celLibrary) counter(org, key, window string) ref.Val {
+	if l.cache == nil {
+		return types.NewErr("counter: no cache configured")
+	}
+	size, err := time.ParseDuration(window)
+	if err != nil || size < time.Second {
+		return types.NewErr("counter: invalid window %q", window)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	bucket := time.Now().Unix() / int64(size/time.Second)
+	cacheKey := fmt.Sprintf("%scelcounter:%s:%s:%s:%d", l.counterPrefix, org, window, key, bucket)
+	count, err := l.cache.Incr(ctx, cacheKey)
+	if err != nil {
+		logger.GetLogger(ctx).Warn().Err(err).Str("key", key).Msg("CEL counter unavailable")
+		return types.Int(0)
+	}
+	if count == 1 {
+		_, _ = l.cache.Expire(ctx, cacheKey, size)
+	}
+	return types.Int(count)
+}
+
+// flagged looks up a flag set by a post-check action. Store errors fail open:
+// the flag reads as unset.
+func (l celLibrary) flagged(org, subject, name string) ref.Val {
+	if l.cache == nil {
+		return types.NewErr("flagged: no cache configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ok, err := l.cache.Exists(ctx, FlagKey(org, subject, name))
+	if err != nil {
+		logger.GetLogger(ctx).Warn().Err(err).Str("flag", name).Msg("CEL flag lookup unavailable")
+		return types.False
+	}
+	return types.Bool(ok)
+}
+
+// celRegex compiles a pattern once
+func celRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := celRegexes.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	celRegexes.Store(pattern, re)
+	return re, nil
 }
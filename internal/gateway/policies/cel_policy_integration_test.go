@@ -21,7 +21,7 @@ func TestCELPolicyIntegration(t *testing.T) {
 			"pre_check_expression": "estimated_tokens < 1000 && model == 'gpt-4'"
 		}`
 
-		policy, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config))
+		policy, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config), policies.PolicyDependencies{})
 		require.NoError(t, err)
 
 		req := &policies.PreRequestContext{
@@ -41,7 +41,7 @@ func TestCELPolicyIntegration(t *testing.T) {
 			"pre_check_expression": "estimated_tokens < 1000 && model == 'gpt-4'"
 		}`
 
-		policy, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config))
+		policy, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config), policies.PolicyDependencies{})
 		require.NoError(t, err)
 
 		// Test cases that should fail
@@ -85,7 +85,7 @@ func TestCELPolicyIntegration(t *testing.T) {
 			"pre_check_expression": "estimated_tokens <= 2000 && (model == 'gpt-4' || model == 'claude-3') && request_size_bytes < 50000"
 		}`
 
-		policy, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config))
+		policy, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config), policies.PolicyDependencies{})
 		require.NoError(t, err)
 
 		// Valid request
@@ -112,7 +112,7 @@ func TestCELPolicyIntegration(t *testing.T) {
 			"post_check_expression": "total_tokens < 2000 && latency_ms < 30000"
 		}`
 
-		policy, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config))
+		policy, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config), policies.PolicyDependencies{})
 		require.NoError(t, err)
 
 		// Pre-check should pass
@@ -149,7 +149,7 @@ func TestCELPolicyIntegration(t *testing.T) {
 			"pre_check_expression": "invalid_syntax +++"
 		}`
 
-		_, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config))
+		_, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config), policies.PolicyDependencies{})
 		require.Error(t, err, "Invalid CEL expression should fail compilation")
 		require.Contains(t, err.Error(), "failed to compile")
 	})
@@ -159,7 +159,7 @@ func TestCELPolicyIntegration(t *testing.T) {
 			"pre_check_expression": "estimated_tokens + 100"
 		}`
 
-		policy, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config))
+		policy, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config), policies.PolicyDependencies{})
 		require.NoError(t, err)
 
 		req := &policies.PreRequestContext{
@@ -177,7 +177,7 @@ func TestCELPolicyIntegration(t *testing.T) {
 			"post_check_expression": "total_tokens < 1000"
 		}`
 
-		policy, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config))
+		policy, err := policies.NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config), policies.PolicyDependencies{})
 		require.NoError(t, err)
 
 		req := &policies.PreRequestContext{
@@ -196,7 +196,7 @@ func TestCELPolicyIntegration(t *testing.T) {
 		}`
 
 		policyType := model.PolicyType("custom_test_policy")
-		policy, err := policies.NewCELPolicy(policyType, []byte(config))
+		policy, err := policies.NewCELPolicy(policyType, []byte(config), policies.PolicyDependencies{})
 		require.NoError(t, err)
 
 		require.Equal(t, policyType, policy.Type())
@@ -0,0 +1,175 @@
+package policies
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCELPolicy(t *testing.T, config string, deps PolicyDependencies) *CELPolicy {
+	t.Helper()
+	policy, err := NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config), deps)
+	require.NoError(t, err)
+	return policy
+}
+
+func TestCELPolicyEnvironment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("InternAfterSixPM", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{
+			"pre_check_expression": "!(has(key_metadata.tags) && 'intern' in key_metadata.tags && model.startsWith('gpt-4') && hour >= 18)",
+			"timezone": "Australia/Sydney"
+		}`, PolicyDependencies{})
+		intern := []byte(`{"tags": ["intern"]}`)
+
+		// 19:30 in Sydney
+		policy.now = func() time.Time { return time.Date(2025, 6, 2, 9, 30, 0, 0, time.UTC) }
+		assert.Error(t, policy.PreCheck(ctx, &PreRequestContext{Model: "gpt-4o", KeyMetadata: intern}))
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{Model: "gpt-3.5-turbo", KeyMetadata: intern}))
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{Model: "gpt-4o", KeyMetadata: []byte(`{"tags": ["staff"]}`)}))
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{Model: "gpt-4o"}))
+
+		// 10:00 in Sydney
+		policy.now = func() time.Time { return time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC) }
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{Model: "gpt-4o", KeyMetadata: intern}))
+	})
+
+	t.Run("Weekday", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{"pre_check_expression": "weekday == 'monday'"}`, PolicyDependencies{})
+		policy.now = func() time.Time { return time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC) }
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{}))
+	})
+
+	t.Run("MissingMetadataKeyFailsEvaluation", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{"pre_check_expression": "key_metadata.team == 'ml'"}`, PolicyDependencies{})
+		err := policy.PreCheck(ctx, &PreRequestContext{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "CEL evaluation error")
+	})
+
+	t.Run("InvalidMetadataReadsAsEmpty", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{"pre_check_expression": "size(key_metadata) == 0"}`, PolicyDependencies{})
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{KeyMetadata: []byte(`["not", "an", "object"]`)}))
+	})
+
+	t.Run("Headers", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{"pre_check_expression": "headers['x-team'] == 'ml'"}`, PolicyDependencies{})
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{CallerHeader: http.Header{"X-Team": {"ml"}}}))
+		assert.Error(t, policy.PreCheck(ctx, &PreRequestContext{CallerHeader: http.Header{"X-Team": {"sales"}}}))
+	})
+
+	t.Run("CredentialHeadersHidden", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{"pre_check_expression": "size(headers) == 1 && 'x-team' in headers"}`, PolicyDependencies{})
+		r, _ := http.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		r.Header.Set("Authorization", "Bearer sk-provider")
+		r.Header.Set("Api-Key", "provider-key")
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{
+			Request: r,
+			Header:  r.Header,
+			CallerHeader: http.Header{
+				"X-Team":        {"ml"},
+				"Authorization": {"Bearer gw_key.secret"},
+				"Api-Key":       {"caller-key"},
+				"X-Api-Key":     {"gw_key.secret"},
+				"Cookie":        {"session=1"},
+			},
+		}), "upstream headers and credentials are not visible")
+	})
+
+	t.Run("Endpoint", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{"pre_check_expression": "endpoint == 'embeddings' || !stream"}`, PolicyDependencies{})
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{Endpoint: "/v1/embeddings", Stream: true}))
+		assert.Error(t, policy.PreCheck(ctx, &PreRequestContext{Endpoint: "/v1/chat/completions", Stream: true}))
+	})
+
+	t.Run("MessagesAndRegex", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{
+			"pre_check_expression": "!messages.exists(m, m.role == 'user' && regex(m.content, '(?i)internal use only'))"
+		}`, PolicyDependencies{})
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{Messages: []Message{
+			{Role: "system", Content: "Internal use only."},
+			{Role: "user", Content: "Hello"},
+		}}))
+		assert.Error(t, policy.PreCheck(ctx, &PreRequestContext{Messages: []Message{
+			{Role: "user", Content: "This is INTERNAL USE ONLY"},
+		}}))
+	})
+
+	t.Run("InvalidRegexFailsEvaluation", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{"pre_check_expression": "regex(prompt, '(')"}`, PolicyDependencies{})
+		assert.Error(t, policy.PreCheck(ctx, &PreRequestContext{Prompt: "text"}))
+	})
+
+	t.Run("Tools", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{"pre_check_expression": "!('run_shell' in tools)"}`, PolicyDependencies{})
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{Tools: []Tool{{Name: "get_weather"}}}))
+		assert.Error(t, policy.PreCheck(ctx, &PreRequestContext{Tools: []Tool{{Name: "get_weather"}, {Name: "run_shell"}}}))
+	})
+
+	t.Run("ContainsPII", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{"pre_check_expression": "!messages.exists(m, contains_pii(m.content))"}`, PolicyDependencies{})
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{Messages: []Message{{Role: "user", Content: "What is the capital of France?"}}}))
+		assert.Error(t, policy.PreCheck(ctx, &PreRequestContext{Messages: []Message{{Role: "user", Content: "Email me at jane@example.com"}}}))
+	})
+
+	t.Run("Counter", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{"pre_check_expression": "counter('gpt-4:' + user_id, '1h') <= 2"}`,
+			PolicyDependencies{Cache: kv.NewMemoryStore()})
+		alice := &PreRequestContext{OrgID: "org-1", UserID: "alice"}
+
+		assert.NoError(t, policy.PreCheck(ctx, alice))
+		assert.NoError(t, policy.PreCheck(ctx, alice))
+		assert.Error(t, policy.PreCheck(ctx, alice))
+
+		// Counters are per key and per org
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{OrgID: "org-1", UserID: "bob"}))
+		assert.NoError(t, policy.PreCheck(ctx, &PreRequestContext{OrgID: "org-2", UserID: "alice"}))
+	})
+
+	t.Run("DryRunCountersAreSeparate", func(t *testing.T) {
+		const expr = `{"pre_check_expression": "counter('k', '1h') <= 1"}`
+		cache := kv.NewMemoryStore()
+		enforced := newTestCELPolicy(t, expr, PolicyDependencies{Cache: cache})
+		dryRun := newTestCELPolicy(t, expr, PolicyDependencies{Cache: cache, DryRun: true})
+		req := &PreRequestContext{OrgID: "org-1"}
+
+		assert.NoError(t, dryRun.PreCheck(ctx, req))
+		assert.Error(t, dryRun.PreCheck(ctx, req))
+		assert.NoError(t, enforced.PreCheck(ctx, req), "dry-run traffic doesn't use up the enforced count")
+	})
+
+	t.Run("CounterWithoutCache", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{"pre_check_expression": "counter('k', '1h') < 10"}`, PolicyDependencies{})
+		assert.Error(t, policy.PreCheck(ctx, &PreRequestContext{}))
+	})
+
+	t.Run("CounterInvalidWindow", func(t *testing.T) {
+		policy := newTestCELPolicy(t, `{"pre_check_expression": "counter('k', 'daily') < 10"}`,
+			PolicyDependencies{Cache: kv.NewMemoryStore()})
+		assert.Error(t, policy.PreCheck(ctx, &PreRequestContext{}))
+	})
+
+	t.Run("VariablesScopedToPhase", func(t *testing.T) {
+		for _, config := range []string{
+			`{"pre_check_expression": "total_tokens < 1000"}`,
+			`{"post_check_expression": "stream"}`,
+			`{"post_check_expression": "'x-team' in headers"}`,
+		} {
+			_, err := NewCELPolicy(model.PolicyTypeCustomCEL, []byte(config), PolicyDependencies{})
+			assert.Error(t, err, config)
+		}
+		newTestCELPolicy(t, `{"post_check_expression": "request_size_bytes < 1000 && model == 'gpt-4o'"}`, PolicyDependencies{})
+	})
+
+	t.Run("InvalidTimezone", func(t *testing.T) {
+		_, err := NewCELPolicy(model.PolicyTypeCustomCEL, []byte(`{"pre_check_expression": "true", "timezone": "Mars/Olympus"}`), PolicyDependencies{})
+		assert.Error(t, err)
+	})
+}
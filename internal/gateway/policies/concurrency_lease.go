@@ -0,0 +1,68 @@
+package policies
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+)
+
+// ConcurrencyLease is a request's hold on concurrency limit slots. The slots
+// expire unless renewed, so while the request is in flight the lease renews
+// them in the background; a gateway that dies simply stops renewing and its
+// slots free up one lease period later. Release it once the response is done.
+type ConcurrencyLease struct {
+	cache  kv.KvStore
+	holder string
+	sems   []kv.Semaphore
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// newConcurrencyLease starts renewing slots already acquired for holder
+func newConcurrencyLease(ctx context.Context, cache kv.KvStore, holder string, sems []kv.Semaphore) *ConcurrencyLease {
+	l := &ConcurrencyLease{cache: cache, holder: holder, sems: sems, stop: make(chan struct{})}
+	go l.renew(context.WithoutCancel(ctx))
+	return l
+}
+
+// renew extends the slots every third of the shortest lease until released
+func (l *ConcurrencyLease) renew(ctx context.Context) {
+	interval := l.sems[0].Lease
+	for _, s := range l.sems[1:] {
+		interval = min(interval, s.Lease)
+	}
+	ticker := time.NewTicker(interval / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if err := l.cache.RenewSemaphores(ctx, l.holder, l.sems); err != nil {
+				logger.GetLogger(ctx).Warn().Err(err).Msg("Failed to renew concurrency lease")
+			}
+		}
+	}
+}
+
+// Release gives the slots back. It is safe to call more than once.
+func (l *ConcurrencyLease) Release(ctx context.Context) {
+	l.once.Do(func() {
+		close(l.stop)
+		if err := l.cache.ReleaseSemaphores(ctx, l.holder, l.sems); err != nil {
+			// The slots free up on their own once the lease expires
+			logger.GetLogger(ctx).Warn().Err(err).Msg("Failed to release concurrency lease")
+		}
+	})
+}
+
+// ReleaseLeases releases every lease held by a request
+func ReleaseLeases(ctx context.Context, leases []*ConcurrencyLease) {
+	for _, l := range leases {
+		l.Release(ctx)
+	}
+}
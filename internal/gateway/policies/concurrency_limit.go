@@ -0,0 +1,130 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+// defaultConcurrencyLease is how long a slot survives without renewal
+const defaultConcurrencyLease = 60 * time.Second
+
+func init() {
+	Register(model.PolicyTypeConcurrency, func(config []byte, deps PolicyDependencies) (Policy, error) {
+		var cfg model.ConcurrencyLimitConfig
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid concurrency limit config: %w", err)
+		}
+		switch cfg.Scope {
+		case "", model.ConcurrencyScopeApp, model.ConcurrencyScopeKey:
+		default:
+			return nil, fmt.Errorf("invalid concurrency limit config: unknown scope %q", cfg.Scope)
+		}
+		if cfg.MaxInFlight < 0 || cfg.MaxStreams < 0 || cfg.LeaseSeconds < 0 {
+			return nil, fmt.Errorf("invalid concurrency limit config: limits must not be negative")
+		}
+		policy := NewConcurrencyLimitPolicy(cfg, deps.Cache)
+		if deps.DryRun {
+			policy.keyPrefix = DryRunKeyPrefix
+		}
+		return policy, nil
+	})
+}
+
+// ConcurrencyLimitPolicy caps the requests an app or key has in flight at
+// once, across every gateway instance, using leased semaphores in the KV store
+type ConcurrencyLimitPolicy struct {
+	config    model.ConcurrencyLimitConfig
+	cache     kv.KvStore
+	lease     time.Duration
+	keyPrefix string // set for dry-run policies so their slots are their own
+}
+
+// NewConcurrencyLimitPolicy creates a new concurrency limit policy
+func NewConcurrencyLimitPolicy(config model.ConcurrencyLimitConfig, cache kv.KvStore) *ConcurrencyLimitPolicy {
+	if config.Scope == "" {
+		config.Scope = model.ConcurrencyScopeApp
+	}
+	lease := defaultConcurrencyLease
+	if config.LeaseSeconds > 0 {
+		lease = time.Duration(config.LeaseSeconds) * time.Second
+	}
+	return &ConcurrencyLimitPolicy{config: config, cache: cache, lease: lease}
+}
+
+// ConcurrencyKey is the semaphore for a scope and kind ("requests" or "streams")
+func ConcurrencyKey(scope, scopeID, kind string) string {
+	return kv.KSConcurrency.Key(scope, scopeID, kind)
+}
+
+// Type returns the policy type
+func (p *ConcurrencyLimitPolicy) Type() model.PolicyType {
+	return model.PolicyTypeConcurrency
+}
+
+// PreCheck takes an in-flight slot (and a stream slot for streaming
+// requests), refusing the request if either limit is full. The slots are
+// handed back through req.Leases and held until the response is done.
+func (p *ConcurrencyLimitPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error {
+	scopeID := req.AppID
+	if p.config.Scope == model.ConcurrencyScopeKey {
+		scopeID = req.APIKeyID
+	}
+	if scopeID == "" {
+		return nil
+	}
+
+	var sems []kv.Semaphore
+	var kinds []string
+	if p.config.MaxInFlight > 0 {
+		sems = append(sems, kv.Semaphore{Key: p.keyPrefix + ConcurrencyKey(p.config.Scope, scopeID, "requests"), Limit: int64(p.config.MaxInFlight), Lease: p.lease})
+		kinds = append(kinds, "in-flight requests")
+	}
+	if req.Stream && p.config.MaxStreams > 0 {
+		sems = append(sems, kv.Semaphore{Key: p.keyPrefix + ConcurrencyKey(p.config.Scope, scopeID, "streams"), Limit: int64(p.config.MaxStreams), Lease: p.lease})
+		kinds = append(kinds, "concurrent streams")
+	}
+	if len(sems) == 0 {
+		return nil
+	}
+
+	holder := uuid.NewString()
+	ok, counts, err := p.cache.AcquireSemaphores(ctx, holder, sems)
+	if err != nil {
+		// Fail closed like the rate limiter - the limit protects upstream capacity
+		logger.GetLogger(ctx).Error().
+			Err(err).
+			Str("app_id", req.AppID).
+			Msg("Concurrency limiter unavailable, blocking request")
+		return rejectf(http.StatusServiceUnavailable, "concurrency limit exceeded: concurrency limiter unavailable")
+	}
+	if !ok {
+		for i, s := range sems {
+			if counts[i] > s.Limit {
+				logger.GetLogger(ctx).Warn().
+					Int64("limit", s.Limit).
+					Str("scope", p.config.Scope).
+					Str("app_id", req.AppID).
+					Str("api_key_id", req.APIKeyID).
+					Msg("Concurrency limit exceeded: " + kinds[i])
+				return rejectf(http.StatusTooManyRequests, "concurrency limit exceeded: %d %s", s.Limit, kinds[i])
+			}
+		}
+		return rejectf(http.StatusTooManyRequests, "concurrency limit exceeded")
+	}
+
+	req.Leases = append(req.Leases, newConcurrencyLease(ctx, p.cache, holder, sems))
+	return nil
+}
+
+// PostCheck does nothing: leases are released by the policy enforcer once the
+// response body has been read or closed
+func (p *ConcurrencyLimitPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {}
@@ -0,0 +1,78 @@
+package policies_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimitPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("InFlightLimit", func(t *testing.T) {
+		policy := policies.NewConcurrencyLimitPolicy(model.ConcurrencyLimitConfig{MaxInFlight: 2}, kv.NewMemoryStore())
+
+		first := &policies.PreRequestContext{AppID: "app-1"}
+		second := &policies.PreRequestContext{AppID: "app-1"}
+		require.NoError(t, policy.PreCheck(ctx, first))
+		require.NoError(t, policy.PreCheck(ctx, second))
+		require.Len(t, first.Leases, 1)
+
+		third := &policies.PreRequestContext{AppID: "app-1"}
+		err := policy.PreCheck(ctx, third)
+		var perr *policies.PolicyError
+		require.ErrorAs(t, err, &perr)
+		assert.Equal(t, 429, perr.Status)
+		assert.Contains(t, err.Error(), "2 in-flight requests")
+		assert.Empty(t, third.Leases)
+
+		// Other apps have their own slots
+		require.NoError(t, policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-2"}))
+
+		policies.ReleaseLeases(ctx, first.Leases)
+		policies.ReleaseLeases(ctx, first.Leases) // releasing twice is harmless
+		require.NoError(t, policy.PreCheck(ctx, third))
+		assert.Error(t, policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1"}))
+	})
+
+	t.Run("StreamsLimitedSeparately", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		policy := policies.NewConcurrencyLimitPolicy(model.ConcurrencyLimitConfig{MaxInFlight: 3, MaxStreams: 1}, cache)
+
+		stream := &policies.PreRequestContext{AppID: "app-1", Stream: true}
+		require.NoError(t, policy.PreCheck(ctx, stream))
+
+		err := policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1", Stream: true})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "1 concurrent streams")
+
+		// A refused stream takes no in-flight slot either
+		require.NoError(t, policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1"}))
+		require.NoError(t, policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1"}))
+		assert.Error(t, policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1"}), "the stream counts as in flight")
+
+		policies.ReleaseLeases(ctx, stream.Leases)
+		require.NoError(t, policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1", Stream: true}))
+	})
+
+	t.Run("KeyScope", func(t *testing.T) {
+		policy := policies.NewConcurrencyLimitPolicy(model.ConcurrencyLimitConfig{Scope: model.ConcurrencyScopeKey, MaxInFlight: 1}, kv.NewMemoryStore())
+
+		require.NoError(t, policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1", APIKeyID: "key-1"}))
+		require.NoError(t, policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1", APIKeyID: "key-2"}))
+		assert.Error(t, policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1", APIKeyID: "key-1"}))
+	})
+
+	t.Run("InvalidConfigRejected", func(t *testing.T) {
+		engine := policies.NewEngine(nil, kv.NewMemoryStore())
+		_, err := engine.NewPolicy(model.PolicyTypeConcurrency, []byte(`{"max_in_flight":5,"scope":"org"}`))
+		assert.Error(t, err)
+		_, err = engine.NewPolicy(model.PolicyTypeConcurrency, []byte(`{"max_in_flight":-1}`))
+		assert.Error(t, err)
+	})
+}
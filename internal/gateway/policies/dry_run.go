@@ -0,0 +1,213 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/observability"
+)
+
+// DryRunKeyPrefix is put in front of the KV keys of dry-run limits so their
+// counters and slots never add to those of enforced policies
+const DryRunKeyPrefix = "dryrun:"
+
+// MetadataDryRun is the request metadata key listing the dry-run policies a
+// request violated, recorded with its usage as an audit trail
+const MetadataDryRun = "dry_run_violations"
+
+// Phases a dry-run violation can happen in
+const (
+	PhaseTransform = "transform"
+	PhasePreCheck  = "pre_check"
+	PhaseResponse  = "response"
+	PhaseAction    = "action"
+)
+
+// DryRunViolation is a check a dry-run policy failed but let through
+type DryRunViolation struct {
+	PolicyType model.PolicyType `json:"policy_type"`
+	Phase      string           `json:"phase"`
+	Status     int              `json:"status,omitempty"` // the status the request would have been refused with
+	Reason     string           `json:"reason"`
+}
+
+// NewDryRunPolicy wraps p so it never blocks, rewrites or withholds anything:
+// whatever it would have refused is logged, counted in the dry-run metric and
+// added to the request's metadata instead. The wrapper only implements
+// Transformer and ResponseGuard when p does, so it doesn't make requests
+// take the slower paths those interfaces opt into.
+func NewDryRunPolicy(p Policy) Policy {
+	base := &dryRunPolicy{inner: p}
+	_, transforms := p.(Transformer)
+	_, guards := p.(ResponseGuard)
+	switch {
+	case transforms && guards:
+		return &dryRunTransformGuard{base}
+	case transforms:
+		return &dryRunTransformer{base}
+	case guards:
+		return &dryRunGuard{base}
+	}
+	return base
+}
+
+// dryRunPolicy runs a policy's checks without enforcing them
+type dryRunPolicy struct {
+	inner Policy
+}
+
+type dryRunTransformer struct{ *dryRunPolicy }
+
+type dryRunGuard struct{ *dryRunPolicy }
+
+type dryRunTransformGuard struct{ *dryRunPolicy }
+
+// Type returns the wrapped policy's type
+func (p *dryRunPolicy) Type() model.PolicyType {
+	return p.inner.Type()
+}
+
+// PreCheck runs the wrapped check on a copy of the request. Only the token
+// reservations and concurrency slots it takes are kept, so its counters
+// settle and its slots are released like an enforced policy's.
+func (p *dryRunPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error {
+	shadow := shadowRequest(req)
+	err := p.inner.PreCheck(ctx, shadow)
+	req.Reservations = append(req.Reservations, shadow.Reservations[len(req.Reservations):]...)
+	req.Leases = append(req.Leases, shadow.Leases[len(req.Leases):]...)
+	if err != nil {
+		recordDryRun(ctx, req, p.inner.Type(), PhasePreCheck, err)
+	}
+	return nil
+}
+
+// PostCheck runs the wrapped post-check; its actions were already made
+// harmless when the policy was built
+func (p *dryRunPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {
+	p.inner.PostCheck(ctx, req)
+}
+
+// transform runs the wrapped transform on a copy of the request and discards it
+func (p *dryRunPolicy) transform(ctx context.Context, req *PreRequestContext) error {
+	if err := p.inner.(Transformer).Transform(ctx, shadowRequest(req)); err != nil {
+		recordDryRun(ctx, req, p.inner.Type(), PhaseTransform, err)
+	}
+	return nil
+}
+
+// checkResponse runs the wrapped guard on a copy of the completion. Streamed
+// prefixes are checked by the guard but only the final text is recorded, so
+// a stream is counted once.
+func (p *dryRunPolicy) checkResponse(ctx context.Context, resp *ResponseContext) error {
+	shadow := *resp
+	err := p.inner.(ResponseGuard).CheckResponse(ctx, &shadow)
+	if err != nil && resp.Final {
+		recordDryRun(ctx, &PreRequestContext{AppID: resp.AppID, Model: resp.Model}, p.inner.Type(), PhaseResponse, err)
+	}
+	return nil
+}
+
+// Transform implements Transformer
+func (p *dryRunTransformer) Transform(ctx context.Context, req *PreRequestContext) error {
+	return p.transform(ctx, req)
+}
+
+// CheckResponse implements ResponseGuard
+func (p *dryRunGuard) CheckResponse(ctx context.Context, resp *ResponseContext) error {
+	return p.checkResponse(ctx, resp)
+}
+
+// Transform implements Transformer
+func (p *dryRunTransformGuard) Transform(ctx context.Context, req *PreRequestContext) error {
+	return p.transform(ctx, req)
+}
+
+// CheckResponse implements ResponseGuard
+func (p *dryRunTransformGuard) CheckResponse(ctx context.Context, resp *ResponseContext) error {
+	return p.checkResponse(ctx, resp)
+}
+
+// shadowRequest copies req so a policy can change the copy freely. The
+// reservation and lease slices are clipped so appends to them never write
+// into req's.
+func shadowRequest(req *PreRequestContext) *PreRequestContext {
+	shadow := *req
+	shadow.Messages = slices.Clone(req.Messages)
+	shadow.Tools = slices.Clone(req.Tools)
+	shadow.Tags = slices.Clip(req.Tags)
+	shadow.Reservations = slices.Clip(req.Reservations)
+	shadow.Leases = slices.Clip(req.Leases)
+	shadow.RateLimits = slices.Clip(req.RateLimits)
+	shadow.Header = req.Header.Clone()
+	shadow.Metadata = maps.Clone(req.Metadata)
+	return &shadow
+}
+
+// recordDryRun logs and counts a violation a dry-run policy let through and
+// lists it in the request's metadata
+func recordDryRun(ctx context.Context, req *PreRequestContext, policyType model.PolicyType, phase string, err error) {
+	violation := DryRunViolation{PolicyType: policyType, Phase: phase, Reason: err.Error()}
+	var policyErr *PolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		violation.Status = policyErr.Status
+	case phase != PhaseResponse:
+		violation.Status = http.StatusTooManyRequests
+	}
+
+	observability.FromContext(ctx).RecordPolicyDryRun(ctx, string(policyType), phase)
+	logger.GetLogger(ctx).Warn().
+		Err(err).
+		Str("policy_type", string(policyType)).
+		Str("phase", phase).
+		Int("status", violation.Status).
+		Str("org_id", req.OrgID).
+		Str("app_id", req.AppID).
+		Str("api_key_id", req.APIKeyID).
+		Str("user_id", req.UserID).
+		Str("model", req.Model).
+		Msg("Dry-run policy would have blocked request")
+
+	if phase == PhaseResponse {
+		return
+	}
+	violations, _ := req.Metadata[MetadataDryRun].([]DryRunViolation)
+	req.SetMetadata(MetadataDryRun, append(slices.Clip(violations), violation))
+}
+
+// dryRunEffects stands in for the alert sink and action executor of dry-run
+// policies: alerts and post-check actions are logged and counted, not carried
+// out
+type dryRunEffects struct{}
+
+// Emit implements AlertSink
+func (dryRunEffects) Emit(ctx context.Context, alert Alert) {
+	observability.FromContext(ctx).RecordPolicyDryRun(ctx, alert.PolicyType, PhaseAction)
+	logger.GetLogger(ctx).Info().
+		Str("alert_type", alert.Type).
+		Str("policy_type", alert.PolicyType).
+		Str("org_id", alert.OrgID).
+		Str("app_id", alert.AppID).
+		Interface("data", alert.Data).
+		Msg("Dry-run policy would have raised alert: " + alert.Message)
+}
+
+// ExecuteActions implements ActionExecutor
+func (dryRunEffects) ExecuteActions(ctx context.Context, source model.PolicyType, req *PostRequestContext, actions []Action) {
+	for _, action := range actions {
+		observability.FromContext(ctx).RecordPolicyDryRun(ctx, string(source), PhaseAction)
+		logger.GetLogger(ctx).Info().
+			Str("action", action.Type).
+			Str("name", action.Name).
+			Str("policy_type", string(source)).
+			Str("org_id", req.OrgID).
+			Str("app_id", req.AppID).
+			Str("api_key_id", req.APIKeyID).
+			Msg("Dry-run policy would have executed action")
+	}
+}
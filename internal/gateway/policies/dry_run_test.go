@@ -0,0 +1,130 @@
+package policies_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	load := func(t *testing.T, engine *policies.Engine, cache kv.KvStore, scope policies.Scope, cached ...policies.CachedPolicy) []policies.Policy {
+		t.Helper()
+		require.NoError(t, policies.SetCachedPoliciesRaw(ctx, cache, scope, cached))
+		loaded, err := engine.LoadPolicies(ctx, scope)
+		require.NoError(t, err)
+		require.Len(t, loaded, len(cached))
+		return loaded
+	}
+
+	t.Run("RateLimitRecordsInsteadOfBlocking", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		engine := policies.NewEngine(nil, cache)
+		scope := policies.Scope{OrgID: uuid.NewString(), AppID: uuid.NewString()}
+		loaded := load(t, engine, cache, scope,
+			policies.CachedPolicy{Type: model.PolicyTypeRateLimit, Config: []byte(`{"requests_per_minute":1}`), Mode: model.PolicyModeDryRun},
+			policies.CachedPolicy{Type: model.PolicyTypeRateLimit, Config: []byte(`{"requests_per_minute":2}`)},
+		)
+		dryRun, enforced := loaded[0], loaded[1]
+
+		req := func() *policies.PreRequestContext {
+			return &policies.PreRequestContext{OrgID: scope.OrgID, AppID: scope.AppID, EstimatedTokens: 10}
+		}
+
+		first := req()
+		require.NoError(t, dryRun.PreCheck(ctx, first))
+		assert.Empty(t, first.Metadata)
+		assert.Empty(t, first.RateLimits, "dry-run limits don't show in response headers")
+
+		second := req()
+		require.NoError(t, dryRun.PreCheck(ctx, second), "over the dry-run limit but let through")
+		violations, ok := second.Metadata[policies.MetadataDryRun].([]policies.DryRunViolation)
+		require.True(t, ok)
+		require.Len(t, violations, 1)
+		assert.Equal(t, model.PolicyTypeRateLimit, violations[0].PolicyType)
+		assert.Equal(t, policies.PhasePreCheck, violations[0].Phase)
+		assert.Equal(t, http.StatusTooManyRequests, violations[0].Status)
+		assert.Contains(t, violations[0].Reason, "rate limit exceeded")
+
+		// The enforced limit counts its own requests only
+		require.NoError(t, enforced.PreCheck(ctx, req()))
+		require.NoError(t, enforced.PreCheck(ctx, req()))
+		assert.Error(t, enforced.PreCheck(ctx, req()))
+	})
+
+	t.Run("ConcurrencySlotsReleased", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		engine := policies.NewEngine(nil, cache)
+		policy, err := engine.NewPolicy(model.PolicyTypeConcurrency, []byte(`{"max_in_flight":1}`))
+		require.NoError(t, err)
+		dryRun := policies.NewDryRunPolicy(policy)
+
+		held := &policies.PreRequestContext{AppID: "app-1"}
+		require.NoError(t, dryRun.PreCheck(ctx, held))
+		require.Len(t, held.Leases, 1, "slots taken in dry run are handed back to be released")
+
+		over := &policies.PreRequestContext{AppID: "app-1"}
+		require.NoError(t, dryRun.PreCheck(ctx, over))
+		assert.Contains(t, over.Metadata, policies.MetadataDryRun)
+
+		policies.ReleaseLeases(ctx, held.Leases)
+		freed := &policies.PreRequestContext{AppID: "app-1"}
+		require.NoError(t, dryRun.PreCheck(ctx, freed))
+		assert.NotContains(t, freed.Metadata, policies.MetadataDryRun)
+	})
+
+	t.Run("TransformChangesDiscarded", func(t *testing.T) {
+		policy, err := policies.NewEngine(nil, kv.NewMemoryStore()).NewPolicy(model.PolicyTypeTransform, []byte(`{"max_tokens":1000}`))
+		require.NoError(t, err)
+		dryRun := policies.NewDryRunPolicy(policy)
+		transformer, ok := dryRun.(policies.Transformer)
+		require.True(t, ok)
+		_, guards := dryRun.(policies.ResponseGuard)
+		assert.False(t, guards, "only the wrapped policy's interfaces are implemented")
+
+		body := []byte(`{"model":"gpt-4","max_tokens":4096}`)
+		req := &policies.PreRequestContext{Body: body, Header: http.Header{}}
+		require.NoError(t, transformer.Transform(ctx, req))
+		assert.False(t, req.BodyRewritten)
+		assert.Equal(t, body, req.Body)
+	})
+
+	t.Run("ResponseGuardNeverWithholds", func(t *testing.T) {
+		policy, err := policies.NewEngine(nil, kv.NewMemoryStore()).NewPolicy(model.PolicyTypeResponseGuard, []byte(`{"keywords":["Project Falcon"],"action":"redact"}`))
+		require.NoError(t, err)
+		guard, ok := policies.NewDryRunPolicy(policy).(policies.ResponseGuard)
+		require.True(t, ok)
+
+		resp := &policies.ResponseContext{Text: "The codename is Project Falcon.", Final: true}
+		require.NoError(t, guard.CheckResponse(ctx, resp))
+		assert.False(t, resp.Redacted)
+		assert.Equal(t, "The codename is Project Falcon.", resp.Text)
+	})
+
+	t.Run("PostCheckActionsNotExecuted", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		engine := policies.NewEngine(nil, cache)
+		sink := &recordingAlertSink{}
+		engine.SetAlertSink(sink)
+		scope := policies.Scope{OrgID: uuid.NewString(), AppID: uuid.NewString()}
+		loaded := load(t, engine, cache, scope, policies.CachedPolicy{
+			Type:   model.PolicyTypeCustomCEL,
+			Config: []byte(`{"post_check_expression": "['alert', {'action': 'flag', 'name': 'heavy_user'}]"}`),
+			Mode:   model.PolicyModeDryRun,
+		})
+
+		loaded[0].PostCheck(ctx, &policies.PostRequestContext{OrgID: scope.OrgID, AppID: scope.AppID, APIKeyID: "key-1"})
+		assert.Empty(t, sink.alerts)
+		exists, err := cache.Exists(ctx, policies.FlagKey(scope.OrgID, "key:key-1", "heavy_user"))
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
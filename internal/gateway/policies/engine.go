@@ -3,6 +3,7 @@ package policies
 import (
 	"context"
 	"fmt"
+	"path"
 	"sync"
 	"time"
 
@@ -28,11 +29,14 @@ type Engine struct {
 	memoryCache *lru.Cache[string, *policyCacheEntry]
 	cacheMu     sync.RWMutex
 	cacheTTL    time.Duration
+	alerts      AlertSink
+	queue       *WaitQueue
+	simulation  bool // a sandbox created by Simulate
 }
 
 // NewEngine creates a new policy engine
 func NewEngine(queries *db.Queries, cache kv.KvStore) *Engine {
-	// Create in-memory LRU cache with 1000 entries (supports ~1000 concurrent apps)
+	// Create in-memory LRU cache with 1000 entries (supports ~1000 concurrent scopes)
 	memCache, _ := lru.New[string, *policyCacheEntry](1000)
 
 	return &Engine{
@@ -40,20 +44,36 @@ func NewEngine(queries *db.Queries, cache kv.KvStore) *Engine {
 		cache:       cache,
 		memoryCache: memCache,
 		cacheTTL:    30 * time.Second, // Short TTL to keep policies fresh
+		alerts:      LogAlertSink{},
+		queue:       NewWaitQueue(),
 	}
 }
 
-// LoadPolicies loads all enabled policies for an application
+// SetAlertSink replaces where policy alerts are delivered (default: log only)
+func (e *Engine) SetAlertSink(sink AlertSink) {
+	e.alerts = sink
+}
+
+// RecordSpend adds a request's cost to the spend ledger read by budget policies.
+// Call it once per request, before RecordPostRequest.
+func (e *Engine) RecordSpend(ctx context.Context, entry SpendEntry) error {
+	return RecordSpend(ctx, e.cache, entry)
+}
+
+// LoadPolicies loads the enabled policies for a request's scope: those
+// attached to its org, app, end-user and API key, merged by MergeScoped
 // Uses three-tier cache: memory (30s TTL) -> Redis (5m TTL) -> DB
-func (e *Engine) LoadPolicies(ctx context.Context, appID string) ([]Policy, error) {
+func (e *Engine) LoadPolicies(ctx context.Context, scope Scope) ([]Policy, error) {
+	appID := scope.AppID
 	appUUID, err := uuid.Parse(appID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid app ID: %w", err)
 	}
+	cacheKey := CacheKey(scope)
 
 	// Tier 1: Check in-memory cache first (fastest - no network RTT)
 	e.cacheMu.RLock()
-	if entry, found := e.memoryCache.Get(appID); found {
+	if entry, found := e.memoryCache.Get(cacheKey); found {
 		// Check if entry is still valid
 		if time.Now().Before(entry.expiresAt) {
 			policies := entry.policies
@@ -67,13 +87,13 @@ func (e *Engine) LoadPolicies(ctx context.Context, appID string) ([]Policy, erro
 	observability.FromContext(ctx).RecordPolicyCacheMiss(ctx, "memory")
 
 	// Tier 2: Check Redis cache (medium - network RTT but cached)
-	cachedPolicies, found, err := GetCachedPolicies(ctx, e.cache, appID)
+	cachedPolicies, found, err := GetCachedPolicies(ctx, e.cache, scope)
 	if err == nil && found {
 		observability.FromContext(ctx).RecordPolicyCacheHit(ctx, "redis")
 		// Reconstruct policies from cached data
 		policies := make([]Policy, 0, len(cachedPolicies))
 		for _, cached := range cachedPolicies {
-			policy, err := e.NewPolicy(cached.Type, cached.Config)
+			policy, err := e.buildPolicy(cached)
 			if err != nil {
 				// Log error but continue with other policies
 				logger.GetLogger(ctx).Error().
@@ -88,7 +108,7 @@ func (e *Engine) LoadPolicies(ctx context.Context, appID string) ([]Policy, erro
 
 		// Store in memory cache for next request
 		e.cacheMu.Lock()
-		e.memoryCache.Add(appID, &policyCacheEntry{
+		e.memoryCache.Add(cacheKey, &policyCacheEntry{
 			policies:  policies,
 			expiresAt: time.Now().Add(e.cacheTTL),
 		})
@@ -106,47 +126,61 @@ func (e *Engine) LoadPolicies(ctx context.Context, appID string) ([]Policy, erro
 		return nil, fmt.Errorf("database not available for loading policies")
 	}
 
-	dbPolicies, err := e.db.ListEnabledPolicies(ctx, db.ListEnabledPoliciesParams{
-		AppID:  appUUID,
-		Limit:  1000, // Load all enabled policies for the app
-		Offset: 0,
-	})
+	params := db.ListEnabledPoliciesForScopeParams{AppID: appUUID}
+	if params.OrgID, err = parseScopeID(scope.OrgID); err != nil {
+		return nil, fmt.Errorf("invalid org ID: %w", err)
+	}
+	if params.UserID, err = parseScopeID(scope.UserID); err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+	if params.KeyID, err = e.keyScopeID(ctx, scope.KeyID); err != nil {
+		return nil, fmt.Errorf("invalid key ID: %w", err)
+	}
+
+	dbPolicies, err := e.db.ListEnabledPoliciesForScope(ctx, params)
 	if err != nil {
 		observability.FromContext(ctx).RecordPolicyLoadError(ctx, appID, err)
 		return nil, fmt.Errorf("failed to load policies: %w", err)
 	}
 
+	attached := make([]ScopedPolicy, len(dbPolicies))
+	for i, dbPolicy := range dbPolicies {
+		attached[i] = ScopedPolicy{
+			ID:    dbPolicy.ID.String(),
+			Scope: model.PolicyScope(dbPolicy.Scope),
+			CachedPolicy: CachedPolicy{
+				Type:   model.PolicyType(dbPolicy.PolicyType),
+				Config: dbPolicy.Config,
+				Mode:   model.PolicyMode(dbPolicy.Mode),
+			},
+		}
+	}
+
 	// Convert DB policies to Policy interfaces
 	// Store original configs for caching
-	policies := make([]Policy, 0, len(dbPolicies))
-	policiesToCache := make([]CachedPolicy, 0, len(dbPolicies))
+	policiesToCache := MergeScoped(attached)
+	policies := make([]Policy, 0, len(policiesToCache))
 
-	for _, dbPolicy := range dbPolicies {
-		policy, err := e.NewPolicy(model.PolicyType(dbPolicy.PolicyType), dbPolicy.Config)
+	for _, cached := range policiesToCache {
+		policy, err := e.buildPolicy(cached)
 		if err != nil {
 			// Log error but continue with other policies
 			logger.GetLogger(ctx).Error().
 				Err(err).
 				Str("app_id", appID).
-				Str("policy_type", string(dbPolicy.PolicyType)).
+				Str("policy_type", string(cached.Type)).
 				Msg("Failed to create policy from database")
 			continue
 		}
 		policies = append(policies, policy)
-
-		// Store for cache
-		policiesToCache = append(policiesToCache, CachedPolicy{
-			Type:   model.PolicyType(dbPolicy.PolicyType),
-			Config: dbPolicy.Config,
-		})
 	}
 
 	// Cache in Redis (ignore errors - cache failures shouldn't break requests)
-	_ = SetCachedPoliciesRaw(ctx, e.cache, appID, policiesToCache)
+	_ = SetCachedPoliciesRaw(ctx, e.cache, scope, policiesToCache)
 
 	// Cache in memory
 	e.cacheMu.Lock()
-	e.memoryCache.Add(appID, &policyCacheEntry{
+	e.memoryCache.Add(cacheKey, &policyCacheEntry{
 		policies:  policies,
 		expiresAt: time.Now().Add(e.cacheTTL),
 	})
@@ -155,6 +189,37 @@ func (e *Engine) LoadPolicies(ctx context.Context, appID string) ([]Policy, erro
 	return policies, nil
 }
 
+// parseScopeID parses an optional scope ID; a missing ID becomes uuid.Nil,
+// which matches no attachments
+func parseScopeID(id string) (uuid.UUID, error) {
+	if id == "" {
+		return uuid.Nil, nil
+	}
+	return uuid.Parse(id)
+}
+
+// keyScopeID returns the ID policies are attached to for an API key, which
+// the gateway identifies by its prefix
+func (e *Engine) keyScopeID(ctx context.Context, keyID string) (uuid.UUID, error) {
+	if id, err := parseScopeID(keyID); err == nil {
+		return id, nil
+	}
+	key, err := e.db.GetAPIKeyByPrefix(ctx, keyID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return key.ID, nil
+}
+
+// SettleReservations settles a request's token reservations against its
+// actual usage (zero for requests that never produced a response)
+func (e *Engine) SettleReservations(ctx context.Context, reservations []TokenReservation, actualTokens int) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+	return NewRateLimiter(e.cache).Settle(ctx, reservations, actualTokens)
+}
+
 // CheckPreRequest runs all pre-request checks for the given policies
 // Returns error if any policy check fails
 func (e *Engine) CheckPreRequest(ctx context.Context, policies []Policy, req *PreRequestContext) error {
@@ -184,32 +249,56 @@ func (e *Engine) RecordPostRequest(ctx context.Context, policies []Policy, req *
 // NewPolicy creates a new policy instance based on the policy type and config
 // Uses the policy registry to look up the appropriate factory function
 func (e *Engine) NewPolicy(policyType model.PolicyType, config []byte) (Policy, error) {
+	return e.newPolicy(policyType, config, PolicyDependencies{Cache: e.cache, Alerts: e.alerts, Queue: e.queue, Actions: e, Simulation: e.simulation})
+}
+
+// buildPolicy creates a loaded policy in its mode. Dry-run policies keep their
+// counters apart from enforced ones and record alerts and post-check actions
+// instead of carrying them out.
+func (e *Engine) buildPolicy(cached CachedPolicy) (Policy, error) {
+	if cached.Mode != model.PolicyModeDryRun {
+		return e.NewPolicy(cached.Type, cached.Config)
+	}
+	policy, err := e.newPolicy(cached.Type, cached.Config, PolicyDependencies{Cache: e.cache, Alerts: dryRunEffects{}, Queue: e.queue, Actions: dryRunEffects{}, DryRun: true, Simulation: e.simulation})
+	if err != nil {
+		return nil, err
+	}
+	return NewDryRunPolicy(policy), nil
+}
+
+func (e *Engine) newPolicy(policyType model.PolicyType, config []byte, deps PolicyDependencies) (Policy, error) {
 	// Try registry first (built-in policies registered via init())
 	factory, exists := GetFactory(policyType)
 	if exists {
-		deps := PolicyDependencies{Cache: e.cache}
 		return factory(config, deps)
 	}
 
 	// Fallback to CEL policy for custom policies
 	if policyType == model.PolicyTypeCustomCEL {
-		return NewCELPolicy(policyType, config)
+		return NewCELPolicy(policyType, config, deps)
 	}
 
 	// Unknown policy type
 	return nil, fmt.Errorf("unknown policy type: %s", policyType)
 }
 
-// InvalidateCache removes an application's policies from all cache tiers
-// Call this when policies are updated/created/deleted for an application
-func (e *Engine) InvalidateCache(ctx context.Context, appID string) error {
+// InvalidateCache removes the policies of every scope that includes the given
+// org, app, user or key from all cache tiers
+// Call this when policies attached at that level are updated/created/deleted
+func (e *Engine) InvalidateCache(ctx context.Context, level model.PolicyScope, id string) error {
+	pattern := CachePattern(level, id)
+
 	// Remove from memory cache
 	e.cacheMu.Lock()
-	e.memoryCache.Remove(appID)
+	for _, key := range e.memoryCache.Keys() {
+		if matched, _ := path.Match(pattern, key); matched {
+			e.memoryCache.Remove(key)
+		}
+	}
 	e.cacheMu.Unlock()
 
 	// Remove from Redis cache
-	return InvalidatePolicyCache(ctx, e.cache, appID)
+	return InvalidatePolicyCache(ctx, e.cache, level, id)
 }
 
 // InvalidateAllCache clears the entire policy cache (all applications)
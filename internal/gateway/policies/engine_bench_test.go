@@ -35,16 +35,16 @@ func BenchmarkCacheTiers(b *testing.B) {
 		engine := NewEngine(nil, cache)
 
 		// Pre-populate Redis cache
-		_ = SetCachedPoliciesRaw(ctx, cache, appID, testPolicies)
+		_ = SetCachedPoliciesRaw(ctx, cache, Scope{AppID: appID}, testPolicies)
 
 		// First call to populate memory cache
-		_, _ = engine.LoadPolicies(ctx, appID)
+		_, _ = engine.LoadPolicies(ctx, Scope{AppID: appID})
 
 		b.ResetTimer()
 		b.ReportAllocs()
 
 		for b.Loop() {
-			_, _ = engine.LoadPolicies(ctx, appID)
+			_, _ = engine.LoadPolicies(ctx, Scope{AppID: appID})
 		}
 	})
 
@@ -53,7 +53,7 @@ func BenchmarkCacheTiers(b *testing.B) {
 		engine := NewEngine(nil, cache)
 
 		// Pre-populate Redis but clear memory cache each time
-		_ = SetCachedPoliciesRaw(ctx, cache, appID, testPolicies)
+		_ = SetCachedPoliciesRaw(ctx, cache, Scope{AppID: appID}, testPolicies)
 
 		b.ResetTimer()
 		b.ReportAllocs()
@@ -61,7 +61,7 @@ func BenchmarkCacheTiers(b *testing.B) {
 		for b.Loop() {
 			// Clear memory cache to force Redis lookup
 			engine.memoryCache.Purge()
-			_, _ = engine.LoadPolicies(ctx, appID)
+			_, _ = engine.LoadPolicies(ctx, Scope{AppID: appID})
 		}
 	})
 }
@@ -85,13 +85,13 @@ func BenchmarkPolicyReconstruction(b *testing.B) {
 					Config: []byte(`{"requests_per_minute":1000}`),
 				}
 			}
-			_ = SetCachedPoliciesRaw(ctx, cache, appID, policies)
+			_ = SetCachedPoliciesRaw(ctx, cache, Scope{AppID: appID}, policies)
 
 			b.ResetTimer()
 			b.ReportAllocs()
 
 			for b.Loop() {
-				_, _ = engine.LoadPolicies(ctx, appID)
+				_, _ = engine.LoadPolicies(ctx, Scope{AppID: appID})
 			}
 		})
 	}
@@ -129,7 +129,7 @@ func BenchmarkCELPolicy(b *testing.B) {
 		b.ReportAllocs()
 
 		for b.Loop() {
-			_, err := NewCELPolicy(model.PolicyTypeCustomCEL, config)
+			_, err := NewCELPolicy(model.PolicyTypeCustomCEL, config, PolicyDependencies{})
 			if err != nil {
 				b.Fatal(err)
 			}
@@ -141,7 +141,7 @@ func BenchmarkCELPolicy(b *testing.B) {
 			"pre_check_expression": "estimated_tokens < 5000 && model.startsWith('gpt-4')"
 		}`)
 
-		policy, err := NewCELPolicy(model.PolicyTypeCustomCEL, config)
+		policy, err := NewCELPolicy(model.PolicyTypeCustomCEL, config, PolicyDependencies{})
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -288,7 +288,7 @@ func BenchmarkConcurrentPolicyLoading(b *testing.B) {
 		{Type: model.PolicyTypeTokenLimit, Config: []byte(`{"max_tokens":8192}`)},
 		{Type: model.PolicyTypeModelAllowlist, Config: []byte(`{"allowed_models":["gpt-4"]}`)},
 	}
-	_ = SetCachedPoliciesRaw(ctx, cache, appID, testPolicies)
+	_ = SetCachedPoliciesRaw(ctx, cache, Scope{AppID: appID}, testPolicies)
 
 	engine := NewEngine(nil, cache)
 
@@ -297,7 +297,7 @@ func BenchmarkConcurrentPolicyLoading(b *testing.B) {
 
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			_, _ = engine.LoadPolicies(ctx, appID)
+			_, _ = engine.LoadPolicies(ctx, Scope{AppID: appID})
 		}
 	})
 }
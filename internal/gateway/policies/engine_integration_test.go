@@ -173,13 +173,13 @@ func TestCachingIntegration(t *testing.T) {
 
 	t.Run("CacheMiss_LoadFromCache", func(t *testing.T) {
 		// Pre-populate cache (simulating previous DB load)
-		err := SetCachedPoliciesRaw(ctx, cache, appID, testPolicies)
+		err := SetCachedPoliciesRaw(ctx, cache, Scope{AppID: appID}, testPolicies)
 		if err != nil {
 			t.Fatalf("Failed to set up cache: %v", err)
 		}
 
 		// First load should hit cache
-		policies, err := engine.LoadPolicies(ctx, appID)
+		policies, err := engine.LoadPolicies(ctx, Scope{AppID: appID})
 		if err != nil {
 			t.Fatalf("Failed to load policies: %v", err)
 		}
@@ -209,7 +209,7 @@ func TestCachingIntegration(t *testing.T) {
 
 	t.Run("CacheHit_MemoryCache", func(t *testing.T) {
 		// Second load should hit memory cache
-		policies, err := engine.LoadPolicies(ctx, appID)
+		policies, err := engine.LoadPolicies(ctx, Scope{AppID: appID})
 		if err != nil {
 			t.Fatalf("Failed to load policies from cache: %v", err)
 		}
@@ -221,7 +221,7 @@ func TestCachingIntegration(t *testing.T) {
 
 	t.Run("CacheInvalidation", func(t *testing.T) {
 		// Invalidate cache
-		err := engine.InvalidateCache(ctx, appID)
+		err := engine.InvalidateCache(ctx, model.PolicyScopeApp, appID)
 		if err != nil {
 			t.Fatalf("Failed to invalidate cache: %v", err)
 		}
@@ -230,7 +230,7 @@ func TestCachingIntegration(t *testing.T) {
 		engine.memoryCache.Purge()
 
 		// Next load should miss all caches and try DB (which is nil, so expect error)
-		_, err = engine.LoadPolicies(ctx, appID)
+		_, err = engine.LoadPolicies(ctx, Scope{AppID: appID})
 		if err == nil {
 			t.Error("Expected error after cache invalidation without DB")
 		}
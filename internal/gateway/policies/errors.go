@@ -0,0 +1,30 @@
+package policies
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// PolicyError is a PreCheck failure that tells the caller why the request was
+// refused. Policies that return plain errors are reported as 429s.
+type PolicyError struct {
+	Status     int           // HTTP status for the rejection
+	Message    string        // shown to the caller as the problem detail
+	RetryAfter time.Duration // when retrying later can succeed; zero otherwise
+}
+
+// Error implements error
+func (e *PolicyError) Error() string {
+	return e.Message
+}
+
+// rejectf builds a PolicyError with a formatted message
+func rejectf(status int, format string, args ...any) *PolicyError {
+	return &PolicyError{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// throttlef builds a 429 PolicyError the caller may retry after retryAfter
+func throttlef(retryAfter time.Duration, format string, args ...any) *PolicyError {
+	return &PolicyError{Status: http.StatusTooManyRequests, Message: fmt.Sprintf(format, args...), RetryAfter: retryAfter}
+}
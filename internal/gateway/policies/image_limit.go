@@ -0,0 +1,108 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+func init() {
+	Register(model.PolicyTypeImageLimit, func(config []byte, deps PolicyDependencies) (Policy, error) {
+		var cfg model.ImageLimitConfig
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid image limit config: %w", err)
+		}
+		return NewImageLimitPolicy(cfg)
+	})
+}
+
+// ImageLimitPolicy restricts the images attached to chat requests: how many,
+// how large, and where they may be fetched from
+type ImageLimitPolicy struct {
+	config model.ImageLimitConfig
+	hosts  []string
+}
+
+// NewImageLimitPolicy creates a new image limit policy
+func NewImageLimitPolicy(config model.ImageLimitConfig) (*ImageLimitPolicy, error) {
+	if config.MaxImages != nil && *config.MaxImages < 0 {
+		return nil, fmt.Errorf("invalid image limit config: max_images cannot be negative")
+	}
+	if config.MaxImageBytes < 0 {
+		return nil, fmt.Errorf("invalid image limit config: max_image_bytes cannot be negative")
+	}
+	p := &ImageLimitPolicy{config: config}
+	// Hosts are matched case-insensitively
+	for _, pattern := range config.AllowedHosts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid image limit config: host pattern %q: %w", pattern, err)
+		}
+		p.hosts = append(p.hosts, strings.ToLower(pattern))
+	}
+	return p, nil
+}
+
+// Type returns the policy type
+func (p *ImageLimitPolicy) Type() model.PolicyType {
+	return model.PolicyTypeImageLimit
+}
+
+// PreCheck checks the request's images against the count, size and source
+// limits
+func (p *ImageLimitPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error {
+	var images []Image
+	for _, msg := range req.Messages {
+		images = append(images, msg.Images...)
+	}
+	if limit := p.config.MaxImages; limit != nil && len(images) > *limit {
+		if *limit == 0 {
+			return rejectf(http.StatusForbidden, "images are not allowed")
+		}
+		return rejectf(http.StatusBadRequest, "request attaches %d images, the limit is %d", len(images), *limit)
+	}
+
+	for _, img := range images {
+		if img.Inline {
+			if p.config.AllowDataURIs != nil && !*p.config.AllowDataURIs {
+				return rejectf(http.StatusForbidden, "inline images are not allowed; link them by URL")
+			}
+			if p.config.MaxImageBytes > 0 && img.Size > p.config.MaxImageBytes {
+				return rejectf(http.StatusRequestEntityTooLarge, "image size limit exceeded (%d > %d bytes)", img.Size, p.config.MaxImageBytes)
+			}
+			continue
+		}
+		if len(p.hosts) > 0 {
+			host := imageHost(img.URL)
+			if host == "" {
+				return rejectf(http.StatusForbidden, "image URLs must be http or https")
+			}
+			if !slices.ContainsFunc(p.hosts, func(pattern string) bool {
+				ok, _ := path.Match(pattern, host)
+				return ok
+			}) {
+				return rejectf(http.StatusForbidden, "image host not allowed: %s", host)
+			}
+		}
+	}
+	return nil
+}
+
+// PostCheck is a no-op for image limits
+func (p *ImageLimitPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {}
+
+// imageHost returns the lowercased host of an http(s) image URL, or "" when
+// the URL has none
+func imageHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,101 @@
+package policies_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageLimitPolicy(t *testing.T) {
+	ctx := context.Background()
+	engine := policies.NewEngine(nil, kv.NewMemoryStore())
+
+	newPolicy := func(t *testing.T, config string) policies.Policy {
+		t.Helper()
+		policy, err := engine.NewPolicy(model.PolicyTypeImageLimit, []byte(config))
+		require.NoError(t, err)
+		return policy
+	}
+	status := func(t *testing.T, err error) int {
+		t.Helper()
+		var policyErr *policies.PolicyError
+		require.True(t, errors.As(err, &policyErr), "expected a policy error, got %v", err)
+		return policyErr.Status
+	}
+	chat := func(images ...policies.Image) *policies.PreRequestContext {
+		return &policies.PreRequestContext{Messages: []policies.Message{
+			{Role: "system", Content: "You describe images."},
+			{Role: "user", Content: "What is this?", Images: images},
+		}}
+	}
+	remote := func(url string) policies.Image { return policies.Image{URL: url} }
+	inline := func(size int) policies.Image {
+		return policies.Image{URL: "data:image/png;base64,iVBORw0KGgo=", Inline: true, Size: size}
+	}
+
+	t.Run("MaxImages", func(t *testing.T) {
+		policy := newPolicy(t, `{"max_images":2}`)
+		assert.NoError(t, policy.PreCheck(ctx, chat(remote("https://a.example/1.png"), inline(10))))
+
+		err := policy.PreCheck(ctx, chat(remote("https://a.example/1.png"), remote("https://a.example/2.png"), inline(10)))
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, status(t, err))
+
+		policy = newPolicy(t, `{"max_images":0}`)
+		err = policy.PreCheck(ctx, chat(inline(10)))
+		require.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, status(t, err))
+		assert.NoError(t, policy.PreCheck(ctx, chat()))
+	})
+
+	t.Run("MaxImageBytes", func(t *testing.T) {
+		policy := newPolicy(t, `{"max_image_bytes":1000}`)
+		assert.NoError(t, policy.PreCheck(ctx, chat(inline(1000), remote("https://a.example/huge.png"))))
+
+		err := policy.PreCheck(ctx, chat(inline(1001)))
+		require.Error(t, err)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, status(t, err))
+	})
+
+	t.Run("AllowedHosts", func(t *testing.T) {
+		policy := newPolicy(t, `{"allowed_hosts":["*.blob.core.windows.net","images.example.com"]}`)
+		assert.NoError(t, policy.PreCheck(ctx, chat(
+			remote("https://acct.blob.core.windows.net/c/cat.png?sig=abc"),
+			remote("https://IMAGES.example.com/dog.jpg"),
+			inline(10),
+		)))
+
+		err := policy.PreCheck(ctx, chat(remote("https://evil.example.net/x.png")))
+		require.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, status(t, err))
+		assert.Contains(t, err.Error(), "evil.example.net")
+
+		assert.Error(t, policy.PreCheck(ctx, chat(remote("file:///etc/passwd"))))
+	})
+
+	t.Run("DataURIsDisallowed", func(t *testing.T) {
+		policy := newPolicy(t, `{"allow_data_uris":false}`)
+		assert.NoError(t, policy.PreCheck(ctx, chat(remote("https://a.example/1.png"))))
+		err := policy.PreCheck(ctx, chat(inline(10)))
+		require.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, status(t, err))
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, config := range []string{
+			`{"max_images":-1}`,
+			`{"max_image_bytes":-1}`,
+			`{"allowed_hosts":["["]}`,
+		} {
+			_, err := engine.NewPolicy(model.PolicyTypeImageLimit, []byte(config))
+			assert.Error(t, err, config)
+		}
+	})
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"slices"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
 	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
 )
 
 func init() {
@@ -42,6 +44,11 @@ func (p *ModelAllowlistPolicy) PreCheck(ctx context.Context, req *PreRequestCont
 		return nil
 	}
 
+	// Files/Batch calls that reference no model have nothing to check
+	if req.Model == "" && provider.IsAccountScopedPath(req.Endpoint) {
+		return nil
+	}
+
 	// Check if the requested model is in the allowlist
 	if slices.Contains(p.config.AllowedModelIDs, req.Model) {
 		return nil
@@ -51,7 +58,7 @@ func (p *ModelAllowlistPolicy) PreCheck(ctx context.Context, req *PreRequestCont
 		Str("model", req.Model).
 		Str("app_id", req.AppID).
 		Msg("Model not in allowlist")
-	return fmt.Errorf("model not allowed")
+	return rejectf(http.StatusForbidden, "model not allowed: %s", req.Model)
 }
 
 // PostCheck is a no-op for model allowlist
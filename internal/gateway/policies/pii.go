@@ -0,0 +1,312 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+func init() {
+	Register(model.PolicyTypePII, func(config []byte, deps PolicyDependencies) (Policy, error) {
+		var cfg model.PIIConfig
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid pii config: %w", err)
+		}
+		return NewPIIPolicy(cfg)
+	})
+}
+
+// piiDetector finds one kind of PII. Matches are confirmed by valid, when set,
+// to weed out numbers that merely look like card numbers or IBANs.
+type piiDetector struct {
+	name    string
+	pattern *regexp.Regexp
+	valid   func(match string) bool
+}
+
+// PIIDetectors lists the built-in detectors in the order they run: structured
+// numbers go before phone numbers so a card number isn't reported as a phone
+var PIIDetectors = []string{model.PIIEmail, model.PIIIBAN, model.PIICreditCard, model.PIINationalID, model.PIIPhone}
+
+var builtinPIIDetectors = map[string]piiDetector{
+	model.PIIEmail: {
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+	model.PIIIBAN: {
+		pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2} ?(?:[A-Z0-9]{4} ?){2,7}[A-Z0-9]{1,4}\b`),
+		valid:   validIBAN,
+	},
+	model.PIICreditCard: {
+		pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		valid:   validCardNumber,
+	},
+	model.PIINationalID: {
+		// US Social Security numbers and UK National Insurance numbers
+		pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b|\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`),
+		valid:   validNationalID,
+	},
+	model.PIIPhone: {
+		pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?)?\d{2,4}(?:[ .-]?\d{2,4}){2,4}`),
+		valid:   validPhone,
+	},
+}
+
+// PIIPolicy finds personal data in prompts and blocks the request, masks the
+// data before it goes upstream, or tags the request for auditing
+type PIIPolicy struct {
+	detectors []piiDetector
+	action    string
+}
+
+// NewPIIPolicy creates a new PII policy
+func NewPIIPolicy(config model.PIIConfig) (*PIIPolicy, error) {
+	action := config.Action
+	if action == "" {
+		action = model.PIIActionBlock
+	}
+	if action != model.PIIActionBlock && action != model.PIIActionMask && action != model.PIIActionTag {
+		return nil, fmt.Errorf("invalid pii config: unknown action %q", config.Action)
+	}
+
+	names := config.Detectors
+	if len(names) == 0 && len(config.CustomPatterns) == 0 {
+		names = PIIDetectors
+	}
+	detectors, err := builtinDetectors(names)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pii config: %w", err)
+	}
+
+	p := &PIIPolicy{detectors: detectors, action: action}
+	for _, custom := range config.CustomPatterns {
+		if custom.Name == "" {
+			return nil, fmt.Errorf("invalid pii config: custom patterns need a name")
+		}
+		pattern, err := regexp.Compile(custom.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pii config: pattern %q: %w", custom.Name, err)
+		}
+		p.detectors = append(p.detectors, piiDetector{name: custom.Name, pattern: pattern})
+	}
+	return p, nil
+}
+
+// builtinDetectors returns the named built-in detectors in PIIDetectors order
+func builtinDetectors(names []string) ([]piiDetector, error) {
+	for _, name := range names {
+		if _, ok := builtinPIIDetectors[name]; !ok {
+			return nil, fmt.Errorf("unknown detector %q", name)
+		}
+	}
+	var detectors []piiDetector
+	for _, name := range PIIDetectors {
+		if slices.Contains(names, name) {
+			d := builtinPIIDetectors[name]
+			d.name = name
+			detectors = append(detectors, d)
+		}
+	}
+	return detectors, nil
+}
+
+// Type returns the policy type
+func (p *PIIPolicy) Type() model.PolicyType {
+	return model.PolicyTypePII
+}
+
+// PreCheck scans the messages and prompt for PII
+func (p *PIIPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error {
+	found := map[string]bool{}
+	for _, msg := range req.Messages {
+		p.redact(msg.Content, found)
+	}
+	p.redact(req.Prompt, found)
+	if len(found) == 0 {
+		return nil
+	}
+
+	kinds := make([]string, 0, len(found))
+	for _, d := range p.detectors {
+		if found[d.name] {
+			kinds = append(kinds, d.name)
+		}
+	}
+
+	if p.action == model.PIIActionBlock {
+		return rejectf(http.StatusBadRequest, "request contains personal data: %s", strings.Join(kinds, ", "))
+	}
+	for _, kind := range kinds {
+		req.Tags = append(req.Tags, "pii:"+kind)
+	}
+	if p.action == model.PIIActionMask {
+		p.mask(req)
+	}
+	return nil
+}
+
+// PostCheck does nothing for PII policies
+func (p *PIIPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {}
+
+// mask replaces the PII in the upstream body and in the request's messages
+func (p *PIIPolicy) mask(req *PreRequestContext) {
+	for i, msg := range req.Messages {
+		req.Messages[i].Content = p.redact(msg.Content, nil)
+	}
+	req.Prompt = p.redact(req.Prompt, nil)
+
+	if body, ok := p.maskBody(req.Body); ok {
+		req.SetBody(body)
+	}
+}
+
+// maskBody redacts message contents (plain strings or text parts) and the
+// prompt in a JSON request body. Bodies that aren't JSON are left alone.
+func (p *PIIPolicy) maskBody(body []byte) ([]byte, bool) {
+	doc, ok := decodeJSONBody(body)
+	if !ok {
+		return nil, false
+	}
+
+	if messages, ok := doc["messages"].([]any); ok {
+		for _, m := range messages {
+			msg, ok := m.(map[string]any)
+			if !ok {
+				continue
+			}
+			switch content := msg["content"].(type) {
+			case string:
+				msg["content"] = p.redact(content, nil)
+			case []any:
+				for _, part := range content {
+					if part, ok := part.(map[string]any); ok {
+						if text, ok := part["text"].(string); ok {
+							part["text"] = p.redact(text, nil)
+						}
+					}
+				}
+			}
+		}
+	}
+	switch prompt := doc["prompt"].(type) {
+	case string:
+		doc["prompt"] = p.redact(prompt, nil)
+	case []any:
+		for i, item := range prompt {
+			if text, ok := item.(string); ok {
+				prompt[i] = p.redact(text, nil)
+			}
+		}
+	}
+
+	masked, err := json.Marshal(doc)
+	if err != nil {
+		return nil, false
+	}
+	return masked, true
+}
+
+// redact replaces every match with a [REDACTED_<KIND>] marker, recording the
+// kinds it found in found (when non-nil)
+func (p *PIIPolicy) redact(text string, found map[string]bool) string {
+	return redactMatches(p.detectors, text, found)
+}
+
+// redactMatches runs detectors over text in order, replacing each confirmed
+// match with a [REDACTED_<NAME>] marker
+func redactMatches(detectors []piiDetector, text string, found map[string]bool) string {
+	if text == "" {
+		return text
+	}
+	for _, d := range detectors {
+		marker := "[REDACTED_" + strings.ToUpper(d.name) + "]"
+		text = d.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if d.valid != nil && !d.valid(match) {
+				return match
+			}
+			if found != nil {
+				found[d.name] = true
+			}
+			return marker
+		})
+	}
+	return text
+}
+
+// digits returns just the digits of s
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// validPhone accepts 9 to 15 digits written the way phone numbers are: with a
+// +country code, a bracketed area code, or at least two separators. A bare run
+// of digits (order numbers, timestamps, IDs) is not reported.
+func validPhone(match string) bool {
+	if n := len(digits(match)); n < 9 || n > 15 {
+		return false
+	}
+	if strings.HasPrefix(match, "+") || strings.Contains(match, "(") {
+		return true
+	}
+	return strings.Count(match, " ")+strings.Count(match, ".")+strings.Count(match, "-") >= 2
+}
+
+// validCardNumber applies the Luhn checksum used by payment card numbers
+func validCardNumber(match string) bool {
+	number := digits(match)
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range len(number) {
+		d := int(number[len(number)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validIBAN applies the ISO 13616 mod-97 check
+func validIBAN(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	// Move the country code and check digits to the end, then turn letters into numbers (A=10 ... Z=35)
+	var numeric strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			fmt.Fprintf(&numeric, "%d", r-'A'+10)
+		} else {
+			numeric.WriteRune(r)
+		}
+	}
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validNationalID rules out SSNs in ranges that are never issued
+func validNationalID(match string) bool {
+	if !strings.Contains(match, "-") {
+		return true // UK National Insurance number, validated by the pattern
+	}
+	area, group, serial := match[0:3], match[4:6], match[7:11]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
@@ -0,0 +1,156 @@
+package policies_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPIIPolicy(t *testing.T) {
+	ctx := context.Background()
+	engine := policies.NewEngine(nil, kv.NewMemoryStore())
+
+	newPolicy := func(t *testing.T, config string) policies.Policy {
+		t.Helper()
+		policy, err := engine.NewPolicy(model.PolicyTypePII, []byte(config))
+		require.NoError(t, err)
+		return policy
+	}
+	chat := func(content string) *policies.PreRequestContext {
+		return &policies.PreRequestContext{
+			AppID:    "app-1",
+			Messages: []policies.Message{{Role: "user", Content: content}},
+		}
+	}
+
+	t.Run("DetectsEachKind", func(t *testing.T) {
+		policy := newPolicy(t, `{"action":"tag"}`)
+		cases := map[string]string{
+			model.PIIEmail:      "mail me at jane.doe@example.com please",
+			model.PIIPhone:      "call +44 20 7946 0958 tomorrow",
+			model.PIICreditCard: "my card is 4111 1111 1111 1111",
+			model.PIIIBAN:       "pay GB82 WEST 1234 5698 7654 32 today",
+			model.PIINationalID: "SSN 123-45-6789",
+		}
+		for kind, content := range cases {
+			req := chat(content)
+			require.NoError(t, policy.PreCheck(ctx, req))
+			assert.Equal(t, []string{"pii:" + kind}, req.Tags, content)
+		}
+
+		req := chat("NINO AB 12 34 56 C")
+		require.NoError(t, policy.PreCheck(ctx, req))
+		assert.Equal(t, []string{"pii:" + model.PIINationalID}, req.Tags)
+	})
+
+	t.Run("IgnoresNumbersFailingChecksums", func(t *testing.T) {
+		policy := newPolicy(t, `{"detectors":["credit_card","iban","national_id"]}`)
+		for _, content := range []string{
+			"order 4111 1111 1111 1112 shipped",
+			"ref GB00 WEST 1234 5698 7654 32",
+			"ticket 000-12-3456",
+			"the answer is 42",
+		} {
+			assert.NoError(t, policy.PreCheck(ctx, chat(content)), content)
+		}
+	})
+
+	t.Run("PhoneNeedsPhoneStructure", func(t *testing.T) {
+		policy := newPolicy(t, `{"detectors":["phone"]}`)
+		for _, content := range []string{
+			"call (020) 7946 0958",
+			"call 020 7946 0958",
+			"call 555-867-5309 now",
+		} {
+			assert.Error(t, policy.PreCheck(ctx, chat(content)), content)
+		}
+		for _, content := range []string{
+			"order 1234567890 shipped",
+			"created at 1700000000123",
+			"invoice 20241019 7",
+			"ref 12345678 9012",
+		} {
+			assert.NoError(t, policy.PreCheck(ctx, chat(content)), content)
+		}
+	})
+
+	t.Run("BlockRejectsRequest", func(t *testing.T) {
+		policy := newPolicy(t, `{}`)
+		err := policy.PreCheck(ctx, chat("reach me on jane@example.com"))
+		require.Error(t, err)
+
+		var perr *policies.PolicyError
+		require.True(t, errors.As(err, &perr))
+		assert.Equal(t, http.StatusBadRequest, perr.Status)
+		assert.Contains(t, perr.Message, model.PIIEmail)
+	})
+
+	t.Run("MaskRewritesBodyAndMessages", func(t *testing.T) {
+		policy := newPolicy(t, `{"action":"mask","detectors":["email","credit_card"]}`)
+		req := &policies.PreRequestContext{
+			AppID: "app-1",
+			Messages: []policies.Message{
+				{Role: "system", Content: "be brief"},
+				{Role: "user", Content: "email jane@example.com, card 4111111111111111"},
+			},
+			Body: []byte(`{"model":"gpt-4","seed":12345678901234567,"messages":[{"role":"system","content":"be brief"},` +
+				`{"role":"user","content":[{"type":"text","text":"email jane@example.com, card 4111111111111111"}]}]}`),
+		}
+		require.NoError(t, policy.PreCheck(ctx, req))
+
+		assert.True(t, req.BodyRewritten)
+		assert.JSONEq(t, `{"model":"gpt-4","seed":12345678901234567,"messages":[{"role":"system","content":"be brief"},`+
+			`{"role":"user","content":[{"type":"text","text":"email [REDACTED_EMAIL], card [REDACTED_CREDIT_CARD]"}]}]}`, string(req.Body))
+		assert.Equal(t, "email [REDACTED_EMAIL], card [REDACTED_CREDIT_CARD]", req.Messages[1].Content)
+		assert.Equal(t, []string{"pii:email", "pii:credit_card"}, req.Tags)
+	})
+
+	t.Run("MaskRewritesPrompt", func(t *testing.T) {
+		policy := newPolicy(t, `{"action":"mask"}`)
+		req := &policies.PreRequestContext{
+			AppID:  "app-1",
+			Prompt: "write to jane@example.com",
+			Body:   []byte(`{"model":"gpt-3.5-turbo-instruct","prompt":"write to jane@example.com"}`),
+		}
+		require.NoError(t, policy.PreCheck(ctx, req))
+		assert.JSONEq(t, `{"model":"gpt-3.5-turbo-instruct","prompt":"write to [REDACTED_EMAIL]"}`, string(req.Body))
+		assert.Equal(t, "write to [REDACTED_EMAIL]", req.Prompt)
+	})
+
+	t.Run("TagLeavesBodyAlone", func(t *testing.T) {
+		policy := newPolicy(t, `{"action":"tag"}`)
+		req := chat("jane@example.com")
+		req.Body = []byte(`{"messages":[{"role":"user","content":"jane@example.com"}]}`)
+		require.NoError(t, policy.PreCheck(ctx, req))
+		assert.False(t, req.BodyRewritten)
+		assert.Equal(t, "jane@example.com", req.Messages[0].Content)
+	})
+
+	t.Run("CustomPatterns", func(t *testing.T) {
+		policy := newPolicy(t, `{"action":"mask","custom_patterns":[{"name":"employee_id","pattern":"EMP-\\d{6}"}]}`)
+		req := chat("ask EMP-123456 or jane@example.com")
+		require.NoError(t, policy.PreCheck(ctx, req))
+		// Only the custom pattern runs when no detectors are listed
+		assert.Equal(t, "ask [REDACTED_EMPLOYEE_ID] or jane@example.com", req.Messages[0].Content)
+		assert.Equal(t, []string{"pii:employee_id"}, req.Tags)
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, config := range []string{
+			`{"detectors":["passport"]}`,
+			`{"action":"redact"}`,
+			`{"custom_patterns":[{"name":"bad","pattern":"("}]}`,
+			`{"custom_patterns":[{"pattern":"x"}]}`,
+		} {
+			_, err := engine.NewPolicy(model.PolicyTypePII, []byte(config))
+			assert.Error(t, err, config)
+		}
+	})
+}
@@ -5,7 +5,9 @@ package policies
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/model"
 )
@@ -23,16 +25,139 @@ type Policy interface {
 	PostCheck(ctx context.Context, req *PostRequestContext)
 }
 
+// Transformer is implemented by policies that rewrite the upstream request.
+// Every Transform runs before any PreCheck, and tokens are re-estimated from
+// the rewritten body so limits apply to what is actually sent.
+type Transformer interface {
+	// Transform edits the body (via SetBody) and Header; an error denies the request
+	Transform(ctx context.Context, req *PreRequestContext) error
+}
+
+// InspectsContent reports whether p reads the request's messages, prompt or
+// tools, and so can't be enforced on a body the gateway couldn't decode.
+// Dry-run policies never refuse and don't count.
+func InspectsContent(p Policy) bool {
+	if IsDryRun(p) {
+		return false
+	}
+	switch p.Type() {
+	case model.PolicyTypePII, model.PolicyTypePromptInjection, model.PolicyTypeToolAllowlist,
+		model.PolicyTypeImageLimit, model.PolicyTypeCustomCEL:
+		return true
+	}
+	return false
+}
+
+// ResponseGuard is implemented by policies that inspect completions before
+// the caller receives them. Streamed completions are checked as they grow.
+type ResponseGuard interface {
+	// CheckResponse may redact the text (via Redact); an error withholds the
+	// completion: it is replaced with a refusal, or a stream is aborted
+	CheckResponse(ctx context.Context, resp *ResponseContext) error
+}
+
+// ResponseContext is the text of one completion choice as seen by response guards
+type ResponseContext struct {
+	AppID    string
+	Model    string
+	Stream   bool
+	Text     string // completion text so far; replace it with Redact
+	Final    bool   // Text is the whole completion rather than a streamed prefix
+	Redacted bool   // a guard replaced Text
+}
+
+// Redact replaces the completion text passed to later guards and the caller
+func (r *ResponseContext) Redact(text string) {
+	r.Text = text
+	r.Redacted = true
+}
+
+// ResponseViolation is returned by a ResponseGuard when a completion must not
+// reach the caller
+type ResponseViolation struct {
+	Reason  string // logged and sent in the error event of an aborted stream
+	Refusal string // replaces the completion of a non-streamed response
+}
+
+// Error implements error
+func (v *ResponseViolation) Error() string {
+	return v.Reason
+}
+
 // PreRequestContext contains information available before sending the request
 type PreRequestContext struct {
 	Request          *http.Request
 	OrgID            string
 	AppID            string
 	APIKeyID         string
+	UserID           string
+	KeyMetadata      []byte // the API key's raw JSON metadata
+	Endpoint         string // normalized "/v1/..." path
 	Model            string
 	EstimatedTokens  int
 	RequestSizeBytes int
-	Body             []byte // Captured request body
+	Stream           bool
+	Messages         []Message           // chat messages, for policies that inspect content
+	Prompt           string              // completions prompt
+	Tools            []Tool              // tools offered to the model
+	ToolChoice       string              // none, auto or required; empty when unset
+	ForcedTool       string              // the tool tool_choice requires the model to call
+	Body             []byte              // request body as it will be sent upstream; read-only, replace it with SetBody
+	Header           http.Header         // upstream request headers; transformers may edit them
+	CallerHeader     http.Header         // headers as the caller sent them, before provider credentials were set; read-only
+	BodyRewritten    bool                // a policy replaced Body
+	ModelOverride    string              // set by a policy to reroute the request to another model
+	Reservations     []TokenReservation  // tokens charged up front, settled after the response
+	RateLimits       []RateLimitStatus   // quota left after this request, for response headers
+	Leases           []*ConcurrencyLease // concurrency slots held until the response is done
+	Priority         *Priority           // scheduling tier, set by a priority policy
+	Tags             []string            // labels policies attach to the request, e.g. "pii:email"
+	Metadata         map[string]any      // values recorded with the request's usage, set with SetMetadata
+}
+
+// Message is a chat message as seen by policies
+type Message struct {
+	Role    string
+	Content string  // text content; text parts are joined with newlines
+	Images  []Image // image parts
+}
+
+// Image is an image attached to a chat message
+type Image struct {
+	URL    string // remote URL, or the data URI of an inline image
+	Inline bool   // embedded as a base64 data URI
+	Size   int    // decoded bytes of an inline image; unknown (0) for remote ones
+	Detail string // low, high or auto
+}
+
+// Tool is a tool the caller offers the model
+type Tool struct {
+	Name       string
+	Parameters json.RawMessage // JSON Schema of the tool's arguments
+}
+
+// SetBody replaces the body sent upstream. Policies that change the content
+// should update Messages and Prompt to match so later policies see it too.
+func (r *PreRequestContext) SetBody(body []byte) {
+	r.Body = body
+	r.BodyRewritten = true
+}
+
+// SetMetadata records a value with the request's usage metric
+func (r *PreRequestContext) SetMetadata(key string, value any) {
+	if r.Metadata == nil {
+		r.Metadata = map[string]any{}
+	}
+	r.Metadata[key] = value
+}
+
+// RateLimitStatus is the quota left on one rate limited metric, reported back
+// to callers in x-ratelimit-* response headers
+type RateLimitStatus struct {
+	Metric     string // "requests" or "tokens"
+	Limit      int64
+	Remaining  int64
+	ResetAfter time.Duration
 }
 
 // PostRequestContext contains information after receiving the response
@@ -48,4 +173,37 @@ type PostRequestContext struct {
 	RequestSizeBytes  int
 	ResponseSizeBytes int
 	LatencyMs         int64
+	FinishReason      string        // e.g. "stop", "length", "end_turn"
+	TimeToFirstToken  time.Duration // streamed responses only
+	CostMicros        int64         // zero when the model is unpriced
+	Currency          string
+	Tags              []string           // labels attached by pre-checks
+	Metadata          map[string]any     // values recorded by pre-checks
+	Reservations      []TokenReservation // token quotas the request was charged to
+}
+
+type tagsKey struct{}
+
+// WithTags stores the labels policies attached to a request
+func WithTags(ctx context.Context, tags []string) context.Context {
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// GetTags retrieves the labels policies attached to a request
+func GetTags(ctx context.Context) []string {
+	tags, _ := ctx.Value(tagsKey{}).([]string)
+	return tags
+}
+
+type metadataKey struct{}
+
+// WithMetadata stores the values policies want recorded with a request's usage
+func WithMetadata(ctx context.Context, metadata map[string]any) context.Context {
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+// GetMetadata retrieves the values policies want recorded with a request's usage
+func GetMetadata(ctx context.Context) map[string]any {
+	metadata, _ := ctx.Value(metadataKey{}).(map[string]any)
+	return metadata
 }
@@ -70,7 +70,7 @@ func TestEndToEndPolicyEnforcementWithDatabase(t *testing.T) {
 
 	t.Run("RateLimitBlocksExcessRequests", func(t *testing.T) {
 		// Load policies from database
-		loadedPolicies, err := engine.LoadPolicies(ctx, appIDStr)
+		loadedPolicies, err := engine.LoadPolicies(ctx, policies.Scope{AppID: appIDStr})
 		require.NoError(t, err)
 		require.Len(t, loadedPolicies, 2, "Expected 2 policies to be loaded")
 
@@ -100,7 +100,7 @@ func TestEndToEndPolicyEnforcementWithDatabase(t *testing.T) {
 
 	t.Run("TokenLimitBlocksLargeRequests", func(t *testing.T) {
 		// Load policies from database
-		loadedPolicies, err := engine.LoadPolicies(ctx, appIDStr)
+		loadedPolicies, err := engine.LoadPolicies(ctx, policies.Scope{AppID: appIDStr})
 		require.NoError(t, err)
 		require.Len(t, loadedPolicies, 2, "Expected 2 policies to be loaded")
 
@@ -116,12 +116,12 @@ func TestEndToEndPolicyEnforcementWithDatabase(t *testing.T) {
 
 	t.Run("PolicyCachingWorks", func(t *testing.T) {
 		// Load policies (should hit DB first time)
-		policies1, err := engine.LoadPolicies(ctx, appIDStr)
+		policies1, err := engine.LoadPolicies(ctx, policies.Scope{AppID: appIDStr})
 		require.NoError(t, err)
 		require.Len(t, policies1, 2, "Expected 2 policies")
 
 		// Load again (should hit cache)
-		policies2, err := engine.LoadPolicies(ctx, appIDStr)
+		policies2, err := engine.LoadPolicies(ctx, policies.Scope{AppID: appIDStr})
 		require.NoError(t, err)
 		require.Len(t, policies2, 2, "Expected 2 policies from cache")
 	})
@@ -180,16 +180,16 @@ func TestPolicyRegistryIntegrationWithDatabase(t *testing.T) {
 
 			t.Cleanup(func() {
 				cleanupTestPolicy(t, pg.Queries, policyID)
-				if err := engine.InvalidateCache(ctx, appIDStr); err != nil {
+				if err := engine.InvalidateCache(ctx, model.PolicyScopeApp, appIDStr); err != nil {
 					t.Logf("Warning: failed to invalidate cache for app %s: %v", appIDStr, err)
 				}
 			})
 
 			// Clear caches to ensure the latest DB state is fetched for each policy type
-			require.NoError(t, engine.InvalidateCache(ctx, appIDStr))
+			require.NoError(t, engine.InvalidateCache(ctx, model.PolicyScopeApp, appIDStr))
 
 			// Load policies from DB (after cache invalidation)
-			policies, err := engine.LoadPolicies(ctx, appIDStr)
+			policies, err := engine.LoadPolicies(ctx, policies.Scope{AppID: appIDStr})
 			require.NoError(t, err)
 
 			// Should have at least one policy
@@ -220,6 +220,77 @@ func createTestOrgAndApp(t *testing.T, queries *db.Queries) (uuid.UUID, uuid.UUI
 	return createTestOrgAndAppWithSuffix(t, queries, "")
 }
 
+// TestScopedPolicyAttachmentWithDatabase loads policies attached to an org,
+// app, end-user and API key and checks they merge by precedence
+func TestScopedPolicyAttachmentWithDatabase(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	pgConnStr, _ := testkit.SetupTestContainers(t, testkit.DefaultContainerConfig())
+
+	ctx := context.Background()
+	pg, err := dbdriver.NewPostgresDriver(ctx, pgConnStr)
+	require.NoError(t, err)
+	defer pg.Pool.Close()
+
+	engine := policies.NewEngine(pg.Queries, kv.NewMemoryStore())
+
+	orgID, appID := createTestOrgAndAppWithSuffix(t, pg.Queries, uuid.New().String()[:8])
+	defer cleanupTestApp(t, pg.Queries, appID)
+	keyID := testkit.NewDBFixtures(pg.Queries, pg.Pool).CreateTestAPIKey(t, orgID, appID)
+	key, err := pg.Queries.GetAPIKeyByID(ctx, keyID)
+	require.NoError(t, err)
+
+	// The app's rate limit is overridden for this key; the org's allowlist is inherited
+	createTestPolicy(t, pg.Queries, orgID, appID, model.PolicyTypeRateLimit, `{"requests_per_minute": 100}`)
+	orgPolicy := createDetachedPolicy(t, pg.Queries, orgID, model.PolicyTypeModelAllowlist, `{"allowed_model_ids": ["gpt-4"]}`)
+	require.NoError(t, pg.Queries.AttachPolicyToOrg(ctx, db.AttachPolicyToOrgParams{PolicyID: orgPolicy, OrgID: orgID}))
+	userPolicy := createDetachedPolicy(t, pg.Queries, orgID, model.PolicyTypeRequestSize, `{"max_request_bytes": 1024}`)
+	require.NoError(t, pg.Queries.AttachPolicyToUser(ctx, db.AttachPolicyToUserParams{PolicyID: userPolicy, UserID: key.UserID}))
+	keyPolicy := createDetachedPolicy(t, pg.Queries, orgID, model.PolicyTypeRateLimit, `{"requests_per_minute": 1}`)
+	require.NoError(t, pg.Queries.AttachPolicyToAPIKey(ctx, db.AttachPolicyToAPIKeyParams{PolicyID: keyPolicy, KeyID: keyID}))
+
+	scope := policies.Scope{OrgID: orgID.String(), AppID: appID.String(), UserID: key.UserID.String(), KeyID: keyID.String()}
+	loaded, err := engine.LoadPolicies(ctx, scope)
+	require.NoError(t, err)
+
+	types := make([]model.PolicyType, len(loaded))
+	for i, p := range loaded {
+		types[i] = p.Type()
+	}
+	require.ElementsMatch(t, []model.PolicyType{model.PolicyTypeModelAllowlist, model.PolicyTypeRateLimit, model.PolicyTypeRequestSize}, types)
+
+	// The key's 1 rpm limit is the one enforced
+	for _, p := range loaded {
+		if p.Type() != model.PolicyTypeRateLimit {
+			continue
+		}
+		require.NoError(t, p.PreCheck(ctx, &policies.PreRequestContext{AppID: appID.String(), APIKeyID: keyID.String()}))
+		require.Error(t, p.PreCheck(ctx, &policies.PreRequestContext{AppID: appID.String(), APIKeyID: keyID.String()}))
+	}
+
+	// Without the key only the app's and org's policies apply
+	loaded, err = engine.LoadPolicies(ctx, policies.Scope{OrgID: orgID.String(), AppID: appID.String()})
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+}
+
+// createDetachedPolicy creates a policy without attaching it to anything
+func createDetachedPolicy(t *testing.T, queries *db.Queries, orgID uuid.UUID, policyType model.PolicyType, config string) uuid.UUID {
+	t.Helper()
+
+	policy, err := queries.CreatePolicy(context.Background(), db.CreatePolicyParams{
+		OrgID:      orgID,
+		PolicyType: string(policyType),
+		Config:     []byte(config),
+		Enabled:    true,
+		Mode:       string(model.PolicyModeEnforce),
+	})
+	require.NoError(t, err)
+	return policy.ID
+}
+
 func createTestOrgAndAppWithSuffix(t *testing.T, queries *db.Queries, suffix string) (uuid.UUID, uuid.UUID) {
 	t.Helper()
 	ctx := context.Background()
@@ -254,6 +325,7 @@ func createTestPolicy(t *testing.T, queries *db.Queries, orgID, appID uuid.UUID,
 		PolicyType: string(policyType),
 		Config:     []byte(config),
 		Enabled:    true,
+		Mode:       string(model.PolicyModeEnforce),
 	})
 	require.NoError(t, err)
 
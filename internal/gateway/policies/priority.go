@@ -0,0 +1,95 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+// PriorityTiers lists the tiers from most to least important
+var PriorityTiers = []string{model.PriorityCritical, model.PriorityHigh, model.PriorityStandard, model.PriorityLow}
+
+// defaultMaxWait is how long each tier queues for a saturated deployment
+// before it is shed: batch-style low priority traffic is shed straight away
+var defaultMaxWait = map[string]time.Duration{
+	model.PriorityCritical: 30 * time.Second,
+	model.PriorityHigh:     10 * time.Second,
+	model.PriorityStandard: 5 * time.Second,
+	model.PriorityLow:      0,
+}
+
+func init() {
+	Register(model.PolicyTypePriority, func(config []byte, deps PolicyDependencies) (Policy, error) {
+		var cfg model.PriorityConfig
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid priority config: %w", err)
+		}
+		if !slices.Contains(PriorityTiers, cfg.Tier) {
+			return nil, fmt.Errorf("invalid priority config: unknown tier %q", cfg.Tier)
+		}
+		if cfg.MaxWaitSeconds != nil && *cfg.MaxWaitSeconds < 0 {
+			return nil, fmt.Errorf("invalid priority config: max_wait_seconds must not be negative")
+		}
+		return NewPriorityPolicy(cfg), nil
+	})
+}
+
+// Priority is the scheduling tier a request runs at
+type Priority struct {
+	Tier    string
+	MaxWait time.Duration // how long to queue for a saturated deployment; 0 sheds at once
+}
+
+// DefaultPriority is the tier of apps without a priority policy
+func DefaultPriority() Priority {
+	return Priority{Tier: model.PriorityStandard, MaxWait: defaultMaxWait[model.PriorityStandard]}
+}
+
+type priorityKey struct{}
+
+// WithPriority stores the tier assigned to a request
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// GetPriority retrieves a request's tier, or the default tier if none was assigned
+func GetPriority(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return DefaultPriority()
+}
+
+// PriorityPolicy assigns an app's requests a tier for the deployment
+// scheduler. It never rejects anything itself.
+type PriorityPolicy struct {
+	priority Priority
+}
+
+// NewPriorityPolicy creates a new priority policy
+func NewPriorityPolicy(config model.PriorityConfig) *PriorityPolicy {
+	maxWait := defaultMaxWait[config.Tier]
+	if config.MaxWaitSeconds != nil {
+		maxWait = time.Duration(*config.MaxWaitSeconds) * time.Second
+	}
+	return &PriorityPolicy{priority: Priority{Tier: config.Tier, MaxWait: maxWait}}
+}
+
+// Type returns the policy type
+func (p *PriorityPolicy) Type() model.PolicyType {
+	return model.PolicyTypePriority
+}
+
+// PreCheck tags the request with the app's tier
+func (p *PriorityPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error {
+	priority := p.priority
+	req.Priority = &priority
+	return nil
+}
+
+// PostCheck is a no-op for priority
+func (p *PriorityPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {}
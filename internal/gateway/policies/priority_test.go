@@ -0,0 +1,50 @@
+package policies_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityPolicy(t *testing.T) {
+	ctx := context.Background()
+	engine := policies.NewEngine(nil, kv.NewMemoryStore())
+
+	t.Run("TagsRequestWithTier", func(t *testing.T) {
+		policy, err := engine.NewPolicy(model.PolicyTypePriority, []byte(`{"tier":"high"}`))
+		require.NoError(t, err)
+
+		req := &policies.PreRequestContext{AppID: "app-1"}
+		require.NoError(t, policy.PreCheck(ctx, req))
+		require.NotNil(t, req.Priority)
+		assert.Equal(t, model.PriorityHigh, req.Priority.Tier)
+		assert.Equal(t, 10*time.Second, req.Priority.MaxWait)
+	})
+
+	t.Run("MaxWaitOverride", func(t *testing.T) {
+		policy, err := engine.NewPolicy(model.PolicyTypePriority, []byte(`{"tier":"critical","max_wait_seconds":0}`))
+		require.NoError(t, err)
+
+		req := &policies.PreRequestContext{AppID: "app-1"}
+		require.NoError(t, policy.PreCheck(ctx, req))
+		assert.Zero(t, req.Priority.MaxWait)
+	})
+
+	t.Run("DefaultsToStandard", func(t *testing.T) {
+		assert.Equal(t, model.PriorityStandard, policies.GetPriority(ctx).Tier)
+
+		tagged := policies.WithPriority(ctx, policies.Priority{Tier: model.PriorityLow})
+		assert.Equal(t, model.PriorityLow, policies.GetPriority(tagged).Tier)
+	})
+
+	t.Run("UnknownTierRejected", func(t *testing.T) {
+		_, err := engine.NewPolicy(model.PolicyTypePriority, []byte(`{"tier":"urgent"}`))
+		assert.Error(t, err)
+	})
+}
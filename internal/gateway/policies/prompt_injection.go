@@ -0,0 +1,215 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+func init() {
+	Register(model.PolicyTypePromptInjection, func(config []byte, deps PolicyDependencies) (Policy, error) {
+		var cfg model.PromptInjectionConfig
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid prompt injection config: %w", err)
+		}
+		return NewPromptInjectionPolicy(cfg)
+	})
+}
+
+// Default prompt injection thresholds
+const (
+	DefaultInjectionFlagThreshold  = 0.5
+	DefaultInjectionBlockThreshold = 0.8
+)
+
+// injectionRule is one heuristic; weight is how strongly a match alone
+// suggests an injection
+type injectionRule struct {
+	name    string
+	pattern *regexp.Regexp
+	weight  float64
+}
+
+// injectionRules is the built-in rule set. Weights are tuned so a single
+// borderline phrase stays below the default flag threshold while the common
+// attack phrasings, or a couple of weaker signals together, cross it.
+var injectionRules = []injectionRule{
+	{
+		name:    "ignore_instructions",
+		pattern: regexp.MustCompile(`(?i)\b(?:ignore|disregard|forget|override)\b[^.\n]{0,40}?\b(?:previous|prior|above|earlier|preceding|all|any|your)\b[^.\n]{0,20}?\b(?:instructions?|prompts?|rules|directions|guidelines)\b`),
+		weight:  0.7,
+	},
+	{
+		name:    "system_prompt_exfiltration",
+		pattern: regexp.MustCompile(`(?i)\b(?:reveal|show|print|repeat|output|leak|tell me)\b[^.\n]{0,40}?\b(?:system prompt|system message|initial instructions|hidden instructions|your instructions)\b`),
+		weight:  0.6,
+	},
+	{
+		name:    "role_override",
+		pattern: regexp.MustCompile(`(?i)\byou are (?:now|no longer)\b|\bfrom now on,? you\b|\bpretend (?:to be|you are)\b|\bact as (?:an? )?(?:unrestricted|unfiltered|uncensored|evil)\b`),
+		weight:  0.35,
+	},
+	{
+		name:    "jailbreak_persona",
+		pattern: regexp.MustCompile(`(?i)\b(?:do anything now|developer mode|jailbreak(?:ed)?|god mode)\b|(?-i:\bDAN\b)`),
+		weight:  0.6,
+	},
+	{
+		name:    "fake_delimiters",
+		pattern: regexp.MustCompile(`(?im)<\|im_(?:start|end)\|>|\[/?inst\]|<</?sys>>|^\s*#{2,}\s*system\s*:`),
+		weight:  0.5,
+	},
+	{
+		name:    "new_instructions",
+		pattern: regexp.MustCompile(`(?i)\b(?:new|updated|real|actual) (?:instructions?|task|objective)\s*:|\bimportant instructions? for the (?:assistant|ai|model)\b`),
+		weight:  0.4,
+	},
+	{
+		name:    "safety_bypass",
+		pattern: regexp.MustCompile(`(?i)\b(?:bypass|disable|ignore|turn off|without)\b[^.\n]{0,30}?\b(?:safety|content|ethical|moral)\s+(?:filters?|guidelines|restrictions|polic(?:y|ies)|rules)\b|\bwith no (?:restrictions|limitations|filters)\b`),
+		weight:  0.6,
+	},
+	{
+		name:    "exfiltration_request",
+		pattern: regexp.MustCompile(`(?i)\b(?:send|forward|email|upload|post)\b[^.\n]{0,40}?\b(?:conversation|chat history|credentials|api keys?|passwords?|secrets)\b[^.\n]{0,40}?\bto\b`),
+		weight:  0.45,
+	},
+	{
+		name:    "encoded_payload",
+		pattern: regexp.MustCompile(`(?i)\b(?:decode|base64|rot13)\b[^.\n]{0,40}?\b(?:then|and)\s+(?:follow|execute|run|obey)\b`),
+		weight:  0.4,
+	},
+}
+
+// InjectionRules lists the names of the built-in rules
+func InjectionRules() []string {
+	names := make([]string, len(injectionRules))
+	for i, rule := range injectionRules {
+		names[i] = rule.name
+	}
+	return names
+}
+
+// untrustedRoles carry content the caller didn't write, such as tool output
+// and retrieved documents; instructions there are a stronger signal
+var untrustedRoles = []string{"tool", "function"}
+
+// untrustedBoost scales rule weights for matches in untrusted messages
+const untrustedBoost = 1.5
+
+// PromptInjectionPolicy scores prompts against injection and jailbreak
+// heuristics, tagging or rejecting requests above its thresholds. The score
+// is recorded in the request's usage metadata either way.
+type PromptInjectionPolicy struct {
+	rules []injectionRule
+	roles []string
+	flag  float64
+	block float64
+}
+
+// NewPromptInjectionPolicy creates a new prompt injection policy
+func NewPromptInjectionPolicy(config model.PromptInjectionConfig) (*PromptInjectionPolicy, error) {
+	p := &PromptInjectionPolicy{
+		rules: slices.Clone(injectionRules),
+		roles: config.Roles,
+		flag:  config.FlagThreshold,
+		block: config.BlockThreshold,
+	}
+	if p.flag == 0 {
+		p.flag = DefaultInjectionFlagThreshold
+	}
+	if p.block == 0 {
+		p.block = DefaultInjectionBlockThreshold
+	}
+	if p.flag < 0 || p.block < 0 {
+		return nil, fmt.Errorf("invalid prompt injection config: thresholds must be positive")
+	}
+	if p.flag > p.block {
+		return nil, fmt.Errorf("invalid prompt injection config: flag_threshold exceeds block_threshold")
+	}
+	if len(p.roles) == 0 {
+		p.roles = []string{"user", "tool", "function"}
+	}
+
+	for _, extra := range config.ExtraPatterns {
+		if extra.Name == "" {
+			return nil, fmt.Errorf("invalid prompt injection config: extra patterns need a name")
+		}
+		if extra.Weight <= 0 || extra.Weight > 1 {
+			return nil, fmt.Errorf("invalid prompt injection config: pattern %q: weight must be between 0 and 1", extra.Name)
+		}
+		pattern, err := regexp.Compile(extra.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt injection config: pattern %q: %w", extra.Name, err)
+		}
+		p.rules = append(p.rules, injectionRule{name: extra.Name, pattern: pattern, weight: extra.Weight})
+	}
+	return p, nil
+}
+
+// Type returns the policy type
+func (p *PromptInjectionPolicy) Type() model.PolicyType {
+	return model.PolicyTypePromptInjection
+}
+
+// PreCheck scores the prompt, records the score and tags or rejects the
+// request when it crosses a threshold
+func (p *PromptInjectionPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error {
+	score, matched := p.Score(req)
+	req.SetMetadata("injection_score", score)
+	if len(matched) > 0 {
+		req.SetMetadata("injection_rules", matched)
+	}
+
+	if score >= p.block {
+		return rejectf(http.StatusBadRequest, "request blocked: possible prompt injection (score %.2f)", score)
+	}
+	if score >= p.flag {
+		req.Tags = append(req.Tags, "prompt_injection")
+	}
+	return nil
+}
+
+// PostCheck does nothing for prompt injection policies
+func (p *PromptInjectionPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {}
+
+// Score rates the request between 0 and 1 and returns the rules that matched.
+// Each rule counts once at its strongest weight, and the weights combine as
+// independent signals: 1 - (1-w1)(1-w2)...
+func (p *PromptInjectionPolicy) Score(req *PreRequestContext) (float64, []string) {
+	weights := map[string]float64{}
+	scan := func(text string, boost float64) {
+		for _, rule := range p.rules {
+			if rule.pattern.MatchString(text) {
+				weights[rule.name] = max(weights[rule.name], min(rule.weight*boost, 1))
+			}
+		}
+	}
+	for _, msg := range req.Messages {
+		if !slices.Contains(p.roles, msg.Role) {
+			continue
+		}
+		boost := 1.0
+		if slices.Contains(untrustedRoles, msg.Role) {
+			boost = untrustedBoost
+		}
+		scan(msg.Content, boost)
+	}
+	scan(req.Prompt, 1)
+
+	var matched []string
+	clean := 1.0
+	for _, rule := range p.rules {
+		if w, ok := weights[rule.name]; ok && !slices.Contains(matched, rule.name) {
+			matched = append(matched, rule.name)
+			clean *= 1 - w
+		}
+	}
+	return math.Round((1-clean)*100) / 100, matched
+}
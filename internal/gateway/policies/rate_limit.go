@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
@@ -17,21 +18,55 @@ func init() {
 		if err := json.Unmarshal(config, &cfg); err != nil {
 			return nil, fmt.Errorf("invalid rate limit config: %w", err)
 		}
-		return NewRateLimitPolicy(cfg, deps.Cache), nil
+		if cfg.KeyShare < 0 || cfg.KeyShare > 1 {
+			return nil, fmt.Errorf("invalid rate limit config: key_share must be between 0 and 1")
+		}
+		switch cfg.Algorithm {
+		case "", model.RateLimitFixedWindow, model.RateLimitSlidingWindow, model.RateLimitTokenBucket:
+		default:
+			return nil, fmt.Errorf("invalid rate limit config: unknown algorithm %q", cfg.Algorithm)
+		}
+		if cfg.QueueTimeoutSeconds < 0 || cfg.QueueSize < 0 {
+			return nil, fmt.Errorf("invalid rate limit config: queue_timeout_seconds and queue_size must not be negative")
+		}
+		policy := NewRateLimitPolicy(cfg, deps.Cache)
+		if deps.Queue != nil {
+			policy.queue = deps.Queue
+		}
+		if deps.DryRun {
+			// Count dry-run traffic separately
+			policy.keyPrefix = DryRunKeyPrefix
+		}
+		if deps.DryRun || deps.Simulation {
+			// Never hold back requests that can't be refused or won't be sent
+			policy.config.QueueTimeoutSeconds = 0
+		}
+		return policy, nil
 	})
 }
 
 // RateLimitPolicy enforces rate limits on requests and tokens per minute
 type RateLimitPolicy struct {
-	config  model.RateLimitConfig
-	limiter *RateLimiter
+	config    model.RateLimitConfig
+	algorithm string
+	limiter   *RateLimiter
+	queue     *WaitQueue // shared by the engine so queues outlive cached policies
+	keyPrefix string     // set for dry-run policies so their counters are their own
+	now       func() time.Time
 }
 
 // NewRateLimitPolicy creates a new rate limit policy
 func NewRateLimitPolicy(config model.RateLimitConfig, cache kv.KvStore) *RateLimitPolicy {
+	algorithm := config.Algorithm
+	if algorithm == "" {
+		algorithm = model.RateLimitFixedWindow
+	}
 	return &RateLimitPolicy{
-		config:  config,
-		limiter: NewRateLimiter(cache),
+		config:    config,
+		algorithm: algorithm,
+		limiter:   NewRateLimiter(cache),
+		queue:     NewWaitQueue(),
+		now:       time.Now,
 	}
 }
 
@@ -40,67 +75,196 @@ func (p *RateLimitPolicy) Type() model.PolicyType {
 	return model.PolicyTypeRateLimit
 }
 
-// PreCheck checks if the request exceeds rate limits
+// quotaLevel is one tier of the org -> app -> key quota hierarchy
+type quotaLevel struct {
+	name string // "org", "app" or "key"; the app level keeps the original key format
+	id   string
+	rpm  int
+	tpm  int
+}
+
+// counterKey is the counter for this level and metric in the minute
+// containing at
+func (l quotaLevel) counterKey(metric string, at time.Time) string {
+	if l.name == "app" {
+		return rateLimitKeyAt(l.id, metric, at)
+	}
+	return quotaKeyAt(l.name, l.id, metric, at)
+}
+
+// limitKey is where this level's state for metric lives under algorithm at
+// time at; only fixed windows change key as time passes
+func (l quotaLevel) limitKey(algorithm, metric string, at time.Time) string {
+	if algorithm == model.RateLimitFixedWindow {
+		return l.counterKey(metric, at)
+	}
+	return RateLimitStateKey(algorithm, l.name, l.id, metric)
+}
+
+// levels resolves the effective limits at each tier for a request. The app
+// limits are bounded by the org's and a key gets KeyShare of its app's.
+func (p *RateLimitPolicy) levels(orgID, appID, apiKeyID string) []quotaLevel {
+	appRPM := boundedLimit(p.config.RequestsPerMinute, p.config.OrgRequestsPerMinute)
+	appTPM := boundedLimit(p.config.TokensPerMinute, p.config.OrgTokensPerMinute)
+
+	levels := make([]quotaLevel, 0, 3)
+	if orgID != "" && (p.config.OrgRequestsPerMinute > 0 || p.config.OrgTokensPerMinute > 0) {
+		levels = append(levels, quotaLevel{name: "org", id: orgID, rpm: p.config.OrgRequestsPerMinute, tpm: p.config.OrgTokensPerMinute})
+	}
+	levels = append(levels, quotaLevel{name: "app", id: appID, rpm: appRPM, tpm: appTPM})
+	if apiKeyID != "" && p.config.KeyShare > 0 && p.config.KeyShare < 1 {
+		levels = append(levels, quotaLevel{name: "key", id: apiKeyID, rpm: shareOf(appRPM, p.config.KeyShare), tpm: shareOf(appTPM, p.config.KeyShare)})
+	}
+	return levels
+}
+
+// limitSet is the limits a request is checked against at one moment, with
+// what each one is called and counts
+type limitSet struct {
+	limits      []kv.RateLimit
+	labels      []string
+	metrics     []string
+	tokenLimits []int // indexes of limits that reserve tokens
+}
+
+// limitSet builds the limits for req at time at. Fixed window keys name the
+// minute, so they must be rebuilt for every attempt.
+func (p *RateLimitPolicy) limitSet(req *PreRequestContext, at time.Time) limitSet {
+	var set limitSet
+	for _, l := range p.levels(req.OrgID, req.AppID, req.APIKeyID) {
+		if l.rpm > 0 {
+			set.limits = append(set.limits, kv.RateLimit{Key: p.keyPrefix + l.limitKey(p.algorithm, "requests", at), Cost: 1, Limit: int64(l.rpm), Window: time.Minute})
+			set.labels = append(set.labels, limitLabel(l.name, "requests per minute"))
+			set.metrics = append(set.metrics, "requests")
+		}
+		// Tokens are reserved at their estimate and settled once usage is known
+		if l.tpm > 0 && req.EstimatedTokens > 0 {
+			set.tokenLimits = append(set.tokenLimits, len(set.limits))
+			set.limits = append(set.limits, kv.RateLimit{Key: p.keyPrefix + l.limitKey(p.algorithm, "tokens", at), Cost: int64(req.EstimatedTokens), Limit: int64(l.tpm), Window: time.Minute})
+			set.labels = append(set.labels, limitLabel(l.name, "tokens per minute"))
+			set.metrics = append(set.metrics, "tokens")
+		}
+	}
+	return set
+}
+
+// PreCheck checks the request against every quota level and consumes from all
+// of them in one atomic KV call, so a denial at any level consumes nothing
 func (p *RateLimitPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error {
-	// Check requests per minute
-	if p.config.RequestsPerMinute > 0 {
-		key := RateLimitKey(req.AppID, "requests")
-		allowed, err := p.limiter.CheckAndIncrement(ctx, key, p.config.RequestsPerMinute, time.Minute)
-		if err != nil {
-			// Redis unavailable - block request to avoid bypassing limits
+	reservedAt := p.now()
+	set := p.limitSet(req, reservedAt)
+	decision, err := p.limiter.Allow(ctx, p.algorithm, set.limits)
+	if err != nil {
+		// Redis unavailable - block request to avoid bypassing limits
+		logger.GetLogger(ctx).Error().
+			Err(err).
+			Str("app_id", req.AppID).
+			Msg("Rate limiter Redis unavailable, blocking request")
+		return rejectf(http.StatusServiceUnavailable, "rate limit exceeded: rate limiter unavailable")
+	}
+	if !decision.Allowed && p.config.QueueTimeoutSeconds > 0 {
+		if err := p.wait(ctx, req, &set, &decision, &reservedAt); err != nil {
 			logger.GetLogger(ctx).Error().
 				Err(err).
 				Str("app_id", req.AppID).
-				Msg("Rate limiter Redis unavailable, blocking request")
-			return rateLimitError("rate limiter unavailable")
-		}
-		if !allowed {
-			logger.GetLogger(ctx).Warn().
-				Int("limit", p.config.RequestsPerMinute).
-				Str("app_id", req.AppID).
-				Msg("Requests per minute limit exceeded")
-			return rateLimitError("requests per minute limit exceeded")
+				Msg("Rate limiter Redis unavailable while queued, blocking request")
+			return rejectf(http.StatusServiceUnavailable, "rate limit exceeded: rate limiter unavailable")
 		}
 	}
+	for i, state := range decision.States {
+		req.RateLimits = append(req.RateLimits, RateLimitStatus{
+			Metric:     set.metrics[i],
+			Limit:      set.limits[i].Limit,
+			Remaining:  state.Remaining,
+			ResetAfter: state.ResetAfter,
+		})
+	}
+	if !decision.Allowed {
+		state := decision.States[decision.Exceeded]
+		logger.GetLogger(ctx).Warn().
+			Int64("limit", set.limits[decision.Exceeded].Limit).
+			Int64("remaining", state.Remaining).
+			Dur("retry_after", state.RetryAfter).
+			Int("estimated_tokens", req.EstimatedTokens).
+			Str("algorithm", p.algorithm).
+			Str("org_id", req.OrgID).
+			Str("app_id", req.AppID).
+			Str("api_key_id", req.APIKeyID).
+			Msg("Rate limit exceeded: " + set.labels[decision.Exceeded])
+		return throttlef(state.RetryAfter, "rate limit exceeded: %s limit exceeded", set.labels[decision.Exceeded])
+	}
 
-	// Check tokens per minute (estimate only)
-	if p.config.TokensPerMinute > 0 && req.EstimatedTokens > 0 {
-		key := RateLimitKey(req.AppID, "tokens")
-		current, err := p.limiter.GetCount(ctx, key)
-		if err == nil {
-			if current+req.EstimatedTokens > p.config.TokensPerMinute {
-				logger.GetLogger(ctx).Warn().
-					Int("current", current).
-					Int("estimated", req.EstimatedTokens).
-					Int("limit", p.config.TokensPerMinute).
-					Str("app_id", req.AppID).
-					Msg("Tokens per minute limit would be exceeded")
-				return rateLimitError("tokens per minute limit exceeded")
-			}
-		}
-		// Increment by estimated tokens
-		_ = p.limiter.Increment(ctx, key, req.EstimatedTokens, time.Minute)
+	for _, i := range set.tokenLimits {
+		req.Reservations = append(req.Reservations, TokenReservation{Algorithm: p.algorithm, Limit: set.limits[i], ReservedAt: reservedAt})
 	}
 
 	return nil
 }
 
-// PostCheck updates rate limit counters with actual token usage (async)
-func (p *RateLimitPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {
-	// Update token counter with actual tokens (if different from estimate)
-	if p.config.TokensPerMinute > 0 {
-		actualTokens := req.ActualTokens.TotalTokens
-		if actualTokens > 0 {
-			key := RateLimitKey(req.AppID, "tokens")
-			// Note: In a real implementation, you'd want to adjust for the difference
-			// between estimated and actual tokens to avoid double-counting
-			// For now, we just track actual tokens
-			_ = p.limiter.Increment(ctx, key, actualTokens, time.Minute)
+// wait holds a denied request in its app's queue, retrying until the limits
+// admit it or the queue timeout passes, and updates set, decision and
+// reservedAt from the last attempt. Each attempt rebuilds the limits, so a
+// request that waits into the next minute is counted in it. A full queue or
+// a caller that gives up leaves the denial as it was.
+func (p *RateLimitPolicy) wait(ctx context.Context, req *PreRequestContext, set *limitSet, decision *RateLimitDecision, reservedAt *time.Time) error {
+	size := p.config.QueueSize
+	if size == 0 {
+		size = DefaultQueueSize
+	}
+	timeout := time.Duration(p.config.QueueTimeoutSeconds) * time.Second
+	appID := req.AppID
+
+	start := time.Now()
+	admitted, err := p.queue.Wait(ctx, appID, size, timeout, func() (time.Duration, bool, error) {
+		at := p.now()
+		limits := p.limitSet(req, at)
+		next, err := p.limiter.Allow(ctx, p.algorithm, limits.limits)
+		if err != nil {
+			return 0, false, err
+		}
+		*set, *decision, *reservedAt = limits, next, at
+		if next.Allowed {
+			return 0, true, nil
 		}
+		return next.States[next.Exceeded].RetryAfter, false, nil
+	})
+	if err != nil && ctx.Err() != nil {
+		err = nil
+	}
+
+	logger.GetLogger(ctx).Debug().
+		Str("app_id", appID).
+		Bool("admitted", admitted).
+		Dur("waited", time.Since(start)).
+		Msg("Rate limited request left wait queue")
+	return err
+}
+
+// PostCheck does nothing: token reservations made in PreCheck are settled
+// against actual usage by the usage recorder, once per request
+func (p *RateLimitPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {}
+
+// limitLabel names a limit in errors, e.g. "org requests per minute"; the app
+// level is left unqualified as it was before hierarchical quotas
+func limitLabel(level, metric string) string {
+	if level == "app" {
+		return metric
+	}
+	return level + " " + metric
+}
+
+// boundedLimit caps limit by parent, where zero means unlimited at either level
+func boundedLimit(limit, parent int) int {
+	if parent > 0 && (limit <= 0 || limit > parent) {
+		return parent
 	}
+	return limit
 }
 
-// rateLimitError creates a structured error for rate limit violations
-func rateLimitError(message string) error {
-	return fmt.Errorf("rate limit exceeded: %s", message)
+// shareOf is a key's slice of an app limit, never rounding a real limit down to zero
+func shareOf(limit int, share float64) int {
+	if limit <= 0 {
+		return 0
+	}
+	return max(1, int(float64(limit)*share))
 }
@@ -236,6 +236,77 @@ func TestRateLimitPolicyIntegration(t *testing.T) {
 		// For now, we'll test that the limit is enforced
 	})
 
+	t.Run("OrgQuotaSharedAcrossApps", func(t *testing.T) {
+		config := model.RateLimitConfig{
+			RequestsPerMinute:    5,
+			OrgRequestsPerMinute: 3,
+		}
+
+		policy := policies.NewRateLimitPolicy(config, cache)
+
+		for i, appID := range []string{"test-app-org-a", "test-app-org-b", "test-app-org-a"} {
+			req := &policies.PreRequestContext{OrgID: "test-org-quota", AppID: appID, Model: "gpt-4"}
+			require.NoError(t, policy.PreCheck(ctx, req), "Request %d within org quota should pass", i+1)
+		}
+
+		req := &policies.PreRequestContext{OrgID: "test-org-quota", AppID: "test-app-org-c", Model: "gpt-4"}
+		err := policy.PreCheck(ctx, req)
+		require.Error(t, err, "Fourth request in the org should be blocked")
+		require.Contains(t, err.Error(), "org requests per minute limit exceeded")
+	})
+
+	t.Run("KeyShareOfAppQuota", func(t *testing.T) {
+		config := model.RateLimitConfig{
+			RequestsPerMinute: 4,
+			KeyShare:          0.5,
+		}
+
+		policy := policies.NewRateLimitPolicy(config, cache)
+
+		for i := 0; i < 2; i++ {
+			req := &policies.PreRequestContext{AppID: "test-app-key-share", APIKeyID: "test-key-a", Model: "gpt-4"}
+			require.NoError(t, policy.PreCheck(ctx, req), "Request %d within key share should pass", i+1)
+		}
+
+		// Key A has used its half; key B still has room
+		req := &policies.PreRequestContext{AppID: "test-app-key-share", APIKeyID: "test-key-a", Model: "gpt-4"}
+		err := policy.PreCheck(ctx, req)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "key requests per minute limit exceeded")
+
+		req = &policies.PreRequestContext{AppID: "test-app-key-share", APIKeyID: "test-key-b", Model: "gpt-4"}
+		require.NoError(t, policy.PreCheck(ctx, req))
+	})
+
+	for _, algorithm := range []string{model.RateLimitSlidingWindow, model.RateLimitTokenBucket} {
+		t.Run("Algorithm_"+algorithm, func(t *testing.T) {
+			config := model.RateLimitConfig{
+				RequestsPerMinute:    3,
+				OrgRequestsPerMinute: 5,
+				Algorithm:            algorithm,
+			}
+
+			policy := policies.NewRateLimitPolicy(config, cache)
+
+			appID := "test-app-" + algorithm
+			for i := 0; i < 3; i++ {
+				req := &policies.PreRequestContext{OrgID: "test-org-" + algorithm, AppID: appID, Model: "gpt-4"}
+				require.NoError(t, policy.PreCheck(ctx, req), "Request %d within limit should pass", i+1)
+			}
+
+			req := &policies.PreRequestContext{OrgID: "test-org-" + algorithm, AppID: appID, Model: "gpt-4"}
+			err := policy.PreCheck(ctx, req)
+			require.Error(t, err, "Request exceeding limit should be blocked")
+			require.Contains(t, err.Error(), "requests per minute limit exceeded")
+
+			// The denied request consumed nothing at the org level
+			for i := 0; i < 2; i++ {
+				req := &policies.PreRequestContext{OrgID: "test-org-" + algorithm, AppID: appID + "-other", Model: "gpt-4"}
+				require.NoError(t, policy.PreCheck(ctx, req))
+			}
+		})
+	}
+
 	t.Run("PolicyTypeIsCorrect", func(t *testing.T) {
 		config := model.RateLimitConfig{
 			RequestsPerMinute: 100,
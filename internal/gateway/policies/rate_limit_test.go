@@ -0,0 +1,155 @@
+package policies_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitPolicy_HierarchicalQuotas(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("AppLimitBoundedByOrg", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		policy := policies.NewRateLimitPolicy(model.RateLimitConfig{
+			RequestsPerMinute:    100,
+			OrgRequestsPerMinute: 2,
+		}, cache)
+
+		req := &policies.PreRequestContext{OrgID: "org-1", AppID: "app-1"}
+		require.NoError(t, policy.PreCheck(ctx, req))
+		require.NoError(t, policy.PreCheck(ctx, req))
+		assert.Error(t, policy.PreCheck(ctx, req))
+	})
+
+	t.Run("DenialConsumesNothing", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		policy := policies.NewRateLimitPolicy(model.RateLimitConfig{
+			RequestsPerMinute:  10,
+			TokensPerMinute:    1000,
+			OrgTokensPerMinute: 1000,
+			KeyShare:           0.5,
+		}, cache)
+
+		// 600 tokens exceeds the key's 500 share, so neither the app nor the
+		// org counters may be charged for it
+		big := &policies.PreRequestContext{OrgID: "org-1", AppID: "app-1", APIKeyID: "key-1", EstimatedTokens: 600}
+		err := policy.PreCheck(ctx, big)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "key tokens per minute limit exceeded")
+
+		requests, _ := cache.Get(ctx, policies.RateLimitKey("app-1", "requests"))
+		orgTokens, _ := cache.Get(ctx, policies.QuotaKey("org", "org-1", "tokens"))
+		assert.Empty(t, requests)
+		assert.Empty(t, orgTokens)
+
+		// Another key can still use the whole app allowance between them
+		for _, key := range []string{"key-2", "key-3"} {
+			req := &policies.PreRequestContext{OrgID: "org-1", AppID: "app-1", APIKeyID: key, EstimatedTokens: 500}
+			require.NoError(t, policy.PreCheck(ctx, req))
+		}
+		orgTokens, _ = cache.Get(ctx, policies.QuotaKey("org", "org-1", "tokens"))
+		assert.Equal(t, "1000", orgTokens)
+	})
+
+	t.Run("KeyShareNeverRoundsToZero", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		policy := policies.NewRateLimitPolicy(model.RateLimitConfig{RequestsPerMinute: 3, KeyShare: 0.1}, cache)
+
+		req := &policies.PreRequestContext{AppID: "app-1", APIKeyID: "key-1"}
+		require.NoError(t, policy.PreCheck(ctx, req))
+		assert.Error(t, policy.PreCheck(ctx, req))
+	})
+
+	t.Run("InvalidKeyShareRejected", func(t *testing.T) {
+		engine := policies.NewEngine(nil, kv.NewMemoryStore())
+		_, err := engine.NewPolicy(model.PolicyTypeRateLimit, []byte(`{"requests_per_minute":10,"key_share":1.5}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestRateLimitPolicy_Algorithms(t *testing.T) {
+	ctx := context.Background()
+
+	for _, algorithm := range []string{model.RateLimitFixedWindow, model.RateLimitSlidingWindow, model.RateLimitTokenBucket} {
+		t.Run(algorithm, func(t *testing.T) {
+			cache := kv.NewMemoryStore()
+			policy := policies.NewRateLimitPolicy(model.RateLimitConfig{
+				RequestsPerMinute: 2,
+				TokensPerMinute:   100,
+				Algorithm:         algorithm,
+			}, cache)
+
+			req := &policies.PreRequestContext{AppID: "app-1", EstimatedTokens: 40}
+			require.NoError(t, policy.PreCheck(ctx, req))
+			require.NoError(t, policy.PreCheck(ctx, req))
+
+			err := policy.PreCheck(ctx, req)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "requests per minute limit exceeded")
+		})
+	}
+
+	t.Run("UnknownAlgorithmRejected", func(t *testing.T) {
+		engine := policies.NewEngine(nil, kv.NewMemoryStore())
+		_, err := engine.NewPolicy(model.PolicyTypeRateLimit, []byte(`{"requests_per_minute":10,"algorithm":"leaky"}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestRateLimiter_AllowReportsQuota(t *testing.T) {
+	ctx := context.Background()
+	limiter := policies.NewRateLimiter(kv.NewMemoryStore())
+
+	limits := []kv.RateLimit{
+		{Key: policies.RateLimitKey("app-1", "requests"), Cost: 1, Limit: 5, Window: time.Minute},
+		{Key: policies.RateLimitKey("app-1", "tokens"), Cost: 300, Limit: 500, Window: time.Minute},
+	}
+
+	decision, err := limiter.Allow(ctx, model.RateLimitFixedWindow, limits)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, -1, decision.Exceeded)
+	assert.Equal(t, int64(4), decision.States[0].Remaining)
+	assert.Equal(t, int64(200), decision.States[1].Remaining)
+	assert.LessOrEqual(t, decision.States[0].ResetAfter, time.Minute)
+
+	decision, err = limiter.Allow(ctx, model.RateLimitFixedWindow, limits)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 1, decision.Exceeded)
+	assert.Equal(t, int64(4), decision.States[0].Remaining, "denied requests consume nothing")
+	assert.Equal(t, int64(200), decision.States[1].Remaining)
+	assert.Positive(t, decision.States[1].RetryAfter)
+}
+
+func TestRateLimitPolicy_ReportsStatus(t *testing.T) {
+	ctx := context.Background()
+	policy := policies.NewRateLimitPolicy(model.RateLimitConfig{RequestsPerMinute: 2, TokensPerMinute: 1000}, kv.NewMemoryStore())
+
+	req := &policies.PreRequestContext{AppID: "app-1", EstimatedTokens: 300}
+	require.NoError(t, policy.PreCheck(ctx, req))
+	require.Len(t, req.RateLimits, 2)
+	assert.Equal(t, "requests", req.RateLimits[0].Metric)
+	assert.Equal(t, int64(2), req.RateLimits[0].Limit)
+	assert.Equal(t, int64(1), req.RateLimits[0].Remaining)
+	assert.Equal(t, "tokens", req.RateLimits[1].Metric)
+	assert.Equal(t, int64(700), req.RateLimits[1].Remaining)
+
+	require.NoError(t, policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1"}))
+
+	denied := &policies.PreRequestContext{AppID: "app-1"}
+	err := policy.PreCheck(ctx, denied)
+	var perr *policies.PolicyError
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, 429, perr.Status)
+	assert.Positive(t, perr.RetryAfter)
+	require.Len(t, denied.RateLimits, 1)
+	assert.Zero(t, denied.RateLimits[0].Remaining)
+}
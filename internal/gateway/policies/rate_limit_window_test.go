@@ -0,0 +1,53 @@
+package policies
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitPolicy_QueuedIntoNextMinute(t *testing.T) {
+	ctx := context.Background()
+	cache := kv.NewMemoryStore()
+	policy := NewRateLimitPolicy(model.RateLimitConfig{RequestsPerMinute: 1, TokensPerMinute: 1000, QueueTimeoutSeconds: 5}, cache)
+
+	first := time.Date(2025, 6, 2, 12, 0, 59, 0, time.UTC)
+	next := first.Add(2 * time.Second)
+	var rolled atomic.Bool
+	policy.now = func() time.Time {
+		if rolled.Load() {
+			return next
+		}
+		return first
+	}
+
+	require.NoError(t, policy.PreCheck(ctx, &PreRequestContext{AppID: "app-1", EstimatedTokens: 100}))
+
+	// The minute ends while the second request is queued
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		rolled.Store(true)
+	}()
+	queued := &PreRequestContext{AppID: "app-1", EstimatedTokens: 100}
+	require.NoError(t, policy.PreCheck(ctx, queued))
+
+	count, _ := cache.Get(ctx, rateLimitKeyAt("app-1", "requests", next))
+	assert.Equal(t, "1", count, "admitted against the minute it was let in")
+	count, _ = cache.Get(ctx, rateLimitKeyAt("app-1", "requests", first))
+	assert.Equal(t, "1", count)
+
+	require.Len(t, queued.Reservations, 1)
+	assert.Equal(t, rateLimitKeyAt("app-1", "tokens", next), queued.Reservations[0].Limit.Key, "settles against the new minute")
+	assert.Equal(t, next, queued.Reservations[0].ReservedAt)
+
+	// The new minute's quota is used up too
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	assert.Error(t, policy.PreCheck(waitCtx, &PreRequestContext{AppID: "app-1"}))
+}
@@ -3,10 +3,11 @@ package policies
 import (
 	"context"
 	"fmt"
-	"log"
+	"strings"
 	"time"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
 )
 
 // RateLimiter provides Redis-backed rate limiting using sliding window algorithm
@@ -19,67 +20,83 @@ func NewRateLimiter(cache kv.KvStore) *RateLimiter {
 	return &RateLimiter{cache: cache}
 }
 
-// CheckAndIncrement checks if the limit is exceeded and increments the counter if not
-// Returns true if the request is allowed, false if rate limited
-// Uses atomic Redis INCR operation to avoid race conditions
-func (rl *RateLimiter) CheckAndIncrement(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
-	if limit <= 0 {
-		// No limit configured
-		return true, nil
-	}
+// Increment atomically increments a counter by amount (for post-check tracking)
+func (rl *RateLimiter) Increment(ctx context.Context, key string, amount int, window time.Duration) error {
+	// Atomically increment by amount
+	// Counter and TTL are set in one call so the key can never outlive its window
+	_, _, err := rl.cache.IncrAllWithinLimits(ctx, []kv.LimitedCounter{{Key: key, Amount: int64(amount), TTL: window}})
+	return err
+}
 
-	log.Printf("DEBUG: RateLimiter.CheckAndIncrement - key=%s, limit=%d", key, limit)
+// RateLimitDecision is the outcome of checking a request against a set of limits
+type RateLimitDecision struct {
+	Allowed  bool
+	Exceeded int                 // index of the first limit that denied the request, -1 if allowed
+	States   []kv.RateLimitState // remaining quota and reset time per limit
+}
 
-	// Atomically increment the counter (handles non-existent keys)
-	newCount, err := rl.cache.Incr(ctx, key)
-	if err != nil {
-		// Redis error - fail open to avoid blocking traffic
-		log.Printf("DEBUG: RateLimiter.CheckAndIncrement - Redis error: %v", err)
-		return true, err
+// Allow checks every limit with the given algorithm and, only if all of them
+// admit the request, consumes its cost from each in a single atomic KV call
+func (rl *RateLimiter) Allow(ctx context.Context, algorithm string, limits []kv.RateLimit) (RateLimitDecision, error) {
+	decision := RateLimitDecision{Allowed: true, Exceeded: -1}
+	if len(limits) == 0 {
+		return decision, nil
 	}
 
-	log.Printf("DEBUG: RateLimiter.CheckAndIncrement - newCount=%d", newCount)
-
-	// Set TTL on first increment
-	if newCount == 1 {
-		// Key was just created, set expiration
-		_, err := rl.cache.Expire(ctx, key, window)
-		if err != nil {
-			// Failed to set TTL, but counter is incremented
-			// Log error but don't fail request
-			log.Printf("DEBUG: RateLimiter.CheckAndIncrement - Failed to set TTL: %v", err)
-		}
+	var err error
+	switch algorithm {
+	case model.RateLimitSlidingWindow:
+		decision.Allowed, decision.States, err = rl.cache.SlidingWindow(ctx, limits)
+	case model.RateLimitTokenBucket:
+		decision.Allowed, decision.States, err = rl.cache.TokenBucket(ctx, limits)
+	default:
+		decision.Allowed, decision.States, err = rl.fixedWindow(ctx, limits)
 	}
-
-	// Check if limit exceeded (after incrementing)
-	if newCount > int64(limit) {
-		log.Printf("DEBUG: RateLimiter.CheckAndIncrement - Limit exceeded: %d > %d", newCount, limit)
-		return false, nil
+	if err != nil {
+		return RateLimitDecision{Allowed: true, Exceeded: -1}, err
 	}
 
-	log.Printf("DEBUG: RateLimiter.CheckAndIncrement - Request allowed")
-	return true, nil
+	if !decision.Allowed {
+		for i, s := range decision.States {
+			if !s.Allowed {
+				decision.Exceeded = i
+				break
+			}
+		}
+	}
+	return decision, nil
 }
 
-// Increment atomically increments a counter by amount (for post-check tracking)
-func (rl *RateLimiter) Increment(ctx context.Context, key string, amount int, window time.Duration) error {
-	// Atomically increment by amount
-	newCount, err := rl.cache.IncrBy(ctx, key, int64(amount))
+// fixedWindow applies limits as counters in the current window, which must
+// divide evenly into a minute-aligned clock (keys carry the window start)
+func (rl *RateLimiter) fixedWindow(ctx context.Context, limits []kv.RateLimit) (bool, []kv.RateLimitState, error) {
+	now := time.Now()
+	counters := make([]kv.LimitedCounter, len(limits))
+	for i, l := range limits {
+		counters[i] = kv.LimitedCounter{Key: l.Key, Amount: l.Cost, Limit: l.Limit, TTL: l.Window}
+	}
+	allowed, totals, err := rl.cache.IncrAllWithinLimits(ctx, counters)
 	if err != nil {
-		return err
+		return false, nil, err
 	}
 
-	// Set TTL on first increment
-	if newCount == int64(amount) {
-		// Key was just created, set expiration
-		_, err := rl.cache.Expire(ctx, key, window)
-		if err != nil {
-			// Failed to set TTL, but counter is incremented
-			return err
+	states := make([]kv.RateLimitState, len(limits))
+	for i, l := range limits {
+		reset := now.Truncate(l.Window).Add(l.Window).Sub(now)
+		used := totals[i]
+		if !allowed {
+			used -= l.Cost // nothing was consumed
+		}
+		states[i] = kv.RateLimitState{
+			Allowed:    totals[i] <= l.Limit,
+			Remaining:  max(0, l.Limit-used),
+			ResetAfter: reset,
+		}
+		if !states[i].Allowed {
+			states[i].RetryAfter = reset
 		}
 	}
-
-	return nil
+	return allowed, states, nil
 }
 
 // GetCount gets the current count for a key
@@ -95,9 +112,30 @@ func (rl *RateLimiter) GetCount(ctx context.Context, key string) (int, error) {
 	return int(count), nil
 }
 
+// RateLimitStateKey generates the key for a sliding window or token bucket
+// limit. Unlike fixed window keys it spans windows, so carries no timestamp.
+func RateLimitStateKey(algorithm string, parts ...string) string {
+	return "ratelimit:" + algorithm + ":" + strings.Join(parts, ":")
+}
+
 // RateLimitKey generates a Redis key for rate limiting
 func RateLimitKey(appID string, metric string) string {
-	// Use current minute as the window
-	minute := time.Now().Truncate(time.Minute).Unix()
+	return rateLimitKeyAt(appID, metric, time.Now())
+}
+
+// rateLimitKeyAt is RateLimitKey for the minute containing at
+func rateLimitKeyAt(appID, metric string, at time.Time) string {
+	minute := at.Truncate(time.Minute).Unix()
 	return fmt.Sprintf("ratelimit:%s:%s:%d", appID, metric, minute)
 }
+
+// QuotaKey generates a Redis key for an org or API key quota in the current minute
+func QuotaKey(scope, id, metric string) string {
+	return quotaKeyAt(scope, id, metric, time.Now())
+}
+
+// quotaKeyAt is QuotaKey for the minute containing at
+func quotaKeyAt(scope, id, metric string, at time.Time) string {
+	minute := at.Truncate(time.Minute).Unix()
+	return fmt.Sprintf("ratelimit:%s:%s:%s:%d", scope, id, metric, minute)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
 	"github.com/stretchr/testify/require"
 )
@@ -28,6 +29,11 @@ func (m *mockKVStore) Set(ctx context.Context, key string, value string, ttl tim
 	return nil
 }
 
+func (m *mockKVStore) SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	// Not used in rate limiter
+	return true, nil
+}
+
 func (m *mockKVStore) Del(ctx context.Context, key string) error {
 	delete(m.data, key)
 	return nil
@@ -53,91 +59,78 @@ func (m *mockKVStore) Expire(ctx context.Context, key string, ttl time.Duration)
 	return true, nil
 }
 
-func (m *mockKVStore) ScanGetAll(ctx context.Context, pattern string, count int64) (map[string]string, error) {
-	// Not used in rate limiter
-	return nil, nil
+func (m *mockKVStore) IncrAllWithinLimits(ctx context.Context, counters []kv.LimitedCounter) (bool, []int64, error) {
+	totals := make([]int64, len(counters))
+	allowed := true
+	for i, c := range counters {
+		totals[i] = m.data[c.Key] + c.Amount
+		if c.Limit > 0 && totals[i] > c.Limit {
+			allowed = false
+		}
+	}
+	if allowed {
+		for i, c := range counters {
+			m.data[c.Key] = totals[i]
+		}
+	}
+	return allowed, totals, nil
 }
 
-func (m *mockKVStore) ScanAll(ctx context.Context, pattern string, count int64) ([]string, error) {
-	// Not used in rate limiter
-	return nil, nil
+func (m *mockKVStore) SlidingWindow(ctx context.Context, limits []kv.RateLimit) (bool, []kv.RateLimitState, error) {
+	// Not used in rate limiter tests
+	return true, make([]kv.RateLimitState, len(limits)), nil
 }
 
-func (m *mockKVStore) Close(ctx context.Context) error {
-	return nil
+func (m *mockKVStore) TokenBucket(ctx context.Context, limits []kv.RateLimit) (bool, []kv.RateLimitState, error) {
+	// Not used in rate limiter tests
+	return true, make([]kv.RateLimitState, len(limits)), nil
 }
 
-func TestNewRateLimiter(t *testing.T) {
-	store := newMockKVStore()
-	limiter := policies.NewRateLimiter(store)
-
-	require.NotNil(t, limiter)
-	// Can't test internal cache field directly since it's unexported
+func (m *mockKVStore) AdjustSlidingWindow(ctx context.Context, limit kv.RateLimit, age time.Duration) error {
+	// Not used in rate limiter tests
+	return nil
 }
 
-func TestRateLimiter_CheckAndIncrement_NoLimit(t *testing.T) {
-	store := newMockKVStore()
-	limiter := policies.NewRateLimiter(store)
-
-	allowed, err := limiter.CheckAndIncrement(context.Background(), "test-key", 0, time.Minute)
-	require.NoError(t, err)
-	require.True(t, allowed) // No limit means always allowed
+func (m *mockKVStore) AdjustTokenBucket(ctx context.Context, limit kv.RateLimit) error {
+	// Not used in rate limiter tests
+	return nil
 }
 
-func TestRateLimiter_CheckAndIncrement_UnderLimit(t *testing.T) {
-	store := newMockKVStore()
-	limiter := policies.NewRateLimiter(store)
-
-	// First request - should be allowed
-	allowed, err := limiter.CheckAndIncrement(context.Background(), "test-key", 5, time.Minute)
-	require.NoError(t, err)
-	require.True(t, allowed)
-
-	// Second request - should still be allowed
-	allowed, err = limiter.CheckAndIncrement(context.Background(), "test-key", 5, time.Minute)
-	require.NoError(t, err)
-	require.True(t, allowed)
+func (m *mockKVStore) AcquireSemaphores(ctx context.Context, holder string, sems []kv.Semaphore) (bool, []int64, error) {
+	// Not used in rate limiter tests
+	return true, make([]int64, len(sems)), nil
+}
 
-	// Check counter
-	count, err := limiter.GetCount(context.Background(), "test-key")
-	require.NoError(t, err)
-	require.Equal(t, 2, count)
+func (m *mockKVStore) RenewSemaphores(ctx context.Context, holder string, sems []kv.Semaphore) error {
+	// Not used in rate limiter tests
+	return nil
 }
 
-func TestRateLimiter_CheckAndIncrement_AtLimit(t *testing.T) {
-	store := newMockKVStore()
-	limiter := policies.NewRateLimiter(store)
+func (m *mockKVStore) ReleaseSemaphores(ctx context.Context, holder string, sems []kv.Semaphore) error {
+	// Not used in rate limiter tests
+	return nil
+}
 
-	// Fill up to the limit
-	for i := 0; i < 3; i++ {
-		allowed, err := limiter.CheckAndIncrement(context.Background(), "test-key", 3, time.Minute)
-		require.NoError(t, err)
-		require.True(t, allowed)
-	}
+func (m *mockKVStore) ScanGetAll(ctx context.Context, pattern string, count int64) (map[string]string, error) {
+	// Not used in rate limiter
+	return nil, nil
+}
 
-	// Next request should be denied
-	allowed, err := limiter.CheckAndIncrement(context.Background(), "test-key", 3, time.Minute)
-	require.NoError(t, err)
-	require.False(t, allowed)
+func (m *mockKVStore) ScanAll(ctx context.Context, pattern string, count int64) ([]string, error) {
+	// Not used in rate limiter
+	return nil, nil
+}
 
-	// Check counter
-	count, err := limiter.GetCount(context.Background(), "test-key")
-	require.NoError(t, err)
-	require.Equal(t, 4, count) // Incremented even though denied
+func (m *mockKVStore) Close(ctx context.Context) error {
+	return nil
 }
 
-func TestRateLimiter_CheckAndIncrement_OverLimit(t *testing.T) {
+func TestNewRateLimiter(t *testing.T) {
 	store := newMockKVStore()
 	limiter := policies.NewRateLimiter(store)
 
-	// Exceed the limit in one go
-	allowed, err := limiter.CheckAndIncrement(context.Background(), "test-key", 1, time.Minute)
-	require.NoError(t, err)
-	require.True(t, allowed) // First request allowed
-
-	allowed, err = limiter.CheckAndIncrement(context.Background(), "test-key", 1, time.Minute)
-	require.NoError(t, err)
-	require.False(t, allowed) // Second request denied
+	require.NotNil(t, limiter)
+	// Can't test internal cache field directly since it's unexported
 }
 
 func TestRateLimiter_Increment(t *testing.T) {
@@ -208,22 +201,16 @@ func TestRateLimiter_DifferentKeys(t *testing.T) {
 	limiter := policies.NewRateLimiter(store)
 
 	// Different keys should be independent
-	allowed1, err := limiter.CheckAndIncrement(context.Background(), "key1", 2, time.Minute)
-	require.NoError(t, err)
-	require.True(t, allowed1)
-
-	allowed2, err := limiter.CheckAndIncrement(context.Background(), "key2", 2, time.Minute)
-	require.NoError(t, err)
-	require.True(t, allowed2)
+	require.NoError(t, limiter.Increment(context.Background(), "key1", 2, time.Minute))
+	require.NoError(t, limiter.Increment(context.Background(), "key2", 1, time.Minute))
 
-	// Both should still be allowed
-	allowed1, err = limiter.CheckAndIncrement(context.Background(), "key1", 2, time.Minute)
+	count, err := limiter.GetCount(context.Background(), "key1")
 	require.NoError(t, err)
-	require.True(t, allowed1)
+	require.Equal(t, 2, count)
 
-	allowed2, err = limiter.CheckAndIncrement(context.Background(), "key2", 2, time.Minute)
+	count, err = limiter.GetCount(context.Background(), "key2")
 	require.NoError(t, err)
-	require.True(t, allowed2)
+	require.Equal(t, 1, count)
 }
 
 // Test that would require a failing KV store - but our mock always succeeds
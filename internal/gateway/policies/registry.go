@@ -13,7 +13,12 @@ type PolicyFactory func(config []byte, deps PolicyDependencies) (Policy, error)
 
 // PolicyDependencies holds shared dependencies that policies might need
 type PolicyDependencies struct {
-	Cache kv.KvStore
+	Cache      kv.KvStore
+	Alerts     AlertSink
+	Queue      *WaitQueue     // where rate limited requests wait for capacity
+	Actions    ActionExecutor // carries out actions returned by post-checks
+	DryRun     bool           // the policy only records what it would do; keep its counters apart
+	Simulation bool           // the policy checks sample requests that are never sent
 }
 
 // PolicyTypeMetadata describes a policy type for UI/API consumers
@@ -116,6 +121,24 @@ func formatPolicyName(policyType model.PolicyType) string {
 		return "Model Allowlist"
 	case model.PolicyTypeRequestSize:
 		return "Request Size Limit"
+	case model.PolicyTypeBudget:
+		return "Budget"
+	case model.PolicyTypeConcurrency:
+		return "Concurrency Limit"
+	case model.PolicyTypePriority:
+		return "Priority"
+	case model.PolicyTypePII:
+		return "PII Detection"
+	case model.PolicyTypeTransform:
+		return "Request Transform"
+	case model.PolicyTypeResponseGuard:
+		return "Response Guard"
+	case model.PolicyTypePromptInjection:
+		return "Prompt Injection Detection"
+	case model.PolicyTypeToolAllowlist:
+		return "Tool Allowlist"
+	case model.PolicyTypeImageLimit:
+		return "Image Limits"
 	default:
 		return string(policyType)
 	}
@@ -132,6 +155,24 @@ func getPolicyDescription(policyType model.PolicyType) string {
 		return "Restrict which AI models can be used"
 	case model.PolicyTypeRequestSize:
 		return "Limit the maximum size of request bodies"
+	case model.PolicyTypeBudget:
+		return "Cap spend per app, org or key each day or month, with alerts at thresholds"
+	case model.PolicyTypeConcurrency:
+		return "Limit concurrent in-flight and streaming requests per app or key"
+	case model.PolicyTypePriority:
+		return "Assign the app a priority tier for admission to saturated deployments"
+	case model.PolicyTypePII:
+		return "Detect personal data in prompts and block, mask or tag the request"
+	case model.PolicyTypeTransform:
+		return "Rewrite requests before they go upstream: clamp max_tokens, force or strip parameters, inject a system prompt"
+	case model.PolicyTypeResponseGuard:
+		return "Inspect completions for denylisted content, PII, secrets or schema violations and redact or refuse them"
+	case model.PolicyTypePromptInjection:
+		return "Score prompts and tool results for injection and jailbreak attempts, flagging or blocking risky requests"
+	case model.PolicyTypeToolAllowlist:
+		return "Restrict which tools requests may offer the model, how many, and which arguments they may take"
+	case model.PolicyTypeImageLimit:
+		return "Limit how many images requests attach, how large inline images are, and which hosts image URLs point at"
 	default:
 		return ""
 	}
@@ -149,8 +190,44 @@ func getPolicySchema(policyType model.PolicyType) map[string]any {
 					"minimum":     1,
 					"description": "Maximum requests per minute",
 				},
+				"tokens_per_minute": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "Maximum tokens per minute",
+				},
+				"algorithm": map[string]any{
+					"type":        "string",
+					"enum":        []string{model.RateLimitFixedWindow, model.RateLimitSlidingWindow, model.RateLimitTokenBucket},
+					"default":     model.RateLimitFixedWindow,
+					"description": "Counting algorithm: per-minute counters, an exact trailing-minute window, or a continuously refilling token bucket",
+				},
+				"org_requests_per_minute": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "Maximum requests per minute across the whole organisation (also caps the app limit)",
+				},
+				"org_tokens_per_minute": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "Maximum tokens per minute across the whole organisation (also caps the app limit)",
+				},
+				"key_share": map[string]any{
+					"type":        "number",
+					"minimum":     0,
+					"maximum":     1,
+					"description": "Fraction of the app limits a single API key may use (e.g. 0.25)",
+				},
+				"queue_timeout_seconds": map[string]any{
+					"type":        "integer",
+					"minimum":     0,
+					"description": "Hold rate limited requests for up to this many seconds until capacity frees up instead of rejecting them (0 rejects immediately)",
+				},
+				"queue_size": map[string]any{
+					"type":        "integer",
+					"minimum":     0,
+					"description": "Maximum requests per app waiting at once when queueing (default 100)",
+				},
 			},
-			"required": []string{"requests_per_minute"},
 		}
 
 	case model.PolicyTypeTokenLimit:
@@ -203,6 +280,310 @@ func getPolicySchema(policyType model.PolicyType) map[string]any {
 			"required": []string{"max_request_bytes"},
 		}
 
+	case model.PolicyTypeBudget:
+		return map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"scope": map[string]any{
+					"type":        "string",
+					"enum":        []string{model.BudgetScopeApp, model.BudgetScopeOrg, model.BudgetScopeKey},
+					"default":     model.BudgetScopeApp,
+					"description": "Whose spend counts against the budget",
+				},
+				"period": map[string]any{
+					"type":        "string",
+					"enum":        []string{model.BudgetPeriodDaily, model.BudgetPeriodMonthly},
+					"default":     model.BudgetPeriodMonthly,
+					"description": "Budget period (UTC calendar day or month)",
+				},
+				"limit": map[string]any{
+					"type":             "number",
+					"exclusiveMinimum": 0,
+					"description":      "Hard cap in currency units",
+				},
+				"currency": map[string]any{
+					"type":        "string",
+					"default":     model.DefaultCurrency,
+					"description": "Currency of the limit; only costs in this currency count",
+				},
+				"alert_thresholds": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type":    "integer",
+						"minimum": 1,
+					},
+					"default":     []int{80, 100},
+					"description": "Percentages of the limit at which to raise an alert",
+				},
+				"action": map[string]any{
+					"type":        "string",
+					"enum":        []string{model.BudgetActionBlock, model.BudgetActionDowngrade},
+					"default":     model.BudgetActionBlock,
+					"description": "What to do once the limit is reached",
+				},
+				"downgrade_model": map[string]any{
+					"type":        "string",
+					"description": "Model to route to when action is downgrade",
+				},
+			},
+			"required": []string{"limit"},
+		}
+
+	case model.PolicyTypeConcurrency:
+		return map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"scope": map[string]any{
+					"type":        "string",
+					"enum":        []string{model.ConcurrencyScopeApp, model.ConcurrencyScopeKey},
+					"default":     model.ConcurrencyScopeApp,
+					"description": "Whether the limits apply to the whole app or to each API key",
+				},
+				"max_in_flight": map[string]any{
+					"type":        "integer",
+					"minimum":     0,
+					"description": "Maximum requests in flight at once, streams included (0 = unlimited)",
+				},
+				"max_streams": map[string]any{
+					"type":        "integer",
+					"minimum":     0,
+					"description": "Maximum streaming requests in flight at once (0 = unlimited)",
+				},
+				"lease_seconds": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"default":     60,
+					"description": "How long a slot outlives a gateway instance that stopped renewing it",
+				},
+			},
+		}
+
+	case model.PolicyTypePriority:
+		return map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"tier": map[string]any{
+					"type":        "string",
+					"enum":        PriorityTiers,
+					"description": "Tier the app's requests run at; higher tiers are admitted first when a deployment is saturated",
+				},
+				"max_wait_seconds": map[string]any{
+					"type":        "integer",
+					"minimum":     0,
+					"description": "How long requests queue for a saturated deployment before being shed (0 sheds at once; defaults: critical 30, high 10, standard 5, low 0)",
+				},
+			},
+			"required": []string{"tier"},
+		}
+
+	case model.PolicyTypePII:
+		return map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"detectors": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "string",
+						"enum": PIIDetectors,
+					},
+					"description": "Built-in detectors to run (defaults to all of them unless custom patterns are given)",
+				},
+				"custom_patterns": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"name":    map[string]any{"type": "string"},
+							"pattern": map[string]any{"type": "string"},
+						},
+						"required": []string{"name", "pattern"},
+					},
+					"description": "Extra named regular expressions to treat as PII (e.g. employee IDs)",
+				},
+				"action": map[string]any{
+					"type":        "string",
+					"enum":        []string{model.PIIActionBlock, model.PIIActionMask, model.PIIActionTag},
+					"default":     model.PIIActionBlock,
+					"description": "Reject the request, replace the PII with [REDACTED_<KIND>] before forwarding, or forward it unchanged and tag the request",
+				},
+			},
+		}
+
+	case model.PolicyTypeTransform:
+		return map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"max_tokens": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "Cap on max_tokens/max_completion_tokens; set as max_tokens when the caller sends neither",
+				},
+				"set": map[string]any{
+					"type":        "object",
+					"description": "Body parameters to force, e.g. {\"temperature\": 0}",
+				},
+				"remove": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "string",
+					},
+					"description": "Body parameters to strip, e.g. [\"logit_bias\"]",
+				},
+				"system_prompt": map[string]any{
+					"type":        "string",
+					"description": "System message prepended to chat requests",
+				},
+				"headers": map[string]any{
+					"type": "object",
+					"additionalProperties": map[string]any{
+						"type": "string",
+					},
+					"description": "Headers to set on the upstream request (credentials and framing headers are not allowed)",
+				},
+			},
+		}
+
+	case model.PolicyTypeResponseGuard:
+		return map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"denylist": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "string",
+					},
+					"description": "Regular expressions the completion must not contain",
+				},
+				"keywords": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "string",
+					},
+					"description": "Words or phrases the completion must not contain (case-insensitive)",
+				},
+				"pii_detectors": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "string",
+						"enum": PIIDetectors,
+					},
+					"description": "PII detectors to run on the completion",
+				},
+				"secrets": map[string]any{
+					"type":        "boolean",
+					"description": "Detect API keys, access tokens and private keys",
+				},
+				"json_schema": map[string]any{
+					"type":        "object",
+					"description": "JSON Schema the whole completion must conform to",
+				},
+				"action": map[string]any{
+					"type":        "string",
+					"enum":        []string{model.ResponseGuardRedact, model.ResponseGuardRefuse},
+					"default":     model.ResponseGuardRefuse,
+					"description": "Replace matches with [REDACTED_<KIND>], or withhold the completion (streams are ended with an error event)",
+				},
+				"refusal_message": map[string]any{
+					"type":        "string",
+					"description": "Completion text used when refusing",
+				},
+			},
+		}
+
+	case model.PolicyTypePromptInjection:
+		return map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"flag_threshold": map[string]any{
+					"type":        "number",
+					"minimum":     0,
+					"default":     DefaultInjectionFlagThreshold,
+					"description": "Score (0-1) at which the request is tagged prompt_injection",
+				},
+				"block_threshold": map[string]any{
+					"type":        "number",
+					"minimum":     0,
+					"default":     DefaultInjectionBlockThreshold,
+					"description": "Score (0-1) at which the request is rejected; set above 1 to only flag",
+				},
+				"roles": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "string",
+					},
+					"description": "Message roles to scan (defaults to user, tool and function); completions prompts are always scanned",
+				},
+				"extra_patterns": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"name":    map[string]any{"type": "string"},
+							"pattern": map[string]any{"type": "string"},
+							"weight":  map[string]any{"type": "number", "exclusiveMinimum": 0, "maximum": 1},
+						},
+						"required": []string{"name", "pattern", "weight"},
+					},
+					"description": "Extra rules scored alongside the built-in set",
+				},
+			},
+		}
+
+	case model.PolicyTypeToolAllowlist:
+		return map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"allowed_tools": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "string",
+					},
+					"description": "Tool names the request may offer or force, with * wildcards (empty allows any name)",
+				},
+				"max_tools": map[string]any{
+					"type":        "integer",
+					"minimum":     0,
+					"description": "Most tools a request may offer; 0 forbids tools (unlimited when unset)",
+				},
+				"forbidden_parameters": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "string",
+					},
+					"description": "Argument names tool schemas may not declare, with * wildcards, matched case-insensitively (e.g. \"*command\", \"sql\")",
+				},
+			},
+		}
+
+	case model.PolicyTypeImageLimit:
+		return map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"max_images": map[string]any{
+					"type":        "integer",
+					"minimum":     0,
+					"description": "Most images a request may attach; 0 forbids images (unlimited when unset)",
+				},
+				"max_image_bytes": map[string]any{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "Largest inline (data URI) image in bytes; remote images aren't fetched to be measured",
+				},
+				"allowed_hosts": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "string",
+					},
+					"description": "Hosts image URLs may point at, with * wildcards (e.g. \"*.blob.core.windows.net\"); empty allows any",
+				},
+				"allow_data_uris": map[string]any{
+					"type":        "boolean",
+					"default":     true,
+					"description": "Allow images embedded as base64 data URIs",
+				},
+			},
+		}
+
 	default:
 		return map[string]any{"type": "object"}
 	}
@@ -223,11 +604,16 @@ func getCELConfigSchema() map[string]any {
 			},
 			"pre_check_expression": map[string]any{
 				"type":        "string",
-				"description": "CEL expression evaluated before request (must return boolean)",
+				"description": "CEL expression evaluated before request (must return boolean). Variables: model, org_id, app_id, api_key_id, user_id, key_metadata, headers, path, endpoint, stream, messages, prompt, tools, estimated_tokens, request_size_bytes, now, hour, weekday. Functions: regex(text, pattern), contains_pii(text), counter(key, window), flagged(name)",
 			},
 			"post_check_expression": map[string]any{
 				"type":        "string",
-				"description": "CEL expression evaluated after response (optional)",
+				"description": "CEL expression evaluated after response (optional). Variables: model, org_id, app_id, api_key_id, prompt_tokens, completion_tokens, total_tokens, latency_ms, request_size_bytes, response_size_bytes, now, hour, weekday. Returns false to log a failed check, or an action (flag, alert, disable_key, penalty, event) or list of actions for the engine to run",
+			},
+			"timezone": map[string]any{
+				"type":        "string",
+				"description": "IANA timezone for hour and weekday, e.g. Australia/Sydney",
+				"default":     "UTC",
 			},
 		},
 		"required": []string{"name", "pre_check_expression"},
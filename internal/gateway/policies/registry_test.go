@@ -148,17 +148,26 @@ func TestListAvailableTypes(t *testing.T) {
 func TestListRegisteredTypes(t *testing.T) {
 	types := ListRegisteredTypes()
 
-	// Should have exactly 4 built-in policies
-	if len(types) != 4 {
-		t.Errorf("expected 4 registered policy types, got %d", len(types))
+	// Should have exactly 13 built-in policies
+	if len(types) != 13 {
+		t.Errorf("expected 13 registered policy types, got %d", len(types))
 	}
 
 	// Verify all expected types are present
 	expectedTypes := map[model.PolicyType]bool{
-		model.PolicyTypeRateLimit:      false,
-		model.PolicyTypeTokenLimit:     false,
-		model.PolicyTypeModelAllowlist: false,
-		model.PolicyTypeRequestSize:    false,
+		model.PolicyTypeRateLimit:       false,
+		model.PolicyTypeTokenLimit:      false,
+		model.PolicyTypeModelAllowlist:  false,
+		model.PolicyTypeRequestSize:     false,
+		model.PolicyTypeBudget:          false,
+		model.PolicyTypeConcurrency:     false,
+		model.PolicyTypePriority:        false,
+		model.PolicyTypePII:             false,
+		model.PolicyTypeTransform:       false,
+		model.PolicyTypeResponseGuard:   false,
+		model.PolicyTypePromptInjection: false,
+		model.PolicyTypeToolAllowlist:   false,
+		model.PolicyTypeImageLimit:      false,
 	}
 
 	for _, policyType := range types {
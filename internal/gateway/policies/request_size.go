@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
 	"github.com/WebDeveloperBen/ai-gateway/internal/model"
@@ -42,7 +43,7 @@ func (p *RequestSizePolicy) PreCheck(ctx context.Context, req *PreRequestContext
 			Int("limit", p.config.MaxRequestBytes).
 			Str("app_id", req.AppID).
 			Msg("Request size limit exceeded")
-		return fmt.Errorf("request size limit exceeded")
+		return rejectf(http.StatusRequestEntityTooLarge, "request size limit exceeded (%d > %d bytes)", req.RequestSizeBytes, p.config.MaxRequestBytes)
 	}
 
 	return nil
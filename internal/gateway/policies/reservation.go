@@ -0,0 +1,82 @@
+package policies
+
+import (
+	"context"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+// TokenReservation is an estimate of tokens charged against a rate limit at
+// PreCheck. Once the actual usage is known it is settled against the same
+// window, so each request is counted exactly once at its real size.
+type TokenReservation struct {
+	Algorithm  string
+	Limit      kv.RateLimit // Cost is the number of tokens reserved
+	ReservedAt time.Time
+}
+
+type reservationsKey struct{}
+
+// WithReservations stores a request's token reservations for settlement
+func WithReservations(ctx context.Context, reservations []TokenReservation) context.Context {
+	return context.WithValue(ctx, reservationsKey{}, reservations)
+}
+
+// GetReservations retrieves the token reservations made for a request
+func GetReservations(ctx context.Context) []TokenReservation {
+	reservations, _ := ctx.Value(reservationsKey{}).([]TokenReservation)
+	return reservations
+}
+
+// Settle replaces each reservation with the actual token count: the
+// difference (positive or negative) is applied to the window the estimate was
+// charged to. Pass zero to release reservations for requests that failed.
+func (rl *RateLimiter) Settle(ctx context.Context, reservations []TokenReservation, actualTokens int) error {
+	now := time.Now()
+	var firstErr error
+	for _, r := range reservations {
+		if err := rl.adjust(ctx, r, int64(actualTokens)-r.Limit.Cost, now); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Charge adds tokens on top of what a request already counted against each
+// of its reserved quotas, e.g. as a penalty
+func (rl *RateLimiter) Charge(ctx context.Context, reservations []TokenReservation, tokens int) error {
+	now := time.Now()
+	var firstErr error
+	for _, r := range reservations {
+		if err := rl.adjust(ctx, r, int64(tokens), now); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// adjust applies a token delta to the window a reservation was charged to
+func (rl *RateLimiter) adjust(ctx context.Context, r TokenReservation, cost int64, now time.Time) error {
+	if cost == 0 {
+		return nil
+	}
+	delta := r.Limit
+	delta.Cost = cost
+
+	switch r.Algorithm {
+	case model.RateLimitSlidingWindow:
+		return rl.cache.AdjustSlidingWindow(ctx, delta, now.Sub(r.ReservedAt))
+	case model.RateLimitTokenBucket:
+		return rl.cache.AdjustTokenBucket(ctx, delta)
+	default:
+		// Fixed window keys carry their minute; once it has rolled over
+		// the counter no longer limits anything
+		if !now.Truncate(r.Limit.Window).Equal(r.ReservedAt.Truncate(r.Limit.Window)) {
+			return nil
+		}
+		_, _, err := rl.cache.IncrAllWithinLimits(ctx, []kv.LimitedCounter{{Key: delta.Key, Amount: delta.Cost, TTL: delta.Window}})
+		return err
+	}
+}
@@ -0,0 +1,58 @@
+package policies_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenReservationSettlement(t *testing.T) {
+	ctx := context.Background()
+
+	for _, algorithm := range []string{model.RateLimitFixedWindow, model.RateLimitSlidingWindow, model.RateLimitTokenBucket} {
+		t.Run(algorithm, func(t *testing.T) {
+			cache := kv.NewMemoryStore()
+			engine := policies.NewEngine(nil, cache)
+			policy := policies.NewRateLimitPolicy(model.RateLimitConfig{TokensPerMinute: 1000, Algorithm: algorithm}, cache)
+
+			// Reserve 600; the response only used 100
+			first := &policies.PreRequestContext{AppID: "app-1", EstimatedTokens: 600}
+			require.NoError(t, policy.PreCheck(ctx, first))
+			require.Len(t, first.Reservations, 1)
+			assert.Equal(t, int64(600), first.Reservations[0].Limit.Cost)
+
+			second := &policies.PreRequestContext{AppID: "app-1", EstimatedTokens: 600}
+			require.Error(t, policy.PreCheck(ctx, second), "1200 reserved tokens exceed the limit")
+
+			require.NoError(t, engine.SettleReservations(ctx, first.Reservations, 100))
+			require.NoError(t, policy.PreCheck(ctx, second), "settling down to 100 frees room for another 600")
+
+			// PostCheck must not charge the actual tokens a second time
+			policy.PostCheck(ctx, &policies.PostRequestContext{AppID: "app-1", ActualTokens: model.TokenUsage{TotalTokens: 100}})
+			third := &policies.PreRequestContext{AppID: "app-1", EstimatedTokens: 300}
+			require.NoError(t, policy.PreCheck(ctx, third))
+
+			// Settling upwards charges the overrun to the same window
+			require.NoError(t, engine.SettleReservations(ctx, third.Reservations, 400))
+			assert.Error(t, policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1", EstimatedTokens: 1}))
+		})
+	}
+
+	t.Run("ReleaseOnFailure", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		engine := policies.NewEngine(nil, cache)
+		policy := policies.NewRateLimitPolicy(model.RateLimitConfig{TokensPerMinute: 500}, cache)
+
+		req := &policies.PreRequestContext{AppID: "app-1", EstimatedTokens: 500}
+		require.NoError(t, policy.PreCheck(ctx, req))
+		require.NoError(t, engine.SettleReservations(ctx, req.Reservations, 0))
+
+		count, _ := cache.Get(ctx, policies.RateLimitKey("app-1", "tokens"))
+		assert.Equal(t, "0", count)
+	})
+}
@@ -0,0 +1,154 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func init() {
+	Register(model.PolicyTypeResponseGuard, func(config []byte, deps PolicyDependencies) (Policy, error) {
+		var cfg model.ResponseGuardConfig
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid response guard config: %w", err)
+		}
+		return NewResponseGuardPolicy(cfg)
+	})
+}
+
+// DefaultRefusalMessage replaces completions withheld by a response guard
+const DefaultRefusalMessage = "The response was withheld by a content policy."
+
+// secretPatterns match credentials that commonly leak into model output
+var secretPatterns = []string{
+	`sk-[A-Za-z0-9_-]{20,}`,                                   // OpenAI style API keys
+	`AKIA[0-9A-Z]{16}`,                                        // AWS access key IDs
+	`gh[pousr]_[A-Za-z0-9]{36,}`,                              // GitHub tokens
+	`xox[abprs]-[A-Za-z0-9-]{10,}`,                            // Slack tokens
+	`eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]+`, // JWTs
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----`,                      // PEM private keys
+}
+
+// ResponseGuardPolicy checks completions against denylists, PII and secret
+// detectors and an optional JSON schema before the caller receives them
+type ResponseGuardPolicy struct {
+	detectors []piiDetector
+	schema    *huma.Schema
+	registry  huma.Registry
+	action    string
+	refusal   string
+}
+
+// NewResponseGuardPolicy creates a new response guard policy
+func NewResponseGuardPolicy(config model.ResponseGuardConfig) (*ResponseGuardPolicy, error) {
+	p := &ResponseGuardPolicy{action: config.Action, refusal: config.RefusalMessage}
+	if p.action == "" {
+		p.action = model.ResponseGuardRefuse
+	}
+	if p.action != model.ResponseGuardRedact && p.action != model.ResponseGuardRefuse {
+		return nil, fmt.Errorf("invalid response guard config: unknown action %q", config.Action)
+	}
+	if p.refusal == "" {
+		p.refusal = DefaultRefusalMessage
+	}
+
+	for _, expr := range config.Denylist {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid response guard config: denylist pattern %q: %w", expr, err)
+		}
+		p.detectors = append(p.detectors, piiDetector{name: "denylist", pattern: pattern})
+	}
+	if len(config.Keywords) > 0 {
+		quoted := make([]string, len(config.Keywords))
+		for i, keyword := range config.Keywords {
+			quoted[i] = regexp.QuoteMeta(keyword)
+		}
+		p.detectors = append(p.detectors, piiDetector{
+			name:    "keyword",
+			pattern: regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`),
+		})
+	}
+	if config.Secrets {
+		p.detectors = append(p.detectors, piiDetector{
+			name:    "secret",
+			pattern: regexp.MustCompile(strings.Join(secretPatterns, "|")),
+		})
+	}
+	pii, err := builtinDetectors(config.PIIDetectors)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response guard config: %w", err)
+	}
+	p.detectors = append(p.detectors, pii...)
+
+	if len(config.JSONSchema) > 0 {
+		p.schema = &huma.Schema{}
+		if err := json.Unmarshal(config.JSONSchema, p.schema); err != nil {
+			return nil, fmt.Errorf("invalid response guard config: json_schema: %w", err)
+		}
+		p.schema.PrecomputeMessages()
+		p.registry = huma.NewMapRegistry("#/components/schemas/", huma.DefaultSchemaNamer)
+	}
+
+	if len(p.detectors) == 0 && p.schema == nil {
+		return nil, fmt.Errorf("invalid response guard config: nothing to check")
+	}
+	return p, nil
+}
+
+// Type returns the policy type
+func (p *ResponseGuardPolicy) Type() model.PolicyType {
+	return model.PolicyTypeResponseGuard
+}
+
+// PreCheck does nothing; responses are checked in CheckResponse
+func (p *ResponseGuardPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error {
+	return nil
+}
+
+// PostCheck does nothing for response guard policies
+func (p *ResponseGuardPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {}
+
+// CheckResponse redacts or refuses matching output. The schema is only
+// checked once the whole completion is known.
+func (p *ResponseGuardPolicy) CheckResponse(ctx context.Context, resp *ResponseContext) error {
+	found := map[string]bool{}
+	redacted := redactMatches(p.detectors, resp.Text, found)
+	if len(found) > 0 {
+		if p.action == model.ResponseGuardRefuse {
+			kinds := make([]string, 0, len(found))
+			for _, d := range p.detectors {
+				if found[d.name] && !slices.Contains(kinds, d.name) {
+					kinds = append(kinds, d.name)
+				}
+			}
+			return &ResponseViolation{
+				Reason:  "response contains disallowed content: " + strings.Join(kinds, ", "),
+				Refusal: p.refusal,
+			}
+		}
+		resp.Redact(redacted)
+	}
+
+	if p.schema != nil && resp.Final {
+		var doc any
+		if err := json.Unmarshal([]byte(resp.Text), &doc); err != nil {
+			return &ResponseViolation{Reason: "response is not valid JSON", Refusal: p.refusal}
+		}
+		res := &huma.ValidateResult{}
+		huma.Validate(p.registry, p.schema, huma.NewPathBuffer([]byte(""), 0), huma.ModeWriteToServer, doc, res)
+		if len(res.Errors) > 0 {
+			return &ResponseViolation{
+				Reason:  fmt.Sprintf("response does not match the JSON schema: %v", res.Errors[0]),
+				Refusal: p.refusal,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package policies_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseGuardPolicy(t *testing.T) {
+	ctx := context.Background()
+	engine := policies.NewEngine(nil, kv.NewMemoryStore())
+
+	newGuard := func(t *testing.T, config string) policies.ResponseGuard {
+		t.Helper()
+		policy, err := engine.NewPolicy(model.PolicyTypeResponseGuard, []byte(config))
+		require.NoError(t, err)
+		guard, ok := policy.(policies.ResponseGuard)
+		require.True(t, ok)
+		return guard
+	}
+
+	t.Run("RefusesDenylistedContent", func(t *testing.T) {
+		guard := newGuard(t, `{"keywords":["Project Falcon"],"refusal_message":"Sorry, I can't share that."}`)
+		resp := &policies.ResponseContext{Text: "The codename is project falcon.", Final: true}
+		err := guard.CheckResponse(ctx, resp)
+		require.Error(t, err)
+
+		var violation *policies.ResponseViolation
+		require.True(t, errors.As(err, &violation))
+		assert.Contains(t, violation.Reason, "keyword")
+		assert.Equal(t, "Sorry, I can't share that.", violation.Refusal)
+
+		assert.NoError(t, guard.CheckResponse(ctx, &policies.ResponseContext{Text: "Falconry is fun.", Final: true}))
+	})
+
+	t.Run("RedactsSecretsAndPII", func(t *testing.T) {
+		guard := newGuard(t, `{"secrets":true,"pii_detectors":["email"],"denylist":["internal-[0-9]+"],"action":"redact"}`)
+		resp := &policies.ResponseContext{Text: "Use key sk-abcdefghijklmnopqrstuvwx on internal-42, ask ops@example.com"}
+		require.NoError(t, guard.CheckResponse(ctx, resp))
+		assert.True(t, resp.Redacted)
+		assert.Equal(t, "Use key [REDACTED_SECRET] on [REDACTED_DENYLIST], ask [REDACTED_EMAIL]", resp.Text)
+	})
+
+	t.Run("JSONSchemaCheckedWhenFinal", func(t *testing.T) {
+		guard := newGuard(t, `{"json_schema":{"type":"object","properties":{"answer":{"type":"integer"}},"required":["answer"]}}`)
+
+		assert.NoError(t, guard.CheckResponse(ctx, &policies.ResponseContext{Text: `{"answer":`}))
+		assert.NoError(t, guard.CheckResponse(ctx, &policies.ResponseContext{Text: `{"answer":42}`, Final: true}))
+		assert.Error(t, guard.CheckResponse(ctx, &policies.ResponseContext{Text: `{"answer":"42"}`, Final: true}))
+		assert.Error(t, guard.CheckResponse(ctx, &policies.ResponseContext{Text: `not json`, Final: true}))
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, config := range []string{
+			`{}`,
+			`{"keywords":["x"],"action":"block"}`,
+			`{"denylist":["("]}`,
+			`{"pii_detectors":["passport"]}`,
+		} {
+			_, err := engine.NewPolicy(model.PolicyTypeResponseGuard, []byte(config))
+			assert.Error(t, err, config)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package policies
+
+import (
+	"slices"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+// Scope identifies who a request is made for, from the organisation down to
+// the API key. Policies attached at any of these levels apply to the request
+type Scope struct {
+	OrgID  string
+	AppID  string
+	UserID string // the end-user that owns the API key
+	KeyID  string
+}
+
+// ScopedPolicy is an attached policy along with the scope it was attached at
+type ScopedPolicy struct {
+	ID    string
+	Scope model.PolicyScope
+	CachedPolicy
+}
+
+// MergeScoped resolves the policies attached across a request's scopes into
+// the set to enforce. A built-in policy type attached at a more specific scope
+// replaces that type from broader ones (a key's rate limit overrides the
+// app's), while custom CEL policies from every scope apply. A policy attached
+// at several scopes is only kept once. Dry-run policies take no part in
+// overriding: they are always kept and never displace an enforced policy.
+// Order is preserved.
+func MergeScoped(attached []ScopedPolicy) []CachedPolicy {
+	winning := make(map[model.PolicyType]int)
+	for _, p := range attached {
+		if overrides(p) {
+			winning[p.Type] = max(winning[p.Type], scopeRank(p.Scope))
+		}
+	}
+
+	merged := make([]CachedPolicy, 0, len(attached))
+	seen := make(map[string]bool, len(attached))
+	for _, p := range attached {
+		if overrides(p) && scopeRank(p.Scope) < winning[p.Type] {
+			continue
+		}
+		if p.ID != "" {
+			if seen[p.ID] {
+				continue
+			}
+			seen[p.ID] = true
+		}
+		merged = append(merged, p.CachedPolicy)
+	}
+	return merged
+}
+
+// overrides reports whether a policy replaces its type from broader scopes
+func overrides(p ScopedPolicy) bool {
+	return p.Type != model.PolicyTypeCustomCEL && p.Mode != model.PolicyModeDryRun
+}
+
+// scopeRank orders scopes by precedence; higher ranks are more specific
+func scopeRank(scope model.PolicyScope) int {
+	return slices.Index(model.PolicyScopes, scope)
+}
@@ -0,0 +1,121 @@
+package policies_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scoped(id string, scope model.PolicyScope, policyType model.PolicyType, config string) policies.ScopedPolicy {
+	return policies.ScopedPolicy{
+		ID:           id,
+		Scope:        scope,
+		CachedPolicy: policies.CachedPolicy{Type: policyType, Config: []byte(config)},
+	}
+}
+
+func TestMergeScoped(t *testing.T) {
+	t.Run("MostSpecificScopeWinsPerType", func(t *testing.T) {
+		merged := policies.MergeScoped([]policies.ScopedPolicy{
+			scoped("p1", model.PolicyScopeOrg, model.PolicyTypeRateLimit, `{"requests_per_minute":1000}`),
+			scoped("p2", model.PolicyScopeApp, model.PolicyTypeRateLimit, `{"requests_per_minute":100}`),
+			scoped("p3", model.PolicyScopeKey, model.PolicyTypeRateLimit, `{"requests_per_minute":10}`),
+			scoped("p4", model.PolicyScopeOrg, model.PolicyTypeModelAllowlist, `{"allowed_model_ids":["gpt-4"]}`),
+			scoped("p5", model.PolicyScopeUser, model.PolicyTypeRequestSize, `{"max_request_bytes":1024}`),
+		})
+
+		require.Len(t, merged, 3)
+		assert.Equal(t, `{"requests_per_minute":10}`, string(merged[0].Config), "the key's rate limit overrides the app's and org's")
+		assert.Equal(t, model.PolicyTypeModelAllowlist, merged[1].Type, "types only set at the org are inherited")
+		assert.Equal(t, model.PolicyTypeRequestSize, merged[2].Type)
+	})
+
+	t.Run("UserOverridesAppButNotKey", func(t *testing.T) {
+		merged := policies.MergeScoped([]policies.ScopedPolicy{
+			scoped("p1", model.PolicyScopeKey, model.PolicyTypeTokenLimit, `{"max_total_tokens":10}`),
+			scoped("p2", model.PolicyScopeUser, model.PolicyTypeTokenLimit, `{"max_total_tokens":100}`),
+			scoped("p3", model.PolicyScopeUser, model.PolicyTypeBudget, `{"limit":5}`),
+			scoped("p4", model.PolicyScopeApp, model.PolicyTypeBudget, `{"limit":50}`),
+		})
+
+		require.Len(t, merged, 2)
+		assert.Equal(t, `{"max_total_tokens":10}`, string(merged[0].Config))
+		assert.Equal(t, `{"limit":5}`, string(merged[1].Config))
+	})
+
+	t.Run("CustomCELAccumulates", func(t *testing.T) {
+		merged := policies.MergeScoped([]policies.ScopedPolicy{
+			scoped("p1", model.PolicyScopeOrg, model.PolicyTypeCustomCEL, `{"name":"org"}`),
+			scoped("p2", model.PolicyScopeKey, model.PolicyTypeCustomCEL, `{"name":"key"}`),
+		})
+		assert.Len(t, merged, 2)
+	})
+
+	t.Run("DryRunNeverOverrides", func(t *testing.T) {
+		dryRun := scoped("p2", model.PolicyScopeKey, model.PolicyTypeRateLimit, `{"requests_per_minute":10}`)
+		dryRun.Mode = model.PolicyModeDryRun
+		merged := policies.MergeScoped([]policies.ScopedPolicy{
+			scoped("p1", model.PolicyScopeApp, model.PolicyTypeRateLimit, `{"requests_per_minute":100}`),
+			dryRun,
+			scoped("p3", model.PolicyScopeUser, model.PolicyTypeRateLimit, `{"requests_per_minute":50}`),
+		})
+
+		require.Len(t, merged, 2)
+		assert.Equal(t, model.PolicyModeDryRun, merged[0].Mode, "a dry-run policy survives a more specific enforced one")
+		assert.Equal(t, `{"requests_per_minute":50}`, string(merged[1].Config), "and doesn't displace the enforced user limit")
+	})
+
+	t.Run("PolicyAttachedTwiceKeptOnce", func(t *testing.T) {
+		merged := policies.MergeScoped([]policies.ScopedPolicy{
+			scoped("p1", model.PolicyScopeOrg, model.PolicyTypeCustomCEL, `{"name":"shared"}`),
+			scoped("p1", model.PolicyScopeApp, model.PolicyTypeCustomCEL, `{"name":"shared"}`),
+			scoped("p2", model.PolicyScopeApp, model.PolicyTypeRequestSize, `{"max_request_bytes":1}`),
+			scoped("p2", model.PolicyScopeUser, model.PolicyTypeRequestSize, `{"max_request_bytes":1}`),
+		})
+		assert.Len(t, merged, 2)
+	})
+}
+
+func TestEngine_ScopedPolicyCache(t *testing.T) {
+	ctx := context.Background()
+	cache := kv.NewMemoryStore()
+	engine := policies.NewEngine(nil, cache)
+
+	appID := uuid.NewString()
+	// The gateway identifies keys by their prefix
+	withKey := policies.Scope{OrgID: uuid.NewString(), AppID: appID, UserID: uuid.NewString(), KeyID: "sk_live_a1b2"}
+	otherKey := withKey
+	otherKey.KeyID = "sk_live_c3d4"
+
+	require.NoError(t, policies.SetCachedPoliciesRaw(ctx, cache, withKey, []policies.CachedPolicy{
+		{Type: model.PolicyTypeRequestSize, Config: []byte(`{"max_request_bytes":10}`)},
+	}))
+	require.NoError(t, policies.SetCachedPoliciesRaw(ctx, cache, otherKey, []policies.CachedPolicy{}))
+
+	loaded, err := engine.LoadPolicies(ctx, withKey)
+	require.NoError(t, err)
+	assert.Len(t, loaded, 1)
+
+	loaded, err = engine.LoadPolicies(ctx, otherKey)
+	require.NoError(t, err)
+	assert.Empty(t, loaded, "another key of the same app has its own cache entry")
+
+	// Invalidating a key drops only that key's scope
+	require.NoError(t, engine.InvalidateCache(ctx, model.PolicyScopeKey, otherKey.KeyID))
+	_, err = engine.LoadPolicies(ctx, otherKey)
+	assert.Error(t, err, "falls through to the database, which this engine doesn't have")
+	loaded, err = engine.LoadPolicies(ctx, withKey)
+	require.NoError(t, err)
+	assert.Len(t, loaded, 1)
+
+	// Invalidating the user drops both tiers for every scope that includes it
+	require.NoError(t, engine.InvalidateCache(ctx, model.PolicyScopeUser, withKey.UserID))
+	_, err = engine.LoadPolicies(ctx, withKey)
+	assert.Error(t, err, "falls through to the database, which this engine doesn't have")
+}
@@ -0,0 +1,280 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/db"
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/google/uuid"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrScopeNotFound is returned when a simulated request names an app or API
+// key that doesn't exist, a key that belongs to another app, or either in
+// another organisation
+var ErrScopeNotFound = errors.New("app or API key not found")
+
+// PolicyOutcome is what one policy did to a simulated request
+type PolicyOutcome struct {
+	Type          model.PolicyType `json:"type"`
+	Mode          model.PolicyMode `json:"mode"`
+	Phase         string           `json:"phase,omitempty"` // where the policy failed
+	Passed        bool             `json:"passed"`
+	Status        int              `json:"status,omitempty"` // the status the request would be refused with
+	Reason        string           `json:"reason,omitempty"`
+	ModelOverride string           `json:"model_override,omitempty"`
+	Priority      string           `json:"priority,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+	Metadata      map[string]any   `json:"metadata,omitempty"` // values recorded with usage, e.g. matched rules
+	BodyRewritten bool             `json:"body_rewritten,omitempty"`
+}
+
+// Simulation is how the gateway would handle a sample request
+type Simulation struct {
+	Scope           Scope
+	Allowed         bool
+	Status          int    // the status of the first enforced refusal
+	Reason          string // and its message
+	Model           string // the model requested
+	RoutedModel     string // the model the request would be sent to
+	Priority        string
+	EstimatedTokens int // after any transforms
+	BodyRewritten   bool
+	Tags            []string
+	Policies        []PolicyOutcome
+}
+
+// TokenEstimator estimates a request body's prompt tokens
+type TokenEstimator func(ctx context.Context, model string, body []byte) (int, error)
+
+// ResolveScope finds the scope in orgID a sample request runs in: that of
+// keyID (the key's prefix, as the gateway identifies it, or its ID) when
+// given, else that of appID alone. It also returns the key's metadata for CEL
+// policies.
+func (e *Engine) ResolveScope(ctx context.Context, orgID, appID, keyID string) (Scope, []byte, error) {
+	if e.db == nil {
+		return Scope{}, nil, fmt.Errorf("database not available for resolving scope")
+	}
+	if keyID != "" {
+		key, err := e.findKey(ctx, keyID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Scope{}, nil, ErrScopeNotFound
+		}
+		if err != nil {
+			return Scope{}, nil, fmt.Errorf("failed to load API key: %w", err)
+		}
+		if appID != "" && appID != key.AppID.String() || orgID != key.OrgID.String() {
+			return Scope{}, nil, ErrScopeNotFound
+		}
+		return Scope{
+			OrgID:  key.OrgID.String(),
+			AppID:  key.AppID.String(),
+			UserID: key.UserID.String(),
+			KeyID:  key.KeyPrefix,
+		}, key.Metadata, nil
+	}
+
+	appUUID, err := uuid.Parse(appID)
+	if err != nil {
+		return Scope{}, nil, fmt.Errorf("invalid app ID: %w", err)
+	}
+	app, err := e.db.GetApplication(ctx, appUUID)
+	if errors.Is(err, pgx.ErrNoRows) || err == nil && orgID != app.OrgID.String() {
+		return Scope{}, nil, ErrScopeNotFound
+	}
+	if err != nil {
+		return Scope{}, nil, fmt.Errorf("failed to load app: %w", err)
+	}
+	return Scope{OrgID: app.OrgID.String(), AppID: app.ID.String()}, nil, nil
+}
+
+// findKey looks an API key up by ID or prefix
+func (e *Engine) findKey(ctx context.Context, keyID string) (db.ApiKey, error) {
+	if id, err := uuid.Parse(keyID); err == nil {
+		return e.db.GetAPIKeyByID(ctx, id)
+	}
+	return e.db.GetAPIKeyByPrefix(ctx, keyID)
+}
+
+// sandbox returns a copy of the engine whose writes never leave it: limits
+// are checked against live counters but consume nothing, nothing queues, and
+// alerts and actions are only logged
+func (e *Engine) sandbox() *Engine {
+	memCache, _ := lru.New[string, *policyCacheEntry](1)
+	return &Engine{
+		db:          e.db,
+		cache:       kv.NewScratchStore(e.cache),
+		memoryCache: memCache,
+		cacheTTL:    e.cacheTTL,
+		alerts:      dryRunEffects{},
+		queue:       NewWaitQueue(),
+		simulation:  true,
+	}
+}
+
+// Simulate runs req through the policies attached to scope as the gateway
+// would, without sending it upstream or changing any counters. Unlike the
+// gateway it keeps going after a refusal so every policy is reported; later
+// policies see the request as the earlier ones left it. Post-checks need a
+// response and are not run.
+func (e *Engine) Simulate(ctx context.Context, scope Scope, req *PreRequestContext, estimate TokenEstimator) (*Simulation, error) {
+	sim := e.sandbox()
+	defer sim.cache.Close(context.WithoutCancel(ctx))
+
+	loaded, err := sim.LoadPolicies(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Simulation{Scope: scope, Allowed: true, Model: req.Model}
+	outcomes := make([]PolicyOutcome, len(loaded))
+	for i, policy := range loaded {
+		outcomes[i] = PolicyOutcome{Type: policy.Type(), Mode: model.PolicyModeEnforce, Passed: true}
+		if IsDryRun(policy) {
+			outcomes[i].Mode = model.PolicyModeDryRun
+		}
+	}
+	refuse := func(i int, phase string, err error) {
+		if !outcomes[i].Passed {
+			return // report where it first failed
+		}
+		outcomes[i].Passed = false
+		outcomes[i].Phase = phase
+		outcomes[i].Status, outcomes[i].Reason = refusal(err)
+		if result.Allowed && outcomes[i].Mode != model.PolicyModeDryRun {
+			result.Allowed = false
+			result.Status, result.Reason = outcomes[i].Status, outcomes[i].Reason
+		}
+	}
+
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	for i, policy := range loaded {
+		transformer, ok := policy.(Transformer)
+		if !ok {
+			continue
+		}
+		before := snapshotRequest(req)
+		err := transformer.Transform(ctx, req)
+		outcomes[i].record(before, req)
+		if err != nil {
+			refuse(i, PhaseTransform, err)
+		}
+	}
+	if req.BodyRewritten && estimate != nil {
+		if n, err := estimate(ctx, req.Model, req.Body); err == nil {
+			req.EstimatedTokens = n
+		}
+	}
+
+	for i, policy := range loaded {
+		before := snapshotRequest(req)
+		err := policy.PreCheck(ctx, req)
+		outcomes[i].record(before, req)
+		if err != nil {
+			refuse(i, PhasePreCheck, err)
+		}
+	}
+	ReleaseLeases(ctx, req.Leases)
+
+	result.RoutedModel = req.Model
+	if req.ModelOverride != "" {
+		result.RoutedModel = req.ModelOverride
+	}
+	result.Priority = DefaultPriority().Tier
+	if req.Priority != nil {
+		result.Priority = req.Priority.Tier
+	}
+	result.EstimatedTokens = req.EstimatedTokens
+	result.BodyRewritten = req.BodyRewritten
+	result.Tags = req.Tags
+	result.Policies = outcomes
+	return result, nil
+}
+
+// IsDryRun reports whether p was loaded in dry-run mode
+func IsDryRun(p Policy) bool {
+	switch p.(type) {
+	case *dryRunPolicy, *dryRunTransformer, *dryRunGuard, *dryRunTransformGuard:
+		return true
+	}
+	return false
+}
+
+// requestSnapshot is the part of a request a policy's effects are read from
+type requestSnapshot struct {
+	modelOverride string
+	priority      *Priority
+	tags          int
+	metadata      map[string]any
+	bodyRewritten bool
+	body          []byte
+	violations    int
+}
+
+func snapshotRequest(req *PreRequestContext) requestSnapshot {
+	return requestSnapshot{
+		modelOverride: req.ModelOverride,
+		priority:      req.Priority,
+		tags:          len(req.Tags),
+		metadata:      maps.Clone(req.Metadata),
+		bodyRewritten: req.BodyRewritten,
+		body:          req.Body,
+		violations:    len(dryRunViolations(req.Metadata)),
+	}
+}
+
+// record notes what a policy changed between before and req. A dry-run
+// policy's violation shows up as a new entry in the request's metadata.
+func (o *PolicyOutcome) record(before requestSnapshot, req *PreRequestContext) {
+	if violations := dryRunViolations(req.Metadata); o.Passed && len(violations) > before.violations {
+		violation := violations[before.violations]
+		o.Passed = false
+		o.Phase, o.Status, o.Reason = violation.Phase, violation.Status, violation.Reason
+	}
+	if req.ModelOverride != before.modelOverride {
+		o.ModelOverride = req.ModelOverride
+	}
+	if req.Priority != nil && req.Priority != before.priority {
+		o.Priority = req.Priority.Tier
+	}
+	if len(req.Tags) > before.tags {
+		o.Tags = append(o.Tags, req.Tags[before.tags:]...)
+	}
+	for key, value := range req.Metadata {
+		if old, ok := before.metadata[key]; key == MetadataDryRun || ok && reflect.DeepEqual(old, value) {
+			continue
+		}
+		if o.Metadata == nil {
+			o.Metadata = map[string]any{}
+		}
+		o.Metadata[key] = value
+	}
+	if req.BodyRewritten && (!before.bodyRewritten || !slices.Equal(req.Body, before.body)) {
+		o.BodyRewritten = true
+	}
+}
+
+// dryRunViolations lists the violations recorded in a request's metadata
+func dryRunViolations(metadata map[string]any) []DryRunViolation {
+	violations, _ := metadata[MetadataDryRun].([]DryRunViolation)
+	return violations
+}
+
+// refusal is the status and message a request refused by err gets
+func refusal(err error) (int, string) {
+	var policyErr *PolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Status, policyErr.Message
+	}
+	return http.StatusTooManyRequests, err.Error()
+}
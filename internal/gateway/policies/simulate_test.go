@@ -0,0 +1,137 @@
+package policies_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Simulate(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, cached ...policies.CachedPolicy) (*policies.Engine, kv.KvStore, policies.Scope) {
+		t.Helper()
+		cache := kv.NewMemoryStore()
+		scope := policies.Scope{OrgID: uuid.NewString(), AppID: uuid.NewString()}
+		require.NoError(t, policies.SetCachedPoliciesRaw(ctx, cache, scope, cached))
+		return policies.NewEngine(nil, cache), cache, scope
+	}
+	sample := func(scope policies.Scope, modelName string) *policies.PreRequestContext {
+		body := []byte(`{"model":"` + modelName + `","max_tokens":4096}`)
+		return &policies.PreRequestContext{
+			OrgID:            scope.OrgID,
+			AppID:            scope.AppID,
+			Model:            modelName,
+			EstimatedTokens:  10,
+			RequestSizeBytes: len(body),
+			Body:             body,
+		}
+	}
+
+	t.Run("LimitsNotConsumed", func(t *testing.T) {
+		engine, _, scope := setup(t, policies.CachedPolicy{Type: model.PolicyTypeRateLimit, Config: []byte(`{"requests_per_minute":1}`)})
+
+		for range 3 {
+			sim, err := engine.Simulate(ctx, scope, sample(scope, "gpt-4"), nil)
+			require.NoError(t, err)
+			assert.True(t, sim.Allowed)
+			require.Len(t, sim.Policies, 1)
+			assert.True(t, sim.Policies[0].Passed)
+		}
+
+		// The live limit still has its one request left
+		loaded, err := engine.LoadPolicies(ctx, scope)
+		require.NoError(t, err)
+		require.NoError(t, engine.CheckPreRequest(ctx, loaded, sample(scope, "gpt-4")))
+		assert.Error(t, engine.CheckPreRequest(ctx, loaded, sample(scope, "gpt-4")))
+
+		// Once it is used up, the simulation sees it too
+		sim, err := engine.Simulate(ctx, scope, sample(scope, "gpt-4"), nil)
+		require.NoError(t, err)
+		assert.False(t, sim.Allowed)
+		assert.Equal(t, http.StatusTooManyRequests, sim.Status)
+		assert.Contains(t, sim.Reason, "rate limit exceeded")
+	})
+
+	t.Run("EveryPolicyReported", func(t *testing.T) {
+		engine, _, scope := setup(t,
+			policies.CachedPolicy{Type: model.PolicyTypeModelAllowlist, Config: []byte(`{"allowed_model_ids":["gpt-4o"]}`)},
+			policies.CachedPolicy{Type: model.PolicyTypeModelAllowlist, Config: []byte(`{"allowed_model_ids":["gpt-4o-mini"]}`), Mode: model.PolicyModeDryRun},
+			policies.CachedPolicy{Type: model.PolicyTypeRequestSize, Config: []byte(`{"max_request_bytes":1}`)},
+		)
+
+		sim, err := engine.Simulate(ctx, scope, sample(scope, "gpt-4"), nil)
+		require.NoError(t, err)
+		assert.False(t, sim.Allowed)
+		assert.Equal(t, http.StatusForbidden, sim.Status, "the first enforced refusal decides the status")
+		assert.Equal(t, "model not allowed: gpt-4", sim.Reason)
+
+		require.Len(t, sim.Policies, 3)
+		assert.False(t, sim.Policies[0].Passed)
+		assert.Equal(t, model.PolicyModeEnforce, sim.Policies[0].Mode)
+		assert.Equal(t, policies.PhasePreCheck, sim.Policies[0].Phase)
+
+		assert.False(t, sim.Policies[1].Passed, "dry-run violations are reported")
+		assert.Equal(t, model.PolicyModeDryRun, sim.Policies[1].Mode)
+		assert.Equal(t, http.StatusForbidden, sim.Policies[1].Status)
+		assert.Empty(t, sim.Policies[1].Metadata)
+
+		assert.False(t, sim.Policies[2].Passed, "later policies still run")
+		assert.Equal(t, model.PolicyTypeRequestSize, sim.Policies[2].Type)
+	})
+
+	t.Run("DryRunDoesNotRefuse", func(t *testing.T) {
+		engine, _, scope := setup(t, policies.CachedPolicy{Type: model.PolicyTypeModelAllowlist, Config: []byte(`{"allowed_model_ids":["gpt-4o"]}`), Mode: model.PolicyModeDryRun})
+
+		sim, err := engine.Simulate(ctx, scope, sample(scope, "gpt-4"), nil)
+		require.NoError(t, err)
+		assert.True(t, sim.Allowed)
+		assert.False(t, sim.Policies[0].Passed)
+	})
+
+	t.Run("RoutingDecision", func(t *testing.T) {
+		engine, cache, scope := setup(t,
+			policies.CachedPolicy{Type: model.PolicyTypeTransform, Config: []byte(`{"max_tokens":1000}`)},
+			policies.CachedPolicy{Type: model.PolicyTypeBudget, Config: []byte(`{"limit":5,"action":"downgrade","downgrade_model":"gpt-4o-mini"}`)},
+			policies.CachedPolicy{Type: model.PolicyTypePriority, Config: []byte(`{"tier":"high"}`)},
+		)
+		require.NoError(t, policies.RecordSpend(ctx, cache, policies.SpendEntry{
+			OrgID: scope.OrgID, AppID: scope.AppID, Currency: "USD", CostMicros: 6_000_000, At: time.Now(),
+		}))
+
+		estimate := func(ctx context.Context, modelName string, body []byte) (int, error) { return 42, nil }
+		sim, err := engine.Simulate(ctx, scope, sample(scope, "gpt-4o"), estimate)
+		require.NoError(t, err)
+		assert.True(t, sim.Allowed)
+		assert.Equal(t, "gpt-4o", sim.Model)
+		assert.Equal(t, "gpt-4o-mini", sim.RoutedModel)
+		assert.Equal(t, model.PriorityHigh, sim.Priority)
+		assert.True(t, sim.BodyRewritten)
+		assert.Equal(t, 42, sim.EstimatedTokens, "re-estimated after the transform")
+
+		require.Len(t, sim.Policies, 3)
+		assert.True(t, sim.Policies[0].BodyRewritten)
+		assert.Equal(t, "gpt-4o-mini", sim.Policies[1].ModelOverride)
+		assert.Equal(t, model.PriorityHigh, sim.Policies[2].Priority)
+		assert.False(t, sim.Policies[1].BodyRewritten)
+	})
+
+	t.Run("DefaultRouting", func(t *testing.T) {
+		engine, _, scope := setup(t)
+
+		sim, err := engine.Simulate(ctx, scope, sample(scope, "gpt-4"), nil)
+		require.NoError(t, err)
+		assert.True(t, sim.Allowed)
+		assert.Equal(t, "gpt-4", sim.RoutedModel)
+		assert.Equal(t, model.PriorityStandard, sim.Priority)
+		assert.Empty(t, sim.Policies)
+	})
+}
@@ -0,0 +1,86 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+// The spend ledger keeps running totals of cost (in micros) per app, org and
+// key for the current day and month. It is written once per request by usage
+// recording so any number of budget policies can read it without double
+// counting.
+
+// SpendEntry is one request's cost, attributed to every scope it counts against
+type SpendEntry struct {
+	OrgID      string
+	AppID      string
+	APIKeyID   string
+	Currency   string
+	CostMicros int64
+	At         time.Time
+}
+
+// spendPeriods are the periods the ledger is kept for
+var spendPeriods = []string{model.BudgetPeriodDaily, model.BudgetPeriodMonthly}
+
+// periodBucket names the period containing t and returns when it ends (UTC)
+func periodBucket(period string, t time.Time) (string, time.Time) {
+	t = t.UTC()
+	if period == model.BudgetPeriodDaily {
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return start.Format("20060102"), start.AddDate(0, 0, 1)
+	}
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start.Format("200601"), start.AddDate(0, 1, 0)
+}
+
+// SpendKey is the ledger counter for a scope, currency and period bucket
+func SpendKey(scope, scopeID, currency, bucket string) string {
+	return kv.KSSpend.Key(scope, scopeID, currency, bucket)
+}
+
+// RecordSpend adds a request's cost to the ledger for its app, org and key
+func RecordSpend(ctx context.Context, cache kv.KvStore, e SpendEntry) error {
+	if e.CostMicros <= 0 {
+		return nil
+	}
+	scopes := map[string]string{
+		model.BudgetScopeApp: e.AppID,
+		model.BudgetScopeOrg: e.OrgID,
+		model.BudgetScopeKey: e.APIKeyID,
+	}
+	for _, period := range spendPeriods {
+		bucket, end := periodBucket(period, e.At)
+		// Keep counters a day past the period so late readers see the total
+		ttl := end.Sub(e.At) + 24*time.Hour
+		for scope, id := range scopes {
+			if id == "" {
+				continue
+			}
+			key := SpendKey(scope, id, e.Currency, bucket)
+			total, err := cache.IncrBy(ctx, key, e.CostMicros)
+			if err != nil {
+				return fmt.Errorf("record spend: %w", err)
+			}
+			if total == e.CostMicros {
+				_, _ = cache.Expire(ctx, key, ttl)
+			}
+		}
+	}
+	return nil
+}
+
+// GetSpend returns the ledger total in micros for a scope in the period containing at
+func GetSpend(ctx context.Context, cache kv.KvStore, scope, scopeID, currency, period string, at time.Time) (int64, error) {
+	bucket, _ := periodBucket(period, at)
+	raw, err := cache.Get(ctx, SpendKey(scope, scopeID, currency, bucket))
+	if err != nil || raw == "" {
+		return 0, err
+	}
+	return strconv.ParseInt(raw, 10, 64)
+}
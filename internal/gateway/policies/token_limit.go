@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/logger"
 	"github.com/WebDeveloperBen/ai-gateway/internal/model"
@@ -43,7 +44,7 @@ func (p *TokenLimitPolicy) PreCheck(ctx context.Context, req *PreRequestContext)
 			Str("app_id", req.AppID).
 			Str("model", req.Model).
 			Msg("Prompt token limit exceeded")
-		return fmt.Errorf("token limit exceeded")
+		return rejectf(http.StatusBadRequest, "token limit exceeded")
 	}
 
 	if p.config.MaxTotalTokens > 0 {
@@ -57,7 +58,7 @@ func (p *TokenLimitPolicy) PreCheck(ctx context.Context, req *PreRequestContext)
 				Str("app_id", req.AppID).
 				Str("model", req.Model).
 				Msg("Total token limit exceeded")
-			return fmt.Errorf("token limit exceeded")
+			return rejectf(http.StatusBadRequest, "token limit exceeded")
 		}
 	}
 
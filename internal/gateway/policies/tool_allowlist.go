@@ -0,0 +1,154 @@
+package policies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+func init() {
+	Register(model.PolicyTypeToolAllowlist, func(config []byte, deps PolicyDependencies) (Policy, error) {
+		var cfg model.ToolAllowlistConfig
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid tool allowlist config: %w", err)
+		}
+		return NewToolAllowlistPolicy(cfg)
+	})
+}
+
+// ToolAllowlistPolicy restricts the tools a request may offer the model: which
+// names, how many, and which arguments their schemas may declare
+type ToolAllowlistPolicy struct {
+	config    model.ToolAllowlistConfig
+	forbidden []string
+}
+
+// NewToolAllowlistPolicy creates a new tool allowlist policy
+func NewToolAllowlistPolicy(config model.ToolAllowlistConfig) (*ToolAllowlistPolicy, error) {
+	if config.MaxTools != nil && *config.MaxTools < 0 {
+		return nil, fmt.Errorf("invalid tool allowlist config: max_tools cannot be negative")
+	}
+	p := &ToolAllowlistPolicy{config: config}
+	for _, pattern := range config.AllowedTools {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid tool allowlist config: pattern %q: %w", pattern, err)
+		}
+	}
+	// Argument names are matched case-insensitively
+	for _, pattern := range config.ForbiddenParameters {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid tool allowlist config: pattern %q: %w", pattern, err)
+		}
+		p.forbidden = append(p.forbidden, strings.ToLower(pattern))
+	}
+	return p, nil
+}
+
+// Type returns the policy type
+func (p *ToolAllowlistPolicy) Type() model.PolicyType {
+	return model.PolicyTypeToolAllowlist
+}
+
+// PreCheck checks the request's tools against the limit, the allowlist and the
+// forbidden arguments
+func (p *ToolAllowlistPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error {
+	if limit := p.config.MaxTools; limit != nil && len(req.Tools) > *limit {
+		if *limit == 0 {
+			return rejectf(http.StatusForbidden, "tools are not allowed")
+		}
+		return rejectf(http.StatusBadRequest, "request offers %d tools, the limit is %d", len(req.Tools), *limit)
+	}
+
+	for _, tool := range req.Tools {
+		if !p.allowed(tool.Name) {
+			return rejectf(http.StatusForbidden, "tool not allowed: %s", tool.Name)
+		}
+		if len(p.forbidden) == 0 || len(tool.Parameters) == 0 {
+			continue
+		}
+		var schema any
+		if err := json.Unmarshal(tool.Parameters, &schema); err != nil {
+			return rejectf(http.StatusBadRequest, "tool %s has an invalid parameters schema", tool.Name)
+		}
+		if name := p.forbiddenParameter(schema); name != "" {
+			return rejectf(http.StatusForbidden, "tool %s declares forbidden parameter %s", tool.Name, name)
+		}
+	}
+
+	if req.ForcedTool != "" && !p.allowed(req.ForcedTool) {
+		return rejectf(http.StatusForbidden, "tool not allowed: %s", req.ForcedTool)
+	}
+	return nil
+}
+
+// PostCheck is a no-op for tool allowlist
+func (p *ToolAllowlistPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {}
+
+// allowed reports whether a tool name matches the allowlist
+func (p *ToolAllowlistPolicy) allowed(name string) bool {
+	if len(p.config.AllowedTools) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(p.config.AllowedTools, func(pattern string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	})
+}
+
+// forbiddenParameter returns the first argument the schema declares that
+// matches a forbidden pattern
+func (p *ToolAllowlistPolicy) forbiddenParameter(schema any) string {
+	var found string
+	schemaProperties(schema, func(name string) {
+		if found != "" {
+			return
+		}
+		lower := strings.ToLower(name)
+		for _, pattern := range p.forbidden {
+			if ok, _ := path.Match(pattern, lower); ok {
+				found = name
+				return
+			}
+		}
+	})
+	return found
+}
+
+// schemaProperties calls visit with every property name a JSON schema
+// declares, including those of nested objects, array items and combinators
+func schemaProperties(schema any, visit func(name string)) {
+	node, ok := schema.(map[string]any)
+	if !ok {
+		return
+	}
+	if props, ok := node["properties"].(map[string]any); ok {
+		for _, name := range slices.Sorted(maps.Keys(props)) {
+			visit(name)
+			schemaProperties(props[name], visit)
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties", "not"} {
+		schemaProperties(node[key], visit)
+	}
+	for _, key := range []string{"anyOf", "oneOf", "allOf", "prefixItems"} {
+		if list, ok := node[key].([]any); ok {
+			for _, sub := range list {
+				schemaProperties(sub, visit)
+			}
+		}
+	}
+	for _, key := range []string{"$defs", "definitions"} {
+		if defs, ok := node[key].(map[string]any); ok {
+			for _, name := range slices.Sorted(maps.Keys(defs)) {
+				schemaProperties(defs[name], visit)
+			}
+		}
+	}
+}
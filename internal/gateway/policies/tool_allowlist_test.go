@@ -0,0 +1,103 @@
+package policies_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolAllowlistPolicy(t *testing.T) {
+	ctx := context.Background()
+	engine := policies.NewEngine(nil, kv.NewMemoryStore())
+
+	newPolicy := func(t *testing.T, config string) policies.Policy {
+		t.Helper()
+		policy, err := engine.NewPolicy(model.PolicyTypeToolAllowlist, []byte(config))
+		require.NoError(t, err)
+		return policy
+	}
+	status := func(t *testing.T, err error) int {
+		t.Helper()
+		var policyErr *policies.PolicyError
+		require.True(t, errors.As(err, &policyErr), "expected a policy error, got %v", err)
+		return policyErr.Status
+	}
+	tool := func(name, parameters string) policies.Tool {
+		return policies.Tool{Name: name, Parameters: json.RawMessage(parameters)}
+	}
+
+	t.Run("AllowlistWithWildcards", func(t *testing.T) {
+		policy := newPolicy(t, `{"allowed_tools":["search_*","get_weather"]}`)
+
+		req := &policies.PreRequestContext{Tools: []policies.Tool{tool("search_docs", ""), tool("get_weather", "")}}
+		assert.NoError(t, policy.PreCheck(ctx, req))
+
+		req = &policies.PreRequestContext{Tools: []policies.Tool{tool("search_docs", ""), tool("send_email", "")}}
+		err := policy.PreCheck(ctx, req)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, status(t, err))
+		assert.Contains(t, err.Error(), "send_email")
+
+		// Requests without tools are unaffected
+		assert.NoError(t, policy.PreCheck(ctx, &policies.PreRequestContext{}))
+	})
+
+	t.Run("ForcedToolMustBeAllowed", func(t *testing.T) {
+		policy := newPolicy(t, `{"allowed_tools":["get_weather"]}`)
+		req := &policies.PreRequestContext{ToolChoice: "required", ForcedTool: "delete_account"}
+		err := policy.PreCheck(ctx, req)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "delete_account")
+	})
+
+	t.Run("MaxTools", func(t *testing.T) {
+		policy := newPolicy(t, `{"max_tools":1}`)
+		req := &policies.PreRequestContext{Tools: []policies.Tool{tool("a", ""), tool("b", "")}}
+		err := policy.PreCheck(ctx, req)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, status(t, err))
+
+		policy = newPolicy(t, `{"max_tools":0}`)
+		err = policy.PreCheck(ctx, &policies.PreRequestContext{Tools: []policies.Tool{tool("a", "")}})
+		require.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, status(t, err))
+		assert.NoError(t, policy.PreCheck(ctx, &policies.PreRequestContext{}))
+	})
+
+	t.Run("ForbiddenParametersFoundInNestedSchemas", func(t *testing.T) {
+		policy := newPolicy(t, `{"forbidden_parameters":["*command","sql"]}`)
+
+		safe := tool("lookup", `{"type":"object","properties":{"query":{"type":"string"}}}`)
+		assert.NoError(t, policy.PreCheck(ctx, &policies.PreRequestContext{Tools: []policies.Tool{safe}}))
+
+		nested := tool("run", `{"type":"object","properties":{"steps":{"type":"array","items":{"anyOf":[{"type":"object","properties":{"ShellCommand":{"type":"string"}}}]}}}}`)
+		err := policy.PreCheck(ctx, &policies.PreRequestContext{Tools: []policies.Tool{safe, nested}})
+		require.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, status(t, err))
+		assert.Contains(t, err.Error(), "ShellCommand")
+
+		broken := tool("broken", `{"type":`)
+		err = policy.PreCheck(ctx, &policies.PreRequestContext{Tools: []policies.Tool{broken}})
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, status(t, err))
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, config := range []string{
+			`{"max_tools":-1}`,
+			`{"allowed_tools":["search_["]}`,
+			`{"forbidden_parameters":["["]}`,
+		} {
+			_, err := engine.NewPolicy(model.PolicyTypeToolAllowlist, []byte(config))
+			assert.Error(t, err, config)
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package policies
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+func init() {
+	Register(model.PolicyTypeTransform, func(config []byte, deps PolicyDependencies) (Policy, error) {
+		var cfg model.TransformConfig
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid transform config: %w", err)
+		}
+		return NewTransformPolicy(cfg)
+	})
+}
+
+// reservedBodyFields are parsed by the gateway itself (routing, streaming and
+// other policies), so transforms may not set or remove them
+var reservedBodyFields = map[string]bool{
+	"model": true, "messages": true, "prompt": true, "stream": true, "stream_options": true,
+	"tools": true, "functions": true, "tool_choice": true, "function_call": true,
+}
+
+// reservedHeaders carry upstream credentials or framing the gateway manages
+var reservedHeaders = map[string]bool{
+	"Authorization": true, "Api-Key": true, "Host": true, "Content-Length": true,
+	"Content-Type": true, "Content-Encoding": true, "Transfer-Encoding": true,
+}
+
+// TransformPolicy rewrites requests before they are sent upstream: clamping
+// max_tokens, forcing or stripping parameters, injecting a system prompt and
+// setting headers
+type TransformPolicy struct {
+	config model.TransformConfig
+}
+
+// NewTransformPolicy creates a new transform policy
+func NewTransformPolicy(config model.TransformConfig) (*TransformPolicy, error) {
+	if config.MaxTokens < 0 {
+		return nil, fmt.Errorf("invalid transform config: max_tokens must not be negative")
+	}
+	switch config.MaxTokensField {
+	case "":
+		config.MaxTokensField = "max_completion_tokens"
+	case "max_completion_tokens", "max_tokens":
+	default:
+		return nil, fmt.Errorf("invalid transform config: max_tokens_field must be max_completion_tokens or max_tokens")
+	}
+	for name := range config.Set {
+		if reservedBodyFields[name] {
+			return nil, fmt.Errorf("invalid transform config: %q cannot be set", name)
+		}
+	}
+	for _, name := range config.Remove {
+		if reservedBodyFields[name] {
+			return nil, fmt.Errorf("invalid transform config: %q cannot be removed", name)
+		}
+	}
+	for name := range config.Headers {
+		if reservedHeaders[http.CanonicalHeaderKey(name)] {
+			return nil, fmt.Errorf("invalid transform config: header %q cannot be set", name)
+		}
+	}
+	return &TransformPolicy{config: config}, nil
+}
+
+// Type returns the policy type
+func (p *TransformPolicy) Type() model.PolicyType {
+	return model.PolicyTypeTransform
+}
+
+// PreCheck does nothing; the work happens in Transform
+func (p *TransformPolicy) PreCheck(ctx context.Context, req *PreRequestContext) error {
+	return nil
+}
+
+// PostCheck does nothing for transform policies
+func (p *TransformPolicy) PostCheck(ctx context.Context, req *PostRequestContext) {}
+
+// Transform applies the configured rewrites. Bodies that aren't JSON objects
+// are forwarded unchanged.
+func (p *TransformPolicy) Transform(ctx context.Context, req *PreRequestContext) error {
+	if req.Header != nil {
+		for name, value := range p.config.Headers {
+			req.Header.Set(name, value)
+		}
+	}
+
+	doc, ok := decodeJSONBody(req.Body)
+	if !ok {
+		return nil
+	}
+	changed := false
+	for _, name := range p.config.Remove {
+		if _, ok := doc[name]; ok {
+			delete(doc, name)
+			changed = true
+		}
+	}
+	for name, value := range p.config.Set {
+		if !sameJSON(doc[name], value) {
+			doc[name] = value
+			changed = true
+		}
+	}
+	if p.config.MaxTokens > 0 && p.clampMaxTokens(doc) {
+		changed = true
+	}
+	if p.config.SystemPrompt != "" {
+		if messages, ok := doc["messages"].([]any); ok {
+			system := map[string]any{"role": "system", "content": p.config.SystemPrompt}
+			doc["messages"] = append([]any{system}, messages...)
+			req.Messages = append([]Message{{Role: "system", Content: p.config.SystemPrompt}}, req.Messages...)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode transformed request: %w", err)
+	}
+	req.SetBody(body)
+	return nil
+}
+
+// clampMaxTokens caps whichever completion limit the caller sent. A request
+// without one gets a limit too, so leaving the field out doesn't escape the
+// cap: chat requests get MaxTokensField (max_completion_tokens by default,
+// which reasoning models require) and completions requests max_tokens. Other
+// endpoints, such as embeddings, take no limit. It reports whether doc changed.
+func (p *TransformPolicy) clampMaxTokens(doc map[string]any) bool {
+	limit := p.config.MaxTokens
+	found, changed := false, false
+	for _, field := range []string{"max_completion_tokens", "max_tokens"} {
+		value, ok := doc[field]
+		if !ok {
+			continue
+		}
+		found = true
+		if n, ok := value.(json.Number); ok {
+			if v, err := n.Int64(); err == nil && v <= int64(limit) {
+				continue
+			}
+		}
+		doc[field] = limit
+		changed = true
+	}
+	if found {
+		return changed
+	}
+	if _, ok := doc["messages"]; ok {
+		doc[p.config.MaxTokensField] = limit
+		return true
+	}
+	if _, ok := doc["prompt"]; ok {
+		doc["max_tokens"] = limit
+		return true
+	}
+	return false
+}
+
+// sameJSON reports whether two decoded JSON values encode to the same JSON.
+func sameJSON(a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	return err == nil && bytes.Equal(ja, jb)
+}
+
+// decodeJSONBody decodes a JSON object request body, keeping numbers exact so
+// re-encoding doesn't change them (e.g. large seeds)
+func decodeJSONBody(body []byte) (map[string]any, bool) {
+	if len(body) == 0 {
+		return nil, false
+	}
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil || doc == nil {
+		return nil, false
+	}
+	return doc, true
+}
@@ -0,0 +1,114 @@
+package policies_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformPolicy(t *testing.T) {
+	ctx := context.Background()
+	engine := policies.NewEngine(nil, kv.NewMemoryStore())
+
+	transform := func(t *testing.T, config string, req *policies.PreRequestContext) {
+		t.Helper()
+		policy, err := engine.NewPolicy(model.PolicyTypeTransform, []byte(config))
+		require.NoError(t, err)
+		transformer, ok := policy.(policies.Transformer)
+		require.True(t, ok)
+		require.NoError(t, policy.PreCheck(ctx, req))
+		require.NoError(t, transformer.Transform(ctx, req))
+	}
+
+	t.Run("ClampsMaxTokens", func(t *testing.T) {
+		req := &policies.PreRequestContext{Body: []byte(`{"model":"gpt-4","max_tokens":4096}`)}
+		transform(t, `{"max_tokens":1000}`, req)
+		assert.True(t, req.BodyRewritten)
+		assert.JSONEq(t, `{"model":"gpt-4","max_tokens":1000}`, string(req.Body))
+
+		req = &policies.PreRequestContext{Body: []byte(`{"model":"o1","max_completion_tokens":500}`)}
+		transform(t, `{"max_tokens":1000}`, req)
+		assert.JSONEq(t, `{"model":"o1","max_completion_tokens":500}`, string(req.Body))
+
+		req = &policies.PreRequestContext{Body: []byte(`{"model":"o1","max_completion_tokens":4096}`)}
+		transform(t, `{"max_tokens":1000}`, req)
+		assert.JSONEq(t, `{"model":"o1","max_completion_tokens":1000}`, string(req.Body))
+
+		// No limit sent: chat requests get max_completion_tokens unless the config names another field
+		req = &policies.PreRequestContext{Body: []byte(`{"model":"o1","messages":[]}`)}
+		transform(t, `{"max_tokens":1000}`, req)
+		assert.JSONEq(t, `{"model":"o1","messages":[],"max_completion_tokens":1000}`, string(req.Body))
+
+		req = &policies.PreRequestContext{Body: []byte(`{"model":"gpt-4","messages":[]}`)}
+		transform(t, `{"max_tokens":1000,"max_tokens_field":"max_tokens"}`, req)
+		assert.JSONEq(t, `{"model":"gpt-4","messages":[],"max_tokens":1000}`, string(req.Body))
+
+		req = &policies.PreRequestContext{Body: []byte(`{"model":"gpt-35-turbo-instruct","prompt":"Hi"}`)}
+		transform(t, `{"max_tokens":1000}`, req)
+		assert.JSONEq(t, `{"model":"gpt-35-turbo-instruct","prompt":"Hi","max_tokens":1000}`, string(req.Body))
+
+		// Embeddings take no limit
+		req = &policies.PreRequestContext{Body: []byte(`{"model":"text-embedding-3-small","input":"Hi"}`)}
+		transform(t, `{"max_tokens":1000}`, req)
+		assert.False(t, req.BodyRewritten)
+	})
+
+	t.Run("UnchangedBodyNotRewritten", func(t *testing.T) {
+		body := `{"model":"gpt-4", "temperature":0.2, "max_tokens":500}`
+		req := &policies.PreRequestContext{Header: http.Header{}, Body: []byte(body)}
+		transform(t, `{"max_tokens":1000,"set":{"temperature":0.2},"remove":["logit_bias"],"headers":{"x-tier":"gold"}}`, req)
+		assert.False(t, req.BodyRewritten)
+		assert.Equal(t, body, string(req.Body))
+	})
+
+	t.Run("SetsAndRemovesParameters", func(t *testing.T) {
+		req := &policies.PreRequestContext{Body: []byte(`{"model":"gpt-4","temperature":1.5,"logit_bias":{"50256":-100},"seed":12345678901234567}`)}
+		transform(t, `{"set":{"temperature":0.2},"remove":["logit_bias"]}`, req)
+		assert.JSONEq(t, `{"model":"gpt-4","temperature":0.2,"seed":12345678901234567}`, string(req.Body))
+	})
+
+	t.Run("InjectsSystemPrompt", func(t *testing.T) {
+		req := &policies.PreRequestContext{
+			Messages: []policies.Message{{Role: "user", Content: "Hi"}},
+			Body:     []byte(`{"model":"gpt-4","messages":[{"role":"user","content":"Hi"}]}`),
+		}
+		transform(t, `{"system_prompt":"Answer in English."}`, req)
+		assert.JSONEq(t, `{"model":"gpt-4","messages":[{"role":"system","content":"Answer in English."},{"role":"user","content":"Hi"}]}`, string(req.Body))
+		require.Len(t, req.Messages, 2)
+		assert.Equal(t, policies.Message{Role: "system", Content: "Answer in English."}, req.Messages[0])
+	})
+
+	t.Run("SetsHeaders", func(t *testing.T) {
+		req := &policies.PreRequestContext{Header: http.Header{}}
+		req.Body = []byte(`{"model":"gpt-4"}`)
+		transform(t, `{"headers":{"x-ms-client-tier":"gold"}}`, req)
+		assert.Equal(t, "gold", req.Header.Get("X-Ms-Client-Tier"))
+		assert.False(t, req.BodyRewritten)
+	})
+
+	t.Run("NonJSONBodyUnchanged", func(t *testing.T) {
+		req := &policies.PreRequestContext{Body: []byte("not json")}
+		transform(t, `{"max_tokens":10}`, req)
+		assert.False(t, req.BodyRewritten)
+		assert.Equal(t, "not json", string(req.Body))
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, config := range []string{
+			`{"max_tokens":-1}`,
+			`{"max_tokens":1000,"max_tokens_field":"max_output_tokens"}`,
+			`{"set":{"model":"gpt-4o"}}`,
+			`{"remove":["stream"]}`,
+			`{"headers":{"authorization":"Bearer x"}}`,
+		} {
+			_, err := engine.NewPolicy(model.PolicyTypeTransform, []byte(config))
+			assert.Error(t, err, config)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package policies
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Bounds on how often the request at the head of a wait queue rechecks its
+// limits. Capacity can free up before the reported retry-after (e.g. when a
+// token reservation settles below its estimate), so it never sleeps longer
+// than the maximum.
+const (
+	minQueuePoll = 25 * time.Millisecond
+	maxQueuePoll = time.Second
+)
+
+// DefaultQueueSize is how many requests per app may wait for capacity when a
+// rate limit policy enables queueing without setting queue_size
+const DefaultQueueSize = 100
+
+// WaitQueue holds rate limited requests until capacity frees up instead of
+// rejecting them. Each app has its own bounded queue, so one app's backlog
+// can't take wait slots or capacity from another, and within an app requests
+// are retried in arrival order: only the request at the head of the queue
+// polls its limits.
+type WaitQueue struct {
+	mu     sync.Mutex
+	queues map[string]*appQueue
+}
+
+// appQueue is one app's waiting requests
+type appQueue struct {
+	waiting int           // requests in the queue, including the head
+	turn    chan struct{} // held by the request at the head of the queue
+}
+
+// NewWaitQueue creates an empty wait queue
+func NewWaitQueue() *WaitQueue {
+	return &WaitQueue{queues: make(map[string]*appQueue)}
+}
+
+// Wait queues the caller behind any requests already waiting for key and
+// retries attempt until it succeeds, timeout passes or ctx is cancelled.
+// attempt reports how long until it could next succeed. Wait returns false
+// without waiting when size requests are already queued.
+func (q *WaitQueue) Wait(ctx context.Context, key string, size int, timeout time.Duration, attempt func() (time.Duration, bool, error)) (bool, error) {
+	aq, ok := q.join(key, size)
+	if !ok {
+		return false, nil
+	}
+	defer q.leave(key, aq)
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	select {
+	case aq.turn <- struct{}{}:
+		defer func() { <-aq.turn }()
+	case <-deadline.C:
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+
+	// Requests ahead of us may have used the capacity we were told about, so
+	// check again as soon as we reach the head of the queue
+	for {
+		retryAfter, ok, err := attempt()
+		if err != nil || ok {
+			return ok, err
+		}
+
+		poll := time.NewTimer(min(max(retryAfter, minQueuePoll), maxQueuePoll))
+		select {
+		case <-poll.C:
+		case <-deadline.C:
+			poll.Stop()
+			return false, nil
+		case <-ctx.Done():
+			poll.Stop()
+			return false, ctx.Err()
+		}
+	}
+}
+
+// Waiting returns how many requests are queued for key
+func (q *WaitQueue) Waiting(key string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if aq, ok := q.queues[key]; ok {
+		return aq.waiting
+	}
+	return 0
+}
+
+// join adds a request to key's queue unless it already holds size requests
+func (q *WaitQueue) join(key string, size int) (*appQueue, bool) {
+	if size <= 0 {
+		return nil, false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	aq, ok := q.queues[key]
+	if !ok {
+		aq = &appQueue{turn: make(chan struct{}, 1)}
+		q.queues[key] = aq
+	}
+	if aq.waiting >= size {
+		return nil, false
+	}
+	aq.waiting++
+	return aq, true
+}
+
+// leave removes a request from key's queue, dropping the queue once empty
+func (q *WaitQueue) leave(key string, aq *appQueue) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	aq.waiting--
+	if aq.waiting == 0 {
+		delete(q.queues, key)
+	}
+}
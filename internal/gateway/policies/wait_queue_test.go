@@ -0,0 +1,164 @@
+package policies_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/drivers/kv"
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/policies"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitQueue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("RetriesUntilAdmitted", func(t *testing.T) {
+		q := policies.NewWaitQueue()
+		attempts := 0
+		ok, err := q.Wait(ctx, "app-1", 1, time.Second, func() (time.Duration, bool, error) {
+			attempts++
+			return time.Millisecond, attempts == 3, nil
+		})
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, 3, attempts)
+		assert.Zero(t, q.Waiting("app-1"))
+	})
+
+	t.Run("TimesOut", func(t *testing.T) {
+		q := policies.NewWaitQueue()
+		ok, err := q.Wait(ctx, "app-1", 1, 50*time.Millisecond, func() (time.Duration, bool, error) {
+			return time.Minute, false, nil
+		})
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("CallerCancels", func(t *testing.T) {
+		q := policies.NewWaitQueue()
+		cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		ok, err := q.Wait(cctx, "app-1", 1, time.Minute, func() (time.Duration, bool, error) {
+			return time.Minute, false, nil
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.False(t, ok)
+	})
+
+	t.Run("FullQueueRejectsWithoutWaiting", func(t *testing.T) {
+		q := policies.NewWaitQueue()
+		release := make(chan struct{})
+		started := make(chan struct{})
+		go func() {
+			_, _ = q.Wait(ctx, "app-1", 1, time.Minute, func() (time.Duration, bool, error) {
+				close(started)
+				<-release
+				return 0, true, nil
+			})
+		}()
+		<-started
+		defer close(release)
+
+		ok, err := q.Wait(ctx, "app-1", 1, time.Minute, func() (time.Duration, bool, error) {
+			t.Fatal("a full queue must not attempt")
+			return 0, false, nil
+		})
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		// Other apps have queues of their own
+		ok, err = q.Wait(ctx, "app-2", 1, time.Minute, func() (time.Duration, bool, error) {
+			return 0, true, nil
+		})
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("OneAttemptAtATimePerApp", func(t *testing.T) {
+		q := policies.NewWaitQueue()
+		var mu sync.Mutex
+		active, maxActive := 0, 0
+		attempt := func() (time.Duration, bool, error) {
+			mu.Lock()
+			active++
+			maxActive = max(maxActive, active)
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			active--
+			mu.Unlock()
+			return 0, true, nil
+		}
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok, err := q.Wait(ctx, "app-1", 5, time.Second, attempt)
+				assert.NoError(t, err)
+				assert.True(t, ok)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, maxActive)
+	})
+}
+
+func TestRateLimitPolicy_Queueing(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("WaitsForCapacity", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		engine := policies.NewEngine(nil, cache)
+		policy := policies.NewRateLimitPolicy(model.RateLimitConfig{TokensPerMinute: 1000, QueueTimeoutSeconds: 5}, cache)
+
+		first := &policies.PreRequestContext{AppID: "app-1", EstimatedTokens: 1000}
+		require.NoError(t, policy.PreCheck(ctx, first))
+
+		// The first response used far fewer tokens than reserved
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = engine.SettleReservations(ctx, first.Reservations, 200)
+		}()
+
+		second := &policies.PreRequestContext{AppID: "app-1", EstimatedTokens: 500}
+		require.NoError(t, policy.PreCheck(ctx, second))
+		require.Len(t, second.Reservations, 1)
+
+		count, _ := cache.Get(ctx, policies.RateLimitKey("app-1", "tokens"))
+		assert.Equal(t, "700", count)
+	})
+
+	t.Run("FullQueueRejects", func(t *testing.T) {
+		cache := kv.NewMemoryStore()
+		policy := policies.NewRateLimitPolicy(model.RateLimitConfig{RequestsPerMinute: 1, QueueTimeoutSeconds: 30, QueueSize: 1}, cache)
+		require.NoError(t, policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1"}))
+
+		// A second request takes the app's only wait slot until it gives up
+		waitCtx, cancel := context.WithCancel(ctx)
+		queued := make(chan error)
+		go func() { queued <- policy.PreCheck(waitCtx, &policies.PreRequestContext{AppID: "app-1"}) }()
+		time.Sleep(50 * time.Millisecond)
+
+		start := time.Now()
+		err := policy.PreCheck(ctx, &policies.PreRequestContext{AppID: "app-1"})
+		var perr *policies.PolicyError
+		require.ErrorAs(t, err, &perr)
+		assert.Equal(t, 429, perr.Status)
+		assert.Less(t, time.Since(start), time.Second, "a full queue rejects immediately")
+
+		cancel()
+		assert.Error(t, <-queued, "a caller that gives up is still rejected")
+	})
+
+	t.Run("InvalidQueueConfigRejected", func(t *testing.T) {
+		engine := policies.NewEngine(nil, kv.NewMemoryStore())
+		_, err := engine.NewPolicy(model.PolicyTypeRateLimit, []byte(`{"requests_per_minute":10,"queue_timeout_seconds":-1}`))
+		assert.Error(t, err)
+	})
+}
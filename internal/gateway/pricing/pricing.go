@@ -0,0 +1,93 @@
+// Package pricing costs gateway usage against the versioned model price list.
+package pricing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	pricingrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/pricing"
+	"github.com/google/uuid"
+)
+
+// DefaultCacheTTL bounds how long a resolved price is reused before the
+// price list is consulted again; new versions take effect within this window.
+const DefaultCacheTTL = time.Minute
+
+type cacheKey struct {
+	orgID          uuid.UUID
+	provider       string
+	modelName      string
+	deploymentType string
+}
+
+type cacheEntry struct {
+	price   *model.ModelPrice // nil caches "no price in force"
+	expires time.Time
+}
+
+// Calculator resolves the price in force for a request and costs its usage.
+// Lookups are cached per org/provider/model/deployment type so recording
+// usage does not add a query per request.
+type Calculator struct {
+	repo pricingrepo.Reader
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu    sync.Mutex
+	cache map[cacheKey]cacheEntry
+}
+
+// NewCalculator creates a calculator backed by the price repository
+func NewCalculator(repo pricingrepo.Reader, ttl time.Duration) *Calculator {
+	return &Calculator{
+		repo:  repo,
+		ttl:   ttl,
+		now:   time.Now,
+		cache: make(map[cacheKey]cacheEntry),
+	}
+}
+
+// Price returns the price in force now, or nil when the model has none
+func (c *Calculator) Price(ctx context.Context, orgID uuid.UUID, provider, modelName, deploymentType string) (*model.ModelPrice, error) {
+	if deploymentType == "" {
+		deploymentType = model.DeploymentTypeStandard
+	}
+	key := cacheKey{orgID: orgID, provider: provider, modelName: modelName, deploymentType: deploymentType}
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.price, nil
+	}
+
+	price, err := c.repo.GetEffective(ctx, orgID, provider, modelName, deploymentType, now)
+	if err != nil && !errors.Is(err, pricingrepo.ErrNotFound) {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.cache[key] = cacheEntry{price: price, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return price, nil
+}
+
+// Cost prices usage in micros of the returned currency. It returns a nil
+// cost when no price is in force so unpriced usage stays distinguishable
+// from free usage.
+func (c *Calculator) Cost(ctx context.Context, orgID uuid.UUID, provider, modelName, deploymentType string, usage model.BillableUsage) (*int64, string, error) {
+	price, err := c.Price(ctx, orgID, provider, modelName, deploymentType)
+	if err != nil || price == nil {
+		return nil, "", err
+	}
+	cost := price.CostMicros(usage)
+	currency := price.Currency
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	return &cost, currency, nil
+}
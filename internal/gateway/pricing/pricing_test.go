@@ -0,0 +1,84 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	pricingrepo "github.com/WebDeveloperBen/ai-gateway/internal/repository/pricing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubPrices struct {
+	pricingrepo.Reader
+	price *model.ModelPrice
+	err   error
+	calls int
+}
+
+func (s *stubPrices) GetEffective(ctx context.Context, orgID uuid.UUID, provider, modelName, deploymentType string, at time.Time) (*model.ModelPrice, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.price == nil {
+		return nil, pricingrepo.ErrNotFound
+	}
+	return s.price, nil
+}
+
+func TestCalculator_Cost(t *testing.T) {
+	orgID := uuid.New()
+	usage := model.BillableUsage{Tokens: model.TokenUsage{PromptTokens: 1000, CompletionTokens: 1000}}
+
+	t.Run("PricedModel", func(t *testing.T) {
+		repo := &stubPrices{price: &model.ModelPrice{Currency: "EUR", InputPerMillion: 1, OutputPerMillion: 2}}
+		calc := NewCalculator(repo, time.Minute)
+
+		cost, currency, err := calc.Cost(context.Background(), orgID, "openai", "gpt-4o", "", usage)
+		require.NoError(t, err)
+		require.NotNil(t, cost)
+		assert.Equal(t, int64(3000), *cost)
+		assert.Equal(t, "EUR", currency)
+	})
+
+	t.Run("UnpricedModel", func(t *testing.T) {
+		calc := NewCalculator(&stubPrices{}, time.Minute)
+
+		cost, currency, err := calc.Cost(context.Background(), orgID, "openai", "gpt-4o", "", usage)
+		require.NoError(t, err)
+		assert.Nil(t, cost)
+		assert.Empty(t, currency)
+	})
+
+	t.Run("RepositoryError", func(t *testing.T) {
+		calc := NewCalculator(&stubPrices{err: errors.New("db down")}, time.Minute)
+
+		cost, _, err := calc.Cost(context.Background(), orgID, "openai", "gpt-4o", "", usage)
+		assert.Error(t, err)
+		assert.Nil(t, cost)
+	})
+
+	t.Run("CachesLookups", func(t *testing.T) {
+		repo := &stubPrices{price: &model.ModelPrice{InputPerMillion: 1}}
+		calc := NewCalculator(repo, time.Minute)
+		now := time.Now()
+		calc.now = func() time.Time { return now }
+
+		for range 3 {
+			_, err := calc.Price(context.Background(), orgID, "openai", "gpt-4o", model.DeploymentTypeStandard)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 1, repo.calls)
+
+		// Misses are cached too, and everything expires after the TTL
+		now = now.Add(2 * time.Minute)
+		_, err := calc.Price(context.Background(), orgID, "openai", "gpt-4o", model.DeploymentTypeStandard)
+		require.NoError(t, err)
+		assert.Equal(t, 2, repo.calls)
+	})
+}
@@ -0,0 +1,278 @@
+// Package scheduling admits requests to upstream deployments by priority when
+// they are saturated, so important traffic is served first and low priority
+// traffic is queued or shed.
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrShed is returned when a request can't be admitted in time
+var ErrShed = errors.New("deployment saturated")
+
+// Request is one request asking to be sent to a deployment
+type Request struct {
+	Deployment string
+	App        string
+	Rank       int           // priority, 0 is most important; out of range ranks are clamped
+	MaxWait    time.Duration // how long to queue when saturated; 0 sheds at once
+}
+
+// Scheduler tracks, per deployment, the requests in flight and whether
+// upstream has recently throttled us. A deployment is saturated while it has
+// MaxInFlight requests in flight. For a cooldown after an upstream 429 the
+// less important ranks get a smaller share of MaxInFlight, so the top tier
+// keeps working while lower tiers back off. Without a MaxInFlight nothing is
+// held back, 429s included.
+//
+// Saturated requests wait in one queue per rank. Freed capacity goes to the
+// most important rank first, and within a rank apps take turns, so a burst
+// from one app can't starve the others in its tier. State is per process.
+type Scheduler struct {
+	mu          sync.Mutex
+	levels      int
+	maxInFlight int // per deployment; 0 = no cap, and 429s don't saturate
+	cooldown    time.Duration
+	deployments map[string]*deployment
+	now         func() time.Time
+}
+
+type deployment struct {
+	inFlight  int
+	coolUntil time.Time
+	queues    []rankQueue
+}
+
+// rankQueue holds a rank's waiting requests, grouped by app and served
+// round robin across apps
+type rankQueue struct {
+	order   []string // apps with waiters, in turn order
+	waiters map[string][]*waiter
+}
+
+type waiter struct {
+	app     string
+	rank    int
+	ready   chan struct{}
+	granted bool
+}
+
+// NewScheduler creates a scheduler for levels priority ranks. cooldown is how
+// long a deployment counts as saturated after an upstream 429 that didn't
+// say when to retry.
+func NewScheduler(levels, maxInFlight int, cooldown time.Duration) *Scheduler {
+	return &Scheduler{
+		levels:      max(levels, 1),
+		maxInFlight: maxInFlight,
+		cooldown:    cooldown,
+		deployments: make(map[string]*deployment),
+		now:         time.Now,
+	}
+}
+
+// Acquire admits req, waiting up to req.MaxWait if the deployment is
+// saturated. Call release once the response is done. It returns ErrShed if
+// req wasn't admitted in time, or the context's error if the caller gave up.
+func (s *Scheduler) Acquire(ctx context.Context, req Request) (release func(), err error) {
+	rank := min(max(req.Rank, 0), s.levels-1)
+
+	s.mu.Lock()
+	d := s.deployment(req.Deployment)
+	if !d.waitingAtOrAbove(rank) && s.canAdmit(d, rank) {
+		d.inFlight++
+		s.mu.Unlock()
+		return s.releaser(req.Deployment), nil
+	}
+	if req.MaxWait <= 0 {
+		s.cleanup(req.Deployment, d)
+		s.mu.Unlock()
+		return nil, ErrShed
+	}
+	w := &waiter{app: req.App, rank: rank, ready: make(chan struct{})}
+	d.queues[rank].push(w)
+	s.mu.Unlock()
+
+	timer := time.NewTimer(req.MaxWait)
+	defer timer.Stop()
+	select {
+	case <-w.ready:
+		return s.releaser(req.Deployment), nil
+	case <-timer.C:
+		err = ErrShed
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w.granted {
+		// Admitted just as we gave up; hand the slot on
+		d.inFlight--
+		s.dispatch(d)
+	} else {
+		d.queues[rank].remove(w)
+	}
+	s.cleanup(req.Deployment, d)
+	return nil, err
+}
+
+// Throttled records that a deployment answered 429, narrowing its lower
+// ranks' share for retryAfter (or the default cooldown when upstream didn't
+// say). It does nothing when there's no MaxInFlight to narrow.
+func (s *Scheduler) Throttled(name string, retryAfter time.Duration) {
+	if s.maxInFlight <= 0 {
+		return
+	}
+	if retryAfter <= 0 {
+		retryAfter = s.cooldown
+	}
+	if retryAfter <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deployment(name)
+	until := s.now().Add(retryAfter)
+	if until.After(d.coolUntil) {
+		d.coolUntil = until
+		// Wake the queues once the cooldown is over
+		time.AfterFunc(retryAfter, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if d, ok := s.deployments[name]; ok {
+				s.dispatch(d)
+				s.cleanup(name, d)
+			}
+		})
+	}
+}
+
+// InFlight reports how many requests are in flight to a deployment
+func (s *Scheduler) InFlight(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d, ok := s.deployments[name]; ok {
+		return d.inFlight
+	}
+	return 0
+}
+
+// releaser returns a func that frees a slot once, handing it to the next waiter
+func (s *Scheduler) releaser(name string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			d := s.deployments[name]
+			d.inFlight--
+			s.dispatch(d)
+			s.cleanup(name, d)
+		})
+	}
+}
+
+// deployment returns a deployment's state, creating it if needed (locked)
+func (s *Scheduler) deployment(name string) *deployment {
+	d, ok := s.deployments[name]
+	if !ok {
+		d = &deployment{queues: make([]rankQueue, s.levels)}
+		s.deployments[name] = d
+	}
+	return d
+}
+
+// cleanup forgets a deployment with nothing in flight, queued or cooling (locked)
+func (s *Scheduler) cleanup(name string, d *deployment) {
+	if d.inFlight == 0 && !d.waitingAtOrAbove(s.levels-1) && !s.now().Before(d.coolUntil) {
+		delete(s.deployments, name)
+	}
+}
+
+// canAdmit reports whether a request of rank may start now (locked)
+func (s *Scheduler) canAdmit(d *deployment, rank int) bool {
+	if s.maxInFlight <= 0 {
+		return true
+	}
+	limit := s.maxInFlight
+	if s.now().Before(d.coolUntil) {
+		// Cooling down: rank 0 keeps the full limit, the least important
+		// rank gets 1/levels of it
+		limit = s.maxInFlight * (s.levels - rank) / s.levels
+	}
+	return d.inFlight < limit
+}
+
+// dispatch admits waiters, most important rank first, while capacity allows (locked)
+func (s *Scheduler) dispatch(d *deployment) {
+	for rank := range d.queues {
+		for len(d.queues[rank].order) > 0 {
+			if !s.canAdmit(d, rank) {
+				return
+			}
+			w := d.queues[rank].pop()
+			w.granted = true
+			d.inFlight++
+			close(w.ready)
+		}
+	}
+}
+
+// waitingAtOrAbove reports whether anything of rank or more important is queued
+func (d *deployment) waitingAtOrAbove(rank int) bool {
+	for r := 0; r <= rank && r < len(d.queues); r++ {
+		if len(d.queues[r].order) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *rankQueue) push(w *waiter) {
+	if q.waiters == nil {
+		q.waiters = make(map[string][]*waiter)
+	}
+	if len(q.waiters[w.app]) == 0 {
+		q.order = append(q.order, w.app)
+	}
+	q.waiters[w.app] = append(q.waiters[w.app], w)
+}
+
+// pop takes the oldest waiter of the app whose turn it is
+func (q *rankQueue) pop() *waiter {
+	app := q.order[0]
+	q.order = q.order[1:]
+	w := q.waiters[app][0]
+	if rest := q.waiters[app][1:]; len(rest) > 0 {
+		q.waiters[app] = rest
+		q.order = append(q.order, app)
+	} else {
+		delete(q.waiters, app)
+	}
+	return w
+}
+
+func (q *rankQueue) remove(w *waiter) {
+	list := q.waiters[w.app]
+	for i, x := range list {
+		if x == w {
+			list = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) > 0 {
+		q.waiters[w.app] = list
+		return
+	}
+	delete(q.waiters, w.app)
+	for i, app := range q.order {
+		if app == w.app {
+			q.order = append(q.order[:i:i], q.order[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package scheduling_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/scheduling"
+)
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("UnsaturatedAdmitsImmediately", func(t *testing.T) {
+		s := scheduling.NewScheduler(4, 0, time.Second)
+		for range 10 {
+			_, err := s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "a", Rank: 3})
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 10, s.InFlight("d"))
+	})
+
+	t.Run("ShedsWhenFullAndNoWait", func(t *testing.T) {
+		s := scheduling.NewScheduler(4, 1, time.Second)
+		release, err := s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "a", Rank: 2})
+		require.NoError(t, err)
+
+		_, err = s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "b", Rank: 3})
+		assert.ErrorIs(t, err, scheduling.ErrShed)
+
+		release()
+		release() // releasing twice is harmless
+		assert.Zero(t, s.InFlight("d"))
+	})
+
+	t.Run("HigherRanksAdmittedFirst", func(t *testing.T) {
+		s := scheduling.NewScheduler(4, 1, time.Second)
+		release, err := s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "a", Rank: 0})
+		require.NoError(t, err)
+
+		var mu sync.Mutex
+		var order []string
+		var wg sync.WaitGroup
+		enqueue := func(app string, rank int) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rel, err := s.Acquire(ctx, scheduling.Request{Deployment: "d", App: app, Rank: rank, MaxWait: 5 * time.Second})
+				if !assert.NoError(t, err) {
+					return
+				}
+				mu.Lock()
+				order = append(order, app)
+				mu.Unlock()
+				rel()
+			}()
+			time.Sleep(10 * time.Millisecond) // queue in a known order
+		}
+		enqueue("batch", 3)
+		enqueue("internal", 2)
+		enqueue("customer", 1)
+
+		release()
+		wg.Wait()
+		assert.Equal(t, []string{"customer", "internal", "batch"}, order)
+	})
+
+	t.Run("AppsTakeTurnsWithinRank", func(t *testing.T) {
+		s := scheduling.NewScheduler(4, 1, time.Second)
+		release, err := s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "x", Rank: 2})
+		require.NoError(t, err)
+
+		var mu sync.Mutex
+		var order []string
+		var wg sync.WaitGroup
+		for _, app := range []string{"a", "a", "a", "b", "b"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rel, err := s.Acquire(ctx, scheduling.Request{Deployment: "d", App: app, Rank: 2, MaxWait: 5 * time.Second})
+				if !assert.NoError(t, err) {
+					return
+				}
+				mu.Lock()
+				order = append(order, app)
+				mu.Unlock()
+				rel()
+			}()
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		release()
+		wg.Wait()
+		assert.Equal(t, []string{"a", "b", "a", "b", "a"}, order)
+	})
+
+	t.Run("WaitTimesOut", func(t *testing.T) {
+		s := scheduling.NewScheduler(4, 1, time.Second)
+		release, err := s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "a"})
+		require.NoError(t, err)
+		defer release()
+
+		_, err = s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "b", MaxWait: 20 * time.Millisecond})
+		assert.ErrorIs(t, err, scheduling.ErrShed)
+		assert.Equal(t, 1, s.InFlight("d"))
+	})
+
+	t.Run("ThrottledAdmitsByRank", func(t *testing.T) {
+		// During the cooldown ranks 0-3 may have 4, 3, 2 and 1 in flight
+		s := scheduling.NewScheduler(4, 4, time.Second)
+		s.Throttled("d", 100*time.Millisecond)
+
+		low, err := s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "batch", Rank: 3})
+		require.NoError(t, err)
+		_, err = s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "batch", Rank: 3})
+		assert.ErrorIs(t, err, scheduling.ErrShed)
+
+		standard, err := s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "app", Rank: 2})
+		require.NoError(t, err)
+		_, err = s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "app", Rank: 2})
+		assert.ErrorIs(t, err, scheduling.ErrShed)
+
+		critical, err := s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "critical", Rank: 0})
+		require.NoError(t, err)
+		critical()
+
+		// Queued requests go through once the cooldown ends
+		start := time.Now()
+		rel, err := s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "batch", Rank: 3, MaxWait: time.Second})
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		rel()
+		low()
+		standard()
+	})
+
+	t.Run("ThrottledWithoutCapHoldsNothingBack", func(t *testing.T) {
+		s := scheduling.NewScheduler(4, 0, time.Second)
+		s.Throttled("d", time.Minute)
+
+		rel, err := s.Acquire(ctx, scheduling.Request{Deployment: "d", App: "batch", Rank: 3})
+		require.NoError(t, err)
+		rel()
+	})
+
+	t.Run("DeploymentsAreIndependent", func(t *testing.T) {
+		s := scheduling.NewScheduler(4, 1, time.Second)
+		_, err := s.Acquire(ctx, scheduling.Request{Deployment: "d1", App: "a", Rank: 3})
+		require.NoError(t, err)
+		_, err = s.Acquire(ctx, scheduling.Request{Deployment: "d2", App: "a", Rank: 3})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,59 @@
+package tokens
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// completionChunk covers the OpenAI-compatible response shapes that carry
+// generated text: chat messages, streamed deltas and legacy completions
+type completionChunk struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		Text string `json:"text"`
+	} `json:"choices"`
+}
+
+// ExtractCompletionText returns the generated text in a response body, which
+// may be a single JSON document or an SSE stream of chunks. It is used to
+// estimate completion tokens when the provider reports no usage.
+func ExtractCompletionText(body []byte) string {
+	var b strings.Builder
+
+	appendChunk := func(raw []byte) {
+		var chunk completionChunk
+		if json.Unmarshal(raw, &chunk) != nil {
+			return
+		}
+		for _, c := range chunk.Choices {
+			b.WriteString(c.Message.Content)
+			b.WriteString(c.Delta.Content)
+			b.WriteString(c.Text)
+		}
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		appendChunk(trimmed)
+		return b.String()
+	}
+
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		payload := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+		if bytes.Equal(payload, []byte("[DONE]")) {
+			continue
+		}
+		appendChunk(payload)
+	}
+	return b.String()
+}
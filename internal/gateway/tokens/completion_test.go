@@ -0,0 +1,32 @@
+package tokens
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractCompletionText(t *testing.T) {
+	t.Run("ChatCompletion", func(t *testing.T) {
+		body := `{"choices":[{"message":{"role":"assistant","content":"Hello there"}}]}`
+		assert.Equal(t, "Hello there", ExtractCompletionText([]byte(body)))
+	})
+
+	t.Run("LegacyCompletion", func(t *testing.T) {
+		body := `{"choices":[{"text":"Once upon"},{"text":" a time"}]}`
+		assert.Equal(t, "Once upon a time", ExtractCompletionText([]byte(body)))
+	})
+
+	t.Run("StreamedDeltas", func(t *testing.T) {
+		body := "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n" +
+			"data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+			"data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n" +
+			"data: [DONE]\n\n"
+		assert.Equal(t, "Hello", ExtractCompletionText([]byte(body)))
+	})
+
+	t.Run("NoText", func(t *testing.T) {
+		assert.Empty(t, ExtractCompletionText([]byte(`{"error":{"message":"bad"}}`)))
+		assert.Empty(t, ExtractCompletionText(nil))
+	})
+}
@@ -0,0 +1,164 @@
+package tokens
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	// Decoders for measuring inline images
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"strings"
+)
+
+// MessageContent is a chat message's content: either a plain string or an
+// array of parts. Text parts are joined with newlines; image parts are kept
+// so they can be priced and checked.
+type MessageContent struct {
+	Text   string
+	Images []ImagePart
+}
+
+// UnmarshalJSON accepts a string, an array of content parts or null
+func (c *MessageContent) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(data, &c.Text); err == nil {
+		return nil
+	}
+
+	var parts []struct {
+		Type     string    `json:"type"`
+		Text     string    `json:"text"`
+		ImageURL ImagePart `json:"image_url"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	var texts []string
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			texts = append(texts, part.Text)
+		case "image_url":
+			c.Images = append(c.Images, part.ImageURL)
+		}
+	}
+	c.Text = strings.Join(texts, "\n")
+	return nil
+}
+
+// PromptContent is a completion request's prompt: a string or an array of
+// strings, which are joined with newlines
+type PromptContent string
+
+// UnmarshalJSON accepts a string, an array of strings or null. Token arrays
+// have no text to read and are an error.
+func (p *PromptContent) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*p = PromptContent(text)
+		return nil
+	}
+	var texts []string
+	if err := json.Unmarshal(data, &texts); err != nil {
+		return err
+	}
+	*p = PromptContent(strings.Join(texts, "\n"))
+	return nil
+}
+
+// ImagePart is an image_url content part: a remote URL or a base64 data URI
+type ImagePart struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"` // low, high or auto
+}
+
+// dataURI returns the base64 payload of an inline image
+func (p ImagePart) dataURI() (string, bool) {
+	rest, ok := strings.CutPrefix(p.URL, "data:")
+	if !ok {
+		return "", false
+	}
+	meta, payload, ok := strings.Cut(rest, ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return "", false
+	}
+	return payload, true
+}
+
+// Inline reports whether the image is embedded in the request as a data URI
+func (p ImagePart) Inline() bool {
+	_, ok := p.dataURI()
+	return ok
+}
+
+// Size returns the decoded size in bytes of an inline image, or 0 for a
+// remote one
+func (p ImagePart) Size() int {
+	payload, ok := p.dataURI()
+	if !ok {
+		return 0
+	}
+	return base64.RawStdEncoding.DecodedLen(len(strings.TrimRight(payload, "=")))
+}
+
+// Dimensions reads the width and height of an inline PNG, JPEG or GIF from
+// its header without decoding the whole image
+func (p ImagePart) Dimensions() (width, height int, ok bool) {
+	payload, ok := p.dataURI()
+	if !ok {
+		return 0, 0, false
+	}
+	cfg, _, err := image.DecodeConfig(base64.NewDecoder(base64.StdEncoding, strings.NewReader(payload)))
+	if err != nil {
+		return 0, 0, false
+	}
+	return cfg.Width, cfg.Height, true
+}
+
+// Image token rates of OpenAI vision models: a base charge per image plus one
+// per 512px tile
+const (
+	ImageBaseTokens = 85
+	ImageTileTokens = 170
+)
+
+// imageTokenRates overrides the base and tile charges for models that price
+// images differently
+var imageTokenRates = map[string][2]int{
+	"gpt-4o-mini": {2833, 5667},
+}
+
+// ImageTokens estimates the prompt tokens an image costs using OpenAI's tile
+// formula. Low detail images cost the base charge; otherwise the image is
+// scaled to fit 2048x2048, then its short side down to 768px, and every 512px
+// tile costs the tile charge on top. Unknown dimensions (width or height 0,
+// e.g. remote images) are priced as 1024x1024.
+func ImageTokens(model string, width, height int, detail string) int {
+	base, tile := ImageBaseTokens, ImageTileTokens
+	if rates, ok := imageTokenRates[model]; ok {
+		base, tile = rates[0], rates[1]
+	}
+	if detail == "low" {
+		return base
+	}
+	if width <= 0 || height <= 0 {
+		width, height = 1024, 1024
+	}
+
+	w, h := float64(width), float64(height)
+	if longest := max(w, h); longest > 2048 {
+		w, h = w*2048/longest, h*2048/longest
+	}
+	if shortest := min(w, h); shortest > 768 {
+		w, h = w*768/shortest, h*768/shortest
+	}
+	tiles := int(math.Ceil(w/512) * math.Ceil(h/512))
+	return base + tiles*tile
+}
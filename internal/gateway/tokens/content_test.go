@@ -0,0 +1,125 @@
+package tokens
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngDataURI encodes a blank PNG of the given size as a data URI
+func pngDataURI(t *testing.T, width, height int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))))
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestMessageContent_UnmarshalJSON(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		var c MessageContent
+		require.NoError(t, json.Unmarshal([]byte(`"Hello"`), &c))
+		assert.Equal(t, "Hello", c.Text)
+		assert.Empty(t, c.Images)
+	})
+
+	t.Run("Null", func(t *testing.T) {
+		var c MessageContent
+		require.NoError(t, json.Unmarshal([]byte(`null`), &c))
+		assert.Empty(t, c.Text)
+	})
+
+	t.Run("Parts", func(t *testing.T) {
+		var c MessageContent
+		data := `[
+			{"type": "text", "text": "What is in this image?"},
+			{"type": "image_url", "image_url": {"url": "https://example.com/cat.png", "detail": "low"}},
+			{"type": "text", "text": "Be brief."}
+		]`
+		require.NoError(t, json.Unmarshal([]byte(data), &c))
+		assert.Equal(t, "What is in this image?\nBe brief.", c.Text)
+		require.Len(t, c.Images, 1)
+		assert.Equal(t, ImagePart{URL: "https://example.com/cat.png", Detail: "low"}, c.Images[0])
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		var c MessageContent
+		assert.Error(t, json.Unmarshal([]byte(`42`), &c))
+	})
+}
+
+func TestPromptContent_UnmarshalJSON(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		var p PromptContent
+		require.NoError(t, json.Unmarshal([]byte(`"Hello"`), &p))
+		assert.Equal(t, PromptContent("Hello"), p)
+	})
+
+	t.Run("Strings", func(t *testing.T) {
+		var p PromptContent
+		require.NoError(t, json.Unmarshal([]byte(`["Hello", "world"]`), &p))
+		assert.Equal(t, PromptContent("Hello\nworld"), p)
+	})
+
+	t.Run("Tokens", func(t *testing.T) {
+		var p PromptContent
+		assert.Error(t, json.Unmarshal([]byte(`[1, 2, 3]`), &p))
+	})
+}
+
+func TestImagePart(t *testing.T) {
+	t.Run("Inline", func(t *testing.T) {
+		uri := pngDataURI(t, 300, 200)
+		payload, _ := base64.StdEncoding.DecodeString(uri[len("data:image/png;base64,"):])
+
+		img := ImagePart{URL: uri}
+		assert.True(t, img.Inline())
+		assert.Equal(t, len(payload), img.Size())
+		width, height, ok := img.Dimensions()
+		require.True(t, ok)
+		assert.Equal(t, 300, width)
+		assert.Equal(t, 200, height)
+	})
+
+	t.Run("Remote", func(t *testing.T) {
+		img := ImagePart{URL: "https://example.com/cat.png"}
+		assert.False(t, img.Inline())
+		assert.Zero(t, img.Size())
+		_, _, ok := img.Dimensions()
+		assert.False(t, ok)
+	})
+
+	t.Run("UnreadableInline", func(t *testing.T) {
+		img := ImagePart{URL: "data:image/webp;base64,UklGRg=="}
+		assert.True(t, img.Inline())
+		_, _, ok := img.Dimensions()
+		assert.False(t, ok)
+	})
+}
+
+func TestImageTokens(t *testing.T) {
+	tests := []struct {
+		name          string
+		model         string
+		width, height int
+		detail        string
+		expected      int
+	}{
+		{"LowDetail", "gpt-4o", 4096, 4096, "low", 85},
+		{"SmallImageOneTile", "gpt-4o", 300, 200, "high", 255},
+		{"SquareScaledTo768", "gpt-4o", 1024, 1024, "auto", 765},
+		{"TallScaledTwice", "gpt-4o", 2048, 4096, "high", 1105},
+		{"UnknownSizeAsSquare", "gpt-4o", 0, 0, "", 765},
+		{"MiniRates", "gpt-4o-mini", 1024, 1024, "high", 2833 + 4*5667},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ImageTokens(tt.model, tt.width, tt.height, tt.detail))
+		})
+	}
+}
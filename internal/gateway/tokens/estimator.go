@@ -40,10 +40,10 @@ func (e *Estimator) EstimateRequest(ctx context.Context, model string, body []by
 	// Parse request body to extract messages/prompt
 	var req struct {
 		Messages []struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role    string         `json:"role"`
+			Content MessageContent `json:"content"`
 		} `json:"messages,omitempty"`
-		Prompt string `json:"prompt,omitempty"`
+		Prompt PromptContent `json:"prompt,omitempty"`
 	}
 
 	if err := json.Unmarshal(body, &req); err != nil {
@@ -61,9 +61,13 @@ func (e *Estimator) EstimateRequest(ctx context.Context, model string, body []by
 	// Estimate from messages (chat completion format)
 	if len(req.Messages) > 0 {
 		for _, msg := range req.Messages {
-			tokens := encoding.Encode(msg.Content, nil, nil)
+			tokens := encoding.Encode(msg.Content.Text, nil, nil)
 			totalTokens += len(tokens)
 			totalTokens += MessageOverheadTokens
+			for _, img := range msg.Content.Images {
+				width, height, _ := img.Dimensions()
+				totalTokens += ImageTokens(model, width, height, img.Detail)
+			}
 		}
 		totalTokens += ArrayOverheadTokens
 		return totalTokens, nil
@@ -71,13 +75,28 @@ func (e *Estimator) EstimateRequest(ctx context.Context, model string, body []by
 
 	// Estimate from prompt (completion format)
 	if req.Prompt != "" {
-		tokens := encoding.Encode(req.Prompt, nil, nil)
+		tokens := encoding.Encode(string(req.Prompt), nil, nil)
 		return len(tokens), nil
 	}
 
 	return 0, fmt.Errorf("no messages or prompt found in request")
 }
 
+// EstimateText counts the tokens in generated text, e.g. a completion whose
+// response carried no usage block
+func (e *Estimator) EstimateText(ctx context.Context, model, text string) (int, error) {
+	start := time.Now()
+	defer func() {
+		observability.FromContext(ctx).RecordTokenEstimation(ctx, model, time.Since(start), 0)
+	}()
+
+	encoding, err := e.getEncoding(model)
+	if err != nil {
+		return 0, err
+	}
+	return len(encoding.Encode(text, nil, nil)), nil
+}
+
 // getEncoding gets or creates a tiktoken encoding for the given model
 func (e *Estimator) getEncoding(model string) (*tiktoken.Tiktoken, error) {
 	// Check LRU cache
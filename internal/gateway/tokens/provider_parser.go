@@ -18,9 +18,12 @@ type OpenAIParser struct{}
 func (p *OpenAIParser) ParseResponse(body []byte) (*model.TokenUsage, error) {
 	var resp struct {
 		Usage struct {
-			PromptTokens     int `json:"prompt_tokens"`
-			CompletionTokens int `json:"completion_tokens"`
-			TotalTokens      int `json:"total_tokens"`
+			PromptTokens        int `json:"prompt_tokens"`
+			CompletionTokens    int `json:"completion_tokens"`
+			TotalTokens         int `json:"total_tokens"`
+			PromptTokensDetails struct {
+				CachedTokens int `json:"cached_tokens"`
+			} `json:"prompt_tokens_details"`
 		} `json:"usage"`
 	}
 
@@ -33,9 +36,10 @@ func (p *OpenAIParser) ParseResponse(body []byte) (*model.TokenUsage, error) {
 	}
 
 	return &model.TokenUsage{
-		PromptTokens:     resp.Usage.PromptTokens,
-		CompletionTokens: resp.Usage.CompletionTokens,
-		TotalTokens:      resp.Usage.TotalTokens,
+		PromptTokens:       resp.Usage.PromptTokens,
+		CompletionTokens:   resp.Usage.CompletionTokens,
+		TotalTokens:        resp.Usage.TotalTokens,
+		CachedPromptTokens: resp.Usage.PromptTokensDetails.CachedTokens,
 	}, nil
 }
 
@@ -45,8 +49,10 @@ type AnthropicParser struct{}
 func (p *AnthropicParser) ParseResponse(body []byte) (*model.TokenUsage, error) {
 	var resp struct {
 		Usage struct {
-			InputTokens  int `json:"input_tokens"`
-			OutputTokens int `json:"output_tokens"`
+			InputTokens              int `json:"input_tokens"`
+			OutputTokens             int `json:"output_tokens"`
+			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 		} `json:"usage"`
 	}
 
@@ -54,15 +60,18 @@ func (p *AnthropicParser) ParseResponse(body []byte) (*model.TokenUsage, error)
 		return nil, fmt.Errorf("failed to parse Anthropic response: %w", err)
 	}
 
-	totalTokens := resp.Usage.InputTokens + resp.Usage.OutputTokens
+	// Anthropic reports cache reads and writes separately from input_tokens
+	promptTokens := resp.Usage.InputTokens + resp.Usage.CacheCreationInputTokens + resp.Usage.CacheReadInputTokens
+	totalTokens := promptTokens + resp.Usage.OutputTokens
 	if totalTokens == 0 {
 		return nil, fmt.Errorf("no usage data in response")
 	}
 
 	return &model.TokenUsage{
-		PromptTokens:     resp.Usage.InputTokens,
-		CompletionTokens: resp.Usage.OutputTokens,
-		TotalTokens:      totalTokens,
+		PromptTokens:       promptTokens,
+		CompletionTokens:   resp.Usage.OutputTokens,
+		TotalTokens:        totalTokens,
+		CachedPromptTokens: resp.Usage.CacheReadInputTokens,
 	}, nil
 }
 
@@ -28,6 +28,22 @@ func TestOpenAIParser(t *testing.T) {
 		assert.Equal(t, 30, usage.TotalTokens)
 	})
 
+	t.Run("Cached prompt tokens", func(t *testing.T) {
+		body := []byte(`{
+			"usage": {
+				"prompt_tokens": 2000,
+				"completion_tokens": 100,
+				"total_tokens": 2100,
+				"prompt_tokens_details": {"cached_tokens": 1920}
+			}
+		}`)
+
+		usage, err := parser.ParseResponse(body)
+		require.NoError(t, err)
+		assert.Equal(t, 2000, usage.PromptTokens)
+		assert.Equal(t, 1920, usage.CachedPromptTokens)
+	})
+
 	t.Run("Invalid JSON", func(t *testing.T) {
 		body := []byte(`invalid json`)
 		_, err := parser.ParseResponse(body)
@@ -78,6 +94,23 @@ func TestAnthropicParser(t *testing.T) {
 		assert.Equal(t, 40, usage.TotalTokens)
 	})
 
+	t.Run("Prompt caching", func(t *testing.T) {
+		body := []byte(`{
+			"usage": {
+				"input_tokens": 10,
+				"cache_creation_input_tokens": 200,
+				"cache_read_input_tokens": 1800,
+				"output_tokens": 50
+			}
+		}`)
+
+		usage, err := parser.ParseResponse(body)
+		require.NoError(t, err)
+		assert.Equal(t, 2010, usage.PromptTokens)
+		assert.Equal(t, 1800, usage.CachedPromptTokens)
+		assert.Equal(t, 2060, usage.TotalTokens)
+	})
+
 	t.Run("Invalid JSON", func(t *testing.T) {
 		body := []byte(`invalid json`)
 		_, err := parser.ParseResponse(body)
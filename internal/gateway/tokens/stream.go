@@ -0,0 +1,338 @@
+package tokens
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+)
+
+// maxBufferedBody bounds how much of a non-streamed response is kept for parsing
+const maxBufferedBody = 10 << 20
+
+// ResponseSummary is what usage recording needs to know about a response
+type ResponseSummary struct {
+	Usage            *model.TokenUsage // nil when the provider reported none
+	FinishReason     string
+	TimeToFirstToken time.Duration // zero for non-streamed responses
+	Text             string        // generated text, for estimating missing usage
+	Size             int
+	Streamed         bool
+	Images           int     // images returned by an image generation call
+	AudioSeconds     float64 // audio duration reported by a transcription call
+}
+
+type decoderMode int
+
+const (
+	modeUnknown decoderMode = iota
+	modeJSON
+	modeSSE
+)
+
+// ResponseDecoder consumes a response body as it is read by the client and
+// keeps only the state usage recording needs. Server-sent event streams are
+// decoded incrementally (multi-line data fields and "event:" types included)
+// so long streams are never buffered whole; plain JSON bodies are buffered up
+// to maxBufferedBody and parsed once at the end.
+type ResponseDecoder struct {
+	parser   *Parser
+	provider string
+	start    time.Time
+	now      func() time.Time
+
+	mode     decoderMode
+	body     bytes.Buffer // JSON mode, or leading bytes before the mode is known
+	line     []byte       // partial SSE line carried between writes
+	afterCR  bool         // the last write ended in CR, so a leading LF completes a CRLF
+	event    string
+	data     bytes.Buffer
+	hasData  bool
+	finished bool
+
+	summary      ResponseSummary
+	text         strings.Builder
+	usage        *model.TokenUsage
+	inputTokens  int // Anthropic message_start, including cache reads and writes
+	cachedTokens int // Anthropic message_start cache reads
+	outputTokens int // Anthropic message_delta (cumulative)
+}
+
+// NewResponseDecoder creates a decoder; start is when the upstream request
+// was sent and anchors the time-to-first-token measurement.
+func NewResponseDecoder(parser *Parser, provider string, start time.Time) *ResponseDecoder {
+	return &ResponseDecoder{parser: parser, provider: provider, start: start, now: time.Now}
+}
+
+// Write implements io.Writer; it never fails so it can sit behind a tee.
+func (d *ResponseDecoder) Write(p []byte) (int, error) {
+	d.summary.Size += len(p)
+
+	if d.mode == modeUnknown {
+		d.body.Write(p)
+		trimmed := bytes.TrimLeft(d.body.Bytes(), " \t\r\n")
+		if len(trimmed) == 0 {
+			return len(p), nil
+		}
+		if trimmed[0] == '{' || trimmed[0] == '[' {
+			d.mode = modeJSON
+			return len(p), nil
+		}
+		d.mode = modeSSE
+		d.summary.Streamed = true
+		pending := append([]byte(nil), d.body.Bytes()...)
+		d.body.Reset()
+		d.feed(pending)
+		return len(p), nil
+	}
+
+	if d.mode == modeJSON {
+		if d.body.Len() < maxBufferedBody {
+			d.body.Write(p)
+		}
+		return len(p), nil
+	}
+
+	d.feed(p)
+	return len(p), nil
+}
+
+// Summary finishes decoding and returns what was observed. It is safe to
+// call more than once.
+func (d *ResponseDecoder) Summary() *ResponseSummary {
+	if !d.finished {
+		d.finished = true
+		switch d.mode {
+		case modeSSE:
+			if len(d.line) > 0 {
+				d.processLine(d.line)
+				d.line = nil
+			}
+			d.dispatch()
+		case modeJSON:
+			d.decodeJSON(d.body.Bytes())
+		}
+
+		d.summary.Text = d.text.String()
+		d.summary.Usage = d.usage
+		if d.usage == nil && d.inputTokens+d.outputTokens > 0 {
+			d.summary.Usage = &model.TokenUsage{
+				PromptTokens:       d.inputTokens,
+				CompletionTokens:   d.outputTokens,
+				TotalTokens:        d.inputTokens + d.outputTokens,
+				CachedPromptTokens: d.cachedTokens,
+			}
+		}
+	}
+	return &d.summary
+}
+
+// feed splits SSE input into lines, carrying partial lines between writes.
+// Lines end in CRLF, LF or a lone CR, as the SSE spec allows, so a CR-only
+// stream can't grow one unbounded line.
+func (d *ResponseDecoder) feed(p []byte) {
+	for len(p) > 0 {
+		if d.afterCR {
+			d.afterCR = false
+			if p[0] == '\n' {
+				p = p[1:] // LF of a CRLF split across writes
+				continue
+			}
+		}
+		i := bytes.IndexAny(p, "\r\n")
+		if i < 0 {
+			d.line = append(d.line, p...)
+			return
+		}
+		line := p[:i]
+		if len(d.line) > 0 {
+			line = append(d.line, line...)
+			d.line = d.line[:0]
+		}
+		d.processLine(line)
+		if p[i] == '\r' {
+			if i+1 == len(p) {
+				d.afterCR = true
+			} else if p[i+1] == '\n' {
+				i++
+			}
+		}
+		p = p[i+1:]
+	}
+}
+
+// processLine applies one SSE line; a blank line dispatches the event
+func (d *ResponseDecoder) processLine(line []byte) {
+	if len(line) == 0 {
+		d.dispatch()
+		return
+	}
+	if line[0] == ':' {
+		return // comment / keep-alive
+	}
+
+	field, value, _ := bytes.Cut(line, []byte(":"))
+	value = bytes.TrimPrefix(value, []byte(" "))
+	switch string(field) {
+	case "event":
+		d.event = string(value)
+	case "data":
+		if d.hasData {
+			d.data.WriteByte('\n')
+		}
+		d.data.Write(value)
+		d.hasData = true
+	}
+}
+
+// nativeStreamProviders stream the OpenAI or Anthropic event shapes decoded
+// directly below; other providers fall back to their registered parser
+var nativeStreamProviders = map[string]bool{
+	"openai":      true,
+	"azureopenai": true,
+	"anthropic":   true,
+}
+
+// streamUsage covers OpenAI (prompt/completion) and Anthropic (input/output) counts
+type streamUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// streamEvent is the union of the OpenAI chunk and Anthropic event payloads
+type streamEvent struct {
+	Type    string `json:"type"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		Text         string  `json:"text"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage   *streamUsage `json:"usage"`
+	Message *struct {
+		Usage *streamUsage `json:"usage"`
+	} `json:"message"`
+	Delta *struct {
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+}
+
+// dispatch handles a complete SSE event
+func (d *ResponseDecoder) dispatch() {
+	event, payload, hasData := d.event, d.data.Bytes(), d.hasData
+	defer func() {
+		d.event, d.hasData = "", false
+		d.data.Reset()
+	}()
+	if !hasData || bytes.Equal(payload, []byte("[DONE]")) {
+		return
+	}
+
+	var ev streamEvent
+	if json.Unmarshal(payload, &ev) != nil {
+		return
+	}
+	if event == "" {
+		event = ev.Type
+	}
+
+	for _, c := range ev.Choices {
+		d.appendText(c.Delta.Content)
+		d.appendText(c.Text)
+		if c.FinishReason != nil && *c.FinishReason != "" {
+			d.summary.FinishReason = *c.FinishReason
+		}
+	}
+
+	switch event {
+	case "message_start":
+		if u := ev.Message; u != nil && u.Usage != nil {
+			d.inputTokens = u.Usage.InputTokens + u.Usage.CacheCreationInputTokens + u.Usage.CacheReadInputTokens
+			d.cachedTokens = u.Usage.CacheReadInputTokens
+			d.outputTokens = u.Usage.OutputTokens
+		}
+		return
+	case "content_block_delta":
+		if ev.Delta != nil {
+			d.appendText(ev.Delta.Text)
+		}
+		return
+	case "message_delta":
+		if ev.Delta != nil && ev.Delta.StopReason != "" {
+			d.summary.FinishReason = ev.Delta.StopReason
+		}
+		if ev.Usage != nil && ev.Usage.OutputTokens > 0 {
+			d.outputTokens = ev.Usage.OutputTokens
+		}
+		return
+	}
+
+	switch {
+	case ev.Usage != nil && ev.Usage.TotalTokens > 0:
+		d.usage = &model.TokenUsage{
+			PromptTokens:       ev.Usage.PromptTokens,
+			CompletionTokens:   ev.Usage.CompletionTokens,
+			TotalTokens:        ev.Usage.TotalTokens,
+			CachedPromptTokens: ev.Usage.PromptTokensDetails.CachedTokens,
+		}
+	case !nativeStreamProviders[d.provider]:
+		// Other providers' shapes (usageMetadata, meta.billed_units, ...)
+		if usage, err := d.parser.ParseResponse(d.provider, payload); err == nil {
+			d.usage = usage
+		}
+	}
+}
+
+func (d *ResponseDecoder) appendText(s string) {
+	if s == "" {
+		return
+	}
+	if d.text.Len() == 0 {
+		d.summary.TimeToFirstToken = d.now().Sub(d.start)
+	}
+	d.text.WriteString(s)
+}
+
+// decodeJSON extracts usage, finish reason and text from a non-streamed body
+func (d *ResponseDecoder) decodeJSON(body []byte) {
+	if usage, err := d.parser.ParseResponse(d.provider, body); err == nil {
+		d.usage = usage
+	}
+	d.text.WriteString(ExtractCompletionText(body))
+
+	var resp struct {
+		Choices []struct {
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		StopReason string `json:"stop_reason"`
+		Data       []struct {
+			URL     string `json:"url"`
+			B64JSON string `json:"b64_json"`
+		} `json:"data"`
+		Duration float64 `json:"duration"`
+	}
+	if json.Unmarshal(body, &resp) == nil {
+		d.summary.FinishReason = resp.StopReason
+		if len(resp.Choices) > 0 && resp.Choices[0].FinishReason != "" {
+			d.summary.FinishReason = resp.Choices[0].FinishReason
+		}
+		for _, item := range resp.Data {
+			if item.URL != "" || item.B64JSON != "" {
+				d.summary.Images++
+			}
+		}
+		d.summary.AudioSeconds = resp.Duration
+	}
+}
@@ -0,0 +1,136 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeAll feeds body to a decoder chunk bytes at a time
+func decodeAll(provider, body string, chunk int) *ResponseSummary {
+	start := time.Now()
+	d := NewResponseDecoder(NewParser(), provider, start)
+	d.now = func() time.Time { return start.Add(150 * time.Millisecond) }
+	for i := 0; i < len(body); i += chunk {
+		end := min(i+chunk, len(body))
+		_, _ = d.Write([]byte(body[i:end]))
+	}
+	return d.Summary()
+}
+
+func TestResponseDecoder(t *testing.T) {
+	openAIStream := "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n" +
+		": keep-alive\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":9,\"completion_tokens\":2,\"total_tokens\":11}}\n\n" +
+		"data: [DONE]\n\n"
+
+	t.Run("OpenAIStream", func(t *testing.T) {
+		s := decodeAll("openai", openAIStream, len(openAIStream))
+		require.NotNil(t, s.Usage)
+		assert.Equal(t, 9, s.Usage.PromptTokens)
+		assert.Equal(t, 2, s.Usage.CompletionTokens)
+		assert.Equal(t, 11, s.Usage.TotalTokens)
+		assert.Equal(t, "stop", s.FinishReason)
+		assert.Equal(t, "Hello", s.Text)
+		assert.Equal(t, 150*time.Millisecond, s.TimeToFirstToken)
+		assert.True(t, s.Streamed)
+		assert.Equal(t, len(openAIStream), s.Size)
+	})
+
+	t.Run("OneByteWrites", func(t *testing.T) {
+		s := decodeAll("openai", openAIStream, 1)
+		require.NotNil(t, s.Usage)
+		assert.Equal(t, 11, s.Usage.TotalTokens)
+		assert.Equal(t, "Hello", s.Text)
+	})
+
+	t.Run("MultiLineData", func(t *testing.T) {
+		body := "data: {\"choices\":[],\r\n" +
+			"data: \"usage\":{\"prompt_tokens\":1,\"completion_tokens\":1,\"total_tokens\":2}}\r\n\r\n"
+		s := decodeAll("openai", body, 7)
+		require.NotNil(t, s.Usage)
+		assert.Equal(t, 2, s.Usage.TotalTokens)
+	})
+
+	t.Run("LineEndings", func(t *testing.T) {
+		for name, eol := range map[string]string{"CRLF": "\r\n", "CR": "\r"} {
+			body := strings.ReplaceAll(openAIStream, "\n", eol)
+			for _, chunk := range []int{1, 2, len(body)} {
+				s := decodeAll("openai", body, chunk)
+				require.NotNil(t, s.Usage, "%s in %d-byte writes", name, chunk)
+				assert.Equal(t, 11, s.Usage.TotalTokens, "%s in %d-byte writes", name, chunk)
+				assert.Equal(t, "Hello", s.Text, "%s in %d-byte writes", name, chunk)
+			}
+		}
+	})
+
+	t.Run("AnthropicEvents", func(t *testing.T) {
+		body := "event: message_start\n" +
+			"data: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":25,\"output_tokens\":1}}}\n\n" +
+			"event: content_block_delta\n" +
+			"data: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n" +
+			"event: message_delta\n" +
+			"data: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":12}}\n\n" +
+			"event: message_stop\n" +
+			"data: {\"type\":\"message_stop\"}\n\n"
+		s := decodeAll("anthropic", body, 5)
+		require.NotNil(t, s.Usage)
+		assert.Equal(t, 25, s.Usage.PromptTokens)
+		assert.Equal(t, 12, s.Usage.CompletionTokens)
+		assert.Equal(t, 37, s.Usage.TotalTokens)
+		assert.Equal(t, "end_turn", s.FinishReason)
+		assert.Equal(t, "Hi", s.Text)
+		assert.Equal(t, 150*time.Millisecond, s.TimeToFirstToken)
+	})
+
+	t.Run("TrailingEventWithoutBlankLine", func(t *testing.T) {
+		body := "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":4,\"total_tokens\":7}}"
+		s := decodeAll("openai", body, len(body))
+		require.NotNil(t, s.Usage)
+		assert.Equal(t, 7, s.Usage.TotalTokens)
+	})
+
+	t.Run("StreamWithoutUsage", func(t *testing.T) {
+		body := "data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\ndata: [DONE]\n\n"
+		s := decodeAll("openai", body, len(body))
+		assert.Nil(t, s.Usage)
+		assert.Equal(t, "Hi", s.Text)
+	})
+
+	t.Run("JSONBody", func(t *testing.T) {
+		body := `{"choices":[{"message":{"content":"Hello"},"finish_reason":"length"}],` +
+			`"usage":{"prompt_tokens":5,"completion_tokens":3,"total_tokens":8}}`
+		s := decodeAll("openai", body, 4)
+		require.NotNil(t, s.Usage)
+		assert.Equal(t, 8, s.Usage.TotalTokens)
+		assert.Equal(t, "length", s.FinishReason)
+		assert.Equal(t, "Hello", s.Text)
+		assert.False(t, s.Streamed)
+		assert.Zero(t, s.TimeToFirstToken)
+	})
+
+	t.Run("ImageGeneration", func(t *testing.T) {
+		body := `{"created":1,"data":[{"url":"https://example.com/a.png"},{"b64_json":"aGk="}]}`
+		s := decodeAll("openai", body, len(body))
+		assert.Equal(t, 2, s.Images)
+	})
+
+	t.Run("Transcription", func(t *testing.T) {
+		body := `{"task":"transcribe","duration":12.5,"text":"hello"}`
+		s := decodeAll("openai", body, len(body))
+		assert.Equal(t, 12.5, s.AudioSeconds)
+	})
+
+	t.Run("SummaryIsIdempotent", func(t *testing.T) {
+		d := NewResponseDecoder(NewParser(), "openai", time.Now())
+		_, _ = d.Write([]byte(openAIStream))
+		first := d.Summary()
+		second := d.Summary()
+		assert.Equal(t, first, second)
+	})
+}
@@ -25,6 +25,11 @@ func Chain(base http.RoundTripper, mws ...func(http.RoundTripper) http.RoundTrip
 func WithAuth(a auth.KeyAuthenticator) func(http.RoundTripper) http.RoundTripper {
 	return func(next http.RoundTripper) http.RoundTripper {
 		return RTFunc(func(r *http.Request) (*http.Response, error) {
+			// Identity was established in-process (async job replay)
+			if auth.IsPreAuthenticated(r.Context()) {
+				return next.RoundTrip(r)
+			}
+
 			keyID, keyData, err := a.Authenticate(r)
 			if err != nil {
 				return deny(401, "unauthorized"), nil
@@ -36,6 +41,7 @@ func WithAuth(a auth.KeyAuthenticator) func(http.RoundTripper) http.RoundTripper
 			ctx = auth.WithOrgID(ctx, keyData.OrgID)
 			ctx = auth.WithAppID(ctx, keyData.AppID)
 			ctx = auth.WithUserID(ctx, keyData.UserID)
+			ctx = auth.WithKeyMetadata(ctx, keyData.Metadata)
 
 			// Update request with enriched context
 			r = r.WithContext(ctx)
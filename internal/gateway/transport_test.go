@@ -95,10 +95,11 @@ func TestChain(t *testing.T) {
 func TestWithAuth_Success(t *testing.T) {
 	authenticator := &mockAuthenticator{
 		keyData: &auth.KeyData{
-			KeyID:  "test-key-id",
-			OrgID:  "test-org",
-			AppID:  "test-app",
-			UserID: "test-user",
+			KeyID:    "test-key-id",
+			OrgID:    "test-org",
+			AppID:    "test-app",
+			UserID:   "test-user",
+			Metadata: []byte(`{"tags":["intern"]}`),
 		},
 	}
 
@@ -109,6 +110,7 @@ func TestWithAuth_Success(t *testing.T) {
 		require.Equal(t, "test-org", auth.GetOrgID(ctx))
 		require.Equal(t, "test-app", auth.GetAppID(ctx))
 		require.Equal(t, "test-user", auth.GetUserID(ctx))
+		require.JSONEq(t, `{"tags":["intern"]}`, string(auth.GetKeyMetadata(ctx)))
 		return &http.Response{StatusCode: 200}, nil
 	})
 
@@ -221,3 +223,21 @@ func TestWithMetrics_Error(t *testing.T) {
 	require.Nil(t, resp)
 	require.False(t, metrics.recorded) // Should not record on error
 }
+
+func TestWithAuth_PreAuthenticated(t *testing.T) {
+	// Async jobs carry no credentials; the stored identity is trusted instead
+	authenticator := &mockAuthenticator{shouldFail: true}
+
+	base := gateway.RTFunc(func(r *http.Request) (*http.Response, error) {
+		require.Equal(t, "key_prefix", auth.GetKeyID(r.Context()))
+		require.Equal(t, "test-app", auth.GetAppID(r.Context()))
+		return &http.Response{StatusCode: 200}, nil
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req = req.WithContext(auth.WithPreAuthenticated(req.Context(), auth.KeyData{KeyID: "key_prefix", AppID: "test-app"}))
+	resp, err := gateway.WithAuth(authenticator)(base).RoundTrip(req)
+
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+}
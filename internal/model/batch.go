@@ -0,0 +1,64 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BatchStatus mirrors the lifecycle states reported by the upstream Batch API.
+type BatchStatus string
+
+const (
+	BatchStatusValidating BatchStatus = "validating"
+	BatchStatusInProgress BatchStatus = "in_progress"
+	BatchStatusFinalizing BatchStatus = "finalizing"
+	BatchStatusCompleted  BatchStatus = "completed"
+	BatchStatusFailed     BatchStatus = "failed"
+	BatchStatusExpired    BatchStatus = "expired"
+	BatchStatusCancelling BatchStatus = "cancelling"
+	BatchStatusCancelled  BatchStatus = "cancelled"
+)
+
+// IsTerminal reports whether the upstream will no longer change the batch.
+func (s BatchStatus) IsTerminal() bool {
+	switch s {
+	case BatchStatusCompleted, BatchStatusFailed, BatchStatusExpired, BatchStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// BatchFile records which app uploaded (or owns the output of) an upstream file.
+type BatchFile struct {
+	ID        uuid.UUID
+	OrgID     uuid.UUID
+	AppID     uuid.UUID
+	KeyPrefix string
+	Provider  string
+	FileID    string
+	Purpose   string
+	CreatedAt time.Time
+}
+
+// BatchJob tracks an upstream batch created through the gateway.
+type BatchJob struct {
+	ID            uuid.UUID
+	OrgID         uuid.UUID
+	AppID         uuid.UUID
+	KeyPrefix     string
+	Provider      string
+	BatchID       string
+	Endpoint      string
+	Status        BatchStatus
+	InputFileID   string
+	OutputFileID  string
+	ErrorFileID   string
+	UsageRecorded bool
+	CompletedAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	PollFailures  int       // consecutive failed polls
+	NextPollAt    time.Time // when the poller next looks at the job
+	PollError     string    // why the last poll failed
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,29 +11,89 @@ type PolicyType string
 
 const (
 	// Predefined policy types
-	PolicyTypeRateLimit      PolicyType = "rate_limit"
-	PolicyTypeTokenLimit     PolicyType = "token_limit"
-	PolicyTypeModelAllowlist PolicyType = "model_allowlist"
-	PolicyTypeRequestSize    PolicyType = "request_size"
+	PolicyTypeRateLimit       PolicyType = "rate_limit"
+	PolicyTypeTokenLimit      PolicyType = "token_limit"
+	PolicyTypeModelAllowlist  PolicyType = "model_allowlist"
+	PolicyTypeRequestSize     PolicyType = "request_size"
+	PolicyTypeBudget          PolicyType = "budget"
+	PolicyTypeConcurrency     PolicyType = "concurrency_limit"
+	PolicyTypePriority        PolicyType = "priority"
+	PolicyTypePII             PolicyType = "pii"
+	PolicyTypeTransform       PolicyType = "transform"
+	PolicyTypeResponseGuard   PolicyType = "response_guard"
+	PolicyTypePromptInjection PolicyType = "prompt_injection"
+	PolicyTypeToolAllowlist   PolicyType = "tool_allowlist"
+	PolicyTypeImageLimit      PolicyType = "image_limit"
 
 	// Custom CEL policy
 	PolicyTypeCustomCEL PolicyType = "custom_cel"
 )
 
+// PolicyScope is what a policy is attached to. Scopes are listed from least
+// to most specific: for built-in policy types the most specific scope with a
+// policy of that type wins, while custom CEL policies from every scope apply
+type PolicyScope string
+
+const (
+	PolicyScopeOrg  PolicyScope = "org" // inherited by every app in the organisation
+	PolicyScopeApp  PolicyScope = "app"
+	PolicyScopeUser PolicyScope = "user" // the end-user that owns the API key
+	PolicyScopeKey  PolicyScope = "key"
+)
+
+// PolicyScopes lists the scopes in order of precedence, lowest first
+var PolicyScopes = []PolicyScope{PolicyScopeOrg, PolicyScopeApp, PolicyScopeUser, PolicyScopeKey}
+
+// PolicyAttachment is an org, app, user or API key a policy is attached to
+type PolicyAttachment struct {
+	Scope    PolicyScope
+	TargetID uuid.UUID
+}
+
+// PolicyMode is how a policy's verdicts are applied
+type PolicyMode string
+
+const (
+	PolicyModeEnforce PolicyMode = "enforce" // violations deny the request
+	PolicyModeDryRun  PolicyMode = "dry_run" // violations are recorded and the request is allowed through
+)
+
 type Policy struct {
 	ID         uuid.UUID
 	OrgID      uuid.UUID
 	PolicyType PolicyType
 	Config     map[string]any // JSON blob
 	Enabled    bool
+	Mode       PolicyMode
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 }
 
+// Rate limit algorithms
+const (
+	RateLimitFixedWindow   = "fixed_window"   // per-minute counters; cheapest, allows 2x bursts at window edges
+	RateLimitSlidingWindow = "sliding_window" // exact trailing-minute log of admitted requests
+	RateLimitTokenBucket   = "token_bucket"   // GCRA: refills continuously, bursts up to one minute's quota
+)
+
 // RateLimitConfig Policy-specific config structures
 type RateLimitConfig struct {
-	RequestsPerMinute int `json:"requests_per_minute"`
-	TokensPerMinute   int `json:"tokens_per_minute"`
+	RequestsPerMinute int    `json:"requests_per_minute"`
+	TokensPerMinute   int    `json:"tokens_per_minute"`
+	Algorithm         string `json:"algorithm,omitempty"` // fixed_window (default), sliding_window or token_bucket
+
+	// Hierarchical quotas: the org's aggregate limit across all of its apps
+	// (which also bounds the app limits above) and the fraction of the app
+	// limits each API key may use on its own
+	OrgRequestsPerMinute int     `json:"org_requests_per_minute,omitempty"`
+	OrgTokensPerMinute   int     `json:"org_tokens_per_minute,omitempty"`
+	KeyShare             float64 `json:"key_share,omitempty"` // 0 < key_share <= 1; 0 disables per-key limits
+
+	// Queueing: instead of an immediate 429, hold a rate limited request for
+	// up to QueueTimeoutSeconds until capacity frees up. At most QueueSize
+	// requests per app wait at once (default 100); beyond that they're rejected.
+	QueueTimeoutSeconds int `json:"queue_timeout_seconds,omitempty"`
+	QueueSize           int `json:"queue_size,omitempty"`
 }
 
 type TokenLimitConfig struct {
@@ -48,3 +109,139 @@ type ModelAllowlistConfig struct {
 type RequestSizeConfig struct {
 	MaxRequestBytes int `json:"max_request_bytes"`
 }
+
+// Budget scopes, periods and actions
+const (
+	BudgetScopeApp = "app"
+	BudgetScopeOrg = "org"
+	BudgetScopeKey = "key"
+
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodMonthly = "monthly"
+
+	BudgetActionBlock     = "block"
+	BudgetActionDowngrade = "downgrade"
+)
+
+// Concurrency limit scopes
+const (
+	ConcurrencyScopeApp = "app"
+	ConcurrencyScopeKey = "key"
+)
+
+// ConcurrencyLimitConfig caps requests in flight at once for an app or key.
+// Streams are also counted as in-flight requests.
+type ConcurrencyLimitConfig struct {
+	Scope        string `json:"scope"`                   // app (default) or key
+	MaxInFlight  int    `json:"max_in_flight"`           // 0 = unlimited
+	MaxStreams   int    `json:"max_streams"`             // concurrent streaming requests; 0 = unlimited
+	LeaseSeconds int    `json:"lease_seconds,omitempty"` // slot expiry if a gateway stops renewing it (default 60)
+}
+
+// Priority tiers, most important first. When a deployment is saturated
+// higher tiers are admitted before lower ones.
+const (
+	PriorityCritical = "critical"
+	PriorityHigh     = "high"
+	PriorityStandard = "standard" // apps without a priority policy
+	PriorityLow      = "low"
+)
+
+// PriorityConfig assigns an app's requests a scheduling tier
+type PriorityConfig struct {
+	Tier           string `json:"tier"`
+	MaxWaitSeconds *int   `json:"max_wait_seconds,omitempty"` // queueing time when saturated; 0 sheds at once (default depends on tier)
+}
+
+type BudgetConfig struct {
+	Scope           string  `json:"scope"`            // app (default), org or key
+	Period          string  `json:"period"`           // daily or monthly (default)
+	Limit           float64 `json:"limit"`            // hard cap in currency units
+	Currency        string  `json:"currency"`         // defaults to USD
+	AlertThresholds []int   `json:"alert_thresholds"` // percent of Limit; defaults to 80 and 100
+	Action          string  `json:"action"`           // at the cap: block (default) or downgrade
+	DowngradeModel  string  `json:"downgrade_model"`  // required when Action is downgrade
+}
+
+// PII detectors and actions
+const (
+	PIIEmail      = "email"
+	PIIPhone      = "phone"
+	PIICreditCard = "credit_card" // Luhn checked
+	PIIIBAN       = "iban"        // mod-97 checked
+	PIINationalID = "national_id" // US SSN and UK National Insurance numbers
+
+	PIIActionBlock = "block"
+	PIIActionMask  = "mask"
+	PIIActionTag   = "tag"
+)
+
+type PIIConfig struct {
+	Detectors      []string     `json:"detectors,omitempty"`       // built-in detectors to run; defaults to all of them
+	CustomPatterns []PIIPattern `json:"custom_patterns,omitempty"` // extra regexes, e.g. employee or customer numbers
+	Action         string       `json:"action,omitempty"`          // block (default), mask or tag
+}
+
+// PIIPattern is a named custom regex; the name labels masks and tags
+type PIIPattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// TransformConfig rewrites requests before they are sent upstream
+type TransformConfig struct {
+	MaxTokens      int               `json:"max_tokens,omitempty"`       // clamp the completion token limit, setting it when the caller didn't
+	MaxTokensField string            `json:"max_tokens_field,omitempty"` // field set on chat requests without a limit; defaults to max_completion_tokens
+	Set            map[string]any    `json:"set,omitempty"`              // body parameters to force, e.g. {"temperature": 0}
+	Remove         []string          `json:"remove,omitempty"`           // body parameters to strip, e.g. ["logit_bias"]
+	SystemPrompt   string            `json:"system_prompt,omitempty"`    // system message prepended to chat requests
+	Headers        map[string]string `json:"headers,omitempty"`          // upstream headers to set
+}
+
+// Response guard actions
+const (
+	ResponseGuardRedact = "redact"
+	ResponseGuardRefuse = "refuse"
+)
+
+// ResponseGuardConfig inspects completions before the caller receives them
+type ResponseGuardConfig struct {
+	Denylist       []string        `json:"denylist,omitempty"`        // regular expressions the output must not contain
+	Keywords       []string        `json:"keywords,omitempty"`        // words or phrases, matched case-insensitively
+	PIIDetectors   []string        `json:"pii_detectors,omitempty"`   // PII detectors to run on the output
+	Secrets        bool            `json:"secrets,omitempty"`         // API keys, access tokens and private keys
+	JSONSchema     json.RawMessage `json:"json_schema,omitempty"`     // schema the whole completion must conform to
+	Action         string          `json:"action,omitempty"`          // redact or refuse (default); schema failures always refuse
+	RefusalMessage string          `json:"refusal_message,omitempty"` // completion text used when refusing
+}
+
+// PromptInjectionConfig scores prompts for injection and jailbreak attempts
+type PromptInjectionConfig struct {
+	FlagThreshold  float64            `json:"flag_threshold,omitempty"`  // score at which the request is tagged (default 0.5)
+	BlockThreshold float64            `json:"block_threshold,omitempty"` // score at which the request is rejected (default 0.8); above 1 never blocks
+	Roles          []string           `json:"roles,omitempty"`           // message roles to scan (default user, tool and function)
+	ExtraPatterns  []InjectionPattern `json:"extra_patterns,omitempty"`  // rules added to the built-in set
+}
+
+// InjectionPattern is a named rule; weight is how strongly a match suggests
+// an injection, between 0 and 1
+type InjectionPattern struct {
+	Name    string  `json:"name"`
+	Pattern string  `json:"pattern"`
+	Weight  float64 `json:"weight"`
+}
+
+// ToolAllowlistConfig restricts the tools chat requests may offer the model
+type ToolAllowlistConfig struct {
+	AllowedTools        []string `json:"allowed_tools,omitempty"`        // tool name patterns, e.g. "search_*"; empty allows any name
+	MaxTools            *int     `json:"max_tools,omitempty"`            // most tools per request; 0 forbids tools (default unlimited)
+	ForbiddenParameters []string `json:"forbidden_parameters,omitempty"` // argument name patterns tool schemas may not declare, e.g. "*command"
+}
+
+// ImageLimitConfig restricts the images chat requests may attach
+type ImageLimitConfig struct {
+	MaxImages     *int     `json:"max_images,omitempty"`      // most images per request; 0 forbids images (default unlimited)
+	MaxImageBytes int      `json:"max_image_bytes,omitempty"` // largest inline image; remote images aren't fetched to be measured
+	AllowedHosts  []string `json:"allowed_hosts,omitempty"`   // host patterns image URLs may point at, e.g. "*.blob.core.windows.net"; empty allows any
+	AllowDataURIs *bool    `json:"allow_data_uris,omitempty"` // inline base64 images (default true)
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Deployment types a price can be scoped to. Prices for "standard" apply to
+// any deployment type that has no price of its own.
+const (
+	DeploymentTypeStandard    = "standard"
+	DeploymentTypeGlobal      = "global"
+	DeploymentTypeProvisioned = "provisioned"
+	DeploymentTypeBatch       = "batch"
+)
+
+// DefaultCurrency is used when a price does not name one.
+const DefaultCurrency = "USD"
+
+// ModelPrice is one version of the price list for a model. A new version is
+// added rather than edited so historical usage keeps the price it was charged.
+type ModelPrice struct {
+	ID                    uuid.UUID
+	OrgID                 uuid.UUID
+	Provider              string
+	ModelName             string
+	DeploymentType        string
+	Currency              string
+	InputPerMillion       float64 // per million uncached prompt tokens
+	CachedInputPerMillion float64 // per million cached prompt tokens; zero falls back to InputPerMillion
+	OutputPerMillion      float64 // per million completion tokens
+	PerImage              float64
+	PerAudioSecond        float64
+	EffectiveFrom         time.Time
+	CreatedAt             time.Time
+}
+
+// BillableUsage is everything a single request can be charged for.
+type BillableUsage struct {
+	Tokens       TokenUsage
+	Images       int
+	AudioSeconds float64
+}
+
+// CostMicros prices usage in millionths of the price's currency. Storing
+// integer micros keeps sums exact across millions of rows.
+func (p *ModelPrice) CostMicros(u BillableUsage) int64 {
+	cached := min(max(u.Tokens.CachedPromptTokens, 0), u.Tokens.PromptTokens)
+	cachedRate := p.CachedInputPerMillion
+	if cachedRate == 0 {
+		cachedRate = p.InputPerMillion
+	}
+
+	// tokens x price-per-million is already in micros
+	cost := float64(u.Tokens.PromptTokens-cached)*p.InputPerMillion +
+		float64(cached)*cachedRate +
+		float64(u.Tokens.CompletionTokens)*p.OutputPerMillion +
+		(float64(u.Images)*p.PerImage+u.AudioSeconds*p.PerAudioSecond)*1e6
+	return int64(math.Round(cost))
+}
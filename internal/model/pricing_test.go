@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelPrice_CostMicros(t *testing.T) {
+	price := &ModelPrice{
+		InputPerMillion:       2.50,
+		CachedInputPerMillion: 1.25,
+		OutputPerMillion:      10,
+		PerImage:              0.04,
+		PerAudioSecond:        0.0001,
+	}
+
+	tests := []struct {
+		name     string
+		usage    BillableUsage
+		expected int64
+	}{
+		{
+			name:     "Tokens",
+			usage:    BillableUsage{Tokens: TokenUsage{PromptTokens: 1000, CompletionTokens: 500}},
+			expected: 2500 + 5000,
+		},
+		{
+			name:     "Cached prompt tokens",
+			usage:    BillableUsage{Tokens: TokenUsage{PromptTokens: 1000, CachedPromptTokens: 800, CompletionTokens: 0}},
+			expected: 200*2.5 + 800*1.25,
+		},
+		{
+			name:     "Cached count is capped at prompt tokens",
+			usage:    BillableUsage{Tokens: TokenUsage{PromptTokens: 100, CachedPromptTokens: 500}},
+			expected: 125,
+		},
+		{
+			name:     "Images and audio",
+			usage:    BillableUsage{Images: 2, AudioSeconds: 30},
+			expected: 80000 + 3000,
+		},
+		{
+			name:     "Nothing billable",
+			usage:    BillableUsage{},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, price.CostMicros(tt.usage))
+		})
+	}
+
+	t.Run("Cached price falls back to input price", func(t *testing.T) {
+		p := &ModelPrice{InputPerMillion: 3}
+		usage := BillableUsage{Tokens: TokenUsage{PromptTokens: 10, CachedPromptTokens: 10}}
+		assert.Equal(t, int64(30), p.CostMicros(usage))
+	})
+}
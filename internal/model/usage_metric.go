@@ -20,10 +20,15 @@ type UsageMetric struct {
 	RequestSizeBytes  int
 	ResponseSizeBytes int
 	Timestamp         time.Time
+	Estimated         bool   // token counts were computed by the gateway, not reported upstream
+	CostMicros        *int64 // nil when no price was in force for the model
+	Currency          string
+	Metadata          map[string]any // values recorded by policies, e.g. injection_score and tags
 }
 
 type TokenUsage struct {
-	PromptTokens     int
-	CompletionTokens int
-	TotalTokens      int
+	PromptTokens       int
+	CompletionTokens   int
+	TotalTokens        int
+	CachedPromptTokens int // portion of PromptTokens served from the provider's prompt cache
 }
@@ -41,6 +41,9 @@ type Observability struct {
 	PolicyCacheMisses   metric.Int64Counter
 	PolicyLoadErrors    metric.Int64Counter
 	PolicyViolations    metric.Int64Counter
+	PolicyAlerts        metric.Int64Counter
+	PolicyEvents        metric.Int64Counter
+	PolicyDryRuns       metric.Int64Counter
 
 	// Rate limiter metrics
 	RateLimitViolations metric.Int64Counter
@@ -51,6 +54,7 @@ type Observability struct {
 	PromptTokens            metric.Int64Histogram
 	CompletionTokens        metric.Int64Histogram
 	TotalTokens             metric.Int64Histogram
+	TimeToFirstToken        metric.Float64Histogram
 
 	// Request metrics
 	RequestDuration metric.Float64Histogram
@@ -179,6 +183,30 @@ func (o *Observability) initMetrics() error {
 		return err
 	}
 
+	o.PolicyAlerts, err = o.meter.Int64Counter(
+		"policy.alerts",
+		metric.WithDescription("Number of policy alert events (e.g. budget thresholds reached)"),
+	)
+	if err != nil {
+		return err
+	}
+
+	o.PolicyEvents, err = o.meter.Int64Counter(
+		"policy.events",
+		metric.WithDescription("Number of named events emitted by policy post-checks"),
+	)
+	if err != nil {
+		return err
+	}
+
+	o.PolicyDryRuns, err = o.meter.Int64Counter(
+		"policy.dry_run_violations",
+		metric.WithDescription("Number of times a dry-run policy would have blocked a request or taken an action"),
+	)
+	if err != nil {
+		return err
+	}
+
 	// Rate limiter metrics
 	o.RateLimitViolations, err = o.meter.Int64Counter(
 		"ratelimit.violations",
@@ -230,6 +258,15 @@ func (o *Observability) initMetrics() error {
 		return err
 	}
 
+	o.TimeToFirstToken, err = o.meter.Float64Histogram(
+		"llm.time_to_first_token",
+		metric.WithDescription("Time from sending a streamed request to its first content token in milliseconds"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return err
+	}
+
 	// Request metrics
 	o.RequestDuration, err = o.meter.Float64Histogram(
 		"http.request.duration",
@@ -28,6 +28,43 @@ func (o *Observability) RecordPolicyCheck(ctx context.Context, policyType string
 	}
 }
 
+// RecordPolicyAlert records an alert event raised by a policy
+func (o *Observability) RecordPolicyAlert(ctx context.Context, policyType, alertType string) {
+	if o.PolicyAlerts == nil {
+		return
+	}
+
+	o.PolicyAlerts.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("policy.type", policyType),
+		attribute.String("alert.type", alertType),
+	))
+}
+
+// RecordPolicyEvent records a named event emitted by a policy post-check
+func (o *Observability) RecordPolicyEvent(ctx context.Context, policyType, eventName string) {
+	if o.PolicyEvents == nil {
+		return
+	}
+
+	o.PolicyEvents.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("policy.type", policyType),
+		attribute.String("event.name", eventName),
+	))
+}
+
+// RecordPolicyDryRun records a violation (or action) of a dry-run policy
+// that was let through; phase is where it happened, e.g. "pre_check"
+func (o *Observability) RecordPolicyDryRun(ctx context.Context, policyType, phase string) {
+	if o.PolicyDryRuns == nil {
+		return
+	}
+
+	o.PolicyDryRuns.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("policy.type", policyType),
+		attribute.String("policy.phase", phase),
+	))
+}
+
 // RecordPolicyCacheHit records a policy cache hit
 func (o *Observability) RecordPolicyCacheHit(ctx context.Context, tier string) {
 	if o.PolicyCacheHits == nil {
@@ -114,6 +151,20 @@ func (o *Observability) RecordLLMTokens(ctx context.Context, provider, model str
 	}
 }
 
+// RecordTimeToFirstToken records latency to the first streamed content token
+func (o *Observability) RecordTimeToFirstToken(ctx context.Context, provider, model string, ttft time.Duration) {
+	if o.TimeToFirstToken == nil {
+		return
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("provider", provider),
+		attribute.String("model", model),
+	}
+
+	o.TimeToFirstToken.Record(ctx, float64(ttft.Milliseconds()), metric.WithAttributes(attrs...))
+}
+
 // RecordHTTPRequest records HTTP request metrics
 func (o *Observability) RecordHTTPRequest(ctx context.Context, method, path string, statusCode int, duration time.Duration, requestSize, responseSize int) {
 	attrs := []attribute.KeyValue{
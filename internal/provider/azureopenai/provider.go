@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/loadbalancing"
@@ -18,6 +19,7 @@ type Entry struct {
 	Deployment string // AOAI deployment name
 	APIVer     string // e.g., "2024-07-01-preview"
 	SecretRef  string
+	Type       string // pricing tier: standard (default), global, provisioned or batch
 }
 
 type Adapter struct {
@@ -38,6 +40,9 @@ func (a *Adapter) Prefix() string { return provider.AzureOpenAIPrefix }
 
 func (a *Adapter) Rewrite(req *http.Request, suffix string, info provider.ReqInfo) error {
 	modelKey := strings.ToLower(strings.TrimSpace(info.Model))
+	if provider.IsAccountScopedPath(suffix) {
+		return a.rewriteAccountScoped(req, suffix, modelKey, info)
+	}
 	instances, ok := a.Instances[modelKey]
 	if !ok || len(instances) == 0 {
 		return fmt.Errorf("no deployments found for model %q", info.Model)
@@ -75,7 +80,54 @@ func (a *Adapter) Rewrite(req *http.Request, suffix string, info provider.ReqInf
 		return err
 	}
 	provider.SetUpstreamURL(req, u)
+	provider.SetDeploymentType(req, ent.Type)
+	a.setAuth(req, ent, info)
+	return nil
+}
+
+// rewriteAccountScoped targets /openai/files and /openai/batches, which live on the
+// Azure resource rather than a deployment. The resource is taken from the requested
+// model when present, otherwise from the first configured model.
+func (a *Adapter) rewriteAccountScoped(req *http.Request, suffix, modelKey string, info provider.ReqInfo) error {
+	ent, ok := a.resourceEntry(modelKey)
+	if !ok {
+		return fmt.Errorf("no azure resource configured for %s", suffix)
+	}
+	base, err := provider.EnsureAbsoluteBase(ent.BaseURL, "openai.azure.com")
+	if err != nil {
+		return err
+	}
+	q := provider.CopyQuery(req)
+	q.Set("api-version", ent.APIVer)
+	u, err := provider.JoinURL(base, []string{"/openai", strings.TrimPrefix(suffix, "/v1")}, q)
+	if err != nil {
+		return err
+	}
+	provider.SetUpstreamURL(req, u)
+	a.setAuth(req, ent, info)
+	return nil
+}
+
+func (a *Adapter) resourceEntry(modelKey string) (Entry, bool) {
+	if ents := a.Instances[modelKey]; len(ents) > 0 && ents[0].BaseURL != "" {
+		return ents[0], true
+	}
+	keys := make([]string, 0, len(a.Instances))
+	for k := range a.Instances {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, ent := range a.Instances[k] {
+			if ent.BaseURL != "" && ent.APIVer != "" {
+				return ent, true
+			}
+		}
+	}
+	return Entry{}, false
+}
 
+func (a *Adapter) setAuth(req *http.Request, ent Entry, info provider.ReqInfo) {
 	provider.StripCallerAuth(req.Header)
 	key := a.Keys.Resolve(info.Tenant, "AZURE_OPENAI_API_KEY")
 	if ent.SecretRef != "" {
@@ -84,7 +136,6 @@ func (a *Adapter) Rewrite(req *http.Request, suffix string, info provider.ReqInf
 		}
 	}
 	provider.SetAPIKey(req.Header, "api-key", key)
-	return nil
 }
 
 // BuildProvider builds and returns a provider.Adapter configured with all models/deployments.
@@ -103,6 +154,7 @@ func BuildProvider(deployments []model.ModelDeployment, selector loadbalancing.I
 			Deployment: md.Deployment,
 			APIVer:     md.Meta["APIVer"],
 			SecretRef:  md.Meta["SecretRef"],
+			Type:       md.Meta["DeploymentType"],
 		}
 		adapter.Instances[md.Model] = append(adapter.Instances[md.Model], ent)
 	}
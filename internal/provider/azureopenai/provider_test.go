@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/gateway/loadbalancing"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
 	"github.com/WebDeveloperBen/ai-gateway/internal/provider"
 	aoai "github.com/WebDeveloperBen/ai-gateway/internal/provider/azureopenai"
 )
@@ -42,6 +43,23 @@ func TestRewrite_GlobalMapping_URL_Auth(t *testing.T) {
 	require.Equal(t, req.URL.Host, req.Host)            // Host aligned
 }
 
+func TestRewrite_RecordsDeploymentType(t *testing.T) {
+	meta := map[string]string{"BaseURL": "myres.openai.azure.com", "APIVer": "2024-07-01-preview"}
+	globalMeta := map[string]string{"BaseURL": "myres.openai.azure.com", "APIVer": "2024-07-01-preview", "DeploymentType": model.DeploymentTypeGlobal}
+	ad := aoai.BuildProvider([]model.ModelDeployment{
+		{Model: "gpt-4o", Deployment: "dep-global", Provider: "azure", Meta: globalMeta},
+		{Model: "gpt-4o-mini", Deployment: "dep-standard", Provider: "azure", Meta: meta},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"gpt-4o"}`))
+	require.NoError(t, ad.Rewrite(req, "/v1/chat/completions", provider.ReqInfo{Model: "gpt-4o"}))
+	require.Equal(t, model.DeploymentTypeGlobal, provider.DeploymentType(req.Context()))
+
+	// Routing again (e.g. a policy reroute) replaces the recorded type
+	require.NoError(t, ad.Rewrite(req, "/v1/chat/completions", provider.ReqInfo{Model: "gpt-4o-mini"}))
+	require.Empty(t, provider.DeploymentType(req.Context()))
+}
+
 func TestRewrite_MergesQuery_AndTrimsV1_ForEmbeddings(t *testing.T) {
 	ad := aoai.New(loadbalancing.NewRoundRobinSelector())
 	ad.Instances["gpt-4o"] = []aoai.Entry{{
@@ -174,3 +192,31 @@ func TestRewrite_ModelLookup_IsCaseInsensitive_AndTrimmed(t *testing.T) {
 	got, _ := io.ReadAll(req.Body)
 	require.Contains(t, string(got), "GPT-4O")
 }
+
+func TestRewrite_BatchesTargetResourceWithoutDeployment(t *testing.T) {
+	ad := aoai.New(loadbalancing.NewRoundRobinSelector())
+	ad.Keys = provider.KeySource{ForTenant: func(string) string { return "k123" }}
+	ad.Instances["gpt-4o"] = []aoai.Entry{{
+		BaseURL:    "myres.openai.azure.com",
+		Deployment: "dep-123",
+		APIVer:     "2024-10-21",
+	}}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/batches/batch_abc", nil)
+	err := ad.Rewrite(req, "/v1/batches/batch_abc", provider.ReqInfo{})
+	require.NoError(t, err)
+
+	require.Equal(t, "myres.openai.azure.com", req.URL.Host)
+	require.Equal(t, "/openai/batches/batch_abc", req.URL.Path)
+	q, _ := url.ParseQuery(req.URL.RawQuery)
+	require.Equal(t, "2024-10-21", q.Get("api-version"))
+	require.Equal(t, "k123", req.Header.Get("api-key"))
+}
+
+func TestRewrite_FilesFailWithoutResource(t *testing.T) {
+	ad := aoai.New(loadbalancing.NewRoundRobinSelector())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/files", nil)
+	err := ad.Rewrite(req, "/v1/files", provider.ReqInfo{})
+	require.Error(t, err)
+}
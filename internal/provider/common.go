@@ -2,6 +2,7 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -67,6 +68,22 @@ func SetUpstreamURL(req *http.Request, u *url.URL) {
 	req.Host = u.Host
 }
 
+type deploymentTypeKey struct{}
+
+// SetDeploymentType records the pricing tier (standard, global, provisioned,
+// batch) of the deployment req was routed to. An empty type means standard.
+func SetDeploymentType(req *http.Request, deploymentType string) {
+	*req = *req.WithContext(context.WithValue(req.Context(), deploymentTypeKey{}, deploymentType))
+}
+
+// DeploymentType returns the pricing tier recorded by SetDeploymentType
+func DeploymentType(ctx context.Context) string {
+	if t, ok := ctx.Value(deploymentTypeKey{}).(string); ok {
+		return t
+	}
+	return ""
+}
+
 // CopyQuery merges req.URL.RawQuery into the provided values.
 func CopyQuery(req *http.Request) url.Values {
 	if req.URL == nil {
@@ -101,6 +118,33 @@ func ForceContentLength(req *http.Request, bodyLen int) {
 	req.Header.Del("Content-Encoding") // ensure we send raw JSON
 }
 
+// IsAccountScopedPath reports whether suffix targets a resource that lives on the
+// provider account rather than a model deployment (Files and Batch APIs).
+func IsAccountScopedPath(suffix string) bool {
+	for _, p := range []string{"/v1/files", "/v1/batches"} {
+		if suffix == p || strings.HasPrefix(suffix, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// EndpointKind names the API a "/v1/..." suffix belongs to: chat,
+// completions, embeddings, responses, images, audio, moderations, files,
+// batches, or other.
+func EndpointKind(suffix string) string {
+	rest, ok := strings.CutPrefix(suffix, "/v1/")
+	if !ok {
+		return "other"
+	}
+	kind, _, _ := strings.Cut(rest, "/")
+	switch kind {
+	case "chat", "completions", "embeddings", "responses", "images", "audio", "moderations", "files", "batches":
+		return kind
+	}
+	return "other"
+}
+
 // ModelOrDefault picks model, falling back to single/default entry.
 // Returns chosen key and ok = true if something usable exists.
 func ModelOrDefault(model string, hasExact func(string) bool, single func() (string, bool), fallbackExists bool, fallbackKey string) (string, bool) {
@@ -483,3 +483,20 @@ func TestRewriteJSONModel(t *testing.T) {
 	expected := `{"model":"gpt-4","temperature":0.5}`
 	assert.JSONEq(t, expected, string(resultBody))
 }
+
+func TestEndpointKind(t *testing.T) {
+	tests := map[string]string{
+		"/v1/chat/completions":       "chat",
+		"/v1/completions":            "completions",
+		"/v1/embeddings":             "embeddings",
+		"/v1/images/generations":     "images",
+		"/v1/audio/transcriptions":   "audio",
+		"/v1/batches/batch_1/cancel": "batches",
+		"/v1/files":                  "files",
+		"/v1/assistants":             "other",
+		"/healthz":                   "other",
+	}
+	for suffix, expected := range tests {
+		assert.Equal(t, expected, EndpointKind(suffix), suffix)
+	}
+}
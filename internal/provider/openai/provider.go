@@ -35,7 +35,7 @@ func (a *Adapter) Prefix() string { return provider.OpenAIPrefix }
 func (a *Adapter) Rewrite(req *http.Request, suffix string, info provider.ReqInfo) error {
 	modelKey := strings.ToLower(strings.TrimSpace(info.Model))
 	instances := a.Instances[modelKey]
-	if len(instances) == 0 {
+	if len(instances) == 0 && !provider.IsAccountScopedPath(suffix) {
 		return nil // fallback, no deployments
 	}
 	// Use chosen deployment ID (string) to set header or param as you need, for now just a placeholder
@@ -110,3 +110,16 @@ func TestRewrite_ModelAlias_IsCaseInsensitive(t *testing.T) {
 	_ = json.Unmarshal(b, &got)
 	require.Equal(t, "gpt-4o-2024-08-06", got["model"])
 }
+
+func TestRewrite_BatchesForwardWithoutModel(t *testing.T) {
+	ad := openai.New(loadbalancing.NewRoundRobinSelector())
+	ad.Keys = provider.KeySource{ForTenant: func(string) string { return "k123" }}
+
+	req := httptest.NewRequest("GET", "/v1/files/file-abc/content", nil)
+	err := ad.Rewrite(req, "/v1/files/file-abc/content", provider.ReqInfo{Tenant: "t1"})
+	require.NoError(t, err)
+
+	require.Equal(t, "api.openai.com", req.URL.Host)
+	require.Equal(t, "/v1/files/file-abc/content", req.URL.Path)
+	require.Equal(t, "Bearer k123", req.Header.Get("Authorization"))
+}
@@ -0,0 +1,38 @@
+// Package batches persists ownership and lifecycle state for upstream
+// Batch API jobs and files proxied through the gateway.
+package batches
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when no tracked file or job matches the lookup.
+var ErrNotFound = errors.New("batch resource not found")
+
+type Reader interface {
+	GetFile(ctx context.Context, provider, fileID string) (*model.BatchFile, error)
+	GetJob(ctx context.Context, provider, batchID string) (*model.BatchJob, error)
+	// ListPendingJobs returns jobs whose usage is not yet recorded, are due a
+	// poll and have failed fewer than maxFailures polls in a row
+	ListPendingJobs(ctx context.Context, limit, maxFailures int) ([]*model.BatchJob, error)
+}
+
+type Writer interface {
+	CreateFile(ctx context.Context, f model.BatchFile) error
+	CreateJob(ctx context.Context, j model.BatchJob) (*model.BatchJob, error)
+	UpdateJobStatus(ctx context.Context, id uuid.UUID, status model.BatchStatus, outputFileID, errorFileID string, completedAt *time.Time) error
+	// RecordUsage writes the job's usage rows and marks its usage recorded in
+	// one transaction; a job already recorded is left alone
+	RecordUsage(ctx context.Context, id uuid.UUID, metrics []*model.UsageMetric) error
+	SetPollState(ctx context.Context, id uuid.UUID, failures int, nextPollAt time.Time, pollErr string) error
+}
+
+type Repository interface {
+	Reader
+	Writer
+}
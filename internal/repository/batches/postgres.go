@@ -0,0 +1,183 @@
+package batches
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/db"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/repository"
+	"github.com/WebDeveloperBen/ai-gateway/internal/repository/usage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type postgresRepo struct {
+	q    *db.Queries
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRepo creates a batch repository. The pool is needed to record
+// usage and mark it recorded in one transaction.
+func NewPostgresRepo(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{q: db.New(pool), pool: pool}
+}
+
+func (r *postgresRepo) GetFile(ctx context.Context, provider, fileID string) (*model.BatchFile, error) {
+	row, err := r.q.GetBatchFile(ctx, db.GetBatchFileParams{Provider: provider, FileID: fileID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &model.BatchFile{
+		ID:        row.ID,
+		OrgID:     row.OrgID,
+		AppID:     row.AppID,
+		KeyPrefix: row.KeyPrefix,
+		Provider:  row.Provider,
+		FileID:    row.FileID,
+		Purpose:   row.Purpose,
+		CreatedAt: row.CreatedAt.Time,
+	}, nil
+}
+
+func (r *postgresRepo) GetJob(ctx context.Context, provider, batchID string) (*model.BatchJob, error) {
+	row, err := r.q.GetBatchJob(ctx, db.GetBatchJobParams{Provider: provider, BatchID: batchID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return toBatchJob(row), nil
+}
+
+func (r *postgresRepo) ListPendingJobs(ctx context.Context, limit, maxFailures int) ([]*model.BatchJob, error) {
+	rows, err := r.q.ListPendingBatchJobs(ctx, db.ListPendingBatchJobsParams{
+		MaxFailures: int32(maxFailures),
+		RowLimit:    int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.BatchJob, len(rows))
+	for i, row := range rows {
+		result[i] = toBatchJob(row)
+	}
+	return result, nil
+}
+
+func (r *postgresRepo) CreateFile(ctx context.Context, f model.BatchFile) error {
+	return r.q.CreateBatchFile(ctx, db.CreateBatchFileParams{
+		OrgID:     f.OrgID,
+		AppID:     f.AppID,
+		KeyPrefix: f.KeyPrefix,
+		Provider:  f.Provider,
+		FileID:    f.FileID,
+		Purpose:   f.Purpose,
+	})
+}
+
+func (r *postgresRepo) CreateJob(ctx context.Context, j model.BatchJob) (*model.BatchJob, error) {
+	row, err := r.q.CreateBatchJob(ctx, db.CreateBatchJobParams{
+		OrgID:       j.OrgID,
+		AppID:       j.AppID,
+		KeyPrefix:   j.KeyPrefix,
+		Provider:    j.Provider,
+		BatchID:     j.BatchID,
+		Endpoint:    j.Endpoint,
+		Status:      string(j.Status),
+		InputFileID: j.InputFileID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toBatchJob(row), nil
+}
+
+func (r *postgresRepo) UpdateJobStatus(ctx context.Context, id uuid.UUID, status model.BatchStatus, outputFileID, errorFileID string, completedAt *time.Time) error {
+	var completed pgtype.Timestamptz
+	if completedAt != nil {
+		completed = pgtype.Timestamptz{Time: *completedAt, Valid: true}
+	}
+	return r.q.UpdateBatchJobStatus(ctx, db.UpdateBatchJobStatusParams{
+		ID:           id,
+		Status:       string(status),
+		OutputFileID: optionalString(outputFileID),
+		ErrorFileID:  optionalString(errorFileID),
+		CompletedAt:  completed,
+	})
+}
+
+func (r *postgresRepo) RecordUsage(ctx context.Context, id uuid.UUID, metrics []*model.UsageMetric) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	q := r.q.WithTx(tx)
+	marked, err := q.MarkBatchJobUsageRecorded(ctx, id)
+	if err != nil {
+		return err
+	}
+	if marked == 0 {
+		// Another poller got there first
+		return nil
+	}
+	usageRepo := usage.NewPostgresRepo(q)
+	for _, metric := range metrics {
+		if err := usageRepo.Create(ctx, metric); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *postgresRepo) SetPollState(ctx context.Context, id uuid.UUID, failures int, nextPollAt time.Time, pollErr string) error {
+	return r.q.SetBatchJobPollState(ctx, db.SetBatchJobPollStateParams{
+		ID:           id,
+		PollFailures: int32(failures),
+		NextPollAt:   pgtype.Timestamptz{Time: nextPollAt, Valid: true},
+		PollError:    optionalString(pollErr),
+	})
+}
+
+func toBatchJob(row db.BatchJob) *model.BatchJob {
+	var completedAt *time.Time
+	if row.CompletedAt.Valid {
+		completedAt = &row.CompletedAt.Time
+	}
+	return &model.BatchJob{
+		ID:            row.ID,
+		OrgID:         row.OrgID,
+		AppID:         row.AppID,
+		KeyPrefix:     row.KeyPrefix,
+		Provider:      row.Provider,
+		BatchID:       row.BatchID,
+		Endpoint:      row.Endpoint,
+		Status:        model.BatchStatus(row.Status),
+		InputFileID:   row.InputFileID,
+		OutputFileID:  repository.DerefString(row.OutputFileID),
+		ErrorFileID:   repository.DerefString(row.ErrorFileID),
+		UsageRecorded: row.UsageRecorded,
+		CompletedAt:   completedAt,
+		CreatedAt:     row.CreatedAt.Time,
+		UpdatedAt:     row.UpdatedAt.Time,
+		PollFailures:  int(row.PollFailures),
+		NextPollAt:    row.NextPollAt.Time,
+		PollError:     repository.DerefString(row.PollError),
+	}
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -2,27 +2,37 @@ package policies
 
 import (
 	"context"
+	"errors"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/model"
 	"github.com/google/uuid"
 )
 
+// ErrNotFound is returned when a policy or the scope it is attached to is not
+// in the caller's organisation
+var ErrNotFound = errors.New("policy or scope target not found")
+
 type Reader interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*model.Policy, error)
 	ListByAppID(ctx context.Context, appID uuid.UUID, limit, offset int) ([]*model.Policy, error)
 	ListEnabledByAppID(ctx context.Context, appID uuid.UUID, limit, offset int) ([]*model.Policy, error)
 	GetByType(ctx context.Context, appID uuid.UUID, policyType model.PolicyType) ([]*model.Policy, error)
 	GetAppsForPolicy(ctx context.Context, policyID uuid.UUID) ([]*model.Application, error)
+	ListAttachments(ctx context.Context, policyID uuid.UUID) ([]model.PolicyAttachment, error)
+	// GetKeyPrefix returns the prefix the gateway identifies an API key by
+	GetKeyPrefix(ctx context.Context, keyID uuid.UUID) (string, error)
 }
 
 type Writer interface {
-	Create(ctx context.Context, orgID uuid.UUID, policyType model.PolicyType, config map[string]any, enabled bool) (*model.Policy, error)
-	Update(ctx context.Context, id uuid.UUID, policyType model.PolicyType, config map[string]any, enabled bool) (*model.Policy, error)
+	Create(ctx context.Context, orgID uuid.UUID, policyType model.PolicyType, config map[string]any, enabled bool, mode model.PolicyMode) (*model.Policy, error)
+	Update(ctx context.Context, id uuid.UUID, policyType model.PolicyType, config map[string]any, enabled bool, mode model.PolicyMode) (*model.Policy, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 	Enable(ctx context.Context, id uuid.UUID) error
 	Disable(ctx context.Context, id uuid.UUID) error
 	AttachToApp(ctx context.Context, policyID, appID uuid.UUID) error
 	DetachFromApp(ctx context.Context, policyID, appID uuid.UUID) error
+	AttachToScope(ctx context.Context, orgID, policyID uuid.UUID, scope model.PolicyScope, targetID uuid.UUID) error
+	DetachFromScope(ctx context.Context, orgID, policyID uuid.UUID, scope model.PolicyScope, targetID uuid.UUID) error
 }
 
 type Repository interface {
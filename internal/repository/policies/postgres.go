@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/db"
 	"github.com/WebDeveloperBen/ai-gateway/internal/exceptions/pg"
@@ -38,6 +40,7 @@ func (r *postgresRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.Policy
 		PolicyType: model.PolicyType(policy.PolicyType),
 		Config:     config,
 		Enabled:    policy.Enabled,
+		Mode:       model.PolicyMode(policy.Mode),
 		CreatedAt:  policy.CreatedAt.Time,
 		UpdatedAt:  policy.UpdatedAt.Time,
 	}, nil
@@ -66,6 +69,7 @@ func (r *postgresRepo) ListByAppID(ctx context.Context, appID uuid.UUID, limit,
 			PolicyType: model.PolicyType(policy.PolicyType),
 			Config:     config,
 			Enabled:    policy.Enabled,
+			Mode:       model.PolicyMode(policy.Mode),
 			CreatedAt:  policy.CreatedAt.Time,
 			UpdatedAt:  policy.UpdatedAt.Time,
 		}
@@ -96,6 +100,7 @@ func (r *postgresRepo) ListEnabledByAppID(ctx context.Context, appID uuid.UUID,
 			PolicyType: model.PolicyType(policy.PolicyType),
 			Config:     config,
 			Enabled:    policy.Enabled,
+			Mode:       model.PolicyMode(policy.Mode),
 			CreatedAt:  policy.CreatedAt.Time,
 			UpdatedAt:  policy.UpdatedAt.Time,
 		}
@@ -125,6 +130,7 @@ func (r *postgresRepo) GetByType(ctx context.Context, appID uuid.UUID, policyTyp
 			PolicyType: model.PolicyType(policy.PolicyType),
 			Config:     config,
 			Enabled:    policy.Enabled,
+			Mode:       model.PolicyMode(policy.Mode),
 			CreatedAt:  policy.CreatedAt.Time,
 			UpdatedAt:  policy.UpdatedAt.Time,
 		}
@@ -132,7 +138,7 @@ func (r *postgresRepo) GetByType(ctx context.Context, appID uuid.UUID, policyTyp
 	return result, nil
 }
 
-func (r *postgresRepo) Create(ctx context.Context, orgID uuid.UUID, policyType model.PolicyType, config map[string]any, enabled bool) (*model.Policy, error) {
+func (r *postgresRepo) Create(ctx context.Context, orgID uuid.UUID, policyType model.PolicyType, config map[string]any, enabled bool, mode model.PolicyMode) (*model.Policy, error) {
 	if orgID == uuid.Nil {
 		return nil, errors.New("orgID cannot be nil")
 	}
@@ -140,6 +146,11 @@ func (r *postgresRepo) Create(ctx context.Context, orgID uuid.UUID, policyType m
 		return nil, errors.New("policyType cannot be empty")
 	}
 
+	mode, err := normalizeMode(mode)
+	if err != nil {
+		return nil, err
+	}
+
 	configBytes, err := r.marshalConfig(config)
 	if err != nil {
 		return nil, err
@@ -150,6 +161,7 @@ func (r *postgresRepo) Create(ctx context.Context, orgID uuid.UUID, policyType m
 		PolicyType: string(policyType),
 		Config:     configBytes,
 		Enabled:    enabled,
+		Mode:       string(mode),
 	})
 	if err != nil {
 		return nil, err
@@ -161,12 +173,13 @@ func (r *postgresRepo) Create(ctx context.Context, orgID uuid.UUID, policyType m
 		PolicyType: model.PolicyType(policy.PolicyType),
 		Config:     config,
 		Enabled:    policy.Enabled,
+		Mode:       model.PolicyMode(policy.Mode),
 		CreatedAt:  policy.CreatedAt.Time,
 		UpdatedAt:  policy.UpdatedAt.Time,
 	}, nil
 }
 
-func (r *postgresRepo) Update(ctx context.Context, id uuid.UUID, policyType model.PolicyType, config map[string]any, enabled bool) (*model.Policy, error) {
+func (r *postgresRepo) Update(ctx context.Context, id uuid.UUID, policyType model.PolicyType, config map[string]any, enabled bool, mode model.PolicyMode) (*model.Policy, error) {
 	if id == uuid.Nil {
 		return nil, errors.New("id cannot be nil")
 	}
@@ -174,6 +187,11 @@ func (r *postgresRepo) Update(ctx context.Context, id uuid.UUID, policyType mode
 		return nil, errors.New("policyType cannot be empty")
 	}
 
+	mode, err := normalizeMode(mode)
+	if err != nil {
+		return nil, err
+	}
+
 	configBytes, err := r.marshalConfig(config)
 	if err != nil {
 		return nil, err
@@ -184,6 +202,7 @@ func (r *postgresRepo) Update(ctx context.Context, id uuid.UUID, policyType mode
 		PolicyType: string(policyType),
 		Config:     configBytes,
 		Enabled:    enabled,
+		Mode:       string(mode),
 	})
 	if err != nil {
 		return nil, err
@@ -195,6 +214,7 @@ func (r *postgresRepo) Update(ctx context.Context, id uuid.UUID, policyType mode
 		PolicyType: model.PolicyType(policy.PolicyType),
 		Config:     config,
 		Enabled:    policy.Enabled,
+		Mode:       model.PolicyMode(policy.Mode),
 		CreatedAt:  policy.CreatedAt.Time,
 		UpdatedAt:  policy.UpdatedAt.Time,
 	}, nil
@@ -273,6 +293,96 @@ func (r *postgresRepo) DetachFromApp(ctx context.Context, policyID, appID uuid.U
 	})
 }
 
+// AttachToScope attaches a policy to an org, app, end-user or API key. Both
+// must belong to orgID, otherwise ErrNotFound is returned.
+func (r *postgresRepo) AttachToScope(ctx context.Context, orgID, policyID uuid.UUID, scope model.PolicyScope, targetID uuid.UUID) error {
+	if err := r.checkScope(ctx, orgID, policyID, scope, targetID); err != nil {
+		return err
+	}
+
+	switch scope {
+	case model.PolicyScopeOrg:
+		return r.q.AttachPolicyToOrg(ctx, db.AttachPolicyToOrgParams{PolicyID: policyID, OrgID: targetID})
+	case model.PolicyScopeApp:
+		return r.AttachToApp(ctx, policyID, targetID)
+	case model.PolicyScopeUser:
+		return r.q.AttachPolicyToUser(ctx, db.AttachPolicyToUserParams{PolicyID: policyID, UserID: targetID})
+	case model.PolicyScopeKey:
+		return r.q.AttachPolicyToAPIKey(ctx, db.AttachPolicyToAPIKeyParams{PolicyID: policyID, KeyID: targetID})
+	default:
+		return fmt.Errorf("unknown policy scope: %s", scope)
+	}
+}
+
+// DetachFromScope detaches a policy from an org, app, end-user or API key.
+// Both must belong to orgID, otherwise ErrNotFound is returned.
+func (r *postgresRepo) DetachFromScope(ctx context.Context, orgID, policyID uuid.UUID, scope model.PolicyScope, targetID uuid.UUID) error {
+	if err := r.checkScope(ctx, orgID, policyID, scope, targetID); err != nil {
+		return err
+	}
+
+	switch scope {
+	case model.PolicyScopeOrg:
+		return r.q.DetachPolicyFromOrg(ctx, db.DetachPolicyFromOrgParams{PolicyID: policyID, OrgID: targetID})
+	case model.PolicyScopeApp:
+		return r.DetachFromApp(ctx, policyID, targetID)
+	case model.PolicyScopeUser:
+		return r.q.DetachPolicyFromUser(ctx, db.DetachPolicyFromUserParams{PolicyID: policyID, UserID: targetID})
+	case model.PolicyScopeKey:
+		return r.q.DetachPolicyFromAPIKey(ctx, db.DetachPolicyFromAPIKeyParams{PolicyID: policyID, KeyID: targetID})
+	default:
+		return fmt.Errorf("unknown policy scope: %s", scope)
+	}
+}
+
+func (r *postgresRepo) ListAttachments(ctx context.Context, policyID uuid.UUID) ([]model.PolicyAttachment, error) {
+	rows, err := r.q.ListPolicyAttachments(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.PolicyAttachment, len(rows))
+	for i, row := range rows {
+		result[i] = model.PolicyAttachment{Scope: model.PolicyScope(row.Scope), TargetID: row.TargetID}
+	}
+	return result, nil
+}
+
+func (r *postgresRepo) GetKeyPrefix(ctx context.Context, keyID uuid.UUID) (string, error) {
+	key, err := r.q.GetAPIKeyByID(ctx, keyID)
+	if err != nil {
+		return "", handleDBError(err)
+	}
+	return key.KeyPrefix, nil
+}
+
+// checkScope verifies that the policy and the scope target are in orgID
+func (r *postgresRepo) checkScope(ctx context.Context, orgID, policyID uuid.UUID, scope model.PolicyScope, targetID uuid.UUID) error {
+	if policyID == uuid.Nil {
+		return errors.New("policyID cannot be nil")
+	}
+	if targetID == uuid.Nil {
+		return errors.New("targetID cannot be nil")
+	}
+	if !slices.Contains(model.PolicyScopes, scope) {
+		return fmt.Errorf("unknown policy scope: %s", scope)
+	}
+
+	inOrg, err := r.q.PolicyScopeInOrg(ctx, db.PolicyScopeInOrgParams{
+		PolicyID: policyID,
+		OrgID:    orgID,
+		Scope:    string(scope),
+		TargetID: targetID,
+	})
+	if err != nil {
+		return err
+	}
+	if !inOrg {
+		return ErrNotFound
+	}
+	return nil
+}
+
 func (r *postgresRepo) marshalConfig(config map[string]any) ([]byte, error) {
 	if config == nil {
 		return []byte("{}"), nil
@@ -292,3 +402,15 @@ func (r *postgresRepo) unmarshalConfig(data []byte) (map[string]any, error) {
 	}
 	return config, nil
 }
+
+// normalizeMode defaults an unset mode to enforce and rejects unknown ones
+func normalizeMode(mode model.PolicyMode) (model.PolicyMode, error) {
+	switch mode {
+	case "":
+		return model.PolicyModeEnforce, nil
+	case model.PolicyModeEnforce, model.PolicyModeDryRun:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid mode %q", mode)
+	}
+}
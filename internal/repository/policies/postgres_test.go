@@ -63,6 +63,7 @@ func TestPostgresRepo_GetByID(t *testing.T) {
 	assert.Equal(t, orgID, policy.OrgID)
 	assert.Equal(t, model.PolicyTypeRateLimit, policy.PolicyType)
 	assert.True(t, policy.Enabled)
+	assert.Equal(t, model.PolicyModeEnforce, policy.Mode)
 	assert.NotZero(t, policy.CreatedAt)
 	assert.NotZero(t, policy.UpdatedAt)
 }
@@ -151,7 +152,7 @@ func TestPostgresRepo_Create(t *testing.T) {
 
 	// Test Create
 	config := map[string]any{"requests_per_minute": 150}
-	policy, err := repo.Create(ctx, orgID, model.PolicyTypeRateLimit, config, true)
+	policy, err := repo.Create(ctx, orgID, model.PolicyTypeRateLimit, config, true, model.PolicyModeEnforce)
 	require.NoError(t, err)
 	assert.Equal(t, orgID, policy.OrgID)
 	assert.Equal(t, model.PolicyTypeRateLimit, policy.PolicyType)
@@ -172,12 +173,13 @@ func TestPostgresRepo_Update(t *testing.T) {
 
 	// Test Update
 	newConfig := map[string]any{"requests_per_minute": 200}
-	updatedPolicy, err := repo.Update(ctx, policyID, model.PolicyTypeRateLimit, newConfig, false)
+	updatedPolicy, err := repo.Update(ctx, policyID, model.PolicyTypeRateLimit, newConfig, false, model.PolicyModeDryRun)
 	require.NoError(t, err)
 	assert.Equal(t, policyID, updatedPolicy.ID)
 	assert.Equal(t, model.PolicyTypeRateLimit, updatedPolicy.PolicyType)
 	assert.Equal(t, newConfig, updatedPolicy.Config)
 	assert.False(t, updatedPolicy.Enabled)
+	assert.Equal(t, model.PolicyModeDryRun, updatedPolicy.Mode)
 }
 
 func TestPostgresRepo_Enable_Disable(t *testing.T) {
@@ -244,14 +246,19 @@ func TestPostgresRepo_Create_Validation(t *testing.T) {
 	config := map[string]any{"test": "config"}
 
 	// Test Create with nil orgID
-	_, err := repo.Create(ctx, uuid.Nil, model.PolicyTypeRateLimit, config, true)
+	_, err := repo.Create(ctx, uuid.Nil, model.PolicyTypeRateLimit, config, true, model.PolicyModeEnforce)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "orgID cannot be nil")
 
 	// Test Create with empty policyType
-	_, err = repo.Create(ctx, uuid.New(), "", config, true)
+	_, err = repo.Create(ctx, uuid.New(), "", config, true, model.PolicyModeEnforce)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "policyType cannot be empty")
+
+	// Test Create with unknown mode
+	_, err = repo.Create(ctx, uuid.New(), model.PolicyTypeRateLimit, config, true, "shadow")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid mode")
 }
 
 func TestPostgresRepo_Update_Validation(t *testing.T) {
@@ -262,12 +269,12 @@ func TestPostgresRepo_Update_Validation(t *testing.T) {
 	config := map[string]any{"test": "config"}
 
 	// Test Update with nil ID
-	_, err := repo.Update(ctx, uuid.Nil, model.PolicyTypeRateLimit, config, true)
+	_, err := repo.Update(ctx, uuid.Nil, model.PolicyTypeRateLimit, config, true, model.PolicyModeEnforce)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "id cannot be nil")
 
 	// Test Update with empty policyType
-	_, err = repo.Update(ctx, uuid.New(), "", config, true)
+	_, err = repo.Update(ctx, uuid.New(), "", config, true, model.PolicyModeEnforce)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "policyType cannot be empty")
 }
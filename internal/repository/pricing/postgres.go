@@ -0,0 +1,107 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/db"
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type postgresRepo struct {
+	q *db.Queries
+}
+
+func NewPostgresRepo(q *db.Queries) Repository {
+	return &postgresRepo{q: q}
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, id uuid.UUID) (*model.ModelPrice, error) {
+	row, err := r.q.GetModelPrice(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return toModelPrice(row), nil
+}
+
+func (r *postgresRepo) GetEffective(ctx context.Context, orgID uuid.UUID, provider, modelName, deploymentType string, at time.Time) (*model.ModelPrice, error) {
+	row, err := r.q.GetEffectiveModelPrice(ctx, db.GetEffectiveModelPriceParams{
+		OrgID:          orgID,
+		Provider:       provider,
+		ModelName:      modelName,
+		DeploymentType: deploymentType,
+		EffectiveFrom:  pgtype.Timestamptz{Time: at, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return toModelPrice(row), nil
+}
+
+func (r *postgresRepo) List(ctx context.Context, orgID uuid.UUID, limit, offset int) ([]*model.ModelPrice, error) {
+	rows, err := r.q.ListModelPrices(ctx, db.ListModelPricesParams{
+		OrgID:  orgID,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.ModelPrice, len(rows))
+	for i, row := range rows {
+		result[i] = toModelPrice(row)
+	}
+	return result, nil
+}
+
+func (r *postgresRepo) Create(ctx context.Context, p model.ModelPrice) (*model.ModelPrice, error) {
+	row, err := r.q.CreateModelPrice(ctx, db.CreateModelPriceParams{
+		OrgID:                 p.OrgID,
+		Provider:              p.Provider,
+		ModelName:             p.ModelName,
+		DeploymentType:        p.DeploymentType,
+		Currency:              p.Currency,
+		InputPerMillion:       p.InputPerMillion,
+		CachedInputPerMillion: p.CachedInputPerMillion,
+		OutputPerMillion:      p.OutputPerMillion,
+		PerImage:              p.PerImage,
+		PerAudioSecond:        p.PerAudioSecond,
+		EffectiveFrom:         pgtype.Timestamptz{Time: p.EffectiveFrom, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toModelPrice(row), nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.q.DeleteModelPrice(ctx, id)
+}
+
+func toModelPrice(row db.ModelPrice) *model.ModelPrice {
+	return &model.ModelPrice{
+		ID:                    row.ID,
+		OrgID:                 row.OrgID,
+		Provider:              row.Provider,
+		ModelName:             row.ModelName,
+		DeploymentType:        row.DeploymentType,
+		Currency:              row.Currency,
+		InputPerMillion:       row.InputPerMillion,
+		CachedInputPerMillion: row.CachedInputPerMillion,
+		OutputPerMillion:      row.OutputPerMillion,
+		PerImage:              row.PerImage,
+		PerAudioSecond:        row.PerAudioSecond,
+		EffectiveFrom:         row.EffectiveFrom.Time,
+		CreatedAt:             row.CreatedAt.Time,
+	}
+}
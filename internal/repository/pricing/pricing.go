@@ -0,0 +1,30 @@
+// Package pricing persists the versioned model price list used to cost usage.
+package pricing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when no price matches the lookup.
+var ErrNotFound = errors.New("model price not found")
+
+type Reader interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*model.ModelPrice, error)
+	GetEffective(ctx context.Context, orgID uuid.UUID, provider, modelName, deploymentType string, at time.Time) (*model.ModelPrice, error)
+	List(ctx context.Context, orgID uuid.UUID, limit, offset int) ([]*model.ModelPrice, error)
+}
+
+type Writer interface {
+	Create(ctx context.Context, price model.ModelPrice) (*model.ModelPrice, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type Repository interface {
+	Reader
+	Writer
+}
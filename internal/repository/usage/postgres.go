@@ -3,10 +3,12 @@ package usage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/WebDeveloperBen/ai-gateway/internal/db"
 	"github.com/WebDeveloperBen/ai-gateway/internal/model"
+	"github.com/WebDeveloperBen/ai-gateway/internal/repository"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -82,12 +84,28 @@ func (r *postgresRepo) SumTokensByAppID(ctx context.Context, appID uuid.UUID, st
 		return nil, err
 	}
 
-	return &TokenSummary{
+	costs, err := r.q.SumCostByApp(ctx, db.SumCostByAppParams{
+		AppID:       appID,
+		Timestamp:   pgtype.Timestamptz{Time: start, Valid: true},
+		Timestamp_2: pgtype.Timestamptz{Time: end, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	summary := &TokenSummary{
 		TotalPromptTokens:     r.convertInterfaceToInt(sum.TotalPromptTokens),
 		TotalCompletionTokens: r.convertInterfaceToInt(sum.TotalCompletionTokens),
 		TotalTokens:           r.convertInterfaceToInt(sum.TotalTokens),
 		RequestCount:          int(sum.RequestCount),
-	}, nil
+		UnpricedCount:         int(sum.UnpricedCount),
+	}
+	for _, c := range costs {
+		summary.Costs = append(summary.Costs, CostTotal{
+			Currency:   repository.DerefString(c.Currency),
+			CostMicros: c.TotalCostMicros,
+		})
+	}
+	return summary, nil
 }
 
 func (r *postgresRepo) SumTokensByOrgID(ctx context.Context, orgID uuid.UUID, start, end time.Time) (*TokenSummary, error) {
@@ -100,12 +118,28 @@ func (r *postgresRepo) SumTokensByOrgID(ctx context.Context, orgID uuid.UUID, st
 		return nil, err
 	}
 
-	return &TokenSummary{
+	costs, err := r.q.SumCostByOrg(ctx, db.SumCostByOrgParams{
+		OrgID:       orgID,
+		Timestamp:   pgtype.Timestamptz{Time: start, Valid: true},
+		Timestamp_2: pgtype.Timestamptz{Time: end, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	summary := &TokenSummary{
 		TotalPromptTokens:     r.convertInterfaceToInt(sum.TotalPromptTokens),
 		TotalCompletionTokens: r.convertInterfaceToInt(sum.TotalCompletionTokens),
 		TotalTokens:           r.convertInterfaceToInt(sum.TotalTokens),
 		RequestCount:          int(sum.RequestCount),
-	}, nil
+		UnpricedCount:         int(sum.UnpricedCount),
+	}
+	for _, c := range costs {
+		summary.Costs = append(summary.Costs, CostTotal{
+			Currency:   repository.DerefString(c.Currency),
+			CostMicros: c.TotalCostMicros,
+		})
+	}
+	return summary, nil
 }
 
 func (r *postgresRepo) GetUsageByModel(ctx context.Context, appID uuid.UUID, start, end time.Time, limit, offset int) ([]*ModelUsageSummary, error) {
@@ -158,11 +192,19 @@ func (r *postgresRepo) Create(ctx context.Context, metric *model.UsageMetric) er
 		RequestSizeBytes:  int32(metric.RequestSizeBytes),
 		ResponseSizeBytes: int32(metric.ResponseSizeBytes),
 		Timestamp:         pgtype.Timestamptz{Time: metric.Timestamp, Valid: true},
+		Estimated:         metric.Estimated,
+		CostMicros:        pgtype.Int8{Int64: derefInt64(metric.CostMicros), Valid: metric.CostMicros != nil},
+		Currency:          nullableString(metric.Currency),
+		Metadata:          encodeMetadata(metric.Metadata),
 	})
 	return err
 }
 
 func (r *postgresRepo) convertToModel(metric db.UsageMetric) *model.UsageMetric {
+	var costMicros *int64
+	if metric.CostMicros.Valid {
+		costMicros = &metric.CostMicros.Int64
+	}
 	return &model.UsageMetric{
 		ID:                metric.ID,
 		OrgID:             metric.OrgID,
@@ -177,9 +219,34 @@ func (r *postgresRepo) convertToModel(metric db.UsageMetric) *model.UsageMetric
 		RequestSizeBytes:  int(metric.RequestSizeBytes),
 		ResponseSizeBytes: int(metric.ResponseSizeBytes),
 		Timestamp:         metric.Timestamp.Time,
+		Estimated:         metric.Estimated,
+		CostMicros:        costMicros,
+		Currency:          repository.DerefString(metric.Currency),
+		Metadata:          decodeMetadata(metric.Metadata),
 	}
 }
 
+// encodeMetadata marshals usage metadata; nil is stored as an empty object
+func encodeMetadata(metadata map[string]any) []byte {
+	if len(metadata) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// decodeMetadata unmarshals usage metadata, returning nil when it is empty
+func decodeMetadata(raw []byte) map[string]any {
+	var metadata map[string]any
+	if err := json.Unmarshal(raw, &metadata); err != nil || len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
 func (r *postgresRepo) convertInterfaceToInt(val interface{}) int {
 	if val == nil {
 		return 0
@@ -197,3 +264,17 @@ func (r *postgresRepo) convertInterfaceToInt(val interface{}) int {
 		return 0
 	}
 }
+
+func derefInt64(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
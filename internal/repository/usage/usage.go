@@ -31,6 +31,14 @@ type TokenSummary struct {
 	TotalCompletionTokens int
 	TotalTokens           int
 	RequestCount          int
+	UnpricedCount         int // requests with no price in force when recorded
+	Costs                 []CostTotal
+}
+
+// CostTotal is the summed cost of priced requests in one currency
+type CostTotal struct {
+	Currency   string
+	CostMicros int64
 }
 
 type ModelUsageSummary struct {
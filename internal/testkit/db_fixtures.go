@@ -67,6 +67,7 @@ func (df *DBFixtures) CreateTestPolicy(t *testing.T, orgID, appID uuid.UUID, pol
 		PolicyType: string(policyType),
 		Config:     []byte(config),
 		Enabled:    true,
+		Mode:       string(model.PolicyModeEnforce),
 	})
 	if err != nil {
 		t.Fatalf("Failed to create test policy: %v", err)